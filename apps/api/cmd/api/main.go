@@ -3,15 +3,30 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"keepup/apps/api/internal/config"
 	"keepup/apps/api/internal/database"
 	"keepup/apps/api/internal/httpapi"
+	"keepup/apps/api/internal/live"
+	"keepup/apps/api/internal/mapmatch"
+	"keepup/apps/api/internal/metrics"
+	"keepup/apps/api/internal/ratelimit"
 	"keepup/apps/api/internal/routes"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	routeEventPruneInterval = time.Minute
+	clusterHubRestartMin    = time.Second
+	clusterHubRestartMax    = 30 * time.Second
 )
 
 func main() {
@@ -33,8 +48,44 @@ func main() {
 	}
 	defer dbPool.Close()
 
-	routeService := routes.NewService(routes.NewPostgresRepository(dbPool), cfg.Routes.DefaultMaxTrackingMembers)
-	handler := httpapi.NewHandler(logger, cfg.App, dbPool, routeService)
+	apiMetrics := metrics.New()
+	hubOptions := []live.HubOption{live.WithMetrics(apiMetrics)}
+	if cfg.Live.SlowConsumerPolicy == config.LiveSlowConsumerDisconnect {
+		hubOptions = append(hubOptions, live.WithSlowConsumerDisconnect())
+	}
+	liveHub, closeLiveHub, err := newLiveHub(ctx, logger, cfg.Live, dbPool, hubOptions...)
+	if err != nil {
+		logger.Error("initialize live hub", "error", err)
+		os.Exit(1)
+	}
+	defer closeLiveHub()
+
+	routeService := routes.NewService(routes.NewPostgresRepository(dbPool), cfg.Routes, routes.WithMetrics(apiMetrics))
+	joinLimiter := ratelimit.NewJoinLimiter(ratelimit.NewMemoryStore(), ratelimit.JoinPolicy{
+		PerIP:    ratelimit.Bucket{Burst: cfg.RateLimit.JoinIPAttempts, Window: cfg.RateLimit.JoinIPWindow},
+		PerRoute: ratelimit.Bucket{Burst: cfg.RateLimit.JoinRouteAttempts, Window: cfg.RateLimit.JoinRouteWindow},
+		Lockout: ratelimit.Lockout{
+			FreeFailures: cfg.RateLimit.PasswordFreeFailures,
+			Base:         cfg.RateLimit.PasswordLockoutBase,
+			Max:          cfg.RateLimit.PasswordLockoutMax,
+		},
+	})
+	handler := httpapi.NewHandler(logger, cfg.App, dbPool, routeService,
+		httpapi.WithLiveHub(liveHub),
+		httpapi.WithMetrics(apiMetrics),
+		httpapi.WithJoinLimiter(joinLimiter),
+	)
+	go func() {
+		if err := httpapi.RunPresenceSweeper(ctx, logger, cfg.App, routeService, httpapi.WithLiveHub(liveHub)); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("presence sweeper stopped", "error", err)
+		}
+	}()
+
+	go runRouteEventPruning(ctx, logger, routeService)
+
+	if cfg.MapMatching.OSRMURL != "" {
+		go runMapMatching(ctx, logger, cfg.MapMatching, routeService)
+	}
 
 	if err := httpapi.Serve(ctx, logger, cfg.App, handler); err != nil && !errors.Is(err, context.Canceled) {
 		logger.Error("serve api", "error", err)
@@ -42,6 +93,114 @@ func main() {
 	}
 }
 
+func newLiveHub(ctx context.Context, logger *slog.Logger, cfg config.LiveConfig, dbPool *pgxpool.Pool, opts ...live.HubOption) (live.Hub, func(), error) {
+	switch cfg.Backend {
+	case config.LiveBackendRedis:
+		options, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse redis url: %w", err)
+		}
+
+		client := redis.NewClient(options)
+		if err := client.Ping(ctx).Err(); err != nil {
+			_ = client.Close()
+			return nil, nil, fmt.Errorf("ping redis: %w", err)
+		}
+
+		broker := live.NewRedisBroker(client)
+		hub := live.NewClusterHub(logger, broker, broker, opts...)
+		runClusterHub(ctx, logger, hub)
+		logger.Info("redis live hub started")
+
+		return hub, func() {
+			_ = client.Close()
+		}, nil
+	case config.LiveBackendPostgres:
+		broker := live.NewPostgresBroker(logger, dbPool)
+		hub := live.NewClusterHub(logger, broker, broker, opts...)
+		runClusterHub(ctx, logger, hub)
+		logger.Info("postgres live hub started")
+
+		return hub, func() {}, nil
+	default:
+		return live.NewLocalHub(opts...), func() {}, nil
+	}
+}
+
+// runClusterHub keeps the hub's relay loop running until ctx ends. The loop also refreshes this
+// instance's connection claims, so it is restarted with backoff instead of left stopped.
+func runClusterHub(ctx context.Context, logger *slog.Logger, hub *live.ClusterHub) {
+	go func() {
+		backoff := clusterHubRestartMin
+		for {
+			startedAt := time.Now()
+			err := hub.Run(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			// A run that stayed up for a while starts the backoff over.
+			if time.Since(startedAt) > clusterHubRestartMax {
+				backoff = clusterHubRestartMin
+			}
+			logger.Error("live hub stopped, restarting", "error", err, "retry_in", backoff)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, clusterHubRestartMax)
+		}
+	}()
+}
+
+// runRouteEventPruning periodically deletes route events that are past the reconnect catch-up window.
+// Every replica prunes; the deletes are idempotent.
+func runRouteEventPruning(ctx context.Context, logger *slog.Logger, routeService *routes.Service) {
+	ticker := time.NewTicker(routeEventPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := routeService.PruneRouteEvents(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("route event pruning failed", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				logger.Debug("pruned route events", "deleted_events", deleted)
+			}
+		}
+	}
+}
+
+// runMapMatching periodically derives snapped geometry for segments that gained raw points.
+func runMapMatching(ctx context.Context, logger *slog.Logger, cfg config.MapMatchingConfig, routeService *routes.Service) {
+	matcher := mapmatch.NewOSRMClient(cfg.OSRMURL)
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	logger.Info("map matching started", "osrm_url", cfg.OSRMURL, "interval", cfg.Interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapped, err := routeService.SnapSegments(ctx, matcher)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("map matching failed", "error", err, "snapped_segments", snapped)
+				continue
+			}
+			if snapped > 0 {
+				logger.Debug("map matching snapped segments", "snapped_segments", snapped)
+			}
+		}
+	}
+}
+
 func newLogger(env string) *slog.Logger {
 	level := slog.LevelInfo
 	if env == "development" {
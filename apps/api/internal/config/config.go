@@ -3,8 +3,12 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"net/url"
 	"os"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,27 +17,91 @@ const (
 	defaultAppPort               = "8080"
 	defaultDatabaseStartupWindow = 20 * time.Second
 	defaultWebSocketAuthTimeout  = 5 * time.Second
+	defaultWebSocketMaxMessage   = 256 << 10
+	defaultWebSocketMessageRate  = 20
+	defaultWebSocketPositionRate = 5
+	defaultWebSocketBatchRate    = 1000
+	defaultWebSocketViolations   = 20
 	defaultTrackingStaleAfter    = 20 * time.Second
 	defaultTrackingOfflineAfter  = 5 * time.Minute
 	defaultSpectatorOfflineAfter = 20 * time.Second
+	defaultPresenceSweepInterval = 10 * time.Second
 	defaultMaxTrackingMembers    = 10
+	defaultPositionMaxAccuracyM  = 100
+	defaultChatMessageMaxLength  = 500
+	defaultChatRateLimitMessages = 5
+	defaultChatRateLimitWindow   = 10 * time.Second
+	defaultEventCatchUpMaxEvents = 500
+	defaultEventCatchUpMaxAge    = 10 * time.Minute
+	defaultTrackingReservation   = time.Minute
+	defaultMapMatchingInterval   = time.Minute
+	defaultJoinIPAttempts        = 10
+	defaultJoinIPWindow          = time.Minute
+	defaultJoinRouteAttempts     = 30
+	defaultJoinRouteWindow       = time.Minute
+	defaultPasswordFreeFailures  = 5
+	defaultPasswordLockoutBase   = 30 * time.Second
+	defaultPasswordLockoutMax    = time.Hour
+	defaultLiveBackend           = LiveBackendMemory
+	defaultLiveSlowConsumer      = LiveSlowConsumerResync
 )
 
+const (
+	// LiveBackendMemory keeps live route rooms inside one API process.
+	LiveBackendMemory = "memory"
+	// LiveBackendRedis shares live route rooms between API instances through Redis.
+	LiveBackendRedis = "redis"
+	// LiveBackendPostgres shares live route rooms between API instances through PostgreSQL LISTEN/NOTIFY.
+	LiveBackendPostgres = "postgres"
+
+	// LiveSlowConsumerResync keeps a lagging live connection open and sends it resync_required.
+	LiveSlowConsumerResync = "resync"
+	// LiveSlowConsumerDisconnect closes a live connection as soon as it misses an event.
+	LiveSlowConsumerDisconnect = "disconnect"
+)
+
+// defaultPositionMaxSpeedMPS holds generous per-transport-mode speed ceilings used to reject impossible jumps.
+var defaultPositionMaxSpeedMPS = map[string]float64{
+	"walking":  10,
+	"bicycle":  25,
+	"car":      70,
+	"bus":      45,
+	"train":    100,
+	"boat":     40,
+	"airplane": 350,
+}
+
 // Config contains the full API runtime configuration.
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	Routes   RouteConfig
+	App         AppConfig
+	Database    DatabaseConfig
+	Routes      RouteConfig
+	Live        LiveConfig
+	MapMatching MapMatchingConfig
+	RateLimit   RateLimitConfig
 }
 
-// AppConfig contains HTTP server settings.
+// AppConfig contains HTTP server settings. AllowedOrigins are browser origins such as
+// https://keepup.example that may call the API and open live connections; a "*" matches any run of
+// characters except "/" (https://*.keepup.example). AllowAllOrigins accepts every origin and is only
+// set in development when no origins are configured. WebSocketMaxMessageBytes caps one inbound live
+// message, and the rate limits apply to each live connection; the per-second position limit counts
+// every position_update, and position_batch samples draw from the separate per-minute batch budget.
 type AppConfig struct {
-	Env                   string
-	Port                  string
-	WebSocketAuthTimeout  time.Duration
-	TrackingStaleAfter    time.Duration
-	TrackingOfflineAfter  time.Duration
-	SpectatorOfflineAfter time.Duration
+	Env                              string
+	Port                             string
+	AllowedOrigins                   []string
+	AllowAllOrigins                  bool
+	WebSocketAuthTimeout             time.Duration
+	WebSocketMaxMessageBytes         int64
+	WebSocketMessagesPerSecond       int
+	WebSocketPositionsPerSecond      int
+	WebSocketBatchPositionsPerMinute int
+	WebSocketMaxRateViolations       int
+	TrackingStaleAfter               time.Duration
+	TrackingOfflineAfter             time.Duration
+	SpectatorOfflineAfter            time.Duration
+	PresenceSweepInterval            time.Duration
 }
 
 // DatabaseConfig contains PostgreSQL connection settings.
@@ -42,9 +110,45 @@ type DatabaseConfig struct {
 	StartupTimeout time.Duration
 }
 
+// LiveConfig selects the live hub backend.
+type LiveConfig struct {
+	Backend            string
+	RedisURL           string
+	SlowConsumerPolicy string
+}
+
+// MapMatchingConfig controls the background job that derives snapped geometry from raw points.
+// An empty OSRMURL disables map matching.
+type MapMatchingConfig struct {
+	OSRMURL  string
+	Interval time.Duration
+}
+
+// RateLimitConfig throttles route join attempts, where route passwords are checked. Each client IP
+// and each route may make the given number of attempts per window, and more than
+// PasswordFreeFailures wrong passwords lock the client out of the route for PasswordLockoutBase,
+// doubling with each further failure up to PasswordLockoutMax.
+type RateLimitConfig struct {
+	JoinIPAttempts       int
+	JoinIPWindow         time.Duration
+	JoinRouteAttempts    int
+	JoinRouteWindow      time.Duration
+	PasswordFreeFailures int
+	PasswordLockoutBase  time.Duration
+	PasswordLockoutMax   time.Duration
+}
+
 // RouteConfig contains route lifecycle defaults.
 type RouteConfig struct {
 	DefaultMaxTrackingMembers int
+	PositionMaxAccuracyM      float64
+	PositionMaxSpeedMPS       map[string]float64
+	ChatMessageMaxLength      int
+	ChatRateLimitMessages     int
+	ChatRateLimitWindow       time.Duration
+	EventCatchUpMaxEvents     int
+	EventCatchUpMaxAge        time.Duration
+	TrackingReservationWindow time.Duration
 }
 
 // Load reads the KeepUp API configuration from the environment.
@@ -60,6 +164,14 @@ func Load() (Config, error) {
 		Routes: RouteConfig{
 			DefaultMaxTrackingMembers: defaultMaxTrackingMembers,
 		},
+		Live: LiveConfig{
+			Backend:            valueOrDefault("LIVE_BACKEND", defaultLiveBackend),
+			RedisURL:           valueOrDefault("REDIS_URL", ""),
+			SlowConsumerPolicy: valueOrDefault("LIVE_SLOW_CONSUMER_POLICY", defaultLiveSlowConsumer),
+		},
+		MapMatching: MapMatchingConfig{
+			OSRMURL: strings.TrimRight(valueOrDefault("MAP_MATCHING_OSRM_URL", ""), "/"),
+		},
 	}
 
 	if cfg.Database.URL == "" {
@@ -84,6 +196,37 @@ func Load() (Config, error) {
 
 	cfg.App.WebSocketAuthTimeout = webSocketAuthTimeout
 
+	webSocketMaxMessage, err := positiveIntOrDefault("WEBSOCKET_MAX_MESSAGE_BYTES", defaultWebSocketMaxMessage)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	cfg.App.WebSocketMaxMessageBytes = int64(webSocketMaxMessage)
+
+	if cfg.App.WebSocketMessagesPerSecond, err = positiveIntOrDefault("WEBSOCKET_MESSAGES_PER_SECOND", defaultWebSocketMessageRate); err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	if cfg.App.WebSocketPositionsPerSecond, err = positiveIntOrDefault("WEBSOCKET_POSITIONS_PER_SECOND", defaultWebSocketPositionRate); err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	if cfg.App.WebSocketBatchPositionsPerMinute, err = positiveIntOrDefault("WEBSOCKET_BATCH_POSITIONS_PER_MINUTE", defaultWebSocketBatchRate); err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	if cfg.App.WebSocketMaxRateViolations, err = positiveIntOrDefault("WEBSOCKET_MAX_RATE_VIOLATIONS", defaultWebSocketViolations); err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+
+	allowedOrigins, err := parseOrigins(valueOrDefault("ALLOWED_ORIGINS", ""))
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	cfg.App.AllowedOrigins = allowedOrigins
+	if len(allowedOrigins) == 0 {
+		if cfg.App.Env != defaultAppEnv {
+			return Config{}, fmt.Errorf("load config: ALLOWED_ORIGINS is required when APP_ENV is not %s", defaultAppEnv)
+		}
+		cfg.App.AllowAllOrigins = true
+	}
+
 	trackingStaleAfter, err := positiveDurationOrDefault("ROUTES_TRACKING_STALE_AFTER", defaultTrackingStaleAfter)
 	if err != nil {
 		return Config{}, fmt.Errorf("load config: %w", err)
@@ -102,6 +245,12 @@ func Load() (Config, error) {
 	}
 	cfg.App.SpectatorOfflineAfter = spectatorOfflineAfter
 
+	presenceSweepInterval, err := positiveDurationOrDefault("ROUTES_PRESENCE_SWEEP_INTERVAL", defaultPresenceSweepInterval)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	cfg.App.PresenceSweepInterval = presenceSweepInterval
+
 	maxTrackingMembers, err := intOrDefault("DEFAULT_MAX_TRACKING_MEMBERS", defaultMaxTrackingMembers)
 	if err != nil {
 		return Config{}, fmt.Errorf("load config: %w", err)
@@ -113,9 +262,166 @@ func Load() (Config, error) {
 
 	cfg.Routes.DefaultMaxTrackingMembers = maxTrackingMembers
 
+	maxAccuracy, err := positiveFloatOrDefault("ROUTES_POSITION_MAX_ACCURACY_M", defaultPositionMaxAccuracyM)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	cfg.Routes.PositionMaxAccuracyM = maxAccuracy
+
+	cfg.Routes.PositionMaxSpeedMPS = make(map[string]float64, len(defaultPositionMaxSpeedMPS))
+	for transportMode, fallback := range defaultPositionMaxSpeedMPS {
+		key := "ROUTES_POSITION_MAX_SPEED_" + strings.ToUpper(transportMode) + "_MPS"
+		maxSpeed, err := positiveFloatOrDefault(key, fallback)
+		if err != nil {
+			return Config{}, fmt.Errorf("load config: %w", err)
+		}
+		cfg.Routes.PositionMaxSpeedMPS[transportMode] = maxSpeed
+	}
+
+	chatMaxLength, err := intOrDefault("ROUTES_CHAT_MESSAGE_MAX_LENGTH", defaultChatMessageMaxLength)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	if chatMaxLength <= 0 {
+		return Config{}, fmt.Errorf("load config: ROUTES_CHAT_MESSAGE_MAX_LENGTH must be greater than zero")
+	}
+	cfg.Routes.ChatMessageMaxLength = chatMaxLength
+
+	chatRateLimitMessages, err := intOrDefault("ROUTES_CHAT_RATE_LIMIT_MESSAGES", defaultChatRateLimitMessages)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	if chatRateLimitMessages <= 0 {
+		return Config{}, fmt.Errorf("load config: ROUTES_CHAT_RATE_LIMIT_MESSAGES must be greater than zero")
+	}
+	cfg.Routes.ChatRateLimitMessages = chatRateLimitMessages
+
+	chatRateLimitWindow, err := positiveDurationOrDefault("ROUTES_CHAT_RATE_LIMIT_WINDOW", defaultChatRateLimitWindow)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	cfg.Routes.ChatRateLimitWindow = chatRateLimitWindow
+
+	eventCatchUpMaxEvents, err := intOrDefault("ROUTES_EVENT_CATCH_UP_MAX_EVENTS", defaultEventCatchUpMaxEvents)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	if eventCatchUpMaxEvents <= 0 {
+		return Config{}, fmt.Errorf("load config: ROUTES_EVENT_CATCH_UP_MAX_EVENTS must be greater than zero")
+	}
+	cfg.Routes.EventCatchUpMaxEvents = eventCatchUpMaxEvents
+
+	eventCatchUpMaxAge, err := positiveDurationOrDefault("ROUTES_EVENT_CATCH_UP_MAX_AGE", defaultEventCatchUpMaxAge)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	cfg.Routes.EventCatchUpMaxAge = eventCatchUpMaxAge
+
+	trackingReservationWindow, err := positiveDurationOrDefault("ROUTES_TRACKING_RESERVATION_WINDOW", defaultTrackingReservation)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	cfg.Routes.TrackingReservationWindow = trackingReservationWindow
+
+	mapMatchingInterval, err := positiveDurationOrDefault("MAP_MATCHING_INTERVAL", defaultMapMatchingInterval)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	cfg.MapMatching.Interval = mapMatchingInterval
+
+	rateLimit, err := loadRateLimitConfig()
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	cfg.RateLimit = rateLimit
+
+	switch cfg.Live.Backend {
+	case LiveBackendMemory, LiveBackendPostgres:
+	case LiveBackendRedis:
+		if cfg.Live.RedisURL == "" {
+			return Config{}, fmt.Errorf("load config: REDIS_URL is required when LIVE_BACKEND is %s", LiveBackendRedis)
+		}
+	default:
+		return Config{}, fmt.Errorf("load config: unsupported LIVE_BACKEND %q", cfg.Live.Backend)
+	}
+
+	switch cfg.Live.SlowConsumerPolicy {
+	case LiveSlowConsumerResync, LiveSlowConsumerDisconnect:
+	default:
+		return Config{}, fmt.Errorf("load config: unsupported LIVE_SLOW_CONSUMER_POLICY %q", cfg.Live.SlowConsumerPolicy)
+	}
+
+	return cfg, nil
+}
+
+// parseOrigins splits a comma-separated origin list and checks that each entry is a bare
+// scheme://host[:port] origin or pattern.
+func parseOrigins(value string) ([]string, error) {
+	var origins []string
+	for _, entry := range strings.Split(value, ",") {
+		origin := strings.ToLower(strings.TrimRight(strings.TrimSpace(entry), "/"))
+		if origin == "" {
+			continue
+		}
+
+		parsed, err := url.Parse(strings.ReplaceAll(origin, "*", "x"))
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+			return nil, fmt.Errorf("ALLOWED_ORIGINS entry %q must be an origin like https://keepup.example", entry)
+		}
+		if _, err := path.Match(origin, ""); err != nil {
+			return nil, fmt.Errorf("ALLOWED_ORIGINS entry %q: %w", entry, err)
+		}
+
+		origins = append(origins, origin)
+	}
+
+	return origins, nil
+}
+
+func loadRateLimitConfig() (RateLimitConfig, error) {
+	var cfg RateLimitConfig
+	var err error
+
+	if cfg.JoinIPAttempts, err = positiveIntOrDefault("RATE_LIMIT_JOIN_IP_ATTEMPTS", defaultJoinIPAttempts); err != nil {
+		return RateLimitConfig{}, err
+	}
+	if cfg.JoinIPWindow, err = positiveDurationOrDefault("RATE_LIMIT_JOIN_IP_WINDOW", defaultJoinIPWindow); err != nil {
+		return RateLimitConfig{}, err
+	}
+	if cfg.JoinRouteAttempts, err = positiveIntOrDefault("RATE_LIMIT_JOIN_ROUTE_ATTEMPTS", defaultJoinRouteAttempts); err != nil {
+		return RateLimitConfig{}, err
+	}
+	if cfg.JoinRouteWindow, err = positiveDurationOrDefault("RATE_LIMIT_JOIN_ROUTE_WINDOW", defaultJoinRouteWindow); err != nil {
+		return RateLimitConfig{}, err
+	}
+	if cfg.PasswordFreeFailures, err = positiveIntOrDefault("RATE_LIMIT_PASSWORD_FREE_FAILURES", defaultPasswordFreeFailures); err != nil {
+		return RateLimitConfig{}, err
+	}
+	if cfg.PasswordLockoutBase, err = positiveDurationOrDefault("RATE_LIMIT_PASSWORD_LOCKOUT_BASE", defaultPasswordLockoutBase); err != nil {
+		return RateLimitConfig{}, err
+	}
+	if cfg.PasswordLockoutMax, err = positiveDurationOrDefault("RATE_LIMIT_PASSWORD_LOCKOUT_MAX", defaultPasswordLockoutMax); err != nil {
+		return RateLimitConfig{}, err
+	}
+	if cfg.PasswordLockoutMax < cfg.PasswordLockoutBase {
+		return RateLimitConfig{}, fmt.Errorf("RATE_LIMIT_PASSWORD_LOCKOUT_MAX must not be shorter than RATE_LIMIT_PASSWORD_LOCKOUT_BASE")
+	}
+
 	return cfg, nil
 }
 
+func positiveIntOrDefault(key string, fallback int) (int, error) {
+	value, err := intOrDefault(key, fallback)
+	if err != nil {
+		return 0, err
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("%s must be greater than zero", key)
+	}
+
+	return value, nil
+}
+
 func positiveDurationOrDefault(key string, fallback time.Duration) (time.Duration, error) {
 	duration, err := durationOrDefault(key, fallback)
 	if err != nil {
@@ -128,6 +434,23 @@ func positiveDurationOrDefault(key string, fallback time.Duration) (time.Duratio
 	return duration, nil
 }
 
+func positiveFloatOrDefault(key string, fallback float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", key, err)
+	}
+	if math.IsNaN(parsed) || math.IsInf(parsed, 0) || parsed <= 0 {
+		return 0, fmt.Errorf("%s must be a finite number greater than zero", key)
+	}
+
+	return parsed, nil
+}
+
 func valueOrDefault(key, fallback string) string {
 	value := os.Getenv(key)
 	if value == "" {
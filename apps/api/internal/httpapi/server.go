@@ -8,12 +8,18 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"keepup/apps/api/internal/config"
 	"keepup/apps/api/internal/live"
+	"keepup/apps/api/internal/metrics"
+	"keepup/apps/api/internal/ratelimit"
 	"keepup/apps/api/internal/routes"
 
 	"github.com/coder/websocket"
@@ -22,6 +28,11 @@ import (
 
 const healthCheckTimeout = 2 * time.Second
 const defaultWebSocketAuthTimeout = 5 * time.Second
+const defaultWebSocketMaxMessageBytes = 256 << 10
+const defaultPresenceSweepInterval = 10 * time.Second
+
+// errWebSocketRateLimited ends a live connection that kept exceeding its message rate limits.
+var errWebSocketRateLimited = errors.New("websocket rate limit exceeded")
 
 // HealthChecker reports whether the API dependencies are reachable.
 type HealthChecker interface {
@@ -30,11 +41,13 @@ type HealthChecker interface {
 
 // Server contains the HTTP handler dependencies.
 type Server struct {
-	appConfig config.AppConfig
-	db        HealthChecker
-	liveHub   *live.Hub
-	logger    *slog.Logger
-	routes    RouteService
+	appConfig   config.AppConfig
+	db          HealthChecker
+	joinLimiter *ratelimit.JoinLimiter
+	liveHub     live.Hub
+	logger      *slog.Logger
+	metrics     *metrics.Metrics
+	routes      RouteService
 }
 
 // RouteService contains the first route lifecycle operations served over HTTP.
@@ -43,47 +56,141 @@ type RouteService interface {
 	AccessRoute(context.Context, string) (routes.AccessRouteResult, error)
 	AuthorizeMember(context.Context, string) (routes.AuthorizedMember, error)
 	JoinRoute(context.Context, string, routes.JoinRouteInput) (routes.JoinRouteResult, error)
-	Snapshot(context.Context, string, string) (routes.Snapshot, error)
+	Snapshot(context.Context, string, string, routes.SnapshotQuery) (routes.Snapshot, error)
+	ExportRoute(context.Context, string, string) (routes.RouteExport, error)
+	Replay(context.Context, string, string, routes.ReplayQuery) (routes.ReplayPage, error)
 	StartSharing(context.Context, string, string) (routes.StartSharingResult, error)
 	StopSharing(context.Context, string, string) (routes.StopSharingResult, error)
+	ChangeTransportMode(context.Context, string, string, string) (routes.ChangeTransportModeResult, error)
+	SetTrackingPermission(context.Context, string, string, string, bool) (routes.SetTrackingPermissionResult, error)
+	RemoveMember(context.Context, string, string, string, bool) (routes.RemoveMemberResult, error)
+	SetModerator(context.Context, string, string, string, bool) (routes.SetModeratorResult, error)
+	TransferOwnership(context.Context, string, string, string) (routes.TransferOwnershipResult, error)
+	IssueOwnerToken(context.Context, string, string) (string, error)
+	JoinTrackingWaitlist(context.Context, string, string) (routes.TrackingWaitlistEntry, error)
+	LeaveTrackingWaitlist(context.Context, string, string) error
+	OfferTrackingSlots(context.Context, string) ([]routes.TrackingReservation, error)
+	ExpireTrackingReservations(context.Context) ([]routes.TrackingReservation, error)
 	MarkMemberOnline(context.Context, string, string) (routes.Member, bool, error)
 	MarkMemberStale(context.Context, string, string) (routes.Member, bool, error)
 	MarkMemberOffline(context.Context, string, string) (routes.Member, bool, error)
+	RecordMemberSeen(context.Context, string, string) error
+	SweepPresence(context.Context, routes.PresenceRules, routes.ConnectionChecker) ([]routes.PresenceTransition, error)
 	RecordPosition(context.Context, string, routes.PositionUpdateInput) (routes.PositionUpdateResult, error)
-	UpdateRoute(context.Context, string, string, routes.UpdateRouteInput) (routes.Route, error)
+	RecordPositionBatch(context.Context, string, string, []routes.PositionUpdateInput) (routes.PositionBatchResult, error)
+	UpdateRoute(context.Context, string, string, routes.UpdateRouteInput) (routes.UpdateRouteResult, error)
+	RecordRouteEvent(context.Context, string, string, map[string]any) (routes.RouteEvent, error)
+	RouteEventsSince(context.Context, string, int64) ([]routes.RouteEvent, error)
+	PostChatMessage(context.Context, string, string) (routes.ChatMessage, error)
+	ListChatMessages(context.Context, string, string, routes.ChatMessageQuery) (routes.ChatMessagePage, error)
+	ListRoutePoints(context.Context, string, string, routes.PointPageQuery) (routes.PointPage, error)
 	LeaveRoute(context.Context, string, string) (routes.LeaveRouteResult, error)
 	DeleteRoute(context.Context, string, string) error
 }
 
-// NewHandler builds the KeepUp API HTTP handler tree.
-func NewHandler(logger *slog.Logger, cfg config.AppConfig, db HealthChecker, routeService RouteService) http.Handler {
-	server := &Server{
-		appConfig: cfg,
-		db:        db,
-		liveHub:   live.NewHub(),
-		logger:    logger,
-		routes:    routeService,
+// HandlerOption customizes optional HTTP handler collaborators.
+type HandlerOption func(*Server)
+
+// WithLiveHub replaces the default in-process live hub, for example with a cluster-wide hub.
+func WithLiveHub(hub live.Hub) HandlerOption {
+	return func(s *Server) {
+		s.liveHub = hub
 	}
-	if server.appConfig.WebSocketAuthTimeout <= 0 {
-		server.appConfig.WebSocketAuthTimeout = defaultWebSocketAuthTimeout
+}
+
+// WithMetrics records request, live, and snapshot metrics and serves them on /metrics.
+func WithMetrics(m *metrics.Metrics) HandlerOption {
+	return func(s *Server) {
+		s.metrics = m
 	}
+}
+
+// WithJoinLimiter replaces the default in-memory route join limiter, for example with one backed by a
+// shared store.
+func WithJoinLimiter(limiter *ratelimit.JoinLimiter) HandlerOption {
+	return func(s *Server) {
+		s.joinLimiter = limiter
+	}
+}
+
+// NewHandler builds the KeepUp API HTTP handler tree.
+func NewHandler(logger *slog.Logger, cfg config.AppConfig, db HealthChecker, routeService RouteService, opts ...HandlerOption) http.Handler {
+	server := newServer(logger, cfg, db, routeService, opts...)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", server.handleRoot)
 	mux.HandleFunc("/livez", server.handleLiveness)
 	mux.HandleFunc("/healthz", server.handleHealth)
+	mux.Handle("GET /metrics", server.metrics.Handler())
 	mux.HandleFunc("POST /routes", server.handleCreateRoute)
 	mux.HandleFunc("GET /routes/{code}/access", server.handleRouteAccess)
 	mux.HandleFunc("POST /routes/{code}/members", server.handleCreateRouteMember)
 	mux.HandleFunc("GET /routes/{code}", server.handleRoute)
+	mux.HandleFunc("GET /routes/{code}/export", server.handleExportRoute)
+	mux.HandleFunc("GET /routes/{code}/replay", server.handleReplayRoute)
+	mux.HandleFunc("GET /routes/{code}/messages", server.handleChatMessages)
+	mux.HandleFunc("GET /routes/{code}/points", server.handleRoutePoints)
+	mux.HandleFunc("POST /routes/{code}/positions:batch", server.handleRecordPositionBatch)
 	mux.HandleFunc("PATCH /routes/{code}", server.handleUpdateRoute)
 	mux.HandleFunc("DELETE /routes/{code}", server.handleDeleteRoute)
+	mux.HandleFunc("PUT /routes/{code}/owner", server.handleTransferOwnership)
+	mux.HandleFunc("PATCH /routes/{code}/members/me", server.handleUpdateMember)
 	mux.HandleFunc("DELETE /routes/{code}/members/me", server.handleLeaveRoute)
+	mux.HandleFunc("DELETE /routes/{code}/members/{memberId}", server.handleRemoveMember)
+	mux.HandleFunc("PUT /routes/{code}/members/{memberId}/tracking-permission", server.handleGrantTrackingPermission)
+	mux.HandleFunc("DELETE /routes/{code}/members/{memberId}/tracking-permission", server.handleRevokeTrackingPermission)
+	mux.HandleFunc("PUT /routes/{code}/members/{memberId}/moderator", server.handleAssignModerator)
+	mux.HandleFunc("DELETE /routes/{code}/members/{memberId}/moderator", server.handleUnassignModerator)
 	mux.HandleFunc("GET /ws", server.handleWebSocket)
 
 	return server.withCORS(server.withLogging(mux))
 }
 
+// RunPresenceSweeper periodically applies overdue presence transitions and broadcasts them like the
+// connection timers do. It must share the handler's live hub so disconnected spectators are detected.
+func RunPresenceSweeper(ctx context.Context, logger *slog.Logger, cfg config.AppConfig, routeService RouteService, opts ...HandlerOption) error {
+	server := newServer(logger, cfg, nil, routeService, opts...)
+	if server.appConfig.PresenceSweepInterval <= 0 {
+		server.appConfig.PresenceSweepInterval = defaultPresenceSweepInterval
+	}
+
+	ticker := time.NewTicker(server.appConfig.PresenceSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			server.sweepPresence(ctx)
+		}
+	}
+}
+
+func newServer(logger *slog.Logger, cfg config.AppConfig, db HealthChecker, routeService RouteService, opts ...HandlerOption) *Server {
+	server := &Server{
+		appConfig: cfg,
+		db:        db,
+		liveHub:   live.NewLocalHub(),
+		logger:    logger,
+		routes:    routeService,
+	}
+	for _, opt := range opts {
+		opt(server)
+	}
+	if server.appConfig.WebSocketAuthTimeout <= 0 {
+		server.appConfig.WebSocketAuthTimeout = defaultWebSocketAuthTimeout
+	}
+	if server.appConfig.WebSocketMaxMessageBytes <= 0 {
+		server.appConfig.WebSocketMaxMessageBytes = defaultWebSocketMaxMessageBytes
+	}
+	if server.joinLimiter == nil {
+		server.joinLimiter = ratelimit.NewJoinLimiter(ratelimit.NewMemoryStore(), ratelimit.JoinPolicy{})
+	}
+
+	return server
+}
+
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
@@ -178,18 +285,43 @@ func (s *Server) handleCreateRouteMember(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Join attempts are limited before the password is checked, so guessing costs no bcrypt work
+	// once a client or route is throttled. Limiter store errors are logged and let the join through.
+	clientIP := requestClientIP(r)
+	routeCode := strings.ToUpper(strings.TrimSpace(r.PathValue("code")))
+	retryAfter, err := s.joinLimiter.Allow(r.Context(), clientIP, routeCode)
+	if err != nil {
+		s.logger.Error("join rate limit check failed", "route_code", routeCode, "error", err)
+	}
+	if retryAfter > 0 {
+		s.writeRateLimited(w, retryAfter)
+		return
+	}
+
 	result, err := s.routes.JoinRoute(r.Context(), r.PathValue("code"), routes.JoinRouteInput{
 		ClientID:      request.ClientID,
 		DisplayName:   request.DisplayName,
 		TransportMode: request.TransportMode,
 		Password:      request.Password,
 	})
+	if errors.Is(err, routes.ErrInvalidPassword) {
+		lockedFor, limitErr := s.joinLimiter.PasswordFailed(r.Context(), clientIP, routeCode)
+		if limitErr != nil {
+			s.logger.Error("record join password failure failed", "route_code", routeCode, "error", limitErr)
+		}
+		if lockedFor > 0 {
+			s.logger.Warn("route join locked out", "route_code", routeCode, "remote_addr", clientIP, "locked_for", lockedFor)
+		}
+	}
 	if err != nil {
 		s.writeRouteError(w, err)
 		return
 	}
+	if err := s.joinLimiter.Joined(r.Context(), clientIP, routeCode); err != nil {
+		s.logger.Error("reset join password failures failed", "route_code", routeCode, "error", err)
+	}
 
-	s.broadcastLiveEvent(result.Route.ID, live.Event{
+	s.broadcastLiveEvent(r.Context(), result.Route.ID, result.Member.ID, live.Event{
 		"type":   "member_joined",
 		"member": result.Member,
 	})
@@ -203,7 +335,132 @@ func (s *Server) handleRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.routes.Snapshot(r.Context(), r.PathValue("code"), token)
+	query := routes.SnapshotQuery{
+		Detail: r.URL.Query().Get("detail"),
+	}
+	if tolerance := r.URL.Query().Get("tolerance"); tolerance != "" {
+		parsed, err := strconv.ParseFloat(tolerance, 64)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_query")
+			return
+		}
+		query.ToleranceM = parsed
+	}
+
+	result, err := s.routes.Snapshot(r.Context(), r.PathValue("code"), token, query)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	written := s.writeJSON(w, http.StatusOK, result)
+	s.metrics.ObserveSnapshot(written, snapshotPointCount(result))
+}
+
+func (s *Server) handleExportRoute(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	format, err := routes.ParseExportFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	result, err := s.routes.ExportRoute(r.Context(), r.PathValue("code"), token)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", routes.ExportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, result.Route.Code, format))
+	w.WriteHeader(http.StatusOK)
+
+	if err := routes.WriteRouteExport(r.Context(), w, result, format); err != nil {
+		s.logger.Error("failed to stream route export", "route_id", result.Route.ID, "format", format, "error", err)
+	}
+}
+
+func (s *Server) handleReplayRoute(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query, err := replayQuery(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_query")
+		return
+	}
+
+	page, err := s.routes.Replay(r.Context(), r.PathValue("code"), token, query)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	events := make([]live.Event, 0, len(page.Events))
+	for _, event := range page.Events {
+		events = append(events, replayLiveEvent(event))
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"routeId":    page.RouteID,
+		"startedAt":  page.StartedAt,
+		"endedAt":    page.EndedAt,
+		"speed":      page.Speed,
+		"events":     events,
+		"nextCursor": page.NextCursor,
+	})
+}
+
+func (s *Server) handleChatMessages(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := routes.ChatMessageQuery{
+		Before: r.URL.Query().Get("before"),
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_query")
+			return
+		}
+		query.Limit = parsed
+	}
+
+	result, err := s.routes.ListChatMessages(r.Context(), r.PathValue("code"), token, query)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleRoutePoints(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query, err := pointPageQuery(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_query")
+		return
+	}
+
+	result, err := s.routes.ListRoutePoints(r.Context(), r.PathValue("code"), token, query)
 	if err != nil {
 		s.writeRouteError(w, err)
 		return
@@ -241,13 +498,119 @@ func (s *Server) handleUpdateRoute(w http.ResponseWriter, r *http.Request) {
 	}
 
 	eventType := "route_updated"
-	if result.Status == routes.RouteStatusClosed {
+	if result.Route.Status == routes.RouteStatusClosed {
 		eventType = "route_closed"
 	}
-	s.broadcastLiveEvent(result.ID, live.Event{
+	s.broadcastLiveEvent(r.Context(), result.Route.ID, result.Actor.ID, live.Event{
 		"type":  eventType,
-		"route": result,
+		"route": result.Route,
+	})
+	s.writeJSON(w, http.StatusOK, result.Route)
+}
+
+func (s *Server) handleUpdateMember(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request struct {
+		TransportMode string `json:"transportMode"`
+	}
+
+	if err := decodeJSON(r.Body, &request); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	result, err := s.routes.ChangeTransportMode(r.Context(), r.PathValue("code"), token, request.TransportMode)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	s.broadcastTransportChange(r.Context(), result)
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) broadcastTransportChange(ctx context.Context, result routes.ChangeTransportModeResult) {
+	if result.Change == nil {
+		return
+	}
+
+	s.broadcastLiveEvent(ctx, result.Member.RouteID, result.Member.ID, live.Event{
+		"type":   "member_transport_changed",
+		"member": result.Member,
+		"change": result.Change,
 	})
+}
+
+func (s *Server) handleGrantTrackingPermission(w http.ResponseWriter, r *http.Request) {
+	s.handleSetTrackingPermission(w, r, true)
+}
+
+func (s *Server) handleRevokeTrackingPermission(w http.ResponseWriter, r *http.Request) {
+	s.handleSetTrackingPermission(w, r, false)
+}
+
+func (s *Server) handleSetTrackingPermission(w http.ResponseWriter, r *http.Request, granted bool) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	result, err := s.routes.SetTrackingPermission(r.Context(), r.PathValue("code"), token, r.PathValue("memberId"), granted)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	if result.Change != nil {
+		s.broadcastLiveEvent(r.Context(), result.Member.RouteID, result.Actor.ID, live.Event{
+			"type":   "member_tracking_permission_changed",
+			"member": result.Member,
+			"change": result.Change,
+		})
+	}
+	if result.StoppedSharing {
+		s.broadcastLiveEvent(r.Context(), result.Member.RouteID, result.Actor.ID, live.Event{
+			"type":   "member_stopped_sharing",
+			"member": result.Member,
+			"reason": routes.PathSegmentEndReasonPermissionRevoked,
+		})
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleAssignModerator(w http.ResponseWriter, r *http.Request) {
+	s.handleSetModerator(w, r, true)
+}
+
+func (s *Server) handleUnassignModerator(w http.ResponseWriter, r *http.Request) {
+	s.handleSetModerator(w, r, false)
+}
+
+func (s *Server) handleSetModerator(w http.ResponseWriter, r *http.Request, moderator bool) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	result, err := s.routes.SetModerator(r.Context(), r.PathValue("code"), token, r.PathValue("memberId"), moderator)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	if result.Changed {
+		s.broadcastLiveEvent(r.Context(), result.Member.RouteID, result.Actor.ID, live.Event{
+			"type":   "member_role_changed",
+			"member": result.Member,
+		})
+	}
 	s.writeJSON(w, http.StatusOK, result)
 }
 
@@ -264,13 +627,136 @@ func (s *Server) handleLeaveRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.broadcastLiveEvent(result.Member.RouteID, live.Event{
+	s.broadcastLiveEvent(r.Context(), result.Member.RouteID, result.Member.ID, live.Event{
 		"type":   "member_left",
 		"member": result.Member,
 	})
 	s.writeJSON(w, http.StatusOK, result)
 }
 
+func (s *Server) handleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	ban := false
+	if value := r.URL.Query().Get("ban"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_query")
+			return
+		}
+		ban = parsed
+	}
+
+	result, err := s.routes.RemoveMember(r.Context(), r.PathValue("code"), token, r.PathValue("memberId"), ban)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	s.broadcastLiveEvent(r.Context(), result.Member.RouteID, result.Actor.ID, live.Event{
+		"type":   "member_removed",
+		"member": result.Member,
+		"banned": result.Banned,
+	})
+	if _, err := s.liveHub.DisconnectMember(r.Context(), result.Member.RouteID, result.Member.ID); err != nil {
+		s.logger.Error("disconnect removed member failed", "route_id", result.Member.RouteID, "member_id", result.Member.ID, "error", err)
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleTransferOwnership(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request struct {
+		MemberID string `json:"memberId"`
+	}
+
+	if err := decodeJSON(r.Body, &request); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	result, err := s.routes.TransferOwnership(r.Context(), r.PathValue("code"), token, request.MemberID)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	routeID := result.Owner.RouteID
+	s.broadcastLiveEvent(r.Context(), routeID, result.PreviousOwner.ID, live.Event{
+		"type":          "route_owner_changed",
+		"previousOwner": result.PreviousOwner,
+		"owner":         result.Owner,
+	})
+	// The owner token goes only to the recipient's own connections and is never recorded as a route
+	// event. A recipient who is not connected claims a fresh token with claim_owner_token later.
+	if _, err := s.liveHub.SendToMember(r.Context(), routeID, result.Owner.ID, live.Event{
+		"type":       "owner_token_issued",
+		"ownerToken": result.OwnerToken,
+	}); err != nil {
+		s.logger.Error("send owner token failed", "route_id", routeID, "member_id", result.Owner.ID, "error", err)
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleRecordPositionBatch(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var request struct {
+		Positions []json.RawMessage `json:"positions"`
+	}
+
+	if err := decodeJSON(r.Body, &request); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	samples, err := positionBatchInput(request.Positions)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	result, err := s.routes.RecordPositionBatch(r.Context(), r.PathValue("code"), token, samples)
+	if err != nil {
+		s.writeRouteError(w, err)
+		return
+	}
+
+	s.broadcastPositionBatch(r.Context(), result)
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// broadcastPositionBatch announces a stale member's recovery and the backfilled points, one event per segment.
+func (s *Server) broadcastPositionBatch(ctx context.Context, result routes.PositionBatchResult) {
+	if result.RecoveredMember != nil {
+		s.broadcastLiveEvent(ctx, result.RouteID, result.MemberID, live.Event{
+			"type":   "member_back_online",
+			"member": result.RecoveredMember,
+		})
+	}
+	for _, segment := range result.Segments {
+		s.broadcastLiveEvent(ctx, result.RouteID, result.MemberID, live.Event{
+			"type":      "positions_backfilled",
+			"memberId":  result.MemberID,
+			"segmentId": segment.SegmentID,
+			"points":    segment.Points,
+		})
+	}
+}
+
 func (s *Server) handleDeleteRoute(w http.ResponseWriter, r *http.Request) {
 	token := bearerToken(r.Header.Get("Authorization"))
 	if token == "" {
@@ -288,7 +774,8 @@ func (s *Server) handleDeleteRoute(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	connection, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		InsecureSkipVerify: true,
+		InsecureSkipVerify: s.appConfig.AllowAllOrigins,
+		OriginPatterns:     s.appConfig.AllowedOrigins,
 	})
 	if err != nil {
 		s.logger.Error("websocket accept failed", "error", err)
@@ -299,10 +786,12 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			s.logger.Debug("websocket close failed", "error", err)
 		}
 	}()
+	connection.SetReadLimit(s.appConfig.WebSocketMaxMessageBytes)
 
 	authMessage, err := readWebSocketAuth(r.Context(), connection, s.appConfig.WebSocketAuthTimeout)
 	if err != nil {
 		s.logger.Info("websocket authentication failed", "error", err)
+		s.metrics.LiveConnectionRejected("authentication_required")
 		_ = connection.Close(websocket.StatusPolicyViolation, "authentication required")
 		return
 	}
@@ -310,10 +799,12 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	authorized, err := s.routes.AuthorizeMember(r.Context(), authMessage.MemberToken)
 	if err != nil {
 		s.logger.Info("websocket token rejected", "error", err)
+		s.metrics.LiveConnectionRejected("unauthorized")
 		_ = connection.Close(websocket.StatusPolicyViolation, "unauthorized")
 		return
 	}
 	if authorized.Route.Status != routes.RouteStatusActive {
+		s.metrics.LiveConnectionRejected("route_closed")
 		_ = writeWebSocketJSON(r.Context(), connection, live.Event{
 			"type":   "live_connection_rejected",
 			"reason": "route_closed",
@@ -321,7 +812,9 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		_ = connection.Close(websocket.StatusPolicyViolation, "route closed")
 		return
 	}
-	if s.liveHub.HasMemberConnection(authorized.Route.ID, authorized.Member.ID) {
+	subscription, err := s.liveHub.Subscribe(r.Context(), authorized.Route.ID, authorized.Member.ID)
+	if errors.Is(err, live.ErrMemberAlreadyConnected) {
+		s.metrics.LiveConnectionRejected("already_active_connection")
 		_ = writeWebSocketJSON(r.Context(), connection, live.Event{
 			"type":   "live_connection_rejected",
 			"reason": "already_active_connection",
@@ -329,17 +822,33 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		_ = connection.Close(websocket.StatusPolicyViolation, "already active")
 		return
 	}
-
-	subscription := s.liveHub.Subscribe(authorized.Route.ID, authorized.Member.ID)
+	if err != nil {
+		s.logger.Error("live subscription failed", "route_id", authorized.Route.ID, "member_id", authorized.Member.ID, "error", err)
+		s.metrics.LiveConnectionRejected("live_unavailable")
+		_ = connection.Close(websocket.StatusInternalError, "live unavailable")
+		return
+	}
 	defer subscription.Close()
+	s.metrics.LiveConnectionOpened()
+	defer s.metrics.LiveConnectionClosed()
 
+	connections, err := s.liveHub.RouteConnectionCount(r.Context(), authorized.Route.ID)
+	if err != nil {
+		s.logger.Debug("count route connections failed", "route_id", authorized.Route.ID, "error", err)
+	}
 	s.logger.Info("websocket subscribed",
 		"route_id", authorized.Route.ID,
 		"route_code", authorized.Route.Code,
 		"member_id", authorized.Member.ID,
-		"connections", s.liveHub.RouteConnectionCount(authorized.Route.ID),
+		"connections", connections,
 	)
 
+	limiter := ratelimit.NewConnectionLimiter(ratelimit.ConnectionPolicy{
+		MessagesPerSecond:       s.appConfig.WebSocketMessagesPerSecond,
+		PositionsPerSecond:      s.appConfig.WebSocketPositionsPerSecond,
+		BatchPositionsPerMinute: s.appConfig.WebSocketBatchPositionsPerMinute,
+		MaxViolations:           s.appConfig.WebSocketMaxRateViolations,
+	})
 	if err := writeWebSocketJSON(r.Context(), connection, map[string]any{
 		"type": "connection_established",
 		"route": map[string]any{
@@ -351,17 +860,34 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			"id":     authorized.Member.ID,
 			"status": authorized.Member.Status,
 		},
+		"limits": map[string]any{
+			"positionsPerSecond": limiter.PositionsPerSecond(),
+			"batchPositions":     limiter.BatchPositions(),
+		},
 	}); err != nil {
 		s.logger.Debug("websocket initial write failed", "error", err)
 		return
 	}
 
+	var replayed map[int64]struct{}
+	if authMessage.LastEventSeq > 0 {
+		replayed, err = s.replayMissedEvents(r.Context(), connection, authorized.Route.ID, authMessage.LastEventSeq)
+		if err != nil {
+			s.logger.Debug("websocket catch-up write failed", "error", err)
+			return
+		}
+	}
+
+	if err := s.routes.RecordMemberSeen(r.Context(), authorized.Route.ID, authorized.Member.ID); err != nil {
+		s.logger.Error("record websocket member seen failed", "error", err)
+	}
+
 	disconnectedStatus := authorized.Member.Status
 	if member, changed, err := s.routes.MarkMemberOnline(r.Context(), authorized.Route.ID, authorized.Member.ID); err != nil {
 		s.logger.Error("mark websocket member online failed", "error", err)
 	} else if changed {
 		disconnectedStatus = member.Status
-		s.broadcastLiveEvent(authorized.Route.ID, live.Event{
+		s.broadcastLiveEvent(r.Context(), authorized.Route.ID, authorized.Member.ID, live.Event{
 			"type":   "member_back_online",
 			"member": member,
 		})
@@ -372,6 +898,9 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	trackingHealthCh := make(chan struct{}, 1)
 	staleOfflineCh := make(chan struct{}, 1)
 	defer func() {
+		if err := s.routes.RecordMemberSeen(context.Background(), authorized.Route.ID, authorized.Member.ID); err != nil {
+			s.logger.Error("record disconnected member seen failed", "error", err)
+		}
 		s.handleDisconnectedMember(context.Background(), authorized.Route.ID, authorized.Member.ID, disconnectedStatus)
 	}()
 	go func() {
@@ -387,6 +916,18 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			allowed, abusive := allowLiveMessage(limiter, message)
+			if abusive {
+				readErrCh <- errWebSocketRateLimited
+				return
+			}
+			if !allowed {
+				if !enqueueLiveEvent(r.Context(), outboundEventCh, rateLimitedEvent(message)) {
+					return
+				}
+				continue
+			}
+
 			switch message.Type {
 			case "start_sharing":
 				wasTracking := disconnectedStatus == routes.MemberStatusTracking
@@ -410,7 +951,7 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				}
 				disconnectedStatus = result.Member.Status
 				resetTimer(trackingHealthCh)
-				s.broadcastLiveEvent(result.Member.RouteID, live.Event{
+				s.broadcastLiveEvent(r.Context(), result.Member.RouteID, result.Member.ID, live.Event{
 					"type":    eventType,
 					"member":  result.Member,
 					"segment": result.Segment,
@@ -432,7 +973,7 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 					continue
 				}
 				disconnectedStatus = result.Member.Status
-				s.broadcastLiveEvent(result.Member.RouteID, live.Event{
+				s.broadcastLiveEvent(r.Context(), result.Member.RouteID, result.Member.ID, live.Event{
 					"type":   "member_stopped_sharing",
 					"member": result.Member,
 				})
@@ -461,18 +1002,117 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 				if result.RecoveredMember != nil {
 					disconnectedStatus = result.RecoveredMember.Status
-					s.broadcastLiveEvent(result.RouteID, live.Event{
+					s.broadcastLiveEvent(r.Context(), result.RouteID, result.MemberID, live.Event{
 						"type":   "member_back_online",
 						"member": result.RecoveredMember,
 					})
 				}
 				resetTimer(trackingHealthCh)
-				s.broadcastLiveEvent(result.RouteID, live.Event{
+				s.broadcastLiveEvent(r.Context(), result.RouteID, result.MemberID, live.Event{
 					"type":      "position_updated",
 					"memberId":  result.MemberID,
 					"segmentId": result.SegmentID,
 					"point":     result.Point,
 				})
+			case "position_batch":
+				samples, err := positionBatchInput(message.Positions)
+				var result routes.PositionBatchResult
+				if err == nil {
+					result, err = s.routes.RecordPositionBatch(r.Context(), authorized.Route.Code, authMessage.MemberToken, samples)
+				}
+				if err != nil {
+					if !enqueueLiveEvent(r.Context(), outboundEventCh, commandRejectedEvent(message, "position_batch", err)) {
+						return
+					}
+					continue
+				}
+
+				ack := commandAckEvent(message, "position_batch")
+				ack["accepted"] = result.Accepted()
+				ack["duplicates"] = result.Duplicates
+				ack["rejected"] = result.Rejected
+				if !enqueueLiveEvent(r.Context(), outboundEventCh, ack) {
+					return
+				}
+				if result.RecoveredMember != nil {
+					disconnectedStatus = result.RecoveredMember.Status
+				}
+				if result.Accepted() > 0 {
+					resetTimer(trackingHealthCh)
+				}
+				s.broadcastPositionBatch(r.Context(), result)
+			case "change_transport_mode":
+				result, err := s.routes.ChangeTransportMode(r.Context(), authorized.Route.Code, authMessage.MemberToken, message.TransportMode)
+				if err != nil {
+					if !enqueueLiveEvent(r.Context(), outboundEventCh, commandRejectedEvent(message, "change_transport_mode", err)) {
+						return
+					}
+					continue
+				}
+
+				if !enqueueLiveEvent(r.Context(), outboundEventCh, commandAckEvent(message, "change_transport_mode")) {
+					return
+				}
+				s.broadcastTransportChange(r.Context(), result)
+			case "chat_message":
+				chatMessage, err := s.routes.PostChatMessage(r.Context(), authMessage.MemberToken, message.Body)
+				if err != nil {
+					if !enqueueLiveEvent(r.Context(), outboundEventCh, commandRejectedEvent(message, "chat_message", err)) {
+						return
+					}
+					continue
+				}
+
+				if !enqueueLiveEvent(r.Context(), outboundEventCh, commandAckEvent(message, "chat_message")) {
+					return
+				}
+				s.broadcastLiveEvent(r.Context(), authorized.Route.ID, authorized.Member.ID, live.Event{
+					"type":    "chat_message_posted",
+					"message": chatMessage,
+				})
+			case "claim_owner_token":
+				ownerToken, err := s.routes.IssueOwnerToken(r.Context(), authorized.Route.Code, authMessage.MemberToken)
+				if err != nil {
+					if !enqueueLiveEvent(r.Context(), outboundEventCh, commandRejectedEvent(message, "claim_owner_token", err)) {
+						return
+					}
+					continue
+				}
+
+				if !enqueueLiveEvent(r.Context(), outboundEventCh, live.Event{
+					"type":       "owner_token_issued",
+					"requestId":  message.RequestID,
+					"ownerToken": ownerToken,
+				}) {
+					return
+				}
+			case "join_tracking_waitlist":
+				entry, err := s.routes.JoinTrackingWaitlist(r.Context(), authorized.Route.Code, authMessage.MemberToken)
+				if err != nil {
+					if !enqueueLiveEvent(r.Context(), outboundEventCh, commandRejectedEvent(message, "join_tracking_waitlist", err)) {
+						return
+					}
+					continue
+				}
+
+				ack := commandAckEvent(message, "join_tracking_waitlist")
+				ack["position"] = entry.Position
+				if !enqueueLiveEvent(r.Context(), outboundEventCh, ack) {
+					return
+				}
+				s.offerTrackingSlots(r.Context(), authorized.Route.ID)
+			case "leave_tracking_waitlist":
+				if err := s.routes.LeaveTrackingWaitlist(r.Context(), authorized.Route.Code, authMessage.MemberToken); err != nil {
+					if !enqueueLiveEvent(r.Context(), outboundEventCh, commandRejectedEvent(message, "leave_tracking_waitlist", err)) {
+						return
+					}
+					continue
+				}
+
+				if !enqueueLiveEvent(r.Context(), outboundEventCh, commandAckEvent(message, "leave_tracking_waitlist")) {
+					return
+				}
+				s.offerTrackingSlots(r.Context(), authorized.Route.ID)
 			default:
 				if !enqueueLiveEvent(r.Context(), outboundEventCh, live.Event{
 					"type":  "message_rejected",
@@ -496,6 +1136,11 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		case <-r.Context().Done():
 			return
 		case err := <-readErrCh:
+			if errors.Is(err, errWebSocketRateLimited) {
+				s.logger.Info("websocket closed rate limited member", "route_id", authorized.Route.ID, "member_id", authorized.Member.ID)
+				_ = connection.Close(websocket.StatusPolicyViolation, "rate limit exceeded")
+				return
+			}
 			s.logger.Debug("websocket read loop ended", "error", err)
 			return
 		case event := <-outboundEventCh:
@@ -505,8 +1150,20 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			}
 		case event, ok := <-subscription.Events():
 			if !ok {
+				if subscription.Removed() {
+					s.logger.Info("websocket closed removed member", "route_id", authorized.Route.ID, "member_id", authorized.Member.ID)
+					_ = connection.Close(websocket.StatusPolicyViolation, "removed from route")
+				} else if subscription.Evicted() {
+					s.logger.Info("websocket closed slow consumer", "route_id", authorized.Route.ID, "member_id", authorized.Member.ID)
+					_ = connection.Close(websocket.StatusTryAgainLater, "slow consumer")
+				}
 				return
 			}
+			if seq, ok := eventSeqOf(event); ok {
+				if _, done := replayed[seq]; done {
+					continue
+				}
+			}
 			if err := writeWebSocketJSON(r.Context(), connection, event); err != nil {
 				s.logger.Debug("websocket event write failed", "error", err)
 				return
@@ -515,15 +1172,115 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *Server) broadcastLiveEvent(routeID string, event live.Event) {
+// replayMissedEvents writes the route events logged after afterSeq to a reconnecting client, or
+// resync_required when the gap can no longer be replayed. The subscription is opened first, so
+// events broadcast during the replay are queued; the returned sequences let the caller skip the
+// ones the replay already covered.
+func (s *Server) replayMissedEvents(ctx context.Context, connection *websocket.Conn, routeID string, afterSeq int64) (map[int64]struct{}, error) {
+	events, err := s.routes.RouteEventsSince(ctx, routeID, afterSeq)
+	if err != nil {
+		if !errors.Is(err, routes.ErrEventCatchUpUnavailable) {
+			s.logger.Error("load missed route events failed", "route_id", routeID, "error", err)
+		}
+		return nil, writeWebSocketJSON(ctx, connection, live.Event{
+			"type":   live.EventTypeResyncRequired,
+			"reason": "catch_up_unavailable",
+		})
+	}
+
+	replayed := make(map[int64]struct{}, len(events))
+	for _, routeEvent := range events {
+		var event live.Event
+		if err := json.Unmarshal(routeEvent.Payload, &event); err != nil {
+			s.logger.Error("decode logged route event failed", "route_id", routeID, "event_id", routeEvent.ID, "error", err)
+			return nil, writeWebSocketJSON(ctx, connection, live.Event{
+				"type":   live.EventTypeResyncRequired,
+				"reason": "catch_up_unavailable",
+			})
+		}
+		event["eventSeq"] = routeEvent.ID
+		event["replayed"] = true
+		if err := writeWebSocketJSON(ctx, connection, event); err != nil {
+			return nil, err
+		}
+		replayed[routeEvent.ID] = struct{}{}
+	}
+
+	return replayed, nil
+}
+
+// eventSeqOf reads the route event log sequence stamped on a broadcast event. Events relayed
+// between instances arrive JSON-decoded, so the number may no longer be an int64.
+func eventSeqOf(event live.Event) (int64, bool) {
+	switch seq := event["eventSeq"].(type) {
+	case int64:
+		return seq, true
+	case float64:
+		return int64(seq), true
+	case json.Number:
+		value, err := seq.Int64()
+		return value, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// broadcastLiveEvent records the event in the route event log and fans it out to the route room.
+// Server-driven presence transitions pass an empty actorMemberID.
+func (s *Server) broadcastLiveEvent(ctx context.Context, routeID, actorMemberID string, event live.Event) {
 	if strings.TrimSpace(routeID) == "" {
 		return
 	}
 
-	delivered := s.liveHub.Broadcast(routeID, event)
+	recorded, err := s.routes.RecordRouteEvent(ctx, routeID, actorMemberID, event)
+	if err != nil {
+		s.logger.Error("record route event failed", "route_id", routeID, "type", event["type"], "error", err)
+	} else {
+		event["eventSeq"] = recorded.ID
+	}
+
+	delivered, err := s.liveHub.Broadcast(ctx, routeID, event)
+	if err != nil {
+		s.logger.Error("broadcast live event failed", "route_id", routeID, "type", event["type"], "error", err)
+	}
 	if delivered > 0 {
 		s.logger.Debug("broadcast live event", "route_id", routeID, "type", event["type"], "delivered", delivered)
 	}
+
+	if eventType, _ := event["type"].(string); slotReleasingEvents[eventType] {
+		s.offerTrackingSlots(ctx, routeID)
+	}
+}
+
+// slotReleasingEvents are the live events after which a tracking slot may have become free.
+var slotReleasingEvents = map[string]bool{
+	"member_stopped_sharing": true,
+	"member_left":            true,
+	"member_removed":         true,
+	"member_went_offline":    true,
+}
+
+// offerTrackingSlots reserves free tracking slots for the next waitlisted members and notifies them.
+func (s *Server) offerTrackingSlots(ctx context.Context, routeID string) {
+	reservations, err := s.routes.OfferTrackingSlots(ctx, routeID)
+	if err != nil {
+		s.logger.Error("offer tracking slots failed", "route_id", routeID, "error", err)
+	}
+	s.sendTrackingSlotOffers(ctx, reservations)
+}
+
+// sendTrackingSlotOffers tells each member that a slot is held for them. Offers are personal, so they
+// go only to the member's own connections and are not recorded as route events; a member who is not
+// connected sees the reservation in the next snapshot.
+func (s *Server) sendTrackingSlotOffers(ctx context.Context, reservations []routes.TrackingReservation) {
+	for _, reservation := range reservations {
+		if _, err := s.liveHub.SendToMember(ctx, reservation.RouteID, reservation.MemberID, live.Event{
+			"type":          "tracking_slot_available",
+			"reservedUntil": reservation.ReservedUntil,
+		}); err != nil {
+			s.logger.Error("send tracking slot offer failed", "route_id", reservation.RouteID, "member_id", reservation.MemberID, "error", err)
+		}
+	}
 }
 
 func (s *Server) runTrackingHealthTimer(ctx context.Context, routeID, memberID string, resetCh <-chan struct{}, _ <-chan struct{}, disconnectedStatus *string) {
@@ -555,7 +1312,7 @@ func (s *Server) runTrackingHealthTimer(ctx context.Context, routeID, memberID s
 		}
 
 		*disconnectedStatus = member.Status
-		s.broadcastLiveEvent(routeID, live.Event{
+		s.broadcastLiveEvent(context.Background(), routeID, "", live.Event{
 			"type":   "member_became_stale",
 			"member": member,
 		})
@@ -578,7 +1335,7 @@ func (s *Server) runTrackingHealthTimer(ctx context.Context, routeID, memberID s
 		}
 		if offlineChanged {
 			*disconnectedStatus = offlineMember.Status
-			s.broadcastLiveEvent(routeID, live.Event{
+			s.broadcastLiveEvent(context.Background(), routeID, "", live.Event{
 				"type":   "member_went_offline",
 				"member": offlineMember,
 			})
@@ -595,7 +1352,7 @@ func (s *Server) handleDisconnectedMember(ctx context.Context, routeID, memberID
 			return
 		}
 		if changed {
-			s.broadcastLiveEvent(routeID, live.Event{
+			s.broadcastLiveEvent(ctx, routeID, "", live.Event{
 				"type":   "member_became_stale",
 				"member": member,
 			})
@@ -624,13 +1381,37 @@ func (s *Server) markOfflineAfter(ctx context.Context, routeID, memberID string,
 		return
 	}
 	if changed {
-		s.broadcastLiveEvent(routeID, live.Event{
+		s.broadcastLiveEvent(ctx, routeID, "", live.Event{
 			"type":   "member_went_offline",
 			"member": member,
 		})
 	}
 }
 
+func (s *Server) sweepPresence(ctx context.Context) {
+	transitions, err := s.routes.SweepPresence(ctx, routes.PresenceRules{
+		TrackingStaleAfter:    s.appConfig.TrackingStaleAfter,
+		TrackingOfflineAfter:  s.appConfig.TrackingOfflineAfter,
+		SpectatorOfflineAfter: s.appConfig.SpectatorOfflineAfter,
+	}, s.liveHub)
+	if err != nil {
+		s.logger.Error("presence sweep failed", "error", err)
+	}
+
+	for _, transition := range transitions {
+		s.broadcastLiveEvent(ctx, transition.RouteID, "", live.Event{
+			"type":   transition.Type,
+			"member": transition.Member,
+		})
+	}
+
+	reservations, err := s.routes.ExpireTrackingReservations(ctx)
+	if err != nil {
+		s.logger.Error("expire tracking reservations failed", "error", err)
+	}
+	s.sendTrackingSlotOffers(ctx, reservations)
+}
+
 func resetTimer(resetCh chan<- struct{}) {
 	select {
 	case resetCh <- struct{}{}:
@@ -655,19 +1436,61 @@ func commandRejectedEvent(message webSocketClientMessage, command string, err er
 	}
 }
 
-func (s *Server) writeJSON(w http.ResponseWriter, status int, payload any) {
+// allowLiveMessage charges a live message against the connection's limits: position updates use the
+// live position rate and position batches the batch budget.
+func allowLiveMessage(limiter *ratelimit.ConnectionLimiter, message webSocketClientMessage) (allowed, abusive bool) {
+	switch message.Type {
+	case "position_update":
+		return limiter.Allow(1)
+	case "position_batch":
+		return limiter.AllowBatch(len(message.Positions))
+	default:
+		return limiter.Allow(0)
+	}
+}
+
+// rateLimitedEvent refuses a live message sent over the connection's rate limit, in the shape the
+// client expects for that message type.
+func rateLimitedEvent(message webSocketClientMessage) live.Event {
+	if message.Type == "position_update" {
+		return live.Event{
+			"type":  "position_rejected",
+			"error": "rate_limited",
+		}
+	}
+
+	return live.Event{
+		"type":      "command_rejected",
+		"requestId": message.RequestID,
+		"command":   message.Type,
+		"reason":    "rate_limited",
+	}
+}
+
+// writeJSON encodes payload as the response body and returns the number of body bytes written.
+func (s *Server) writeJSON(w http.ResponseWriter, status int, payload any) int {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
 
-	if err := json.NewEncoder(w).Encode(payload); err != nil {
+	body := &countingWriter{w: w}
+	if err := json.NewEncoder(body).Encode(payload); err != nil {
 		s.logger.Error("failed to write json response", "error", err)
 	}
+
+	return body.n
 }
 
 func (s *Server) writeError(w http.ResponseWriter, status int, reason string) {
 	s.writeJSON(w, status, map[string]string{"error": reason})
 }
 
+// writeRateLimited refuses a throttled request with Retry-After in whole seconds, rounded up.
+func (s *Server) writeRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	s.writeError(w, http.StatusTooManyRequests, "rate_limited")
+}
+
 func (s *Server) writeRouteError(w http.ResponseWriter, err error) {
 	status, reason := routeErrorStatusAndReason(err)
 	if status == http.StatusInternalServerError {
@@ -682,11 +1505,18 @@ func routeErrorReason(err error) string {
 }
 
 func routeErrorStatusAndReason(err error) (int, string) {
+	var positionRejected *routes.PositionRejectedError
 	switch {
+	case errors.As(err, &positionRejected):
+		return http.StatusUnprocessableEntity, positionRejected.Reason
 	case errors.Is(err, routes.ErrInvalidInput):
 		return http.StatusBadRequest, "invalid_input"
 	case errors.Is(err, routes.ErrRouteNotFound):
 		return http.StatusNotFound, "route_not_found"
+	case errors.Is(err, routes.ErrMemberNotFound):
+		return http.StatusNotFound, "member_not_found"
+	case errors.Is(err, routes.ErrMemberBanned):
+		return http.StatusForbidden, "member_banned"
 	case errors.Is(err, routes.ErrInvalidPassword):
 		return http.StatusUnauthorized, "invalid_password"
 	case errors.Is(err, routes.ErrAliasTaken):
@@ -695,10 +1525,16 @@ func routeErrorStatusAndReason(err error) (int, string) {
 		return http.StatusUnauthorized, "unauthorized"
 	case errors.Is(err, routes.ErrRouteClosed):
 		return http.StatusConflict, "route_closed"
+	case errors.Is(err, routes.ErrRouteActive):
+		return http.StatusConflict, "route_active"
 	case errors.Is(err, routes.ErrSharingNotAllowed):
 		return http.StatusForbidden, "sharing_not_allowed"
 	case errors.Is(err, routes.ErrTrackingLimitReached):
 		return http.StatusConflict, "tracking_limit_reached"
+	case errors.Is(err, routes.ErrChatMessageTooLong):
+		return http.StatusUnprocessableEntity, "message_too_long"
+	case errors.Is(err, routes.ErrChatRateLimited):
+		return http.StatusTooManyRequests, "chat_rate_limited"
 	default:
 		return http.StatusInternalServerError, "internal_error"
 	}
@@ -712,13 +1548,62 @@ func (s *Server) withLogging(next http.Handler) http.Handler {
 			"remote_addr", r.RemoteAddr,
 		)
 
-		next.ServeHTTP(w, r)
+		startedAt := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// The mux stores the matched pattern on the request, which keeps the route label bounded.
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		s.metrics.ObserveHTTPRequest(r.Method, route, recorder.status, time.Since(startedAt))
 	})
 }
 
+// statusRecorder captures the response status for metrics. Unwrap keeps WebSocket hijacking working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+
+	return n, err
+}
+
+// withCORS grants cross-origin access to the configured origins only, echoing the matched origin.
+// Requests from other origins get no CORS headers, so browsers refuse them.
 func (s *Server) withCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if s.appConfig.AllowAllOrigins {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Add("Vary", "Origin")
+			if origin := r.Header.Get("Origin"); origin != "" && originAllowed(s.appConfig.AllowedOrigins, origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
 
@@ -731,6 +1616,24 @@ func (s *Server) withCORS(next http.Handler) http.Handler {
 	})
 }
 
+// originAllowed matches an Origin header against the allowed origin patterns the same way the
+// WebSocket handshake does, so REST and live connections accept the same browsers.
+func originAllowed(patterns []string, origin string) bool {
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	target := strings.ToLower(parsed.Scheme + "://" + parsed.Host)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, target); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Serve starts the HTTP server and blocks until it exits.
 func Serve(ctx context.Context, logger *slog.Logger, cfg config.AppConfig, handler http.Handler) error {
 	server := &http.Server{
@@ -781,6 +1684,17 @@ func decodeJSON(body io.ReadCloser, dst any) error {
 	return nil
 }
 
+// requestClientIP returns the peer address without its port. Forwarding headers are ignored, since
+// the API is served directly rather than behind a trusted proxy.
+func requestClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
 func bearerToken(header string) string {
 	const prefix = "Bearer "
 
@@ -792,20 +1706,24 @@ func bearerToken(header string) string {
 }
 
 type webSocketAuthMessage struct {
-	Type        string `json:"type"`
-	MemberToken string `json:"memberToken"`
+	Type         string `json:"type"`
+	MemberToken  string `json:"memberToken"`
+	LastEventSeq int64  `json:"lastEventSeq"`
 }
 
 type webSocketClientMessage struct {
-	Type             string     `json:"type"`
-	RequestID        string     `json:"requestId"`
-	Latitude         *float64   `json:"latitude"`
-	Longitude        *float64   `json:"longitude"`
-	AccuracyM        *float64   `json:"accuracyM"`
-	AltitudeM        *float64   `json:"altitudeM"`
-	SpeedMPS         *float64   `json:"speedMps"`
-	HeadingDeg       *float64   `json:"headingDeg"`
-	ClientRecordedAt *time.Time `json:"clientRecordedAt"`
+	Type             string            `json:"type"`
+	RequestID        string            `json:"requestId"`
+	Latitude         *float64          `json:"latitude"`
+	Longitude        *float64          `json:"longitude"`
+	AccuracyM        *float64          `json:"accuracyM"`
+	AltitudeM        *float64          `json:"altitudeM"`
+	SpeedMPS         *float64          `json:"speedMps"`
+	HeadingDeg       *float64          `json:"headingDeg"`
+	ClientRecordedAt *time.Time        `json:"clientRecordedAt"`
+	Body             string            `json:"body"`
+	Positions        []json.RawMessage `json:"positions"`
+	TransportMode    string            `json:"transportMode"`
 }
 
 func positionUpdateInput(message webSocketClientMessage, rawPayload json.RawMessage) (routes.PositionUpdateInput, error) {
@@ -825,6 +1743,125 @@ func positionUpdateInput(message webSocketClientMessage, rawPayload json.RawMess
 	}, nil
 }
 
+// positionBatchInput decodes batch samples, which use the position_update fields, keeping each
+// sample's own JSON as its raw payload.
+func positionBatchInput(rawSamples []json.RawMessage) ([]routes.PositionUpdateInput, error) {
+	samples := make([]routes.PositionUpdateInput, 0, len(rawSamples))
+	for _, rawSample := range rawSamples {
+		var message webSocketClientMessage
+		if err := json.Unmarshal(rawSample, &message); err != nil {
+			return nil, routes.ErrInvalidInput
+		}
+
+		sample, err := positionUpdateInput(message, rawSample)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+func replayQuery(r *http.Request) (routes.ReplayQuery, error) {
+	values := r.URL.Query()
+	query := routes.ReplayQuery{
+		Cursor: values.Get("cursor"),
+	}
+
+	if from := values.Get("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, from)
+		if err != nil {
+			return routes.ReplayQuery{}, fmt.Errorf("parse from: %w", err)
+		}
+		query.From = &parsed
+	}
+
+	if speed := values.Get("speed"); speed != "" {
+		parsed, err := strconv.Atoi(strings.TrimSuffix(speed, "x"))
+		if err != nil {
+			return routes.ReplayQuery{}, fmt.Errorf("parse speed: %w", err)
+		}
+		query.Speed = parsed
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			return routes.ReplayQuery{}, fmt.Errorf("parse limit: %w", err)
+		}
+		query.Limit = parsed
+	}
+
+	return query, nil
+}
+
+func pointPageQuery(r *http.Request) (routes.PointPageQuery, error) {
+	values := r.URL.Query()
+	query := routes.PointPageQuery{
+		Member:  values.Get("member"),
+		Segment: values.Get("segment"),
+	}
+
+	if afterSeq := values.Get("after_seq"); afterSeq != "" {
+		parsed, err := strconv.ParseInt(afterSeq, 10, 64)
+		if err != nil {
+			return routes.PointPageQuery{}, fmt.Errorf("parse after_seq: %w", err)
+		}
+		query.AfterSeq = parsed
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			return routes.PointPageQuery{}, fmt.Errorf("parse limit: %w", err)
+		}
+		query.Limit = parsed
+	}
+
+	return query, nil
+}
+
+func snapshotPointCount(snapshot routes.Snapshot) int {
+	points := 0
+	for _, member := range snapshot.Members {
+		for _, segment := range member.Paths {
+			points += len(segment.Points)
+		}
+	}
+
+	return points
+}
+
+func replayLiveEvent(event routes.ReplayEvent) live.Event {
+	liveEvent := live.Event{
+		"type":             event.Type,
+		"at":               event.At,
+		"playbackOffsetMs": event.PlaybackOffsetMS,
+	}
+
+	switch event.Type {
+	case "position_updated":
+		liveEvent["memberId"] = event.MemberID
+		liveEvent["segmentId"] = event.SegmentID
+		liveEvent["point"] = event.Point
+	case "route_closed":
+		liveEvent["route"] = event.Route
+	case "chat_message_posted":
+		liveEvent["message"] = event.Message
+	default:
+		liveEvent["member"] = event.Member
+		if event.Segment != nil {
+			liveEvent["segment"] = event.Segment
+		}
+		if event.TransportChange != nil {
+			liveEvent["change"] = event.TransportChange
+		}
+	}
+
+	return liveEvent
+}
+
 func enqueueLiveEvent(ctx context.Context, events chan<- live.Event, event live.Event) bool {
 	select {
 	case events <- event:
@@ -5,14 +5,19 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"keepup/apps/api/internal/config"
+	"keepup/apps/api/internal/live"
+	"keepup/apps/api/internal/metrics"
+	"keepup/apps/api/internal/ratelimit"
 	"keepup/apps/api/internal/routes"
 
 	"github.com/coder/websocket"
@@ -28,20 +33,40 @@ func (s stubHealthChecker) Ping(_ context.Context) error {
 }
 
 type stubRouteService struct {
-	accessRouteFn     func(context.Context, string) (routes.AccessRouteResult, error)
-	authorizeMemberFn func(context.Context, string) (routes.AuthorizedMember, error)
-	createRouteFn     func(context.Context, routes.CreateRouteInput) (routes.CreateRouteResult, error)
-	deleteRouteFn     func(context.Context, string, string) error
-	joinRouteFn       func(context.Context, string, routes.JoinRouteInput) (routes.JoinRouteResult, error)
-	leaveRouteFn      func(context.Context, string, string) (routes.LeaveRouteResult, error)
-	snapshotFn        func(context.Context, string, string) (routes.Snapshot, error)
-	startSharingFn    func(context.Context, string, string) (routes.StartSharingResult, error)
-	stopSharingFn     func(context.Context, string, string) (routes.StopSharingResult, error)
-	markOnlineFn      func(context.Context, string, string) (routes.Member, bool, error)
-	markStaleFn       func(context.Context, string, string) (routes.Member, bool, error)
-	markOfflineFn     func(context.Context, string, string) (routes.Member, bool, error)
-	recordPositionFn  func(context.Context, string, routes.PositionUpdateInput) (routes.PositionUpdateResult, error)
-	updateRouteFn     func(context.Context, string, string, routes.UpdateRouteInput) (routes.Route, error)
+	accessRouteFn         func(context.Context, string) (routes.AccessRouteResult, error)
+	authorizeMemberFn     func(context.Context, string) (routes.AuthorizedMember, error)
+	createRouteFn         func(context.Context, routes.CreateRouteInput) (routes.CreateRouteResult, error)
+	deleteRouteFn         func(context.Context, string, string) error
+	joinRouteFn           func(context.Context, string, routes.JoinRouteInput) (routes.JoinRouteResult, error)
+	leaveRouteFn          func(context.Context, string, string) (routes.LeaveRouteResult, error)
+	snapshotFn            func(context.Context, string, string, routes.SnapshotQuery) (routes.Snapshot, error)
+	exportRouteFn         func(context.Context, string, string) (routes.RouteExport, error)
+	replayFn              func(context.Context, string, string, routes.ReplayQuery) (routes.ReplayPage, error)
+	startSharingFn        func(context.Context, string, string) (routes.StartSharingResult, error)
+	stopSharingFn         func(context.Context, string, string) (routes.StopSharingResult, error)
+	changeTransportModeFn func(context.Context, string, string, string) (routes.ChangeTransportModeResult, error)
+	setTrackingFn         func(context.Context, string, string, string, bool) (routes.SetTrackingPermissionResult, error)
+	removeMemberFn        func(context.Context, string, string, string, bool) (routes.RemoveMemberResult, error)
+	transferOwnershipFn   func(context.Context, string, string, string) (routes.TransferOwnershipResult, error)
+	setModeratorFn        func(context.Context, string, string, string, bool) (routes.SetModeratorResult, error)
+	issueOwnerTokenFn     func(context.Context, string, string) (string, error)
+	joinWaitlistFn        func(context.Context, string, string) (routes.TrackingWaitlistEntry, error)
+	leaveWaitlistFn       func(context.Context, string, string) error
+	offerTrackingSlotsFn  func(context.Context, string) ([]routes.TrackingReservation, error)
+	expireReservationsFn  func(context.Context) ([]routes.TrackingReservation, error)
+	markOnlineFn          func(context.Context, string, string) (routes.Member, bool, error)
+	markStaleFn           func(context.Context, string, string) (routes.Member, bool, error)
+	markOfflineFn         func(context.Context, string, string) (routes.Member, bool, error)
+	recordSeenFn          func(context.Context, string, string) error
+	sweepPresenceFn       func(context.Context, routes.PresenceRules, routes.ConnectionChecker) ([]routes.PresenceTransition, error)
+	recordPositionFn      func(context.Context, string, routes.PositionUpdateInput) (routes.PositionUpdateResult, error)
+	recordPositionBatchFn func(context.Context, string, string, []routes.PositionUpdateInput) (routes.PositionBatchResult, error)
+	updateRouteFn         func(context.Context, string, string, routes.UpdateRouteInput) (routes.UpdateRouteResult, error)
+	recordRouteEventFn    func(context.Context, string, string, map[string]any) (routes.RouteEvent, error)
+	routeEventsSinceFn    func(context.Context, string, int64) ([]routes.RouteEvent, error)
+	postChatMessageFn     func(context.Context, string, string) (routes.ChatMessage, error)
+	listChatMessagesFn    func(context.Context, string, string, routes.ChatMessageQuery) (routes.ChatMessagePage, error)
+	listRoutePointsFn     func(context.Context, string, string, routes.PointPageQuery) (routes.PointPage, error)
 }
 
 func (s stubRouteService) CreateRoute(ctx context.Context, input routes.CreateRouteInput) (routes.CreateRouteResult, error) {
@@ -76,12 +101,28 @@ func (s stubRouteService) JoinRoute(ctx context.Context, code string, input rout
 	return s.joinRouteFn(ctx, code, input)
 }
 
-func (s stubRouteService) Snapshot(ctx context.Context, code, memberToken string) (routes.Snapshot, error) {
+func (s stubRouteService) Snapshot(ctx context.Context, code, memberToken string, query routes.SnapshotQuery) (routes.Snapshot, error) {
 	if s.snapshotFn == nil {
 		return routes.Snapshot{}, nil
 	}
 
-	return s.snapshotFn(ctx, code, memberToken)
+	return s.snapshotFn(ctx, code, memberToken, query)
+}
+
+func (s stubRouteService) ExportRoute(ctx context.Context, code, memberToken string) (routes.RouteExport, error) {
+	if s.exportRouteFn == nil {
+		return routes.RouteExport{}, nil
+	}
+
+	return s.exportRouteFn(ctx, code, memberToken)
+}
+
+func (s stubRouteService) Replay(ctx context.Context, code, memberToken string, query routes.ReplayQuery) (routes.ReplayPage, error) {
+	if s.replayFn == nil {
+		return routes.ReplayPage{}, nil
+	}
+
+	return s.replayFn(ctx, code, memberToken, query)
 }
 
 func (s stubRouteService) StartSharing(ctx context.Context, code, memberToken string) (routes.StartSharingResult, error) {
@@ -124,6 +165,22 @@ func (s stubRouteService) MarkMemberOffline(ctx context.Context, routeID, member
 	return s.markOfflineFn(ctx, routeID, memberID)
 }
 
+func (s stubRouteService) RecordMemberSeen(ctx context.Context, routeID, memberID string) error {
+	if s.recordSeenFn == nil {
+		return nil
+	}
+
+	return s.recordSeenFn(ctx, routeID, memberID)
+}
+
+func (s stubRouteService) SweepPresence(ctx context.Context, rules routes.PresenceRules, connections routes.ConnectionChecker) ([]routes.PresenceTransition, error) {
+	if s.sweepPresenceFn == nil {
+		return nil, nil
+	}
+
+	return s.sweepPresenceFn(ctx, rules, connections)
+}
+
 func (s stubRouteService) RecordPosition(ctx context.Context, memberToken string, input routes.PositionUpdateInput) (routes.PositionUpdateResult, error) {
 	if s.recordPositionFn == nil {
 		return routes.PositionUpdateResult{}, nil
@@ -132,14 +189,142 @@ func (s stubRouteService) RecordPosition(ctx context.Context, memberToken string
 	return s.recordPositionFn(ctx, memberToken, input)
 }
 
-func (s stubRouteService) UpdateRoute(ctx context.Context, code, ownerToken string, input routes.UpdateRouteInput) (routes.Route, error) {
+func (s stubRouteService) UpdateRoute(ctx context.Context, code, ownerToken string, input routes.UpdateRouteInput) (routes.UpdateRouteResult, error) {
 	if s.updateRouteFn == nil {
-		return routes.Route{}, nil
+		return routes.UpdateRouteResult{}, nil
 	}
 
 	return s.updateRouteFn(ctx, code, ownerToken, input)
 }
 
+func (s stubRouteService) RecordPositionBatch(ctx context.Context, code, memberToken string, samples []routes.PositionUpdateInput) (routes.PositionBatchResult, error) {
+	if s.recordPositionBatchFn == nil {
+		return routes.PositionBatchResult{}, nil
+	}
+
+	return s.recordPositionBatchFn(ctx, code, memberToken, samples)
+}
+
+func (s stubRouteService) RecordRouteEvent(ctx context.Context, routeID, actorMemberID string, payload map[string]any) (routes.RouteEvent, error) {
+	if s.recordRouteEventFn == nil {
+		return routes.RouteEvent{}, nil
+	}
+
+	return s.recordRouteEventFn(ctx, routeID, actorMemberID, payload)
+}
+
+func (s stubRouteService) RouteEventsSince(ctx context.Context, routeID string, afterSeq int64) ([]routes.RouteEvent, error) {
+	if s.routeEventsSinceFn == nil {
+		return nil, nil
+	}
+
+	return s.routeEventsSinceFn(ctx, routeID, afterSeq)
+}
+
+func (s stubRouteService) PostChatMessage(ctx context.Context, memberToken, body string) (routes.ChatMessage, error) {
+	if s.postChatMessageFn == nil {
+		return routes.ChatMessage{}, nil
+	}
+
+	return s.postChatMessageFn(ctx, memberToken, body)
+}
+
+func (s stubRouteService) ChangeTransportMode(ctx context.Context, code, memberToken, transportMode string) (routes.ChangeTransportModeResult, error) {
+	if s.changeTransportModeFn == nil {
+		return routes.ChangeTransportModeResult{}, nil
+	}
+
+	return s.changeTransportModeFn(ctx, code, memberToken, transportMode)
+}
+
+func (s stubRouteService) SetTrackingPermission(ctx context.Context, code, ownerToken, memberID string, granted bool) (routes.SetTrackingPermissionResult, error) {
+	if s.setTrackingFn == nil {
+		return routes.SetTrackingPermissionResult{}, nil
+	}
+
+	return s.setTrackingFn(ctx, code, ownerToken, memberID, granted)
+}
+
+func (s stubRouteService) RemoveMember(ctx context.Context, code, ownerToken, memberID string, ban bool) (routes.RemoveMemberResult, error) {
+	if s.removeMemberFn == nil {
+		return routes.RemoveMemberResult{}, nil
+	}
+
+	return s.removeMemberFn(ctx, code, ownerToken, memberID, ban)
+}
+
+func (s stubRouteService) SetModerator(ctx context.Context, code, ownerToken, memberID string, moderator bool) (routes.SetModeratorResult, error) {
+	if s.setModeratorFn == nil {
+		return routes.SetModeratorResult{}, nil
+	}
+
+	return s.setModeratorFn(ctx, code, ownerToken, memberID, moderator)
+}
+
+func (s stubRouteService) TransferOwnership(ctx context.Context, code, ownerToken, memberID string) (routes.TransferOwnershipResult, error) {
+	if s.transferOwnershipFn == nil {
+		return routes.TransferOwnershipResult{}, nil
+	}
+
+	return s.transferOwnershipFn(ctx, code, ownerToken, memberID)
+}
+
+func (s stubRouteService) IssueOwnerToken(ctx context.Context, code, memberToken string) (string, error) {
+	if s.issueOwnerTokenFn == nil {
+		return "", nil
+	}
+
+	return s.issueOwnerTokenFn(ctx, code, memberToken)
+}
+
+func (s stubRouteService) JoinTrackingWaitlist(ctx context.Context, code, memberToken string) (routes.TrackingWaitlistEntry, error) {
+	if s.joinWaitlistFn == nil {
+		return routes.TrackingWaitlistEntry{}, nil
+	}
+
+	return s.joinWaitlistFn(ctx, code, memberToken)
+}
+
+func (s stubRouteService) LeaveTrackingWaitlist(ctx context.Context, code, memberToken string) error {
+	if s.leaveWaitlistFn == nil {
+		return nil
+	}
+
+	return s.leaveWaitlistFn(ctx, code, memberToken)
+}
+
+func (s stubRouteService) OfferTrackingSlots(ctx context.Context, routeID string) ([]routes.TrackingReservation, error) {
+	if s.offerTrackingSlotsFn == nil {
+		return nil, nil
+	}
+
+	return s.offerTrackingSlotsFn(ctx, routeID)
+}
+
+func (s stubRouteService) ExpireTrackingReservations(ctx context.Context) ([]routes.TrackingReservation, error) {
+	if s.expireReservationsFn == nil {
+		return nil, nil
+	}
+
+	return s.expireReservationsFn(ctx)
+}
+
+func (s stubRouteService) ListRoutePoints(ctx context.Context, code, memberToken string, query routes.PointPageQuery) (routes.PointPage, error) {
+	if s.listRoutePointsFn == nil {
+		return routes.PointPage{}, nil
+	}
+
+	return s.listRoutePointsFn(ctx, code, memberToken, query)
+}
+
+func (s stubRouteService) ListChatMessages(ctx context.Context, code, memberToken string, query routes.ChatMessageQuery) (routes.ChatMessagePage, error) {
+	if s.listChatMessagesFn == nil {
+		return routes.ChatMessagePage{}, nil
+	}
+
+	return s.listChatMessagesFn(ctx, code, memberToken, query)
+}
+
 func (s stubRouteService) LeaveRoute(ctx context.Context, code, memberToken string) (routes.LeaveRouteResult, error) {
 	if s.leaveRouteFn == nil {
 		return routes.LeaveRouteResult{}, nil
@@ -239,6 +424,73 @@ func TestRootHandler(t *testing.T) {
 	}
 }
 
+func TestCORSAllowsConfiguredOriginsOnly(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "production", Port: "8080", AllowedOrigins: []string{"https://keepup.example", "https://*.keepup.example"}},
+		stubHealthChecker{},
+		stubRouteService{},
+	)
+
+	testCases := []struct {
+		origin string
+		want   string
+	}{
+		{origin: "https://keepup.example", want: "https://keepup.example"},
+		{origin: "https://Beta.KeepUp.example", want: "https://Beta.KeepUp.example"},
+		{origin: "http://keepup.example", want: ""},
+		{origin: "https://evil.example", want: ""},
+	}
+
+	for _, tc := range testCases {
+		request := httptest.NewRequest(http.MethodOptions, "/routes", nil)
+		request.Header.Set("Origin", tc.origin)
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, request)
+
+		if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != tc.want {
+			t.Fatalf("Origin %q: Access-Control-Allow-Origin = %q, want %q", tc.origin, got, tc.want)
+		}
+		if recorder.Header().Get("Vary") != "Origin" {
+			t.Fatalf("Origin %q: Vary = %q, want Origin", tc.origin, recorder.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestWebSocketRejectsDisallowedOrigin(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "production", Port: "8080", WebSocketAuthTimeout: time.Second, AllowedOrigins: []string{"https://keepup.example"}},
+		stubHealthChecker{},
+		stubRouteService{},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, response, err := websocket.Dial(ctx, webSocketURL(server.URL), &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"https://evil.example"}},
+	})
+	if err == nil || response == nil || response.StatusCode != http.StatusForbidden {
+		t.Fatalf("websocket.Dial() from disallowed origin = %v, %v; want 403", response, err)
+	}
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"https://keepup.example"}},
+	})
+	if err != nil {
+		t.Fatalf("websocket.Dial() from allowed origin error = %v", err)
+	}
+	_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+}
+
 func TestCreateRouteHandler(t *testing.T) {
 	t.Parallel()
 
@@ -366,7 +618,7 @@ func TestRouteHandler(t *testing.T) {
 		config.AppConfig{Env: "test", Port: "8080"},
 		stubHealthChecker{},
 		stubRouteService{
-			snapshotFn: func(_ context.Context, code, token string) (routes.Snapshot, error) {
+			snapshotFn: func(_ context.Context, code, token string, query routes.SnapshotQuery) (routes.Snapshot, error) {
 				if code != "K7P9QD" {
 					t.Fatalf("Snapshot() code = %q, want K7P9QD", code)
 				}
@@ -375,6 +627,10 @@ func TestRouteHandler(t *testing.T) {
 					t.Fatalf("Snapshot() token = %q, want member-token", token)
 				}
 
+				if query.Detail != routes.SnapshotDetailSimplified || query.ToleranceM != 25 {
+					t.Fatalf("Snapshot() query = %#v, want simplified detail with 25m tolerance", query)
+				}
+
 				return routes.Snapshot{
 					Route: routes.Route{
 						Code:               "K7P9QD",
@@ -408,7 +664,7 @@ func TestRouteHandler(t *testing.T) {
 		},
 	)
 
-	request := httptest.NewRequest(http.MethodGet, "/routes/K7P9QD", nil)
+	request := httptest.NewRequest(http.MethodGet, "/routes/K7P9QD?detail=simplified&tolerance=25", nil)
 	request.Header.Set("Authorization", "Bearer member-token")
 	recorder := httptest.NewRecorder()
 
@@ -423,7 +679,7 @@ func TestRouteHandler(t *testing.T) {
 	}
 }
 
-func TestCreateRouteMemberHandler(t *testing.T) {
+func TestMetricsHandlerRecordsRequestsAndSnapshots(t *testing.T) {
 	t.Parallel()
 
 	handler := NewHandler(
@@ -431,96 +687,80 @@ func TestCreateRouteMemberHandler(t *testing.T) {
 		config.AppConfig{Env: "test", Port: "8080"},
 		stubHealthChecker{},
 		stubRouteService{
-			joinRouteFn: func(_ context.Context, code string, input routes.JoinRouteInput) (routes.JoinRouteResult, error) {
-				if code != "K7P9QD" {
-					t.Fatalf("JoinRoute() code = %q, want K7P9QD", code)
-				}
-
-				if input.DisplayName != "Matej" {
-					t.Fatalf("JoinRoute() displayName = %q, want Matej", input.DisplayName)
-				}
-
-				return routes.JoinRouteResult{
-					Route: routes.Route{
-						Code:          "K7P9QD",
-						Name:          "Morning convoy",
-						SharingPolicy: routes.SharingPolicyEveryoneCanShare,
-						Status:        routes.RouteStatusActive,
-					},
-					Member: routes.Member{
-						ID:          "member-2",
-						DisplayName: input.DisplayName,
-					},
-					MemberToken: "member-token",
+			snapshotFn: func(context.Context, string, string, routes.SnapshotQuery) (routes.Snapshot, error) {
+				return routes.Snapshot{
+					Members: []routes.SnapshotMember{{
+						ID: "member-1",
+						Paths: []routes.PathSegment{
+							{Points: []routes.RoutePoint{{Seq: 1}, {Seq: 2}}},
+							{Points: []routes.RoutePoint{{Seq: 1}}},
+						},
+					}},
 				}, nil
 			},
 		},
+		WithMetrics(metrics.New()),
 	)
 
-	body := map[string]string{
-		"clientId":      "client-2",
-		"displayName":   "Matej",
-		"transportMode": "train",
-		"password":      "secret",
-	}
-
-	payload, err := json.Marshal(body)
-	if err != nil {
-		t.Fatalf("json.Marshal() error = %v", err)
-	}
+	request := httptest.NewRequest(http.MethodGet, "/routes/K7P9QD", nil)
+	request.Header.Set("Authorization", "Bearer member-token")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
 
-	request := httptest.NewRequest(http.MethodPost, "/routes/K7P9QD/members", bytes.NewReader(payload))
 	recorder := httptest.NewRecorder()
-
-	handler.ServeHTTP(recorder, request)
-
-	if recorder.Code != http.StatusCreated {
-		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusCreated)
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP(/metrics) status = %d, want %d", recorder.Code, http.StatusOK)
 	}
 
-	if !strings.Contains(recorder.Body.String(), `"memberToken":"member-token"`) {
-		t.Fatalf("ServeHTTP() body = %q, want member token", recorder.Body.String())
+	body := recorder.Body.String()
+	for _, want := range []string{
+		`keepup_http_requests_total{method="GET",route="GET /routes/{code}",status="200"} 1`,
+		`keepup_route_snapshot_points_sum 3`,
+		`keepup_route_snapshot_bytes_count 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("ServeHTTP(/metrics) body missing %q", want)
+		}
 	}
 }
 
-func TestUpdateRouteHandler(t *testing.T) {
+func TestExportRouteHandler(t *testing.T) {
 	t.Parallel()
 
+	recordedAt := time.Now().UTC()
 	handler := NewHandler(
 		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
 		config.AppConfig{Env: "test", Port: "8080"},
 		stubHealthChecker{},
 		stubRouteService{
-			updateRouteFn: func(_ context.Context, code, token string, input routes.UpdateRouteInput) (routes.Route, error) {
-				if code != "K7P9QD" || token != "owner-token" {
-					t.Fatalf("UpdateRoute() got code=%q token=%q", code, token)
-				}
-
-				if input.Status != routes.RouteStatusClosed {
-					t.Fatalf("UpdateRoute() status = %q, want closed", input.Status)
+			exportRouteFn: func(_ context.Context, code, token string) (routes.RouteExport, error) {
+				if code != "K7P9QD" || token != "member-token" {
+					t.Fatalf("ExportRoute() got code=%q token=%q", code, token)
 				}
 
-				return routes.Route{
-					Code:          code,
-					Name:          "Morning convoy",
-					Status:        routes.RouteStatusClosed,
-					SharingPolicy: routes.SharingPolicyEveryoneCanShare,
+				return routes.RouteExport{
+					Route: routes.Route{ID: "route-1", Code: "K7P9QD", Name: "Morning convoy"},
+					Members: []routes.SnapshotMember{
+						{
+							ID:          "member-1",
+							DisplayName: "Ana",
+							Paths: []routes.PathSegment{
+								{
+									ID: "segment-1",
+									Points: []routes.RoutePoint{
+										{Seq: 1, Latitude: 46.0569, Longitude: 14.5058, RecordedAt: recordedAt},
+									},
+								},
+							},
+						},
+					},
 				}, nil
 			},
 		},
 	)
 
-	body := map[string]string{
-		"status": routes.RouteStatusClosed,
-	}
-
-	payload, err := json.Marshal(body)
-	if err != nil {
-		t.Fatalf("json.Marshal() error = %v", err)
-	}
-
-	request := httptest.NewRequest(http.MethodPatch, "/routes/K7P9QD", bytes.NewReader(payload))
-	request.Header.Set("Authorization", "Bearer owner-token")
+	request := httptest.NewRequest(http.MethodGet, "/routes/K7P9QD/export?format=geojson", nil)
+	request.Header.Set("Authorization", "Bearer member-token")
 	recorder := httptest.NewRecorder()
 
 	handler.ServeHTTP(recorder, request)
@@ -529,12 +769,20 @@ func TestUpdateRouteHandler(t *testing.T) {
 		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusOK)
 	}
 
-	if !strings.Contains(recorder.Body.String(), `"status":"closed"`) {
-		t.Fatalf("ServeHTTP() body = %q, want closed status", recorder.Body.String())
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/geo+json" {
+		t.Fatalf("ServeHTTP() content type = %q, want application/geo+json", contentType)
+	}
+
+	if disposition := recorder.Header().Get("Content-Disposition"); !strings.Contains(disposition, "K7P9QD.geojson") {
+		t.Fatalf("ServeHTTP() content disposition = %q, want geojson filename", disposition)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `"coordinates":[[[14.5058,46.0569]]]`) {
+		t.Fatalf("ServeHTTP() body = %q, want member track coordinates", recorder.Body.String())
 	}
 }
 
-func TestLeaveRouteHandler(t *testing.T) {
+func TestExportRouteHandlerRejectsUnknownFormat(t *testing.T) {
 	t.Parallel()
 
 	handler := NewHandler(
@@ -542,24 +790,63 @@ func TestLeaveRouteHandler(t *testing.T) {
 		config.AppConfig{Env: "test", Port: "8080"},
 		stubHealthChecker{},
 		stubRouteService{
-			leaveRouteFn: func(_ context.Context, code, token string) (routes.LeaveRouteResult, error) {
+			exportRouteFn: func(context.Context, string, string) (routes.RouteExport, error) {
+				t.Fatal("ExportRoute() should not run for an unknown format")
+				return routes.RouteExport{}, nil
+			},
+		},
+	)
+
+	request := httptest.NewRequest(http.MethodGet, "/routes/K7P9QD/export?format=shp", nil)
+	request.Header.Set("Authorization", "Bearer member-token")
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestReplayRouteHandler(t *testing.T) {
+	t.Parallel()
+
+	startedAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	from := startedAt.Add(time.Minute)
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			replayFn: func(_ context.Context, code, token string, query routes.ReplayQuery) (routes.ReplayPage, error) {
 				if code != "K7P9QD" || token != "member-token" {
-					t.Fatalf("LeaveRoute() got code=%q token=%q", code, token)
+					t.Fatalf("Replay() got code=%q token=%q", code, token)
+				}
+				if query.Speed != 4 || query.Limit != 2 || query.Cursor != "" || query.From == nil || !query.From.Equal(from) {
+					t.Fatalf("Replay() query = %#v, want speed 4, limit 2, from %s", query, from)
 				}
 
-				now := time.Now().UTC()
-				return routes.LeaveRouteResult{
-					Member: routes.Member{
-						ID:     "member-2",
-						Status: routes.MemberStatusLeft,
-						LeftAt: &now,
+				return routes.ReplayPage{
+					RouteID:   "route-1",
+					StartedAt: startedAt,
+					Speed:     4,
+					Events: []routes.ReplayEvent{
+						{
+							Type:             "position_updated",
+							At:               from,
+							PlaybackOffsetMS: 0,
+							MemberID:         "member-1",
+							SegmentID:        "segment-1",
+							Point:            &routes.RoutePoint{Seq: 1, Latitude: 46.0569, Longitude: 14.5058, RecordedAt: from},
+						},
 					},
+					NextCursor: "2",
 				}, nil
 			},
 		},
 	)
 
-	request := httptest.NewRequest(http.MethodDelete, "/routes/K7P9QD/members/me", nil)
+	request := httptest.NewRequest(http.MethodGet, "/routes/K7P9QD/replay?speed=4x&limit=2&from="+from.Format(time.RFC3339), nil)
 	request.Header.Set("Authorization", "Bearer member-token")
 	recorder := httptest.NewRecorder()
 
@@ -569,12 +856,30 @@ func TestLeaveRouteHandler(t *testing.T) {
 		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusOK)
 	}
 
-	if !strings.Contains(recorder.Body.String(), `"status":"left"`) {
-		t.Fatalf("ServeHTTP() body = %q, want left status", recorder.Body.String())
+	var body struct {
+		RouteID    string           `json:"routeId"`
+		Speed      int              `json:"speed"`
+		NextCursor string           `json:"nextCursor"`
+		Events     []map[string]any `json:"events"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if body.RouteID != "route-1" || body.Speed != 4 || body.NextCursor != "2" || len(body.Events) != 1 {
+		t.Fatalf("ServeHTTP() body = %s, want one replay event and next cursor", recorder.Body.String())
+	}
+
+	event := body.Events[0]
+	if event["type"] != "position_updated" || event["memberId"] != "member-1" || event["segmentId"] != "segment-1" {
+		t.Fatalf("replay event = %#v, want live position_updated shape", event)
+	}
+	if _, ok := event["point"].(map[string]any); !ok {
+		t.Fatalf("replay event point = %#v, want point object", event["point"])
 	}
 }
 
-func TestDeleteRouteHandler(t *testing.T) {
+func TestReplayRouteHandlerRejectsActiveRoute(t *testing.T) {
 	t.Parallel()
 
 	handler := NewHandler(
@@ -582,40 +887,1521 @@ func TestDeleteRouteHandler(t *testing.T) {
 		config.AppConfig{Env: "test", Port: "8080"},
 		stubHealthChecker{},
 		stubRouteService{
-			deleteRouteFn: func(_ context.Context, code, token string) error {
-				if code != "K7P9QD" || token != "owner-token" {
-					t.Fatalf("DeleteRoute() got code=%q token=%q", code, token)
-				}
-
-				return nil
+			replayFn: func(context.Context, string, string, routes.ReplayQuery) (routes.ReplayPage, error) {
+				return routes.ReplayPage{}, routes.ErrRouteActive
 			},
 		},
 	)
 
-	request := httptest.NewRequest(http.MethodDelete, "/routes/K7P9QD", nil)
-	request.Header.Set("Authorization", "Bearer owner-token")
+	request := httptest.NewRequest(http.MethodGet, "/routes/K7P9QD/replay", nil)
+	request.Header.Set("Authorization", "Bearer member-token")
 	recorder := httptest.NewRecorder()
 
 	handler.ServeHTTP(recorder, request)
 
-	if recorder.Code != http.StatusNoContent {
-		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusNoContent)
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusConflict)
 	}
 }
 
-func TestWebSocketAuthenticatesFirstMessage(t *testing.T) {
+func TestCreateRouteMemberHandler(t *testing.T) {
 	t.Parallel()
 
 	handler := NewHandler(
 		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
-		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			joinRouteFn: func(_ context.Context, code string, input routes.JoinRouteInput) (routes.JoinRouteResult, error) {
+				if code != "K7P9QD" {
+					t.Fatalf("JoinRoute() code = %q, want K7P9QD", code)
+				}
+
+				if input.DisplayName != "Matej" {
+					t.Fatalf("JoinRoute() displayName = %q, want Matej", input.DisplayName)
+				}
+
+				return routes.JoinRouteResult{
+					Route: routes.Route{
+						Code:          "K7P9QD",
+						Name:          "Morning convoy",
+						SharingPolicy: routes.SharingPolicyEveryoneCanShare,
+						Status:        routes.RouteStatusActive,
+					},
+					Member: routes.Member{
+						ID:          "member-2",
+						DisplayName: input.DisplayName,
+					},
+					MemberToken: "member-token",
+				}, nil
+			},
+		},
+	)
+
+	body := map[string]string{
+		"clientId":      "client-2",
+		"displayName":   "Matej",
+		"transportMode": "train",
+		"password":      "secret",
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/routes/K7P9QD/members", bytes.NewReader(payload))
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusCreated)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `"memberToken":"member-token"`) {
+		t.Fatalf("ServeHTTP() body = %q, want member token", recorder.Body.String())
+	}
+}
+
+func TestCreateRouteMemberLocksOutWrongPasswords(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			joinRouteFn: func(context.Context, string, routes.JoinRouteInput) (routes.JoinRouteResult, error) {
+				attempts++
+				return routes.JoinRouteResult{}, routes.ErrInvalidPassword
+			},
+		},
+		WithJoinLimiter(ratelimit.NewJoinLimiter(ratelimit.NewMemoryStore(), ratelimit.JoinPolicy{
+			Lockout: ratelimit.Lockout{FreeFailures: 2, Base: 30 * time.Second, Max: time.Minute},
+		})),
+	)
+
+	join := func(remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/routes/k7p9qd/members", strings.NewReader(`{"displayName":"Matej","password":"guess"}`))
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for range 3 {
+		if recorder := join("203.0.113.7:4100"); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong password status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+		}
+	}
+
+	recorder := join("203.0.113.7:4200")
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "30" {
+		t.Fatalf("locked out status = %d, Retry-After = %q; want %d and 30", recorder.Code, recorder.Header().Get("Retry-After"), http.StatusTooManyRequests)
+	}
+	if !strings.Contains(recorder.Body.String(), `"error":"rate_limited"`) || attempts != 3 {
+		t.Fatalf("locked out body = %q, join attempts = %d; want rate_limited after 3 attempts", recorder.Body.String(), attempts)
+	}
+
+	if recorder := join("198.51.100.4:4100"); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("other client status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestUpdateRouteHandler(t *testing.T) {
+	t.Parallel()
+
+	var recordedEvents []string
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			updateRouteFn: func(_ context.Context, code, token string, input routes.UpdateRouteInput) (routes.UpdateRouteResult, error) {
+				if code != "K7P9QD" || token != "owner-token" {
+					t.Fatalf("UpdateRoute() got code=%q token=%q", code, token)
+				}
+
+				if input.Status != routes.RouteStatusClosed {
+					t.Fatalf("UpdateRoute() status = %q, want closed", input.Status)
+				}
+
+				return routes.UpdateRouteResult{
+					Route: routes.Route{
+						ID:            "route-1",
+						Code:          code,
+						Name:          "Morning convoy",
+						Status:        routes.RouteStatusClosed,
+						SharingPolicy: routes.SharingPolicyEveryoneCanShare,
+					},
+					Actor: routes.Member{ID: "member-1", IsOwner: true},
+				}, nil
+			},
+			recordRouteEventFn: func(_ context.Context, routeID, actorMemberID string, payload map[string]any) (routes.RouteEvent, error) {
+				recordedEvents = append(recordedEvents, routeID+"/"+actorMemberID+"/"+payload["type"].(string))
+				return routes.RouteEvent{}, nil
+			},
+		},
+	)
+
+	body := map[string]string{
+		"status": routes.RouteStatusClosed,
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	request := httptest.NewRequest(http.MethodPatch, "/routes/K7P9QD", bytes.NewReader(payload))
+	request.Header.Set("Authorization", "Bearer owner-token")
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `"status":"closed"`) {
+		t.Fatalf("ServeHTTP() body = %q, want closed status", recorder.Body.String())
+	}
+
+	if len(recordedEvents) != 1 || recordedEvents[0] != "route-1/member-1/route_closed" {
+		t.Fatalf("RecordRouteEvent() calls = %v, want route_closed by owner", recordedEvents)
+	}
+}
+
+func TestLeaveRouteHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			leaveRouteFn: func(_ context.Context, code, token string) (routes.LeaveRouteResult, error) {
+				if code != "K7P9QD" || token != "member-token" {
+					t.Fatalf("LeaveRoute() got code=%q token=%q", code, token)
+				}
+
+				now := time.Now().UTC()
+				return routes.LeaveRouteResult{
+					Member: routes.Member{
+						ID:     "member-2",
+						Status: routes.MemberStatusLeft,
+						LeftAt: &now,
+					},
+				}, nil
+			},
+		},
+	)
+
+	request := httptest.NewRequest(http.MethodDelete, "/routes/K7P9QD/members/me", nil)
+	request.Header.Set("Authorization", "Bearer member-token")
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `"status":"left"`) {
+		t.Fatalf("ServeHTTP() body = %q, want left status", recorder.Body.String())
+	}
+}
+
+func TestTrackingPermissionHandlers(t *testing.T) {
+	t.Parallel()
+
+	var recordedEvents []string
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			setTrackingFn: func(_ context.Context, code, token, memberID string, granted bool) (routes.SetTrackingPermissionResult, error) {
+				if code != "K7P9QD" || token != "owner-token" {
+					t.Fatalf("SetTrackingPermission() got code=%q token=%q", code, token)
+				}
+				if memberID == "missing" {
+					return routes.SetTrackingPermissionResult{}, routes.ErrMemberNotFound
+				}
+
+				return routes.SetTrackingPermissionResult{
+					Member:         routes.Member{ID: memberID, RouteID: "route-1", CanTrack: granted, Status: routes.MemberStatusSpectating},
+					Change:         &routes.TrackingPermissionChange{MemberID: memberID, Granted: granted, ChangedBy: "owner-1", ChangedAt: time.Now().UTC()},
+					StoppedSharing: !granted,
+					Actor:          routes.Member{ID: "owner-1", IsOwner: true},
+				}, nil
+			},
+			recordRouteEventFn: func(_ context.Context, routeID, actorMemberID string, payload map[string]any) (routes.RouteEvent, error) {
+				recordedEvents = append(recordedEvents, routeID+"/"+actorMemberID+"/"+payload["type"].(string))
+				return routes.RouteEvent{}, nil
+			},
+		},
+	)
+
+	request := httptest.NewRequest(http.MethodPut, "/routes/K7P9QD/members/member-2/tracking-permission", nil)
+	request.Header.Set("Authorization", "Bearer owner-token")
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("grant status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `"canTrack":true`) || !strings.Contains(recorder.Body.String(), `"granted":true`) {
+		t.Fatalf("grant body = %q, want granted member", recorder.Body.String())
+	}
+
+	request = httptest.NewRequest(http.MethodDelete, "/routes/K7P9QD/members/member-2/tracking-permission", nil)
+	request.Header.Set("Authorization", "Bearer owner-token")
+	recorder = httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("revoke status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `"stoppedSharing":true`) {
+		t.Fatalf("revoke body = %q, want stopped sharing", recorder.Body.String())
+	}
+
+	want := []string{
+		"route-1/owner-1/member_tracking_permission_changed",
+		"route-1/owner-1/member_tracking_permission_changed",
+		"route-1/owner-1/member_stopped_sharing",
+	}
+	if !slices.Equal(recordedEvents, want) {
+		t.Fatalf("RecordRouteEvent() calls = %v, want %v", recordedEvents, want)
+	}
+
+	request = httptest.NewRequest(http.MethodDelete, "/routes/K7P9QD/members/missing/tracking-permission", nil)
+	request.Header.Set("Authorization", "Bearer owner-token")
+	recorder = httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNotFound || !strings.Contains(recorder.Body.String(), "member_not_found") {
+		t.Fatalf("missing member response = %d %q, want 404 member_not_found", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestModeratorHandlers(t *testing.T) {
+	t.Parallel()
+
+	var recordedEvents []string
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			setModeratorFn: func(_ context.Context, code, token, memberID string, moderator bool) (routes.SetModeratorResult, error) {
+				if code != "K7P9QD" || token != "owner-token" || memberID != "member-2" {
+					t.Fatalf("SetModerator() got code=%q token=%q memberID=%q", code, token, memberID)
+				}
+
+				return routes.SetModeratorResult{
+					Member:  routes.Member{ID: memberID, RouteID: "route-1", IsModerator: moderator},
+					Changed: moderator,
+					Actor:   routes.Member{ID: "owner-1", IsOwner: true},
+				}, nil
+			},
+			recordRouteEventFn: func(_ context.Context, routeID, actorMemberID string, payload map[string]any) (routes.RouteEvent, error) {
+				recordedEvents = append(recordedEvents, routeID+"/"+actorMemberID+"/"+payload["type"].(string))
+				return routes.RouteEvent{}, nil
+			},
+		},
+	)
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		request := httptest.NewRequest(method, "/routes/K7P9QD/members/member-2/moderator", nil)
+		request.Header.Set("Authorization", "Bearer owner-token")
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s moderator status = %d, want %d", method, recorder.Code, http.StatusOK)
+		}
+	}
+
+	if want := []string{"route-1/owner-1/member_role_changed"}; !slices.Equal(recordedEvents, want) {
+		t.Fatalf("RecordRouteEvent() calls = %v, want %v", recordedEvents, want)
+	}
+}
+
+func TestRecordPositionBatchHandler(t *testing.T) {
+	t.Parallel()
+
+	var recorded []map[string]any
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			recordPositionBatchFn: func(_ context.Context, code, token string, samples []routes.PositionUpdateInput) (routes.PositionBatchResult, error) {
+				if code != "K7P9QD" || token != "member-token" {
+					t.Fatalf("RecordPositionBatch() got code=%q token=%q", code, token)
+				}
+				if len(samples) != 2 || samples[1].ClientRecordedAt == nil || !strings.Contains(string(samples[1].RawPayload), `"accuracyM":8`) {
+					t.Fatalf("RecordPositionBatch() samples = %#v, want two samples with raw payloads", samples)
+				}
+
+				return routes.PositionBatchResult{
+					RouteID:  "route-1",
+					MemberID: "member-2",
+					Segments: []routes.PositionBatchSegment{
+						{SegmentID: "segment-1", Points: []routes.RoutePoint{{Seq: 7, Latitude: samples[0].Latitude, Longitude: samples[0].Longitude}}},
+						{SegmentID: "segment-2", Points: []routes.RoutePoint{{Seq: 1, Latitude: samples[1].Latitude, Longitude: samples[1].Longitude}}},
+					},
+					Duplicates: 1,
+				}, nil
+			},
+			recordRouteEventFn: func(_ context.Context, _, _ string, payload map[string]any) (routes.RouteEvent, error) {
+				recorded = append(recorded, payload)
+				return routes.RouteEvent{}, nil
+			},
+		},
+	)
+
+	request := httptest.NewRequest(http.MethodPost, "/routes/K7P9QD/positions:batch", strings.NewReader(`{"positions":[
+		{"latitude":46.0569,"longitude":14.5058,"clientRecordedAt":"2026-05-01T08:00:00Z"},
+		{"latitude":46.0570,"longitude":14.5059,"accuracyM":8,"clientRecordedAt":"2026-05-01T08:00:05Z"}
+	]}`))
+	request.Header.Set("Authorization", "Bearer member-token")
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `"duplicates":1`) {
+		t.Fatalf("ServeHTTP() body = %q, want duplicate count", recorder.Body.String())
+	}
+
+	if len(recorded) != 2 {
+		t.Fatalf("recorded events = %#v, want one positions_backfilled per segment", recorded)
+	}
+	for i, wantSegmentID := range []string{"segment-1", "segment-2"} {
+		if recorded[i]["type"] != "positions_backfilled" || recorded[i]["segmentId"] != wantSegmentID {
+			t.Fatalf("recorded event %d = %#v, want positions_backfilled for %s", i, recorded[i], wantSegmentID)
+		}
+		if points, _ := recorded[i]["points"].([]routes.RoutePoint); len(points) != 1 {
+			t.Fatalf("positions_backfilled points = %#v, want 1", recorded[i]["points"])
+		}
+	}
+}
+
+func TestDeleteRouteHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			deleteRouteFn: func(_ context.Context, code, token string) error {
+				if code != "K7P9QD" || token != "owner-token" {
+					t.Fatalf("DeleteRoute() got code=%q token=%q", code, token)
+				}
+
+				return nil
+			},
+		},
+	)
+
+	request := httptest.NewRequest(http.MethodDelete, "/routes/K7P9QD", nil)
+	request.Header.Set("Authorization", "Bearer owner-token")
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+}
+
+func TestWebSocketAuthenticatesFirstMessage(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		stubHealthChecker{},
+		stubRouteService{
+			authorizeMemberFn: func(_ context.Context, token string) (routes.AuthorizedMember, error) {
+				if token != "member-token" {
+					t.Fatalf("AuthorizeMember() token = %q, want member-token", token)
+				}
+
+				return routes.AuthorizedMember{
+					Route: routes.Route{
+						ID:     "route-1",
+						Code:   "K7P9QD",
+						Status: routes.RouteStatusActive,
+					},
+					Member: routes.Member{
+						ID:     "member-1",
+						Status: routes.MemberStatusSpectating,
+					},
+				}, nil
+			},
+		},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	defer func() {
+		_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":        "authenticate",
+		"memberToken": "member-token",
+	}); err != nil {
+		t.Fatalf("wsjson.Write() error = %v", err)
+	}
+
+	var event struct {
+		Type  string `json:"type"`
+		Route struct {
+			Code string `json:"code"`
+		} `json:"route"`
+		Member struct {
+			ID string `json:"id"`
+		} `json:"member"`
+	}
+	if err := wsjson.Read(ctx, connection, &event); err != nil {
+		t.Fatalf("wsjson.Read() error = %v", err)
+	}
+
+	if event.Type != "connection_established" {
+		t.Fatalf("event type = %q, want connection_established", event.Type)
+	}
+
+	if event.Route.Code != "K7P9QD" || event.Member.ID != "member-1" {
+		t.Fatalf("event route/member = %q/%q, want K7P9QD/member-1", event.Route.Code, event.Member.ID)
+	}
+}
+
+func TestWebSocketReplaysMissedEventsAfterLastEventSeq(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		events     []routes.RouteEvent
+		err        error
+		wantEvents []map[string]any
+	}{
+		{
+			name: "replays logged events in order",
+			events: []routes.RouteEvent{
+				{ID: 42, Type: "member_joined", Payload: json.RawMessage(`{"type":"member_joined","member":{"id":"member-2"}}`)},
+				{ID: 43, Type: "member_left", Payload: json.RawMessage(`{"type":"member_left","member":{"id":"member-2"}}`)},
+			},
+			wantEvents: []map[string]any{
+				{"type": "member_joined", "eventSeq": float64(42), "replayed": true},
+				{"type": "member_left", "eventSeq": float64(43), "replayed": true},
+			},
+		},
+		{
+			name: "requests resync when the gap is too old",
+			err:  routes.ErrEventCatchUpUnavailable,
+			wantEvents: []map[string]any{
+				{"type": live.EventTypeResyncRequired, "reason": "catch_up_unavailable"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewHandler(
+				slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+				config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+				stubHealthChecker{},
+				stubRouteService{
+					authorizeMemberFn: func(_ context.Context, _ string) (routes.AuthorizedMember, error) {
+						return routes.AuthorizedMember{
+							Route:  routes.Route{ID: "route-1", Code: "K7P9QD", Status: routes.RouteStatusActive},
+							Member: routes.Member{ID: "member-1", Status: routes.MemberStatusSpectating},
+						}, nil
+					},
+					routeEventsSinceFn: func(_ context.Context, routeID string, afterSeq int64) ([]routes.RouteEvent, error) {
+						if routeID != "route-1" || afterSeq != 41 {
+							t.Fatalf("RouteEventsSince() = %q/%d, want route-1/41", routeID, afterSeq)
+						}
+
+						return tc.events, tc.err
+					},
+				},
+			)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+			if err != nil {
+				t.Fatalf("websocket.Dial() error = %v", err)
+			}
+			defer func() {
+				_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+			}()
+
+			if err := wsjson.Write(ctx, connection, map[string]any{
+				"type":         "authenticate",
+				"memberToken":  "member-token",
+				"lastEventSeq": 41,
+			}); err != nil {
+				t.Fatalf("wsjson.Write() error = %v", err)
+			}
+
+			var established map[string]any
+			if err := wsjson.Read(ctx, connection, &established); err != nil {
+				t.Fatalf("read connection_established error = %v", err)
+			}
+
+			for _, want := range tc.wantEvents {
+				var event map[string]any
+				if err := wsjson.Read(ctx, connection, &event); err != nil {
+					t.Fatalf("read catch-up event error = %v", err)
+				}
+				for key, value := range want {
+					if event[key] != value {
+						t.Fatalf("catch-up event %s = %v, want %v (event %#v)", key, event[key], value, event)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWebSocketRejectsMemberConnectedToAnotherInstance(t *testing.T) {
+	t.Parallel()
+
+	broker := live.NewMemoryBroker()
+	routeService := stubRouteService{
+		authorizeMemberFn: func(context.Context, string) (routes.AuthorizedMember, error) {
+			return routes.AuthorizedMember{
+				Route:  routes.Route{ID: "route-1", Code: "K7P9QD", Status: routes.RouteStatusActive},
+				Member: routes.Member{ID: "member-1", Status: routes.MemberStatusSpectating},
+			}, nil
+		},
+	}
+	newInstance := func() *httptest.Server {
+		logger := slog.New(slog.NewTextHandler(testWriter{t: t}, nil))
+		hub := live.NewClusterHub(logger, broker, broker)
+		return httptest.NewServer(NewHandler(
+			logger,
+			config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+			stubHealthChecker{},
+			routeService,
+			WithLiveHub(hub),
+		))
+	}
+	first := newInstance()
+	defer first.Close()
+	second := newInstance()
+	defer second.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connect := func(serverURL string) (*websocket.Conn, map[string]any) {
+		connection, _, err := websocket.Dial(ctx, webSocketURL(serverURL), nil)
+		if err != nil {
+			t.Fatalf("websocket.Dial() error = %v", err)
+		}
+		if err := wsjson.Write(ctx, connection, map[string]string{
+			"type":        "authenticate",
+			"memberToken": "member-token",
+		}); err != nil {
+			t.Fatalf("wsjson.Write() error = %v", err)
+		}
+
+		var event map[string]any
+		if err := wsjson.Read(ctx, connection, &event); err != nil {
+			t.Fatalf("wsjson.Read() error = %v", err)
+		}
+
+		return connection, event
+	}
+
+	firstConnection, established := connect(first.URL)
+	defer func() {
+		_ = firstConnection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+	if established["type"] != "connection_established" {
+		t.Fatalf("first instance event = %#v, want connection_established", established)
+	}
+
+	secondConnection, rejected := connect(second.URL)
+	defer func() {
+		_ = secondConnection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+	if rejected["type"] != "live_connection_rejected" || rejected["reason"] != "already_active_connection" {
+		t.Fatalf("second instance event = %#v, want already_active_connection rejection", rejected)
+	}
+}
+
+func TestWebSocketReceivesMemberJoinedBroadcast(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		stubHealthChecker{},
+		stubRouteService{
+			authorizeMemberFn: func(_ context.Context, token string) (routes.AuthorizedMember, error) {
+				if token != "member-token" {
+					t.Fatalf("AuthorizeMember() token = %q, want member-token", token)
+				}
+
+				return routes.AuthorizedMember{
+					Route: routes.Route{
+						ID:     "route-1",
+						Code:   "K7P9QD",
+						Status: routes.RouteStatusActive,
+					},
+					Member: routes.Member{
+						ID:     "member-1",
+						Status: routes.MemberStatusSpectating,
+					},
+				}, nil
+			},
+			joinRouteFn: func(_ context.Context, code string, input routes.JoinRouteInput) (routes.JoinRouteResult, error) {
+				if code != "K7P9QD" {
+					t.Fatalf("JoinRoute() code = %q, want K7P9QD", code)
+				}
+
+				return routes.JoinRouteResult{
+					Route: routes.Route{
+						ID:     "route-1",
+						Code:   "K7P9QD",
+						Status: routes.RouteStatusActive,
+					},
+					Member: routes.Member{
+						ID:            "member-2",
+						RouteID:       "route-1",
+						DisplayName:   input.DisplayName,
+						TransportMode: input.TransportMode,
+						Status:        routes.MemberStatusSpectating,
+						JoinedAt:      now,
+					},
+					MemberToken: "new-member-token",
+				}, nil
+			},
+		},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	defer func() {
+		_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":        "authenticate",
+		"memberToken": "member-token",
+	}); err != nil {
+		t.Fatalf("wsjson.Write() error = %v", err)
+	}
+
+	var established map[string]any
+	if err := wsjson.Read(ctx, connection, &established); err != nil {
+		t.Fatalf("read connection_established error = %v", err)
+	}
+
+	body := map[string]string{
+		"clientId":      "client-2",
+		"displayName":   "Matej",
+		"transportMode": "train",
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	response, err := http.Post(server.URL+"/routes/K7P9QD/members", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("http.Post() error = %v", err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /routes/K7P9QD/members status = %d, want %d", response.StatusCode, http.StatusCreated)
+	}
+
+	var event struct {
+		Type   string `json:"type"`
+		Member struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"displayName"`
+			Status      string `json:"status"`
+		} `json:"member"`
+	}
+	if err := wsjson.Read(ctx, connection, &event); err != nil {
+		t.Fatalf("read member_joined event error = %v", err)
+	}
+
+	if event.Type != "member_joined" {
+		t.Fatalf("event type = %q, want member_joined", event.Type)
+	}
+
+	if event.Member.ID != "member-2" || event.Member.DisplayName != "Matej" || event.Member.Status != routes.MemberStatusSpectating {
+		t.Fatalf("event member = %#v, want joined member", event.Member)
+	}
+}
+
+func TestRemoveMemberHandlerClosesLiveConnection(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		stubHealthChecker{},
+		stubRouteService{
+			authorizeMemberFn: func(context.Context, string) (routes.AuthorizedMember, error) {
+				return routes.AuthorizedMember{
+					Route:  routes.Route{ID: "route-1", Code: "K7P9QD", Status: routes.RouteStatusActive},
+					Member: routes.Member{ID: "member-2", RouteID: "route-1", Status: routes.MemberStatusSpectating},
+				}, nil
+			},
+			removeMemberFn: func(_ context.Context, code, token, memberID string, ban bool) (routes.RemoveMemberResult, error) {
+				if code != "K7P9QD" || token != "owner-token" || memberID != "member-2" || !ban {
+					t.Fatalf("RemoveMember() got code=%q token=%q memberID=%q ban=%v", code, token, memberID, ban)
+				}
+
+				now := time.Now().UTC()
+				return routes.RemoveMemberResult{
+					Member: routes.Member{ID: memberID, RouteID: "route-1", Status: routes.MemberStatusRemoved, LeftAt: &now},
+					Banned: ban,
+					Actor:  routes.Member{ID: "owner-1", IsOwner: true},
+				}, nil
+			},
+		},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	defer func() {
+		_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":        "authenticate",
+		"memberToken": "member-token",
+	}); err != nil {
+		t.Fatalf("write authenticate error = %v", err)
+	}
+
+	var established map[string]any
+	if err := wsjson.Read(ctx, connection, &established); err != nil {
+		t.Fatalf("read connection_established error = %v", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodDelete, server.URL+"/routes/K7P9QD/members/member-2?ban=maybe", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest() error = %v", err)
+	}
+	request.Header.Set("Authorization", "Bearer owner-token")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("DELETE member error = %v", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("DELETE member with invalid ban status = %d, want %d", response.StatusCode, http.StatusBadRequest)
+	}
+
+	request, err = http.NewRequestWithContext(ctx, http.MethodDelete, server.URL+"/routes/K7P9QD/members/member-2?ban=true", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest() error = %v", err)
+	}
+	request.Header.Set("Authorization", "Bearer owner-token")
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("DELETE member error = %v", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE member status = %d, want %d", response.StatusCode, http.StatusOK)
+	}
+
+	var removed map[string]any
+	if err := wsjson.Read(ctx, connection, &removed); err != nil {
+		t.Fatalf("read member_removed error = %v", err)
+	}
+	member, _ := removed["member"].(map[string]any)
+	if removed["type"] != "member_removed" || removed["banned"] != true || member["status"] != routes.MemberStatusRemoved {
+		t.Fatalf("member_removed = %#v, want banned removed member", removed)
+	}
+
+	var next map[string]any
+	err = wsjson.Read(ctx, connection, &next)
+	if status := websocket.CloseStatus(err); status != websocket.StatusPolicyViolation {
+		t.Fatalf("read after removal error = %v (status %d), want policy violation close", err, status)
+	}
+}
+
+func TestJoinTrackingWaitlistOffersReservedSlot(t *testing.T) {
+	t.Parallel()
+
+	reservedUntil := time.Date(2026, 5, 1, 12, 1, 0, 0, time.UTC)
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		stubHealthChecker{},
+		stubRouteService{
+			authorizeMemberFn: func(context.Context, string) (routes.AuthorizedMember, error) {
+				return routes.AuthorizedMember{
+					Route:  routes.Route{ID: "route-1", Code: "K7P9QD", Status: routes.RouteStatusActive},
+					Member: routes.Member{ID: "member-2", RouteID: "route-1", Status: routes.MemberStatusSpectating},
+				}, nil
+			},
+			joinWaitlistFn: func(_ context.Context, code, memberToken string) (routes.TrackingWaitlistEntry, error) {
+				if code != "K7P9QD" || memberToken != "member-token" {
+					t.Fatalf("JoinTrackingWaitlist() got code=%q memberToken=%q", code, memberToken)
+				}
+
+				return routes.TrackingWaitlistEntry{MemberID: "member-2", Position: 1}, nil
+			},
+			offerTrackingSlotsFn: func(_ context.Context, routeID string) ([]routes.TrackingReservation, error) {
+				return []routes.TrackingReservation{{RouteID: routeID, MemberID: "member-2", ReservedUntil: reservedUntil}}, nil
+			},
+		},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	defer func() {
+		_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":        "authenticate",
+		"memberToken": "member-token",
+	}); err != nil {
+		t.Fatalf("write authenticate error = %v", err)
+	}
+
+	var established map[string]any
+	if err := wsjson.Read(ctx, connection, &established); err != nil {
+		t.Fatalf("read connection_established error = %v", err)
+	}
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":      "join_tracking_waitlist",
+		"requestId": "req-1",
+	}); err != nil {
+		t.Fatalf("write join_tracking_waitlist error = %v", err)
+	}
+
+	var ack map[string]any
+	if err := wsjson.Read(ctx, connection, &ack); err != nil {
+		t.Fatalf("read command_ack error = %v", err)
+	}
+	if ack["type"] != "command_ack" || ack["command"] != "join_tracking_waitlist" || ack["requestId"] != "req-1" || ack["position"] != float64(1) {
+		t.Fatalf("join_tracking_waitlist ack = %#v", ack)
+	}
+
+	var offer map[string]any
+	if err := wsjson.Read(ctx, connection, &offer); err != nil {
+		t.Fatalf("read tracking_slot_available error = %v", err)
+	}
+	if offer["type"] != "tracking_slot_available" || offer["reservedUntil"] != reservedUntil.Format(time.RFC3339) {
+		t.Fatalf("tracking_slot_available = %#v", offer)
+	}
+}
+
+func TestTransferOwnershipSendsTokenToRecipientOnly(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		stubHealthChecker{},
+		stubRouteService{
+			authorizeMemberFn: func(context.Context, string) (routes.AuthorizedMember, error) {
+				return routes.AuthorizedMember{
+					Route:  routes.Route{ID: "route-1", Code: "K7P9QD", Status: routes.RouteStatusActive},
+					Member: routes.Member{ID: "member-2", RouteID: "route-1", Status: routes.MemberStatusSpectating},
+				}, nil
+			},
+			transferOwnershipFn: func(_ context.Context, code, token, memberID string) (routes.TransferOwnershipResult, error) {
+				if code != "K7P9QD" || token != "owner-token" || memberID != "member-2" {
+					t.Fatalf("TransferOwnership() got code=%q token=%q memberID=%q", code, token, memberID)
+				}
+
+				return routes.TransferOwnershipResult{
+					PreviousOwner: routes.Member{ID: "owner-1", RouteID: "route-1"},
+					Owner:         routes.Member{ID: memberID, RouteID: "route-1", IsOwner: true},
+					OwnerToken:    "new-owner-token",
+				}, nil
+			},
+			issueOwnerTokenFn: func(_ context.Context, code, memberToken string) (string, error) {
+				if code != "K7P9QD" || memberToken != "member-token" {
+					t.Fatalf("IssueOwnerToken() got code=%q memberToken=%q", code, memberToken)
+				}
+
+				return "claimed-owner-token", nil
+			},
+		},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	defer func() {
+		_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":        "authenticate",
+		"memberToken": "member-token",
+	}); err != nil {
+		t.Fatalf("write authenticate error = %v", err)
+	}
+
+	var established map[string]any
+	if err := wsjson.Read(ctx, connection, &established); err != nil {
+		t.Fatalf("read connection_established error = %v", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, server.URL+"/routes/K7P9QD/owner", strings.NewReader(`{"memberId":"member-2"}`))
+	if err != nil {
+		t.Fatalf("http.NewRequest() error = %v", err)
+	}
+	request.Header.Set("Authorization", "Bearer owner-token")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("PUT owner error = %v", err)
+	}
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		t.Fatalf("read PUT owner body error = %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("PUT owner status = %d, want %d", response.StatusCode, http.StatusOK)
+	}
+	if strings.Contains(string(body), "new-owner-token") {
+		t.Fatalf("PUT owner body = %s, must not contain the recipient's owner token", body)
+	}
+
+	var changed map[string]any
+	if err := wsjson.Read(ctx, connection, &changed); err != nil {
+		t.Fatalf("read route_owner_changed error = %v", err)
+	}
+	owner, _ := changed["owner"].(map[string]any)
+	if changed["type"] != "route_owner_changed" || owner["id"] != "member-2" || changed["ownerToken"] != nil {
+		t.Fatalf("route_owner_changed = %#v, want member-2 as owner without a token", changed)
+	}
+
+	var issued map[string]any
+	if err := wsjson.Read(ctx, connection, &issued); err != nil {
+		t.Fatalf("read owner_token_issued error = %v", err)
+	}
+	if issued["type"] != "owner_token_issued" || issued["ownerToken"] != "new-owner-token" {
+		t.Fatalf("owner_token_issued = %#v, want the new owner token", issued)
+	}
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":      "claim_owner_token",
+		"requestId": "claim-1",
+	}); err != nil {
+		t.Fatalf("write claim_owner_token error = %v", err)
+	}
+
+	var claimed map[string]any
+	if err := wsjson.Read(ctx, connection, &claimed); err != nil {
+		t.Fatalf("read claimed owner token error = %v", err)
+	}
+	if claimed["type"] != "owner_token_issued" || claimed["requestId"] != "claim-1" || claimed["ownerToken"] != "claimed-owner-token" {
+		t.Fatalf("claim_owner_token reply = %#v, want claimed owner token", claimed)
+	}
+}
+
+func TestWebSocketRecordsAndBroadcastsPositionUpdate(t *testing.T) {
+	t.Parallel()
+
+	recordedAt := time.Now().UTC()
+	clientRecordedAt := recordedAt.Add(-2 * time.Second).UTC()
+	accuracy := 8.5
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
 		stubHealthChecker{},
 		stubRouteService{
 			authorizeMemberFn: func(_ context.Context, token string) (routes.AuthorizedMember, error) {
 				if token != "member-token" {
-					t.Fatalf("AuthorizeMember() token = %q, want member-token", token)
+					t.Fatalf("AuthorizeMember() token = %q, want member-token", token)
+				}
+
+				return routes.AuthorizedMember{
+					Route: routes.Route{
+						ID:     "route-1",
+						Code:   "K7P9QD",
+						Status: routes.RouteStatusActive,
+					},
+					Member: routes.Member{
+						ID:     "member-1",
+						Status: routes.MemberStatusTracking,
+					},
+				}, nil
+			},
+			recordPositionFn: func(_ context.Context, token string, input routes.PositionUpdateInput) (routes.PositionUpdateResult, error) {
+				if token != "member-token" {
+					t.Fatalf("RecordPosition() token = %q, want member-token", token)
+				}
+
+				if input.Latitude != 46.0569 || input.Longitude != 14.5058 {
+					t.Fatalf("RecordPosition() coordinates = %f,%f", input.Latitude, input.Longitude)
+				}
+
+				if input.AccuracyM == nil || *input.AccuracyM != accuracy {
+					t.Fatal("RecordPosition() expected accuracy")
+				}
+
+				if input.ClientRecordedAt == nil || !input.ClientRecordedAt.Equal(clientRecordedAt) {
+					t.Fatal("RecordPosition() expected client recorded time")
+				}
+
+				if !strings.Contains(string(input.RawPayload), `"type":"position_update"`) {
+					t.Fatalf("RecordPosition() raw payload = %s, want original message", string(input.RawPayload))
+				}
+
+				return routes.PositionUpdateResult{
+					RouteID:   "route-1",
+					MemberID:  "member-1",
+					SegmentID: "segment-1",
+					Point: routes.RoutePoint{
+						Seq:              7,
+						Latitude:         input.Latitude,
+						Longitude:        input.Longitude,
+						AccuracyM:        input.AccuracyM,
+						ClientRecordedAt: input.ClientRecordedAt,
+						RecordedAt:       recordedAt,
+					},
+				}, nil
+			},
+		},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	defer func() {
+		_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":        "authenticate",
+		"memberToken": "member-token",
+	}); err != nil {
+		t.Fatalf("write authenticate error = %v", err)
+	}
+
+	var established map[string]any
+	if err := wsjson.Read(ctx, connection, &established); err != nil {
+		t.Fatalf("read connection_established error = %v", err)
+	}
+
+	if err := wsjson.Write(ctx, connection, map[string]any{
+		"type":             "position_update",
+		"latitude":         46.0569,
+		"longitude":        14.5058,
+		"accuracyM":        accuracy,
+		"clientRecordedAt": clientRecordedAt,
+	}); err != nil {
+		t.Fatalf("write position_update error = %v", err)
+	}
+
+	var event struct {
+		Type      string `json:"type"`
+		MemberID  string `json:"memberId"`
+		SegmentID string `json:"segmentId"`
+		Point     struct {
+			Seq       int64   `json:"seq"`
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"point"`
+	}
+	if err := wsjson.Read(ctx, connection, &event); err != nil {
+		t.Fatalf("read position_updated event error = %v", err)
+	}
+
+	if event.Type != "position_updated" {
+		t.Fatalf("event type = %q, want position_updated", event.Type)
+	}
+
+	if event.MemberID != "member-1" || event.SegmentID != "segment-1" {
+		t.Fatalf("event member/segment = %q/%q, want member-1/segment-1", event.MemberID, event.SegmentID)
+	}
+
+	if event.Point.Seq != 7 || event.Point.Latitude != 46.0569 || event.Point.Longitude != 14.5058 {
+		t.Fatalf("event point = %#v, want accepted point", event.Point)
+	}
+}
+
+func TestWebSocketPostsAndBroadcastsChatMessage(t *testing.T) {
+	t.Parallel()
+
+	postedAt := time.Now().UTC()
+	posted := 0
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		stubHealthChecker{},
+		stubRouteService{
+			authorizeMemberFn: func(context.Context, string) (routes.AuthorizedMember, error) {
+				return routes.AuthorizedMember{
+					Route:  routes.Route{ID: "route-1", Code: "K7P9QD", Status: routes.RouteStatusActive},
+					Member: routes.Member{ID: "member-1", Status: routes.MemberStatusSpectating},
+				}, nil
+			},
+			postChatMessageFn: func(_ context.Context, token, body string) (routes.ChatMessage, error) {
+				if token != "member-token" {
+					t.Fatalf("PostChatMessage() token = %q, want member-token", token)
+				}
+
+				posted++
+				if posted > 1 {
+					return routes.ChatMessage{}, routes.ErrChatRateLimited
+				}
+
+				return routes.ChatMessage{ID: "message-1", MemberID: "member-1", Body: body, PostedAt: postedAt}, nil
+			},
+		},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	defer func() {
+		_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":        "authenticate",
+		"memberToken": "member-token",
+	}); err != nil {
+		t.Fatalf("write authenticate error = %v", err)
+	}
+
+	var established map[string]any
+	if err := wsjson.Read(ctx, connection, &established); err != nil {
+		t.Fatalf("read connection_established error = %v", err)
+	}
+
+	if err := wsjson.Write(ctx, connection, map[string]any{
+		"type":      "chat_message",
+		"requestId": "chat-1",
+		"body":      "Waiting at the bridge",
+	}); err != nil {
+		t.Fatalf("write chat_message error = %v", err)
+	}
+
+	events := map[string]map[string]any{}
+	for range 2 {
+		var event map[string]any
+		if err := wsjson.Read(ctx, connection, &event); err != nil {
+			t.Fatalf("read chat event error = %v", err)
+		}
+		events[event["type"].(string)] = event
+	}
+
+	if ack := events["command_ack"]; ack == nil || ack["requestId"] != "chat-1" || ack["command"] != "chat_message" {
+		t.Fatalf("chat ack = %#v, want command_ack for chat-1", ack)
+	}
+
+	postedEvent := events["chat_message_posted"]
+	message, ok := postedEvent["message"].(map[string]any)
+	if !ok || message["body"] != "Waiting at the bridge" || message["memberId"] != "member-1" {
+		t.Fatalf("chat_message_posted = %#v, want posted message", postedEvent)
+	}
+
+	if err := wsjson.Write(ctx, connection, map[string]any{
+		"type":      "chat_message",
+		"requestId": "chat-2",
+		"body":      "Again",
+	}); err != nil {
+		t.Fatalf("write second chat_message error = %v", err)
+	}
+
+	var rejected map[string]any
+	if err := wsjson.Read(ctx, connection, &rejected); err != nil {
+		t.Fatalf("read chat rejection error = %v", err)
+	}
+
+	if rejected["type"] != "command_rejected" || rejected["reason"] != "chat_rate_limited" {
+		t.Fatalf("chat rejection = %#v, want chat_rate_limited command_rejected", rejected)
+	}
+}
+
+func TestWebSocketChangesTransportMode(t *testing.T) {
+	t.Parallel()
+
+	changedAt := time.Now().UTC()
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		stubHealthChecker{},
+		stubRouteService{
+			authorizeMemberFn: func(context.Context, string) (routes.AuthorizedMember, error) {
+				return routes.AuthorizedMember{
+					Route:  routes.Route{ID: "route-1", Code: "K7P9QD", Status: routes.RouteStatusActive},
+					Member: routes.Member{ID: "member-1", TransportMode: "walking", Status: routes.MemberStatusTracking},
+				}, nil
+			},
+			changeTransportModeFn: func(_ context.Context, code, token, transportMode string) (routes.ChangeTransportModeResult, error) {
+				if code != "K7P9QD" || token != "member-token" {
+					t.Fatalf("ChangeTransportMode() got code=%q token=%q", code, token)
+				}
+				if transportMode == "rocket" {
+					return routes.ChangeTransportModeResult{}, routes.ErrInvalidInput
+				}
+
+				return routes.ChangeTransportModeResult{
+					Member: routes.Member{ID: "member-1", RouteID: "route-1", TransportMode: transportMode, Status: routes.MemberStatusTracking},
+					Change: &routes.TransportChange{MemberID: "member-1", FromTransportMode: "walking", TransportMode: transportMode, ChangedAt: changedAt},
+				}, nil
+			},
+		},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
+	}
+	defer func() {
+		_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
+
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":        "authenticate",
+		"memberToken": "member-token",
+	}); err != nil {
+		t.Fatalf("write authenticate error = %v", err)
+	}
+
+	var established map[string]any
+	if err := wsjson.Read(ctx, connection, &established); err != nil {
+		t.Fatalf("read connection_established error = %v", err)
+	}
+
+	if err := wsjson.Write(ctx, connection, map[string]any{
+		"type":          "change_transport_mode",
+		"requestId":     "mode-1",
+		"transportMode": "bus",
+	}); err != nil {
+		t.Fatalf("write change_transport_mode error = %v", err)
+	}
+
+	events := map[string]map[string]any{}
+	for range 2 {
+		var event map[string]any
+		if err := wsjson.Read(ctx, connection, &event); err != nil {
+			t.Fatalf("read transport event error = %v", err)
+		}
+		events[event["type"].(string)] = event
+	}
+
+	if ack := events["command_ack"]; ack == nil || ack["requestId"] != "mode-1" || ack["command"] != "change_transport_mode" {
+		t.Fatalf("transport ack = %#v, want command_ack for mode-1", ack)
+	}
+
+	changed := events["member_transport_changed"]
+	member, _ := changed["member"].(map[string]any)
+	change, _ := changed["change"].(map[string]any)
+	if member["transportMode"] != "bus" || change["fromTransportMode"] != "walking" || change["transportMode"] != "bus" {
+		t.Fatalf("member_transport_changed = %#v, want walking to bus", changed)
+	}
+
+	if err := wsjson.Write(ctx, connection, map[string]any{
+		"type":          "change_transport_mode",
+		"requestId":     "mode-2",
+		"transportMode": "rocket",
+	}); err != nil {
+		t.Fatalf("write invalid change_transport_mode error = %v", err)
+	}
+
+	var rejected map[string]any
+	if err := wsjson.Read(ctx, connection, &rejected); err != nil {
+		t.Fatalf("read transport rejection error = %v", err)
+	}
+
+	if rejected["type"] != "command_rejected" || rejected["reason"] != "invalid_input" {
+		t.Fatalf("transport rejection = %#v, want invalid_input command_rejected", rejected)
+	}
+}
+
+func TestChatMessagesHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			listChatMessagesFn: func(_ context.Context, code, token string, query routes.ChatMessageQuery) (routes.ChatMessagePage, error) {
+				if code != "K7P9QD" || token != "member-token" {
+					t.Fatalf("ListChatMessages() got code=%q token=%q", code, token)
+				}
+				if query.Before != "message-9" || query.Limit != 20 {
+					t.Fatalf("ListChatMessages() query = %#v, want before message-9 limit 20", query)
+				}
+
+				return routes.ChatMessagePage{
+					Messages:   []routes.ChatMessage{{ID: "message-8", MemberID: "member-1", Body: "Hi"}},
+					NextCursor: "message-8",
+				}, nil
+			},
+		},
+	)
+
+	request := httptest.NewRequest(http.MethodGet, "/routes/K7P9QD/messages?before=message-9&limit=20", nil)
+	request.Header.Set("Authorization", "Bearer member-token")
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `"nextCursor":"message-8"`) || !strings.Contains(recorder.Body.String(), `"body":"Hi"`) {
+		t.Fatalf("ServeHTTP() body = %q, want chat page", recorder.Body.String())
+	}
+}
+
+func TestRoutePointsHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080"},
+		stubHealthChecker{},
+		stubRouteService{
+			listRoutePointsFn: func(_ context.Context, code, token string, query routes.PointPageQuery) (routes.PointPage, error) {
+				if code != "K7P9QD" || token != "member-token" {
+					t.Fatalf("ListRoutePoints() got code=%q token=%q", code, token)
 				}
+				if query.Member != "member-1" || query.Segment != "segment-1" || query.AfterSeq != 40 || query.Limit != 2 {
+					t.Fatalf("ListRoutePoints() query = %#v, want member-1 segment-1 after 40 limit 2", query)
+				}
+
+				return routes.PointPage{
+					Points: []routes.SegmentPoint{
+						{SegmentID: "segment-1", MemberID: "member-1", RoutePoint: routes.RoutePoint{Seq: 41, Latitude: 46.05, Longitude: 14.5}},
+						{SegmentID: "segment-1", MemberID: "member-1", RoutePoint: routes.RoutePoint{Seq: 42, Latitude: 46.06, Longitude: 14.5}},
+					},
+					NextSegment:  "segment-1",
+					NextAfterSeq: 42,
+				}, nil
+			},
+		},
+	)
+
+	request := httptest.NewRequest(http.MethodGet, "/routes/K7P9QD/points?member=member-1&segment=segment-1&after_seq=40&limit=2", nil)
+	request.Header.Set("Authorization", "Bearer member-token")
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if !strings.Contains(recorder.Body.String(), `"segmentId":"segment-1","memberId":"member-1","seq":41`) || !strings.Contains(recorder.Body.String(), `"nextAfterSeq":42`) {
+		t.Fatalf("ServeHTTP() body = %q, want flattened points and next cursor", recorder.Body.String())
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/routes/K7P9QD/points?after_seq=soon", nil)
+	request.Header.Set("Authorization", "Bearer member-token")
+	recorder = httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("ServeHTTP() invalid cursor status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestWebSocketRejectsInvalidPositionUpdate(t *testing.T) {
+	t.Parallel()
 
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		stubHealthChecker{},
+		stubRouteService{
+			authorizeMemberFn: func(_ context.Context, _ string) (routes.AuthorizedMember, error) {
 				return routes.AuthorizedMember{
 					Route: routes.Route{
 						ID:     "route-1",
@@ -624,10 +2410,14 @@ func TestWebSocketAuthenticatesFirstMessage(t *testing.T) {
 					},
 					Member: routes.Member{
 						ID:     "member-1",
-						Status: routes.MemberStatusSpectating,
+						Status: routes.MemberStatusTracking,
 					},
 				}, nil
 			},
+			recordPositionFn: func(context.Context, string, routes.PositionUpdateInput) (routes.PositionUpdateResult, error) {
+				t.Fatal("RecordPosition() should not run for a malformed position message")
+				return routes.PositionUpdateResult{}, nil
+			},
 		},
 	)
 	server := httptest.NewServer(handler)
@@ -648,45 +2438,49 @@ func TestWebSocketAuthenticatesFirstMessage(t *testing.T) {
 		"type":        "authenticate",
 		"memberToken": "member-token",
 	}); err != nil {
-		t.Fatalf("wsjson.Write() error = %v", err)
+		t.Fatalf("write authenticate error = %v", err)
+	}
+
+	var established map[string]any
+	if err := wsjson.Read(ctx, connection, &established); err != nil {
+		t.Fatalf("read connection_established error = %v", err)
+	}
+
+	if err := wsjson.Write(ctx, connection, map[string]any{
+		"type":      "position_update",
+		"longitude": 14.5058,
+	}); err != nil {
+		t.Fatalf("write position_update error = %v", err)
 	}
 
 	var event struct {
 		Type  string `json:"type"`
-		Route struct {
-			Code string `json:"code"`
-		} `json:"route"`
-		Member struct {
-			ID string `json:"id"`
-		} `json:"member"`
+		Error string `json:"error"`
 	}
 	if err := wsjson.Read(ctx, connection, &event); err != nil {
-		t.Fatalf("wsjson.Read() error = %v", err)
-	}
-
-	if event.Type != "connection_established" {
-		t.Fatalf("event type = %q, want connection_established", event.Type)
+		t.Fatalf("read position_rejected event error = %v", err)
 	}
 
-	if event.Route.Code != "K7P9QD" || event.Member.ID != "member-1" {
-		t.Fatalf("event route/member = %q/%q, want K7P9QD/member-1", event.Route.Code, event.Member.ID)
+	if event.Type != "position_rejected" || event.Error != "invalid_input" {
+		t.Fatalf("event = %#v, want invalid position rejection", event)
 	}
 }
 
-func TestWebSocketReceivesMemberJoinedBroadcast(t *testing.T) {
+func TestWebSocketRateLimitsAndDisconnectsFloodingMember(t *testing.T) {
 	t.Parallel()
 
-	now := time.Now().UTC()
 	handler := NewHandler(
 		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
-		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		config.AppConfig{
+			Env:                         "test",
+			Port:                        "8080",
+			WebSocketAuthTimeout:        time.Second,
+			WebSocketPositionsPerSecond: 1,
+			WebSocketMaxRateViolations:  2,
+		},
 		stubHealthChecker{},
 		stubRouteService{
-			authorizeMemberFn: func(_ context.Context, token string) (routes.AuthorizedMember, error) {
-				if token != "member-token" {
-					t.Fatalf("AuthorizeMember() token = %q, want member-token", token)
-				}
-
+			authorizeMemberFn: func(_ context.Context, _ string) (routes.AuthorizedMember, error) {
 				return routes.AuthorizedMember{
 					Route: routes.Route{
 						ID:     "route-1",
@@ -695,30 +2489,8 @@ func TestWebSocketReceivesMemberJoinedBroadcast(t *testing.T) {
 					},
 					Member: routes.Member{
 						ID:     "member-1",
-						Status: routes.MemberStatusSpectating,
-					},
-				}, nil
-			},
-			joinRouteFn: func(_ context.Context, code string, input routes.JoinRouteInput) (routes.JoinRouteResult, error) {
-				if code != "K7P9QD" {
-					t.Fatalf("JoinRoute() code = %q, want K7P9QD", code)
-				}
-
-				return routes.JoinRouteResult{
-					Route: routes.Route{
-						ID:     "route-1",
-						Code:   "K7P9QD",
-						Status: routes.RouteStatusActive,
-					},
-					Member: routes.Member{
-						ID:            "member-2",
-						RouteID:       "route-1",
-						DisplayName:   input.DisplayName,
-						TransportMode: input.TransportMode,
-						Status:        routes.MemberStatusSpectating,
-						JoinedAt:      now,
+						Status: routes.MemberStatusTracking,
 					},
-					MemberToken: "new-member-token",
 				}, nil
 			},
 		},
@@ -741,7 +2513,7 @@ func TestWebSocketReceivesMemberJoinedBroadcast(t *testing.T) {
 		"type":        "authenticate",
 		"memberToken": "member-token",
 	}); err != nil {
-		t.Fatalf("wsjson.Write() error = %v", err)
+		t.Fatalf("write authenticate error = %v", err)
 	}
 
 	var established map[string]any
@@ -749,65 +2521,54 @@ func TestWebSocketReceivesMemberJoinedBroadcast(t *testing.T) {
 		t.Fatalf("read connection_established error = %v", err)
 	}
 
-	body := map[string]string{
-		"clientId":      "client-2",
-		"displayName":   "Matej",
-		"transportMode": "train",
-	}
-	payload, err := json.Marshal(body)
-	if err != nil {
-		t.Fatalf("json.Marshal() error = %v", err)
-	}
-
-	response, err := http.Post(server.URL+"/routes/K7P9QD/members", "application/json", bytes.NewReader(payload))
-	if err != nil {
-		t.Fatalf("http.Post() error = %v", err)
-	}
-	defer func() {
-		_ = response.Body.Close()
-	}()
-
-	if response.StatusCode != http.StatusCreated {
-		t.Fatalf("POST /routes/K7P9QD/members status = %d, want %d", response.StatusCode, http.StatusCreated)
+	positionUpdate := map[string]any{
+		"type":      "position_update",
+		"longitude": 14.5058,
 	}
+	for _, wantError := range []string{"invalid_input", "rate_limited"} {
+		if err := wsjson.Write(ctx, connection, positionUpdate); err != nil {
+			t.Fatalf("write position_update error = %v", err)
+		}
 
-	var event struct {
-		Type   string `json:"type"`
-		Member struct {
-			ID          string `json:"id"`
-			DisplayName string `json:"displayName"`
-			Status      string `json:"status"`
-		} `json:"member"`
-	}
-	if err := wsjson.Read(ctx, connection, &event); err != nil {
-		t.Fatalf("read member_joined event error = %v", err)
+		var event struct {
+			Type  string `json:"type"`
+			Error string `json:"error"`
+		}
+		if err := wsjson.Read(ctx, connection, &event); err != nil {
+			t.Fatalf("read position_rejected event error = %v", err)
+		}
+		if event.Type != "position_rejected" || event.Error != wantError {
+			t.Fatalf("event = %#v, want %s position rejection", event, wantError)
+		}
 	}
 
-	if event.Type != "member_joined" {
-		t.Fatalf("event type = %q, want member_joined", event.Type)
+	if err := wsjson.Write(ctx, connection, positionUpdate); err != nil {
+		t.Fatalf("write position_update error = %v", err)
 	}
 
-	if event.Member.ID != "member-2" || event.Member.DisplayName != "Matej" || event.Member.Status != routes.MemberStatusSpectating {
-		t.Fatalf("event member = %#v, want joined member", event.Member)
+	var event map[string]any
+	err = wsjson.Read(ctx, connection, &event)
+	if status := websocket.CloseStatus(err); status != websocket.StatusPolicyViolation {
+		t.Fatalf("wsjson.Read() error = %v, want policy violation close", err)
 	}
 }
 
-func TestWebSocketRecordsAndBroadcastsPositionUpdate(t *testing.T) {
+func TestWebSocketChargesBatchedPositionsAgainstBatchBudget(t *testing.T) {
 	t.Parallel()
 
-	recordedAt := time.Now().UTC()
-	clientRecordedAt := recordedAt.Add(-2 * time.Second).UTC()
-	accuracy := 8.5
+	var batchSizes []int
 	handler := NewHandler(
 		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
-		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second},
+		config.AppConfig{
+			Env:                              "test",
+			Port:                             "8080",
+			WebSocketAuthTimeout:             time.Second,
+			WebSocketPositionsPerSecond:      2,
+			WebSocketBatchPositionsPerMinute: 4,
+		},
 		stubHealthChecker{},
 		stubRouteService{
-			authorizeMemberFn: func(_ context.Context, token string) (routes.AuthorizedMember, error) {
-				if token != "member-token" {
-					t.Fatalf("AuthorizeMember() token = %q, want member-token", token)
-				}
-
+			authorizeMemberFn: func(_ context.Context, _ string) (routes.AuthorizedMember, error) {
 				return routes.AuthorizedMember{
 					Route: routes.Route{
 						ID:     "route-1",
@@ -820,40 +2581,9 @@ func TestWebSocketRecordsAndBroadcastsPositionUpdate(t *testing.T) {
 					},
 				}, nil
 			},
-			recordPositionFn: func(_ context.Context, token string, input routes.PositionUpdateInput) (routes.PositionUpdateResult, error) {
-				if token != "member-token" {
-					t.Fatalf("RecordPosition() token = %q, want member-token", token)
-				}
-
-				if input.Latitude != 46.0569 || input.Longitude != 14.5058 {
-					t.Fatalf("RecordPosition() coordinates = %f,%f", input.Latitude, input.Longitude)
-				}
-
-				if input.AccuracyM == nil || *input.AccuracyM != accuracy {
-					t.Fatal("RecordPosition() expected accuracy")
-				}
-
-				if input.ClientRecordedAt == nil || !input.ClientRecordedAt.Equal(clientRecordedAt) {
-					t.Fatal("RecordPosition() expected client recorded time")
-				}
-
-				if !strings.Contains(string(input.RawPayload), `"type":"position_update"`) {
-					t.Fatalf("RecordPosition() raw payload = %s, want original message", string(input.RawPayload))
-				}
-
-				return routes.PositionUpdateResult{
-					RouteID:   "route-1",
-					MemberID:  "member-1",
-					SegmentID: "segment-1",
-					Point: routes.RoutePoint{
-						Seq:              7,
-						Latitude:         input.Latitude,
-						Longitude:        input.Longitude,
-						AccuracyM:        input.AccuracyM,
-						ClientRecordedAt: input.ClientRecordedAt,
-						RecordedAt:       recordedAt,
-					},
-				}, nil
+			recordPositionBatchFn: func(_ context.Context, _, _ string, samples []routes.PositionUpdateInput) (routes.PositionBatchResult, error) {
+				batchSizes = append(batchSizes, len(samples))
+				return routes.PositionBatchResult{RouteID: "route-1", MemberID: "member-1"}, nil
 			},
 		},
 	)
@@ -878,49 +2608,99 @@ func TestWebSocketRecordsAndBroadcastsPositionUpdate(t *testing.T) {
 		t.Fatalf("write authenticate error = %v", err)
 	}
 
-	var established map[string]any
+	var established struct {
+		Limits struct {
+			PositionsPerSecond int `json:"positionsPerSecond"`
+			BatchPositions     int `json:"batchPositions"`
+		} `json:"limits"`
+	}
 	if err := wsjson.Read(ctx, connection, &established); err != nil {
 		t.Fatalf("read connection_established error = %v", err)
 	}
-
-	if err := wsjson.Write(ctx, connection, map[string]any{
-		"type":             "position_update",
-		"latitude":         46.0569,
-		"longitude":        14.5058,
-		"accuracyM":        accuracy,
-		"clientRecordedAt": clientRecordedAt,
-	}); err != nil {
-		t.Fatalf("write position_update error = %v", err)
+	if established.Limits.PositionsPerSecond != 2 || established.Limits.BatchPositions != 4 {
+		t.Fatalf("connection_established limits = %#v, want 2 positions per second and batches of 4", established.Limits)
 	}
 
-	var event struct {
-		Type      string `json:"type"`
-		MemberID  string `json:"memberId"`
-		SegmentID string `json:"segmentId"`
-		Point     struct {
-			Seq       int64   `json:"seq"`
-			Latitude  float64 `json:"latitude"`
-			Longitude float64 `json:"longitude"`
-		} `json:"point"`
-	}
-	if err := wsjson.Read(ctx, connection, &event); err != nil {
-		t.Fatalf("read position_updated event error = %v", err)
+	sample := map[string]any{"latitude": 46.0569, "longitude": 14.5058, "clientRecordedAt": "2026-05-01T08:00:00Z"}
+	for _, tc := range []struct {
+		samples  int
+		wantType string
+	}{
+		{samples: 5, wantType: "command_rejected"},
+		{samples: 3, wantType: "command_ack"},
+		{samples: 2, wantType: "command_rejected"},
+	} {
+		positions := make([]map[string]any, tc.samples)
+		for index := range positions {
+			positions[index] = sample
+		}
+		if err := wsjson.Write(ctx, connection, map[string]any{
+			"type":      "position_batch",
+			"requestId": "batch-1",
+			"positions": positions,
+		}); err != nil {
+			t.Fatalf("write position_batch error = %v", err)
+		}
+
+		var event struct {
+			Type    string `json:"type"`
+			Command string `json:"command"`
+			Reason  string `json:"reason"`
+		}
+		if err := wsjson.Read(ctx, connection, &event); err != nil {
+			t.Fatalf("read position_batch reply error = %v", err)
+		}
+		if event.Type != tc.wantType || event.Command != "position_batch" {
+			t.Fatalf("reply to %d-sample batch = %#v, want %s", tc.samples, event, tc.wantType)
+		}
+		if event.Type == "command_rejected" && event.Reason != "rate_limited" {
+			t.Fatalf("reject reason = %q, want rate_limited", event.Reason)
+		}
+	}
+
+	if len(batchSizes) != 1 || batchSizes[0] != 3 {
+		t.Fatalf("RecordPositionBatch() batch sizes = %v, want only the 3-sample batch", batchSizes)
 	}
+}
 
-	if event.Type != "position_updated" {
-		t.Fatalf("event type = %q, want position_updated", event.Type)
+func TestWebSocketClosesOversizedMessage(t *testing.T) {
+	t.Parallel()
+
+	handler := NewHandler(
+		slog.New(slog.NewTextHandler(testWriter{t: t}, nil)),
+		config.AppConfig{Env: "test", Port: "8080", WebSocketAuthTimeout: time.Second, WebSocketMaxMessageBytes: 64},
+		stubHealthChecker{},
+		stubRouteService{},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connection, _, err := websocket.Dial(ctx, webSocketURL(server.URL), nil)
+	if err != nil {
+		t.Fatalf("websocket.Dial() error = %v", err)
 	}
+	defer func() {
+		_ = connection.Close(websocket.StatusNormalClosure, "test complete")
+	}()
 
-	if event.MemberID != "member-1" || event.SegmentID != "segment-1" {
-		t.Fatalf("event member/segment = %q/%q, want member-1/segment-1", event.MemberID, event.SegmentID)
+	if err := wsjson.Write(ctx, connection, map[string]string{
+		"type":        "authenticate",
+		"memberToken": strings.Repeat("x", 128),
+	}); err != nil {
+		t.Fatalf("wsjson.Write() error = %v", err)
 	}
 
-	if event.Point.Seq != 7 || event.Point.Latitude != 46.0569 || event.Point.Longitude != 14.5058 {
-		t.Fatalf("event point = %#v, want accepted point", event.Point)
+	var event map[string]any
+	err = wsjson.Read(ctx, connection, &event)
+	if status := websocket.CloseStatus(err); status != websocket.StatusMessageTooBig {
+		t.Fatalf("wsjson.Read() error = %v, want message too big close", err)
 	}
 }
 
-func TestWebSocketRejectsInvalidPositionUpdate(t *testing.T) {
+func TestWebSocketReportsPositionValidationReason(t *testing.T) {
 	t.Parallel()
 
 	handler := NewHandler(
@@ -942,8 +2722,7 @@ func TestWebSocketRejectsInvalidPositionUpdate(t *testing.T) {
 				}, nil
 			},
 			recordPositionFn: func(context.Context, string, routes.PositionUpdateInput) (routes.PositionUpdateResult, error) {
-				t.Fatal("RecordPosition() should not run for a malformed position message")
-				return routes.PositionUpdateResult{}, nil
+				return routes.PositionUpdateResult{}, &routes.PositionRejectedError{Reason: routes.PositionRejectionImpossibleSpeed}
 			},
 		},
 	)
@@ -975,6 +2754,7 @@ func TestWebSocketRejectsInvalidPositionUpdate(t *testing.T) {
 
 	if err := wsjson.Write(ctx, connection, map[string]any{
 		"type":      "position_update",
+		"latitude":  46.0569,
 		"longitude": 14.5058,
 	}); err != nil {
 		t.Fatalf("write position_update error = %v", err)
@@ -988,8 +2768,78 @@ func TestWebSocketRejectsInvalidPositionUpdate(t *testing.T) {
 		t.Fatalf("read position_rejected event error = %v", err)
 	}
 
-	if event.Type != "position_rejected" || event.Error != "invalid_input" {
-		t.Fatalf("event = %#v, want invalid position rejection", event)
+	if event.Type != "position_rejected" || event.Error != routes.PositionRejectionImpossibleSpeed {
+		t.Fatalf("event = %#v, want impossible speed rejection", event)
+	}
+}
+
+func TestPresenceSweeperBroadcastsTransitions(t *testing.T) {
+	t.Parallel()
+
+	hub := live.NewLocalHub()
+	recorded := make(chan string, 1)
+	swept := make(chan struct{})
+	routeService := stubRouteService{
+		sweepPresenceFn: func(_ context.Context, rules routes.PresenceRules, connections routes.ConnectionChecker) ([]routes.PresenceTransition, error) {
+			if rules.SpectatorOfflineAfter != 20*time.Second || rules.TrackingStaleAfter != 15*time.Second {
+				t.Errorf("SweepPresence() rules = %#v, want app config timeouts", rules)
+			}
+			if connections != live.Hub(hub) {
+				t.Errorf("SweepPresence() connections = %T, want the handler live hub", connections)
+			}
+
+			select {
+			case <-swept:
+				return nil, nil
+			default:
+				close(swept)
+			}
+
+			return []routes.PresenceTransition{{
+				RouteID: "route-1",
+				Type:    routes.PresenceEventMemberWentOffline,
+				Member:  routes.Member{ID: "member-2", Status: routes.MemberStatusOffline},
+			}}, nil
+		},
+		recordRouteEventFn: func(_ context.Context, routeID, actorMemberID string, payload map[string]any) (routes.RouteEvent, error) {
+			recorded <- routeID + "/" + actorMemberID + "/" + payload["type"].(string)
+			return routes.RouteEvent{}, nil
+		},
+	}
+
+	subscription, err := hub.Subscribe(context.Background(), "route-1", "member-1")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer subscription.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		_ = RunPresenceSweeper(ctx, slog.New(slog.NewTextHandler(testWriter{t: t}, nil)), config.AppConfig{
+			TrackingStaleAfter:    15 * time.Second,
+			SpectatorOfflineAfter: 20 * time.Second,
+			PresenceSweepInterval: 10 * time.Millisecond,
+		}, routeService, WithLiveHub(hub))
+	}()
+
+	select {
+	case event := <-subscription.Events():
+		member, _ := event["member"].(routes.Member)
+		if event["type"] != "member_went_offline" || member.ID != "member-2" {
+			t.Fatalf("presence sweep event = %#v, want member_went_offline for member-2", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("presence sweep event was not broadcast")
+	}
+
+	select {
+	case got := <-recorded:
+		if got != "route-1//member_went_offline" {
+			t.Fatalf("recorded route event = %q, want route-1//member_went_offline", got)
+		}
+	case <-ctx.Done():
+		t.Fatal("presence sweep event was not recorded")
 	}
 }
 
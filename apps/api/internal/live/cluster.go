@@ -0,0 +1,209 @@
+package live
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultConnectionTTL   = 30 * time.Second
+	connectionReleaseAfter = 2 * time.Second
+)
+
+var errBrokerStreamClosed = errors.New("live broker stream closed")
+
+// BrokerMessage is one live event travelling between API instances. MemberID limits delivery to
+// one member's connection; with Disconnect set the message carries no event and closes that
+// connection instead.
+type BrokerMessage struct {
+	Origin     string `json:"origin"`
+	RouteID    string `json:"routeId"`
+	Event      Event  `json:"event,omitempty"`
+	MemberID   string `json:"memberId,omitempty"`
+	Disconnect bool   `json:"disconnect,omitempty"`
+}
+
+// Broker carries live events between API instances.
+type Broker interface {
+	Publish(ctx context.Context, message BrokerMessage) error
+	// Messages streams every published message until ctx is done.
+	Messages(ctx context.Context) (<-chan BrokerMessage, error)
+}
+
+// ConnectionRegistry tracks live member connections across API instances.
+// Claims expire after ttl unless refreshed, so a crashed instance cannot hold a member forever.
+type ConnectionRegistry interface {
+	Claim(ctx context.Context, routeID, memberID, connectionID string, ttl time.Duration) (bool, error)
+	Refresh(ctx context.Context, routeID, memberID, connectionID string, ttl time.Duration) error
+	Release(ctx context.Context, routeID, memberID, connectionID string) error
+	HasConnection(ctx context.Context, routeID, memberID string) (bool, error)
+	CountConnections(ctx context.Context, routeID string) (int, error)
+}
+
+// ClusterHub shares route rooms between API instances through a broker and connection registry.
+// Each instance delivers to its own sockets through a LocalHub.
+type ClusterHub struct {
+	local         *LocalHub
+	broker        Broker
+	registry      ConnectionRegistry
+	logger        *slog.Logger
+	nodeID        string
+	connectionTTL time.Duration
+}
+
+// NewClusterHub builds a cluster-wide live hub. Run must be started to receive remote events.
+func NewClusterHub(logger *slog.Logger, broker Broker, registry ConnectionRegistry, opts ...HubOption) *ClusterHub {
+	return &ClusterHub{
+		local:         NewLocalHub(opts...),
+		broker:        broker,
+		registry:      registry,
+		logger:        logger,
+		nodeID:        randomID(),
+		connectionTTL: defaultConnectionTTL,
+	}
+}
+
+// Run relays remote broker messages to local subscriptions and keeps local connection claims fresh.
+func (h *ClusterHub) Run(ctx context.Context) error {
+	messages, err := h.broker.Messages(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribe live broker: %w", err)
+	}
+
+	return h.relay(ctx, messages)
+}
+
+func (h *ClusterHub) relay(ctx context.Context, messages <-chan BrokerMessage) error {
+	heartbeat := time.NewTicker(h.connectionTTL / 3)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message, ok := <-messages:
+			if !ok {
+				return errBrokerStreamClosed
+			}
+			if message.Origin == h.nodeID {
+				continue
+			}
+			switch {
+			case message.Disconnect:
+				h.local.disconnectMember(message.RouteID, message.MemberID)
+			case message.MemberID != "":
+				h.local.deliverToMember(message.RouteID, message.MemberID, message.Event)
+			default:
+				h.local.deliver(message.RouteID, message.Event)
+			}
+		case <-heartbeat.C:
+			h.refreshConnections(ctx)
+		}
+	}
+}
+
+// Subscribe claims the member connection cluster-wide and registers it in the local route room.
+func (h *ClusterHub) Subscribe(ctx context.Context, routeID, memberID string) (*Subscription, error) {
+	connectionID := randomID()
+	claimed, err := h.registry.Claim(ctx, routeID, memberID, connectionID, h.connectionTTL)
+	if err != nil {
+		return nil, fmt.Errorf("claim live connection: %w", err)
+	}
+	if !claimed {
+		return nil, ErrMemberAlreadyConnected
+	}
+
+	subscription, err := h.local.subscribe(routeID, memberID, connectionID, func() {
+		h.release(routeID, memberID, connectionID)
+	})
+	if err != nil {
+		h.release(routeID, memberID, connectionID)
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// HasMemberConnection reports whether a member is connected to any instance.
+func (h *ClusterHub) HasMemberConnection(ctx context.Context, routeID, memberID string) (bool, error) {
+	return h.registry.HasConnection(ctx, routeID, memberID)
+}
+
+// RouteConnectionCount returns the number of live connections for a route across instances.
+func (h *ClusterHub) RouteConnectionCount(ctx context.Context, routeID string) (int, error) {
+	return h.registry.CountConnections(ctx, routeID)
+}
+
+// Broadcast delivers to local subscriptions immediately and publishes the event for other instances.
+func (h *ClusterHub) Broadcast(ctx context.Context, routeID string, event Event) (int, error) {
+	h.local.metrics.LiveEventBroadcast(event.Type())
+	delivered := h.local.deliver(routeID, event)
+	if err := h.broker.Publish(ctx, BrokerMessage{
+		Origin:  h.nodeID,
+		RouteID: routeID,
+		Event:   event,
+	}); err != nil {
+		return delivered, fmt.Errorf("publish live event: %w", err)
+	}
+
+	return delivered, nil
+}
+
+// SendToMember delivers to the member's local subscription and publishes the event for other instances.
+func (h *ClusterHub) SendToMember(ctx context.Context, routeID, memberID string, event Event) (int, error) {
+	delivered := h.local.deliverToMember(routeID, memberID, event)
+	if err := h.broker.Publish(ctx, BrokerMessage{
+		Origin:   h.nodeID,
+		RouteID:  routeID,
+		Event:    event,
+		MemberID: memberID,
+	}); err != nil {
+		return delivered, fmt.Errorf("publish live member event: %w", err)
+	}
+
+	return delivered, nil
+}
+
+// DisconnectMember closes the member's local subscription and asks other instances to close theirs.
+func (h *ClusterHub) DisconnectMember(ctx context.Context, routeID, memberID string) (int, error) {
+	closed := h.local.disconnectMember(routeID, memberID)
+	if err := h.broker.Publish(ctx, BrokerMessage{
+		Origin:     h.nodeID,
+		RouteID:    routeID,
+		MemberID:   memberID,
+		Disconnect: true,
+	}); err != nil {
+		return closed, fmt.Errorf("publish live disconnect: %w", err)
+	}
+
+	return closed, nil
+}
+
+func (h *ClusterHub) refreshConnections(ctx context.Context) {
+	for _, subscription := range h.local.subscriptions() {
+		if err := h.registry.Refresh(ctx, subscription.routeID, subscription.memberID, subscription.connectionID, h.connectionTTL); err != nil {
+			h.logger.Warn("refresh live connection failed", "route_id", subscription.routeID, "member_id", subscription.memberID, "error", err)
+		}
+	}
+}
+
+func (h *ClusterHub) release(routeID, memberID, connectionID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), connectionReleaseAfter)
+	defer cancel()
+
+	if err := h.registry.Release(ctx, routeID, memberID, connectionID); err != nil {
+		h.logger.Warn("release live connection failed", "route_id", routeID, "member_id", memberID, "error", err)
+	}
+}
+
+func randomID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
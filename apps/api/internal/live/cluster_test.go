@@ -0,0 +1,208 @@
+package live
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestClusterHubSharesRoomsAcrossInstances(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	broker := NewMemoryBroker()
+	first := startClusterHub(ctx, t, broker)
+	second := startClusterHub(ctx, t, broker)
+
+	subscription := mustSubscribe(t, first, "route-1", "member-1")
+	defer subscription.Close()
+
+	connected, err := second.HasMemberConnection(ctx, "route-1", "member-1")
+	if err != nil {
+		t.Fatalf("HasMemberConnection() error = %v", err)
+	}
+	if !connected {
+		t.Fatal("HasMemberConnection() on second instance = false, want true")
+	}
+
+	if _, err := second.Subscribe(ctx, "route-1", "member-1"); !errors.Is(err, ErrMemberAlreadyConnected) {
+		t.Fatalf("Subscribe() on second instance error = %v, want ErrMemberAlreadyConnected", err)
+	}
+
+	other := mustSubscribe(t, second, "route-1", "member-2")
+	defer other.Close()
+
+	if count, _ := first.RouteConnectionCount(ctx, "route-1"); count != 2 {
+		t.Fatalf("RouteConnectionCount() = %d, want 2", count)
+	}
+
+	delivered, err := second.Broadcast(ctx, "route-1", Event{
+		"type":     "position_updated",
+		"memberId": "member-2",
+	})
+	if err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
+	if delivered != 1 {
+		t.Fatalf("Broadcast() local deliveries = %d, want 1", delivered)
+	}
+
+	select {
+	case event := <-subscription.Events():
+		if event["type"] != "position_updated" || event["memberId"] != "member-2" {
+			t.Fatalf("remote event = %#v, want position_updated from member-2", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("remote instance did not receive broadcast")
+	}
+
+	select {
+	case event := <-other.Events():
+		if event["type"] != "position_updated" {
+			t.Fatalf("local event = %#v, want position_updated", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("local subscription did not receive broadcast")
+	}
+
+	select {
+	case event := <-other.Events():
+		t.Fatalf("local subscription received its own broadcast twice: %#v", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestClusterHubSendsToOneMemberOnOtherInstance(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	broker := NewMemoryBroker()
+	first := startClusterHub(ctx, t, broker)
+	second := startClusterHub(ctx, t, broker)
+
+	recipient := mustSubscribe(t, first, "route-1", "member-1")
+	defer recipient.Close()
+	other := mustSubscribe(t, first, "route-1", "member-2")
+	defer other.Close()
+
+	if _, err := second.SendToMember(ctx, "route-1", "member-1", Event{"type": "owner_token_issued"}); err != nil {
+		t.Fatalf("SendToMember() error = %v", err)
+	}
+
+	select {
+	case event := <-recipient.Events():
+		if event["type"] != "owner_token_issued" {
+			t.Fatalf("recipient event = %#v, want owner_token_issued", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("recipient did not receive the member event")
+	}
+
+	select {
+	case event := <-other.Events():
+		t.Fatalf("other member received %#v, want nothing", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestClusterHubDisconnectsMemberOnOtherInstance(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	broker := NewMemoryBroker()
+	first := startClusterHub(ctx, t, broker)
+	second := startClusterHub(ctx, t, broker)
+
+	subscription := mustSubscribe(t, first, "route-1", "member-1")
+	defer subscription.Close()
+	other := mustSubscribe(t, first, "route-1", "member-2")
+	defer other.Close()
+
+	closed, err := second.DisconnectMember(ctx, "route-1", "member-1")
+	if err != nil {
+		t.Fatalf("DisconnectMember() error = %v", err)
+	}
+	if closed != 0 {
+		t.Fatalf("DisconnectMember() local closures = %d, want 0", closed)
+	}
+
+	select {
+	case _, ok := <-subscription.Events():
+		if ok {
+			t.Fatal("removed subscription received an event, want closed stream")
+		}
+	case <-ctx.Done():
+		t.Fatal("remote instance did not close the removed member's subscription")
+	}
+
+	if !subscription.Removed() {
+		t.Fatal("Removed() = false, want true after DisconnectMember")
+	}
+	if other.Removed() {
+		t.Fatal("Removed() for another member = true, want false")
+	}
+}
+
+func TestClusterHubReleasesClosedConnections(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	broker := NewMemoryBroker()
+	first := startClusterHub(ctx, t, broker)
+	second := startClusterHub(ctx, t, broker)
+
+	subscription := mustSubscribe(t, first, "route-1", "member-1")
+	subscription.Close()
+
+	reconnected := mustSubscribe(t, second, "route-1", "member-1")
+	defer reconnected.Close()
+
+	if count, _ := first.RouteConnectionCount(ctx, "route-1"); count != 1 {
+		t.Fatalf("RouteConnectionCount() = %d, want 1", count)
+	}
+}
+
+func TestMemoryBrokerExpiresUnrefreshedClaims(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	broker := NewMemoryBroker()
+	broker.now = func() time.Time { return now }
+
+	if claimed, _ := broker.Claim(ctx, "route-1", "member-1", "connection-1", 30*time.Second); !claimed {
+		t.Fatal("Claim() = false, want true")
+	}
+	if claimed, _ := broker.Claim(ctx, "route-1", "member-1", "connection-2", 30*time.Second); claimed {
+		t.Fatal("Claim() for live member = true, want false")
+	}
+
+	now = now.Add(31 * time.Second)
+	if claimed, _ := broker.Claim(ctx, "route-1", "member-1", "connection-2", 30*time.Second); !claimed {
+		t.Fatal("Claim() after expiry = false, want true")
+	}
+}
+
+func startClusterHub(ctx context.Context, t *testing.T, broker *MemoryBroker) *ClusterHub {
+	t.Helper()
+
+	hub := NewClusterHub(slog.New(slog.NewTextHandler(io.Discard, nil)), broker, broker)
+	messages, err := broker.Messages(ctx)
+	if err != nil {
+		t.Fatalf("Messages() error = %v", err)
+	}
+	go hub.relay(ctx, messages)
+
+	return hub
+}
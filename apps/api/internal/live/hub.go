@@ -1,45 +1,139 @@
-// Package live owns in-memory realtime route room coordination.
+// Package live owns realtime route room coordination.
 package live
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"keepup/apps/api/internal/metrics"
+)
 
 const subscriptionEventBuffer = 32
 
-// Hub tracks active WebSocket subscriptions by route.
-type Hub struct {
-	mu    sync.RWMutex
-	rooms map[string]map[*Subscription]struct{}
+// EventTypeResyncRequired tells a client it missed room events and must reload the route snapshot.
+const EventTypeResyncRequired = "resync_required"
+
+// ErrMemberAlreadyConnected is returned when a member already holds a live subscription for a route.
+var ErrMemberAlreadyConnected = errors.New("member already connected")
+
+// Hub fans live route events out to subscribed connections.
+type Hub interface {
+	// Subscribe registers one member connection in a route room.
+	// It returns ErrMemberAlreadyConnected when the member already has an active subscription.
+	Subscribe(ctx context.Context, routeID, memberID string) (*Subscription, error)
+	// HasMemberConnection reports whether a member already has an active subscription.
+	HasMemberConnection(ctx context.Context, routeID, memberID string) (bool, error)
+	// RouteConnectionCount returns the number of active subscriptions for a route.
+	RouteConnectionCount(ctx context.Context, routeID string) (int, error)
+	// Broadcast publishes an event to a route room and reports deliveries on this instance.
+	Broadcast(ctx context.Context, routeID string, event Event) (int, error)
+	// SendToMember delivers an event to one member's subscription only and reports deliveries on this instance.
+	SendToMember(ctx context.Context, routeID, memberID string, event Event) (int, error)
+	// DisconnectMember closes a member's subscription in a route room and reports closures on this instance.
+	DisconnectMember(ctx context.Context, routeID, memberID string) (int, error)
 }
 
 // Event is one live route event ready to send to subscribed clients.
 type Event map[string]any
 
+// Type returns the event type, or an empty string when it is missing.
+func (e Event) Type() string {
+	eventType, _ := e["type"].(string)
+
+	return eventType
+}
+
+// withSeq returns a shallow copy of the event stamped with a subscription sequence number,
+// leaving the shared broadcast event untouched.
+func (e Event) withSeq(seq int64) Event {
+	numbered := make(Event, len(e)+1)
+	for key, value := range e {
+		numbered[key] = value
+	}
+	numbered["seq"] = seq
+
+	return numbered
+}
+
+// LocalHub tracks active WebSocket subscriptions by route inside one process.
+type LocalHub struct {
+	mu                     sync.RWMutex
+	rooms                  map[string]map[*Subscription]struct{}
+	metrics                *metrics.Metrics
+	disconnectSlowConsumer bool
+}
+
+// HubOption customizes optional live hub collaborators.
+type HubOption func(*LocalHub)
+
+// WithMetrics records broadcasts, deliveries, and dropped events.
+func WithMetrics(m *metrics.Metrics) HubOption {
+	return func(h *LocalHub) {
+		h.metrics = m
+	}
+}
+
 // Subscription represents one live route connection.
+// Every room event offered to it carries a per-subscription "seq"; dropped events still consume
+// a number, so clients can spot gaps even before the resync_required notice arrives.
 type Subscription struct {
-	hub      *Hub
-	routeID  string
-	memberID string
-	events   chan Event
-	closed   bool
+	hub          *LocalHub
+	routeID      string
+	memberID     string
+	connectionID string
+	events       chan Event
+	closed       bool
+	onClose      func()
+
+	sendMu        sync.Mutex
+	seq           int64
+	missed        int
+	resyncPending bool
+	evicted       bool
+	removed       bool
 }
 
-// NewHub builds an empty live route hub.
-func NewHub() *Hub {
-	return &Hub{
+// WithSlowConsumerDisconnect closes a subscription as soon as its buffer overflows instead of
+// asking it to resync, for deployments that prefer a reconnect over a long catch-up.
+func WithSlowConsumerDisconnect() HubOption {
+	return func(h *LocalHub) {
+		h.disconnectSlowConsumer = true
+	}
+}
+
+// NewLocalHub builds an empty in-process live route hub.
+func NewLocalHub(opts ...HubOption) *LocalHub {
+	hub := &LocalHub{
 		rooms: make(map[string]map[*Subscription]struct{}),
 	}
+	for _, opt := range opts {
+		opt(hub)
+	}
+
+	return hub
 }
 
 // Subscribe registers one connection in a route room.
-func (h *Hub) Subscribe(routeID, memberID string) *Subscription {
+func (h *LocalHub) Subscribe(_ context.Context, routeID, memberID string) (*Subscription, error) {
+	return h.subscribe(routeID, memberID, "", nil)
+}
+
+func (h *LocalHub) subscribe(routeID, memberID, connectionID string, onClose func()) (*Subscription, error) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if h.hasMemberConnectionLocked(routeID, memberID) {
+		return nil, ErrMemberAlreadyConnected
+	}
+
 	subscription := &Subscription{
-		hub:      h,
-		routeID:  routeID,
-		memberID: memberID,
-		events:   make(chan Event, subscriptionEventBuffer),
+		hub:          h,
+		routeID:      routeID,
+		memberID:     memberID,
+		connectionID: connectionID,
+		events:       make(chan Event, subscriptionEventBuffer),
+		onClose:      onClose,
 	}
 
 	if h.rooms[routeID] == nil {
@@ -47,14 +141,18 @@ func (h *Hub) Subscribe(routeID, memberID string) *Subscription {
 	}
 	h.rooms[routeID][subscription] = struct{}{}
 
-	return subscription
+	return subscription, nil
 }
 
 // HasMemberConnection reports whether a member already has an active subscription.
-func (h *Hub) HasMemberConnection(routeID, memberID string) bool {
+func (h *LocalHub) HasMemberConnection(_ context.Context, routeID, memberID string) (bool, error) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	return h.hasMemberConnectionLocked(routeID, memberID), nil
+}
+
+func (h *LocalHub) hasMemberConnectionLocked(routeID, memberID string) bool {
 	for subscription := range h.rooms[routeID] {
 		if subscription.memberID == memberID && !subscription.closed {
 			return true
@@ -67,9 +165,8 @@ func (h *Hub) HasMemberConnection(routeID, memberID string) bool {
 // Close removes the subscription from its route room.
 func (s *Subscription) Close() {
 	s.hub.mu.Lock()
-	defer s.hub.mu.Unlock()
-
 	if s.closed {
+		s.hub.mu.Unlock()
 		return
 	}
 
@@ -80,6 +177,12 @@ func (s *Subscription) Close() {
 	if len(room) == 0 {
 		delete(s.hub.rooms, s.routeID)
 	}
+	onClose := s.onClose
+	s.hub.mu.Unlock()
+
+	if onClose != nil {
+		onClose()
+	}
 }
 
 // Events returns the live event stream for this subscription.
@@ -98,26 +201,155 @@ func (s *Subscription) MemberID() string {
 }
 
 // RouteConnectionCount returns the number of active subscriptions for a route.
-func (h *Hub) RouteConnectionCount(routeID string) int {
+func (h *LocalHub) RouteConnectionCount(_ context.Context, routeID string) (int, error) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	return len(h.rooms[routeID])
+	return len(h.rooms[routeID]), nil
 }
 
 // Broadcast publishes an event to active subscriptions in one route room.
-func (h *Hub) Broadcast(routeID string, event Event) int {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+func (h *LocalHub) Broadcast(_ context.Context, routeID string, event Event) (int, error) {
+	h.metrics.LiveEventBroadcast(event.Type())
 
-	delivered := 0
+	return h.deliver(routeID, event), nil
+}
+
+// SendToMember delivers an event to one member's subscription in a route room.
+func (h *LocalHub) SendToMember(_ context.Context, routeID, memberID string, event Event) (int, error) {
+	h.metrics.LiveEventBroadcast(event.Type())
+
+	return h.deliverToMember(routeID, memberID, event), nil
+}
+
+// deliver queues the event for every subscription in the room and returns how many accepted it.
+// A subscription whose buffer is full misses the event and is asked to resync, or is closed
+// when the hub disconnects slow consumers.
+func (h *LocalHub) deliver(routeID string, event Event) int {
+	return h.deliverWhere(routeID, event, func(*Subscription) bool { return true })
+}
+
+func (h *LocalHub) deliverToMember(routeID, memberID string, event Event) int {
+	return h.deliverWhere(routeID, event, func(subscription *Subscription) bool {
+		return subscription.memberID == memberID
+	})
+}
+
+func (h *LocalHub) deliverWhere(routeID string, event Event, match func(*Subscription) bool) int {
+	h.mu.RLock()
+	delivered, dropped := 0, 0
+	var slow []*Subscription
 	for subscription := range h.rooms[routeID] {
-		select {
-		case subscription.events <- event:
+		if !match(subscription) {
+			continue
+		}
+		if subscription.offer(event, h.disconnectSlowConsumer) {
 			delivered++
-		default:
+			continue
+		}
+		dropped++
+		if h.disconnectSlowConsumer {
+			slow = append(slow, subscription)
 		}
 	}
+	h.mu.RUnlock()
+
+	h.metrics.LiveEventDelivered(event.Type(), delivered, dropped)
+	for _, subscription := range slow {
+		h.metrics.LiveSlowConsumerDisconnected()
+		subscription.Close()
+	}
 
 	return delivered
 }
+
+// offer numbers the event for this subscription and queues it without blocking.
+// A pending resync notice goes out first, once the buffer has room for it.
+func (s *Subscription) offer(event Event, evictOnOverflow bool) bool {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.evicted {
+		return false
+	}
+
+	if s.resyncPending {
+		notice := Event{"type": EventTypeResyncRequired, "seq": s.seq + 1, "missed": s.missed}
+		select {
+		case s.events <- notice:
+			s.seq++
+			s.resyncPending = false
+			s.missed = 0
+		default:
+		}
+	}
+
+	s.seq++
+	select {
+	case s.events <- event.withSeq(s.seq):
+		return true
+	default:
+	}
+
+	s.missed++
+	s.resyncPending = true
+	s.evicted = evictOnOverflow
+
+	return false
+}
+
+// Evicted reports whether the hub closed this subscription because it could not keep up.
+func (s *Subscription) Evicted() bool {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	return s.evicted
+}
+
+// DisconnectMember closes the member's subscriptions in one route room.
+func (h *LocalHub) DisconnectMember(_ context.Context, routeID, memberID string) (int, error) {
+	return h.disconnectMember(routeID, memberID), nil
+}
+
+func (h *LocalHub) disconnectMember(routeID, memberID string) int {
+	h.mu.RLock()
+	var removed []*Subscription
+	for subscription := range h.rooms[routeID] {
+		if subscription.memberID == memberID {
+			removed = append(removed, subscription)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, subscription := range removed {
+		subscription.sendMu.Lock()
+		subscription.removed = true
+		subscription.sendMu.Unlock()
+		subscription.Close()
+	}
+
+	return len(removed)
+}
+
+// Removed reports whether the hub closed this subscription because the member was disconnected
+// from the route, for example after an owner removed them.
+func (s *Subscription) Removed() bool {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	return s.removed
+}
+
+func (h *LocalHub) subscriptions() []*Subscription {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	subscriptions := make([]*Subscription, 0)
+	for _, room := range h.rooms {
+		for subscription := range room {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	return subscriptions
+}
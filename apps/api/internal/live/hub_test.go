@@ -1,19 +1,33 @@
 package live
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"keepup/apps/api/internal/metrics"
+)
 
 func TestBroadcastDeliversToRouteSubscribers(t *testing.T) {
 	t.Parallel()
 
-	hub := NewHub()
-	routeSubscriber := hub.Subscribe("route-1", "member-1")
+	ctx := context.Background()
+	hub := NewLocalHub()
+	routeSubscriber := mustSubscribe(t, hub, "route-1", "member-1")
 	defer routeSubscriber.Close()
-	otherRouteSubscriber := hub.Subscribe("route-2", "member-2")
+	otherRouteSubscriber := mustSubscribe(t, hub, "route-2", "member-2")
 	defer otherRouteSubscriber.Close()
 
-	delivered := hub.Broadcast("route-1", Event{
+	delivered, err := hub.Broadcast(ctx, "route-1", Event{
 		"type": "member_joined",
 	})
+	if err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
 	if delivered != 1 {
 		t.Fatalf("Broadcast() delivered = %d, want 1", delivered)
 	}
@@ -33,18 +47,149 @@ func TestBroadcastDeliversToRouteSubscribers(t *testing.T) {
 func TestClosedSubscriptionDoesNotReceiveBroadcasts(t *testing.T) {
 	t.Parallel()
 
-	hub := NewHub()
-	subscription := hub.Subscribe("route-1", "member-1")
+	ctx := context.Background()
+	hub := NewLocalHub()
+	subscription := mustSubscribe(t, hub, "route-1", "member-1")
 	subscription.Close()
 
-	delivered := hub.Broadcast("route-1", Event{
+	delivered, err := hub.Broadcast(ctx, "route-1", Event{
 		"type": "member_left",
 	})
+	if err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
 	if delivered != 0 {
 		t.Fatalf("Broadcast() delivered = %d, want 0", delivered)
 	}
 
-	if count := hub.RouteConnectionCount("route-1"); count != 0 {
+	if count, _ := hub.RouteConnectionCount(ctx, "route-1"); count != 0 {
 		t.Fatalf("RouteConnectionCount() = %d, want 0", count)
 	}
 }
+
+func TestSubscribeRejectsSecondMemberConnection(t *testing.T) {
+	t.Parallel()
+
+	hub := NewLocalHub()
+	subscription := mustSubscribe(t, hub, "route-1", "member-1")
+
+	if _, err := hub.Subscribe(context.Background(), "route-1", "member-1"); !errors.Is(err, ErrMemberAlreadyConnected) {
+		t.Fatalf("Subscribe() error = %v, want ErrMemberAlreadyConnected", err)
+	}
+
+	subscription.Close()
+	replacement := mustSubscribe(t, hub, "route-1", "member-1")
+	replacement.Close()
+}
+
+func TestBroadcastCountsDroppedEventsWhenBufferIsFull(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := metrics.New()
+	hub := NewLocalHub(WithMetrics(m))
+	subscription := mustSubscribe(t, hub, "route-1", "member-1")
+	defer subscription.Close()
+
+	for range subscriptionEventBuffer {
+		if _, err := hub.Broadcast(ctx, "route-1", Event{"type": "position_updated"}); err != nil {
+			t.Fatalf("Broadcast() error = %v", err)
+		}
+	}
+
+	delivered, err := hub.Broadcast(ctx, "route-1", Event{"type": "position_updated"})
+	if err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
+	if delivered != 0 {
+		t.Fatalf("Broadcast() delivered = %d, want 0 with a full buffer", delivered)
+	}
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+	if !strings.Contains(string(body), `keepup_live_event_deliveries_dropped_total{type="position_updated"} 1`) {
+		t.Fatalf("metrics output missing dropped delivery:\n%s", body)
+	}
+}
+
+func TestBroadcastNumbersEventsAndRequestsResyncAfterDrop(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	hub := NewLocalHub()
+	subscription := mustSubscribe(t, hub, "route-1", "member-1")
+	defer subscription.Close()
+
+	for range subscriptionEventBuffer + 2 {
+		if _, err := hub.Broadcast(ctx, "route-1", Event{"type": "position_updated"}); err != nil {
+			t.Fatalf("Broadcast() error = %v", err)
+		}
+	}
+
+	for want := int64(1); want <= subscriptionEventBuffer; want++ {
+		event := <-subscription.Events()
+		if event["seq"] != want {
+			t.Fatalf("event seq = %v, want %d", event["seq"], want)
+		}
+	}
+
+	shared := Event{"type": "member_joined"}
+	if _, err := hub.Broadcast(ctx, "route-1", shared); err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
+	if _, stamped := shared["seq"]; stamped {
+		t.Fatal("Broadcast() stamped seq on the caller's event")
+	}
+
+	notice := <-subscription.Events()
+	if notice["type"] != EventTypeResyncRequired || notice["seq"] != int64(subscriptionEventBuffer+3) || notice["missed"] != 2 {
+		t.Fatalf("resync notice = %#v, want resync_required seq %d missed 2", notice, subscriptionEventBuffer+3)
+	}
+
+	event := <-subscription.Events()
+	if event["type"] != "member_joined" || event["seq"] != int64(subscriptionEventBuffer+4) {
+		t.Fatalf("event after resync = %#v, want member_joined seq %d", event, subscriptionEventBuffer+4)
+	}
+}
+
+func TestBroadcastDisconnectsSlowConsumerWhenConfigured(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	hub := NewLocalHub(WithSlowConsumerDisconnect())
+	subscription := mustSubscribe(t, hub, "route-1", "member-1")
+	defer subscription.Close()
+
+	for range subscriptionEventBuffer + 1 {
+		if _, err := hub.Broadcast(ctx, "route-1", Event{"type": "position_updated"}); err != nil {
+			t.Fatalf("Broadcast() error = %v", err)
+		}
+	}
+
+	if !subscription.Evicted() {
+		t.Fatal("Evicted() = false, want true after buffer overflow")
+	}
+	if count, _ := hub.RouteConnectionCount(ctx, "route-1"); count != 0 {
+		t.Fatalf("RouteConnectionCount() = %d, want 0 after eviction", count)
+	}
+
+	received := 0
+	for range subscription.Events() {
+		received++
+	}
+	if received != subscriptionEventBuffer {
+		t.Fatalf("received %d buffered events before close, want %d", received, subscriptionEventBuffer)
+	}
+}
+
+func mustSubscribe(t *testing.T, hub Hub, routeID, memberID string) *Subscription {
+	t.Helper()
+
+	subscription, err := hub.Subscribe(context.Background(), routeID, memberID)
+	if err != nil {
+		t.Fatalf("Subscribe(%q, %q) error = %v", routeID, memberID, err)
+	}
+
+	return subscription
+}
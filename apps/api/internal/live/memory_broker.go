@@ -0,0 +1,148 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// MemoryBroker is an in-process Broker and ConnectionRegistry.
+// It lets several ClusterHubs in one process behave like separate API instances, mainly in tests.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	streams     map[chan BrokerMessage]struct{}
+	connections map[string]map[string]memoryConnection
+	now         func() time.Time
+}
+
+type memoryConnection struct {
+	connectionID string
+	expiresAt    time.Time
+}
+
+// NewMemoryBroker builds an empty in-process broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		streams:     make(map[chan BrokerMessage]struct{}),
+		connections: make(map[string]map[string]memoryConnection),
+		now:         time.Now,
+	}
+}
+
+// Publish implements Broker. Events are JSON round-tripped like a network broker would,
+// and a stream that is not keeping up misses the message.
+func (b *MemoryBroker) Publish(_ context.Context, message BrokerMessage) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for stream := range b.streams {
+		var decoded BrokerMessage
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			return err
+		}
+
+		select {
+		case stream <- decoded:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Messages implements Broker.
+func (b *MemoryBroker) Messages(ctx context.Context) (<-chan BrokerMessage, error) {
+	stream := make(chan BrokerMessage, subscriptionEventBuffer)
+
+	b.mu.Lock()
+	b.streams[stream] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.streams, stream)
+		close(stream)
+		b.mu.Unlock()
+	}()
+
+	return stream, nil
+}
+
+// Claim implements ConnectionRegistry.
+func (b *MemoryBroker) Claim(_ context.Context, routeID, memberID, connectionID string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if current, ok := b.connections[routeID][memberID]; ok && current.expiresAt.After(now) && current.connectionID != connectionID {
+		return false, nil
+	}
+
+	if b.connections[routeID] == nil {
+		b.connections[routeID] = make(map[string]memoryConnection)
+	}
+	b.connections[routeID][memberID] = memoryConnection{connectionID: connectionID, expiresAt: now.Add(ttl)}
+
+	return true, nil
+}
+
+// Refresh implements ConnectionRegistry.
+func (b *MemoryBroker) Refresh(_ context.Context, routeID, memberID, connectionID string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if current, ok := b.connections[routeID][memberID]; ok && current.connectionID == connectionID {
+		b.connections[routeID][memberID] = memoryConnection{connectionID: connectionID, expiresAt: b.now().Add(ttl)}
+	}
+
+	return nil
+}
+
+// Release implements ConnectionRegistry.
+func (b *MemoryBroker) Release(_ context.Context, routeID, memberID, connectionID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if current, ok := b.connections[routeID][memberID]; ok && current.connectionID == connectionID {
+		delete(b.connections[routeID], memberID)
+		if len(b.connections[routeID]) == 0 {
+			delete(b.connections, routeID)
+		}
+	}
+
+	return nil
+}
+
+// HasConnection implements ConnectionRegistry.
+func (b *MemoryBroker) HasConnection(_ context.Context, routeID, memberID string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current, ok := b.connections[routeID][memberID]
+
+	return ok && current.expiresAt.After(b.now()), nil
+}
+
+// CountConnections implements ConnectionRegistry.
+func (b *MemoryBroker) CountConnections(_ context.Context, routeID string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	count := 0
+	for _, connection := range b.connections[routeID] {
+		if connection.expiresAt.After(now) {
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,273 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	postgresEventChannel = "keepup_live_events"
+	// pg_notify rejects payloads of 8000 bytes or more; larger events are spilled to a table.
+	postgresNotifyPayloadLimit = 7900
+	postgresSpillRetention     = 5 * time.Minute
+	postgresListenRetryAfter   = time.Second
+	postgresSpillPrefix        = "ref:"
+)
+
+// PostgresBroker implements Broker with LISTEN/NOTIFY and ConnectionRegistry with an expiring claims table.
+// It reuses the API connection pool, so horizontal scaling needs no extra infrastructure.
+type PostgresBroker struct {
+	db     *pgxpool.Pool
+	logger *slog.Logger
+}
+
+// postgresQuerier is the part of the pool that publishing and spill lookups use.
+type postgresQuerier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// NewPostgresBroker builds a PostgreSQL-backed live broker and connection registry.
+func NewPostgresBroker(logger *slog.Logger, db *pgxpool.Pool) *PostgresBroker {
+	return &PostgresBroker{db: db, logger: logger}
+}
+
+// Publish implements Broker.
+func (b *PostgresBroker) Publish(ctx context.Context, message BrokerMessage) error {
+	return publishPostgresMessage(ctx, b.db, message)
+}
+
+// publishPostgresMessage notifies the event channel with the message inline, or with a reference to
+// a spilled row when the message does not fit in a notification.
+func publishPostgresMessage(ctx context.Context, db postgresQuerier, message BrokerMessage) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("encode live broker message: %w", err)
+	}
+
+	payload := string(encoded)
+	if len(encoded) > postgresNotifyPayloadLimit {
+		var id int64
+		if err := db.QueryRow(ctx, `
+			WITH expired AS (
+				DELETE FROM live_broker_messages
+				WHERE created_at < NOW() - $2 * INTERVAL '1 millisecond'
+			)
+			INSERT INTO live_broker_messages (payload)
+			VALUES ($1)
+			RETURNING id
+		`, encoded, postgresSpillRetention.Milliseconds()).Scan(&id); err != nil {
+			return fmt.Errorf("spill live broker message: %w", err)
+		}
+		payload = postgresSpillPrefix + strconv.FormatInt(id, 10)
+	}
+
+	if _, err := db.Exec(ctx, `SELECT pg_notify($1, $2)`, postgresEventChannel, payload); err != nil {
+		return fmt.Errorf("postgres notify: %w", err)
+	}
+
+	return nil
+}
+
+// Messages implements Broker. The listener holds one pooled connection and reconnects after failures;
+// notifications sent while it is reconnecting are lost.
+func (b *PostgresBroker) Messages(ctx context.Context) (<-chan BrokerMessage, error) {
+	conn, err := b.listen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(chan BrokerMessage, subscriptionEventBuffer)
+	go func() {
+		defer close(messages)
+
+		for {
+			err := b.forwardNotifications(ctx, conn, messages)
+			closeListener(conn)
+			if ctx.Err() != nil {
+				return
+			}
+			b.logger.Warn("postgres live listener interrupted", "error", err)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(postgresListenRetryAfter):
+				}
+
+				conn, err = b.listen(ctx)
+				if err == nil {
+					break
+				}
+				b.logger.Warn("postgres live listener reconnect failed", "error", err)
+			}
+		}
+	}()
+
+	return messages, nil
+}
+
+func (b *PostgresBroker) listen(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := b.db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire postgres listener: %w", err)
+	}
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{postgresEventChannel}.Sanitize()); err != nil {
+		closeListener(conn)
+		return nil, fmt.Errorf("postgres listen: %w", err)
+	}
+
+	return conn, nil
+}
+
+// closeListener drops the listening connection instead of returning it to the pool,
+// so no other caller inherits the LISTEN registration.
+func closeListener(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), connectionReleaseAfter)
+	defer cancel()
+
+	_ = conn.Hijack().Close(ctx)
+}
+
+func (b *PostgresBroker) forwardNotifications(ctx context.Context, conn *pgxpool.Conn, messages chan<- BrokerMessage) error {
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		message, err := decodePostgresNotification(ctx, b.db, notification.Payload)
+		if err != nil {
+			b.logger.Warn("decode live broker message failed", "error", err)
+			continue
+		}
+
+		select {
+		case messages <- message:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// decodePostgresNotification decodes an inline message or loads the spilled message it references.
+func decodePostgresNotification(ctx context.Context, db postgresQuerier, payload string) (BrokerMessage, error) {
+	encoded := []byte(payload)
+	if ref, ok := spilledMessageID(payload); ok {
+		if err := db.QueryRow(ctx, `
+			SELECT payload
+			FROM live_broker_messages
+			WHERE id = $1
+		`, ref).Scan(&encoded); err != nil {
+			return BrokerMessage{}, fmt.Errorf("load spilled live broker message %d: %w", ref, err)
+		}
+	}
+
+	var message BrokerMessage
+	if err := json.Unmarshal(encoded, &message); err != nil {
+		return BrokerMessage{}, fmt.Errorf("decode live broker message: %w", err)
+	}
+
+	return message, nil
+}
+
+func spilledMessageID(payload string) (int64, bool) {
+	if len(payload) <= len(postgresSpillPrefix) || payload[:len(postgresSpillPrefix)] != postgresSpillPrefix {
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(payload[len(postgresSpillPrefix):], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// Claim implements ConnectionRegistry.
+func (b *PostgresBroker) Claim(ctx context.Context, routeID, memberID, connectionID string, ttl time.Duration) (bool, error) {
+	var claimedBy string
+	err := b.db.QueryRow(ctx, `
+		INSERT INTO live_connections (route_id, member_id, connection_id, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 millisecond')
+		ON CONFLICT (route_id, member_id) DO UPDATE
+		SET connection_id = EXCLUDED.connection_id, expires_at = EXCLUDED.expires_at
+		WHERE live_connections.expires_at <= NOW()
+			OR live_connections.connection_id = EXCLUDED.connection_id
+		RETURNING connection_id
+	`, routeID, memberID, connectionID, ttl.Milliseconds()).Scan(&claimedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claim live connection: %w", err)
+	}
+
+	return claimedBy == connectionID, nil
+}
+
+// Refresh implements ConnectionRegistry.
+func (b *PostgresBroker) Refresh(ctx context.Context, routeID, memberID, connectionID string, ttl time.Duration) error {
+	if _, err := b.db.Exec(ctx, `
+		UPDATE live_connections
+		SET expires_at = NOW() + $4 * INTERVAL '1 millisecond'
+		WHERE route_id = $1 AND member_id = $2 AND connection_id = $3
+	`, routeID, memberID, connectionID, ttl.Milliseconds()); err != nil {
+		return fmt.Errorf("refresh live connection: %w", err)
+	}
+
+	return nil
+}
+
+// Release implements ConnectionRegistry.
+func (b *PostgresBroker) Release(ctx context.Context, routeID, memberID, connectionID string) error {
+	if _, err := b.db.Exec(ctx, `
+		DELETE FROM live_connections
+		WHERE route_id = $1 AND member_id = $2 AND connection_id = $3
+	`, routeID, memberID, connectionID); err != nil {
+		return fmt.Errorf("release live connection: %w", err)
+	}
+
+	return nil
+}
+
+// HasConnection implements ConnectionRegistry.
+func (b *PostgresBroker) HasConnection(ctx context.Context, routeID, memberID string) (bool, error) {
+	var connected bool
+	if err := b.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM live_connections
+			WHERE route_id = $1 AND member_id = $2 AND expires_at > NOW()
+		)
+	`, routeID, memberID).Scan(&connected); err != nil {
+		return false, fmt.Errorf("check live connection: %w", err)
+	}
+
+	return connected, nil
+}
+
+// CountConnections implements ConnectionRegistry.
+func (b *PostgresBroker) CountConnections(ctx context.Context, routeID string) (int, error) {
+	var count int
+	if err := b.db.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM live_connections
+		WHERE route_id = $1 AND expires_at > NOW()
+	`, routeID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count live connections: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,193 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type stubPostgresQuerier struct {
+	execFn     func(context.Context, string, ...any) (pgconn.CommandTag, error)
+	queryRowFn func(context.Context, string, ...any) pgx.Row
+}
+
+func (s stubPostgresQuerier) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return s.execFn(ctx, sql, arguments...)
+}
+
+func (s stubPostgresQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return s.queryRowFn(ctx, sql, args...)
+}
+
+type stubRow struct {
+	scanFn func(...any) error
+}
+
+func (r stubRow) Scan(dest ...any) error {
+	return r.scanFn(dest...)
+}
+
+func TestSpilledMessageID(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		payload string
+		wantID  int64
+		wantOK  bool
+	}{
+		{name: "spill reference", payload: "ref:42", wantID: 42, wantOK: true},
+		{name: "inline message", payload: `{"origin":"instance-1"}`},
+		{name: "bare prefix", payload: "ref:"},
+		{name: "non-numeric reference", payload: "ref:abc"},
+		{name: "overflowing reference", payload: "ref:99999999999999999999"},
+		{name: "prefix not at start", payload: " ref:42"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			id, ok := spilledMessageID(testCase.payload)
+			if id != testCase.wantID || ok != testCase.wantOK {
+				t.Fatalf("spilledMessageID(%q) = %d, %t; want %d, %t", testCase.payload, id, ok, testCase.wantID, testCase.wantOK)
+			}
+		})
+	}
+}
+
+func TestDecodePostgresNotification(t *testing.T) {
+	t.Parallel()
+
+	spilled := BrokerMessage{Origin: "instance-1", RouteID: "route-1", Event: Event{"type": "route_updated"}}
+	spilledPayload, err := json.Marshal(spilled)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var loadedIDs []any
+	db := stubPostgresQuerier{
+		queryRowFn: func(_ context.Context, _ string, args ...any) pgx.Row {
+			loadedIDs = append(loadedIDs, args...)
+			return stubRow{scanFn: func(dest ...any) error {
+				if args[0] != int64(7) {
+					return pgx.ErrNoRows
+				}
+				*dest[0].(*[]byte) = spilledPayload
+				return nil
+			}}
+		},
+	}
+
+	inline, err := decodePostgresNotification(context.Background(), db, `{"origin":"instance-2","routeId":"route-2","memberId":"member-1","disconnect":true}`)
+	if err != nil {
+		t.Fatalf("decodePostgresNotification(inline) error = %v", err)
+	}
+	if inline.Origin != "instance-2" || inline.RouteID != "route-2" || inline.MemberID != "member-1" || !inline.Disconnect {
+		t.Fatalf("decodePostgresNotification(inline) = %#v, want disconnect for member-1", inline)
+	}
+	if len(loadedIDs) != 0 {
+		t.Fatalf("decodePostgresNotification(inline) loaded spilled rows %v, want none", loadedIDs)
+	}
+
+	message, err := decodePostgresNotification(context.Background(), db, "ref:7")
+	if err != nil {
+		t.Fatalf("decodePostgresNotification(ref) error = %v", err)
+	}
+	if message.Origin != spilled.Origin || message.RouteID != spilled.RouteID || message.Event["type"] != "route_updated" {
+		t.Fatalf("decodePostgresNotification(ref) = %#v, want spilled message", message)
+	}
+
+	if _, err := decodePostgresNotification(context.Background(), db, "ref:8"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("decodePostgresNotification(expired ref) error = %v, want pgx.ErrNoRows", err)
+	}
+
+	if _, err := decodePostgresNotification(context.Background(), db, "not json"); err == nil {
+		t.Fatal("decodePostgresNotification(garbage) error = nil, want decode error")
+	}
+}
+
+func TestPublishPostgresMessageSpillsOversizedMessages(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		message     BrokerMessage
+		wantSpilled bool
+	}{
+		{
+			name:    "small message goes inline",
+			message: BrokerMessage{Origin: "instance-1", RouteID: "route-1", Event: Event{"type": "position_updated"}},
+		},
+		{
+			name: "oversized message is spilled",
+			message: BrokerMessage{Origin: "instance-1", RouteID: "route-1", Event: Event{
+				"type": "chat_message_posted",
+				"body": strings.Repeat("x", postgresNotifyPayloadLimit),
+			}},
+			wantSpilled: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var spilledRows [][]byte
+			var notified []string
+			db := stubPostgresQuerier{
+				queryRowFn: func(_ context.Context, sql string, args ...any) pgx.Row {
+					if !strings.Contains(sql, "INSERT INTO live_broker_messages") {
+						t.Fatalf("QueryRow() sql = %q, want spill insert", sql)
+					}
+					spilledRows = append(spilledRows, args[0].([]byte))
+					return stubRow{scanFn: func(dest ...any) error {
+						*dest[0].(*int64) = 42
+						return nil
+					}}
+				},
+				execFn: func(_ context.Context, _ string, args ...any) (pgconn.CommandTag, error) {
+					if args[0] != postgresEventChannel {
+						t.Fatalf("pg_notify channel = %v, want %q", args[0], postgresEventChannel)
+					}
+					notified = append(notified, args[1].(string))
+					return pgconn.CommandTag{}, nil
+				},
+			}
+
+			if err := publishPostgresMessage(context.Background(), db, testCase.message); err != nil {
+				t.Fatalf("publishPostgresMessage() error = %v", err)
+			}
+
+			if len(notified) != 1 {
+				t.Fatalf("pg_notify calls = %d, want 1", len(notified))
+			}
+			if len(notified[0]) > postgresNotifyPayloadLimit {
+				t.Fatalf("pg_notify payload = %d bytes, want at most %d", len(notified[0]), postgresNotifyPayloadLimit)
+			}
+
+			if !testCase.wantSpilled {
+				if len(spilledRows) != 0 {
+					t.Fatalf("spilled rows = %d, want 0", len(spilledRows))
+				}
+				var inline BrokerMessage
+				if err := json.Unmarshal([]byte(notified[0]), &inline); err != nil || inline.RouteID != "route-1" {
+					t.Fatalf("pg_notify payload = %q, want inline message", notified[0])
+				}
+				return
+			}
+
+			if len(spilledRows) != 1 || len(spilledRows[0]) <= postgresNotifyPayloadLimit {
+				t.Fatalf("spilled rows = %d, want the oversized message spilled once", len(spilledRows))
+			}
+			if notified[0] != "ref:42" {
+				t.Fatalf("pg_notify payload = %q, want ref:42", notified[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisEventChannel = "keepup:live:events"
+	redisKeyPrefix    = "keepup:live:route:"
+)
+
+// Connection claims live in two keys per route: a sorted set of member IDs scored by claim
+// expiry (server time, milliseconds) and a hash of member ID to owning connection ID.
+var (
+	redisClaimScript = redis.NewScript(`
+local now = redis.call('TIME')
+local nowMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+local ttl = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', nowMs)
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if score and redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZADD', KEYS[1], nowMs + ttl, ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ttl)
+redis.call('PEXPIRE', KEYS[2], ttl)
+return 1
+`)
+	redisRefreshScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+local now = redis.call('TIME')
+local nowMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+local ttl = tonumber(ARGV[3])
+redis.call('ZADD', KEYS[1], nowMs + ttl, ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ttl)
+redis.call('PEXPIRE', KEYS[2], ttl)
+return 1
+`)
+	redisReleaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+return 1
+`)
+	redisCountScript = redis.NewScript(`
+local now = redis.call('TIME')
+local nowMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+if ARGV[1] ~= '' then
+	local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+	if score and tonumber(score) > nowMs then
+		return 1
+	end
+	return 0
+end
+return redis.call('ZCOUNT', KEYS[1], '(' .. nowMs, '+inf')
+`)
+)
+
+// RedisBroker implements Broker with Redis pub/sub and ConnectionRegistry with expiring Redis claims.
+type RedisBroker struct {
+	client redis.UniversalClient
+}
+
+// NewRedisBroker builds a Redis-backed live broker and connection registry.
+func NewRedisBroker(client redis.UniversalClient) *RedisBroker {
+	return &RedisBroker{client: client}
+}
+
+// Publish implements Broker.
+func (b *RedisBroker) Publish(ctx context.Context, message BrokerMessage) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("encode live broker message: %w", err)
+	}
+
+	if err := b.client.Publish(ctx, redisEventChannel, encoded).Err(); err != nil {
+		return fmt.Errorf("redis publish: %w", err)
+	}
+
+	return nil
+}
+
+// Messages implements Broker.
+func (b *RedisBroker) Messages(ctx context.Context) (<-chan BrokerMessage, error) {
+	pubsub := b.client.Subscribe(ctx, redisEventChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("redis subscribe: %w", err)
+	}
+
+	messages := make(chan BrokerMessage, subscriptionEventBuffer)
+	go func() {
+		defer close(messages)
+		defer func() {
+			_ = pubsub.Close()
+		}()
+
+		incoming := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case raw, ok := <-incoming:
+				if !ok {
+					return
+				}
+
+				var message BrokerMessage
+				if err := json.Unmarshal([]byte(raw.Payload), &message); err != nil {
+					continue
+				}
+
+				select {
+				case messages <- message:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return messages, nil
+}
+
+// Claim implements ConnectionRegistry.
+func (b *RedisBroker) Claim(ctx context.Context, routeID, memberID, connectionID string, ttl time.Duration) (bool, error) {
+	claimed, err := redisClaimScript.Run(ctx, b.client, redisConnectionKeys(routeID), memberID, connectionID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis claim connection: %w", err)
+	}
+
+	return claimed == 1, nil
+}
+
+// Refresh implements ConnectionRegistry.
+func (b *RedisBroker) Refresh(ctx context.Context, routeID, memberID, connectionID string, ttl time.Duration) error {
+	if err := redisRefreshScript.Run(ctx, b.client, redisConnectionKeys(routeID), memberID, connectionID, ttl.Milliseconds()).Err(); err != nil {
+		return fmt.Errorf("redis refresh connection: %w", err)
+	}
+
+	return nil
+}
+
+// Release implements ConnectionRegistry.
+func (b *RedisBroker) Release(ctx context.Context, routeID, memberID, connectionID string) error {
+	if err := redisReleaseScript.Run(ctx, b.client, redisConnectionKeys(routeID), memberID, connectionID).Err(); err != nil {
+		return fmt.Errorf("redis release connection: %w", err)
+	}
+
+	return nil
+}
+
+// HasConnection implements ConnectionRegistry.
+func (b *RedisBroker) HasConnection(ctx context.Context, routeID, memberID string) (bool, error) {
+	found, err := redisCountScript.Run(ctx, b.client, redisConnectionKeys(routeID)[:1], memberID).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis check connection: %w", err)
+	}
+
+	return found == 1, nil
+}
+
+// CountConnections implements ConnectionRegistry.
+func (b *RedisBroker) CountConnections(ctx context.Context, routeID string) (int, error) {
+	count, err := redisCountScript.Run(ctx, b.client, redisConnectionKeys(routeID)[:1], "").Int()
+	if err != nil {
+		return 0, fmt.Errorf("redis count connections: %w", err)
+	}
+
+	return count, nil
+}
+
+func redisConnectionKeys(routeID string) []string {
+	return []string{
+		redisKeyPrefix + routeID + ":members",
+		redisKeyPrefix + routeID + ":connections",
+	}
+}
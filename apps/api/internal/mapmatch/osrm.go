@@ -0,0 +1,223 @@
+// Package mapmatch snaps raw route traces onto a road or path network.
+package mapmatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"keepup/apps/api/internal/routes"
+)
+
+const (
+	osrmMatcherName           = "osrm"
+	defaultOSRMMaxCoordinates = 100
+	defaultOSRMTimeout        = 10 * time.Second
+	minOSRMRadiusM            = 5
+	maxOSRMRadiusM            = 50
+)
+
+// osrmProfiles maps KeepUp transport modes to the OSRM profiles that can match them.
+// Modes without a road or path network (train, boat, airplane) are not matched.
+var osrmProfiles = map[string]string{
+	"walking": "foot",
+	"bicycle": "bike",
+	"car":     "car",
+	"bus":     "car",
+}
+
+// OSRMClient calls the match service of an OSRM-compatible HTTP server.
+type OSRMClient struct {
+	baseURL        string
+	httpClient     *http.Client
+	maxCoordinates int
+}
+
+// OSRMOption customizes an OSRMClient.
+type OSRMOption func(*OSRMClient)
+
+// WithHTTPClient replaces the HTTP client used for match requests.
+func WithHTTPClient(client *http.Client) OSRMOption {
+	return func(c *OSRMClient) {
+		c.httpClient = client
+	}
+}
+
+// WithMaxCoordinates caps the points sent per request; longer traces are matched in overlapping chunks.
+func WithMaxCoordinates(limit int) OSRMOption {
+	return func(c *OSRMClient) {
+		if limit >= 2 {
+			c.maxCoordinates = limit
+		}
+	}
+}
+
+// NewOSRMClient builds a matcher for the OSRM server at baseURL, for example http://osrm:5000.
+func NewOSRMClient(baseURL string, opts ...OSRMOption) *OSRMClient {
+	client := &OSRMClient{
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		httpClient:     &http.Client{Timeout: defaultOSRMTimeout},
+		maxCoordinates: defaultOSRMMaxCoordinates,
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client
+}
+
+type osrmMatchResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Matchings []struct {
+		Confidence float64 `json:"confidence"`
+		Geometry   struct {
+			Coordinates [][2]float64 `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"matchings"`
+	// Tracepoints has one entry per input coordinate; null marks a point OSRM dropped as an outlier.
+	Tracepoints []*struct {
+		MatchingsIndex int `json:"matchings_index"`
+	} `json:"tracepoints"`
+}
+
+// startsFirstMatching reports whether the chunk's first point belongs to its first matching.
+func (r osrmMatchResponse) startsFirstMatching() bool {
+	return len(r.Tracepoints) > 0 && r.Tracepoints[0] != nil && r.Tracepoints[0].MatchingsIndex == 0
+}
+
+// endsLastMatching reports whether the chunk's last point belongs to its last matching.
+func (r osrmMatchResponse) endsLastMatching() bool {
+	last := len(r.Tracepoints) - 1
+	return last >= 0 && r.Tracepoints[last] != nil && r.Tracepoints[last].MatchingsIndex == len(r.Matchings)-1
+}
+
+// Match snaps one segment's points. Traces longer than the coordinate limit are split into chunks
+// that share their boundary point. Every OSRM matching becomes its own line, except that a chunk's
+// first matching continues the previous chunk's last one when both matched the shared point; chunks
+// that do not match and OSRM's own trace splits leave gaps instead of straight bridges. The reported
+// confidence is the lowest confidence of any matching, and the result starts at the first point when
+// OSRM matched it into the first matching.
+func (c *OSRMClient) Match(ctx context.Context, input routes.MatchInput) (routes.MatchResult, error) {
+	result := routes.MatchResult{Matcher: osrmMatcherName}
+	profile, ok := osrmProfiles[input.TransportMode]
+	if !ok || len(input.Points) < 2 {
+		return result, routes.ErrNoMatch
+	}
+
+	var confidence *float64
+	connected := false
+	for start := 0; start < len(input.Points)-1; start += c.maxCoordinates - 1 {
+		end := min(start+c.maxCoordinates, len(input.Points))
+		response, err := c.matchChunk(ctx, profile, input.Points[start:end])
+		if errors.Is(err, routes.ErrNoMatch) {
+			connected = false
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		if start == 0 {
+			result.StartsAtFirstPoint = response.startsFirstMatching()
+		}
+
+		for index, matching := range response.Matchings {
+			coordinates := matching.Geometry.Coordinates
+			if index == 0 && connected && response.startsFirstMatching() {
+				line := &result.Lines[len(result.Lines)-1]
+				if len(coordinates) > 0 && coordinates[0] == (*line)[len(*line)-1] {
+					coordinates = coordinates[1:]
+				}
+				*line = append(*line, coordinates...)
+			} else {
+				result.Lines = append(result.Lines, coordinates)
+			}
+			if confidence == nil || matching.Confidence < *confidence {
+				value := matching.Confidence
+				confidence = &value
+			}
+		}
+		connected = len(result.Lines) > 0 && response.endsLastMatching()
+	}
+
+	lines := make([][][2]float64, 0, len(result.Lines))
+	for index, line := range result.Lines {
+		if len(line) >= 2 {
+			lines = append(lines, line)
+		} else if index == 0 {
+			result.StartsAtFirstPoint = false
+		}
+	}
+	if len(lines) == 0 {
+		return routes.MatchResult{Matcher: osrmMatcherName}, routes.ErrNoMatch
+	}
+	result.Lines = lines
+	result.Confidence = confidence
+
+	return result, nil
+}
+
+func (c *OSRMClient) matchChunk(ctx context.Context, profile string, points []routes.RoutePoint) (osrmMatchResponse, error) {
+	coordinates := make([]string, len(points))
+	timestamps := make([]string, len(points))
+	radiuses := make([]string, len(points))
+	var previousTimestamp int64
+	for i, point := range points {
+		coordinates[i] = strconv.FormatFloat(point.Longitude, 'f', -1, 64) + "," + strconv.FormatFloat(point.Latitude, 'f', -1, 64)
+
+		recordedAt := point.RecordedAt
+		if point.ClientRecordedAt != nil {
+			recordedAt = *point.ClientRecordedAt
+		}
+		// OSRM requires non-decreasing timestamps; clock jitter between samples must not fail the match.
+		timestamp := max(recordedAt.Unix(), previousTimestamp)
+		previousTimestamp = timestamp
+		timestamps[i] = strconv.FormatInt(timestamp, 10)
+
+		radius := float64(minOSRMRadiusM)
+		if point.AccuracyM != nil {
+			radius = math.Min(math.Max(*point.AccuracyM, minOSRMRadiusM), maxOSRMRadiusM)
+		}
+		radiuses[i] = strconv.FormatFloat(radius, 'f', 1, 64)
+	}
+
+	query := url.Values{}
+	query.Set("geometries", "geojson")
+	query.Set("overview", "full")
+	query.Set("timestamps", strings.Join(timestamps, ";"))
+	query.Set("radiuses", strings.Join(radiuses, ";"))
+	endpoint := c.baseURL + "/match/v1/" + profile + "/" + strings.Join(coordinates, ";") + "?" + query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return osrmMatchResponse{}, fmt.Errorf("build osrm match request: %w", err)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return osrmMatchResponse{}, fmt.Errorf("osrm match request: %w", err)
+	}
+	defer response.Body.Close()
+
+	var payload osrmMatchResponse
+	if err := json.NewDecoder(io.LimitReader(response.Body, 16<<20)).Decode(&payload); err != nil {
+		return osrmMatchResponse{}, fmt.Errorf("decode osrm match response (status %d): %w", response.StatusCode, err)
+	}
+
+	switch payload.Code {
+	case "Ok":
+		return payload, nil
+	case "NoMatch", "NoSegment":
+		return osrmMatchResponse{}, routes.ErrNoMatch
+	default:
+		return osrmMatchResponse{}, fmt.Errorf("osrm match failed with %s: %s", payload.Code, payload.Message)
+	}
+}
@@ -0,0 +1,174 @@
+package mapmatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"keepup/apps/api/internal/routes"
+)
+
+func TestOSRMClientMatch(t *testing.T) {
+	t.Parallel()
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Query().Get("geometries") != "geojson" || r.URL.Query().Get("overview") != "full" {
+			t.Errorf("match query = %q, want full geojson overview", r.URL.RawQuery)
+		}
+
+		coordinates := strings.Split(strings.TrimPrefix(r.URL.Path, "/match/v1/foot/"), ";")
+		if got := len(strings.Split(r.URL.Query().Get("radiuses"), ";")); got != len(coordinates) {
+			t.Errorf("radiuses = %d, want %d", got, len(coordinates))
+		}
+
+		matched := make([][2]float64, 0, len(coordinates))
+		tracepoints := make([]map[string]any, 0, len(coordinates))
+		for _, coordinate := range coordinates {
+			var lon, lat float64
+			if err := json.Unmarshal([]byte("["+coordinate+"]"), &[]*float64{&lon, &lat}); err != nil {
+				t.Errorf("decode coordinate %q: %v", coordinate, err)
+			}
+			matched = append(matched, [2]float64{lon, lat + 0.0001})
+			tracepoints = append(tracepoints, map[string]any{"matchings_index": 0})
+		}
+
+		confidence := 0.9
+		if len(paths) == 2 {
+			confidence = 0.6
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"code": "Ok",
+			"matchings": []map[string]any{{
+				"confidence": confidence,
+				"geometry":   map[string]any{"type": "LineString", "coordinates": matched},
+			}},
+			"tracepoints": tracepoints,
+		})
+	}))
+	defer server.Close()
+
+	accuracy := 80.0
+	startedAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	points := make([]routes.RoutePoint, 5)
+	for i := range points {
+		points[i] = routes.RoutePoint{
+			Seq:        int64(i + 1),
+			Latitude:   52.52,
+			Longitude:  13.40 + float64(i)/1000,
+			AccuracyM:  &accuracy,
+			RecordedAt: startedAt.Add(time.Duration(i) * 5 * time.Second),
+		}
+	}
+
+	client := NewOSRMClient(server.URL+"/", WithHTTPClient(server.Client()), WithMaxCoordinates(3))
+	result, err := client.Match(context.Background(), routes.MatchInput{TransportMode: "walking", Points: points})
+	if err != nil {
+		t.Fatalf("Match() error = %v", err)
+	}
+
+	if len(paths) != 2 {
+		t.Fatalf("match requests = %v, want 2 overlapping chunks", paths)
+	}
+	if result.Matcher != "osrm" {
+		t.Fatalf("Match() matcher = %q, want osrm", result.Matcher)
+	}
+	if len(result.Lines) != 1 || len(result.Lines[0]) != len(points) {
+		t.Fatalf("Match() lines = %v, want one line of %d stitched points", result.Lines, len(points))
+	}
+	if result.Confidence == nil || *result.Confidence != 0.6 {
+		t.Fatalf("Match() confidence = %v, want lowest chunk confidence 0.6", result.Confidence)
+	}
+	if !result.StartsAtFirstPoint {
+		t.Fatal("Match() StartsAtFirstPoint = false, want true for a matched first point")
+	}
+}
+
+func TestOSRMClientMatchKeepsUnmatchedGapsOpen(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"code": "Ok",
+				"matchings": []map[string]any{
+					{"confidence": 0.9, "geometry": map[string]any{"coordinates": [][2]float64{{13.400, 52.52}, {13.401, 52.52}, {13.402, 52.52}}}},
+				},
+				"tracepoints": []map[string]any{{"matchings_index": 0}, {"matchings_index": 0}, {"matchings_index": 0}},
+			})
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"code": "NoMatch"})
+		default:
+			// OSRM split this chunk into two matchings around a dropped middle point.
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"code": "Ok",
+				"matchings": []map[string]any{
+					{"confidence": 0.8, "geometry": map[string]any{"coordinates": [][2]float64{{13.404, 52.52}, {13.4045, 52.52}}}},
+					{"confidence": 0.7, "geometry": map[string]any{"coordinates": [][2]float64{{13.4055, 52.52}, {13.406, 52.52}}}},
+				},
+				"tracepoints": []any{map[string]any{"matchings_index": 0}, nil, map[string]any{"matchings_index": 1}},
+			})
+		}
+	}))
+	defer server.Close()
+
+	startedAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	points := make([]routes.RoutePoint, 7)
+	for i := range points {
+		points[i] = routes.RoutePoint{
+			Seq:        int64(i + 1),
+			Latitude:   52.52,
+			Longitude:  13.40 + float64(i)/1000,
+			RecordedAt: startedAt.Add(time.Duration(i) * 5 * time.Second),
+		}
+	}
+
+	client := NewOSRMClient(server.URL, WithHTTPClient(server.Client()), WithMaxCoordinates(3))
+	result, err := client.Match(context.Background(), routes.MatchInput{TransportMode: "walking", Points: points})
+	if err != nil {
+		t.Fatalf("Match() error = %v", err)
+	}
+
+	if len(result.Lines) != 3 {
+		t.Fatalf("Match() lines = %v, want the first chunk and both matchings of the last chunk as separate lines", result.Lines)
+	}
+	if len(result.Lines[0]) != 3 || result.Lines[1][0] != [2]float64{13.404, 52.52} || result.Lines[2][0] != [2]float64{13.4055, 52.52} {
+		t.Fatalf("Match() lines = %v, want gaps left unbridged", result.Lines)
+	}
+	if result.Confidence == nil || *result.Confidence != 0.7 {
+		t.Fatalf("Match() confidence = %v, want lowest matching confidence 0.7", result.Confidence)
+	}
+}
+
+func TestOSRMClientMatchReportsNoMatch(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"code": "NoMatch", "message": "Could not match the trace."})
+	}))
+	defer server.Close()
+
+	points := []routes.RoutePoint{
+		{Seq: 1, Latitude: 52.52, Longitude: 13.40},
+		{Seq: 2, Latitude: 52.53, Longitude: 13.41},
+	}
+	client := NewOSRMClient(server.URL, WithHTTPClient(server.Client()))
+
+	if _, err := client.Match(context.Background(), routes.MatchInput{TransportMode: "car", Points: points}); !errors.Is(err, routes.ErrNoMatch) {
+		t.Fatalf("Match() error = %v, want ErrNoMatch", err)
+	}
+	if _, err := client.Match(context.Background(), routes.MatchInput{TransportMode: "boat", Points: points}); !errors.Is(err, routes.ErrNoMatch) {
+		t.Fatalf("Match() boat error = %v, want ErrNoMatch", err)
+	}
+}
@@ -0,0 +1,227 @@
+// Package metrics owns the Prometheus collectors exposed on /metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "keepup"
+
+// Metrics holds the API collectors in a private registry.
+// A nil *Metrics is valid and records nothing, so collaborators can treat metrics as optional.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+
+	liveConnections          prometheus.Gauge
+	liveConnectionsRejected  *prometheus.CounterVec
+	liveEventsBroadcast      *prometheus.CounterVec
+	liveEventDeliveries      *prometheus.CounterVec
+	liveEventDeliveryDropped *prometheus.CounterVec
+	liveSlowConsumers        prometheus.Counter
+
+	positionUpdates *prometheus.CounterVec
+
+	snapshotBytes  prometheus.Histogram
+	snapshotPoints prometheus.Histogram
+}
+
+// New builds and registers every API collector plus Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern, and response status.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern, excluding WebSocket sessions.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		liveConnections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "live_connections",
+			Help:      "Authenticated WebSocket connections currently open on this instance.",
+		}),
+		liveConnectionsRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "live_connections_rejected_total",
+			Help:      "WebSocket connections closed before subscribing, by reason.",
+		}, []string{"reason"}),
+		liveEventsBroadcast: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "live_events_broadcast_total",
+			Help:      "Live events broadcast by this instance, by event type.",
+		}, []string{"type"}),
+		liveEventDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "live_event_deliveries_total",
+			Help:      "Live events queued to local subscriptions, by event type.",
+		}, []string{"type"}),
+		liveEventDeliveryDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "live_event_deliveries_dropped_total",
+			Help:      "Live events dropped because a local subscription buffer was full, by event type.",
+		}, []string{"type"}),
+		liveSlowConsumers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "live_slow_consumers_disconnected_total",
+			Help:      "Live subscriptions closed because their event buffer overflowed.",
+		}),
+		positionUpdates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "position_updates_total",
+			Help:      "Position updates by result and rejection reason.",
+		}, []string{"result", "reason"}),
+		snapshotBytes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "route_snapshot_bytes",
+			Help:      "Encoded route snapshot response size.",
+			Buckets:   prometheus.ExponentialBuckets(1024, 4, 8),
+		}),
+		snapshotPoints: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "route_snapshot_points",
+			Help:      "Position points included in a route snapshot.",
+			Buckets:   prometheus.ExponentialBuckets(10, 4, 8),
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.liveConnections,
+		m.liveConnectionsRejected,
+		m.liveEventsBroadcast,
+		m.liveEventDeliveries,
+		m.liveEventDeliveryDropped,
+		m.liveSlowConsumers,
+		m.positionUpdates,
+		m.snapshotBytes,
+		m.snapshotPoints,
+	)
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveHTTPRequest records one finished HTTP request. Upgraded WebSocket requests are counted
+// but kept out of the latency histogram, since their duration is the session length.
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	if status != http.StatusSwitchingProtocols {
+		m.httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+	}
+}
+
+// LiveConnectionOpened records an authenticated, subscribed WebSocket connection.
+func (m *Metrics) LiveConnectionOpened() {
+	if m == nil {
+		return
+	}
+
+	m.liveConnections.Inc()
+}
+
+// LiveConnectionClosed records the end of a connection previously passed to LiveConnectionOpened.
+func (m *Metrics) LiveConnectionClosed() {
+	if m == nil {
+		return
+	}
+
+	m.liveConnections.Dec()
+}
+
+// LiveConnectionRejected records a WebSocket connection closed before it subscribed.
+func (m *Metrics) LiveConnectionRejected(reason string) {
+	if m == nil {
+		return
+	}
+
+	m.liveConnectionsRejected.WithLabelValues(reason).Inc()
+}
+
+// LiveEventBroadcast records one live event published by this instance.
+func (m *Metrics) LiveEventBroadcast(eventType string) {
+	if m == nil {
+		return
+	}
+
+	m.liveEventsBroadcast.WithLabelValues(eventType).Inc()
+}
+
+// LiveEventDelivered records the outcome of fanning one event out to local subscriptions.
+func (m *Metrics) LiveEventDelivered(eventType string, delivered, dropped int) {
+	if m == nil {
+		return
+	}
+
+	if delivered > 0 {
+		m.liveEventDeliveries.WithLabelValues(eventType).Add(float64(delivered))
+	}
+	if dropped > 0 {
+		m.liveEventDeliveryDropped.WithLabelValues(eventType).Add(float64(dropped))
+	}
+}
+
+// LiveSlowConsumerDisconnected records a subscription closed for falling behind.
+func (m *Metrics) LiveSlowConsumerDisconnected() {
+	if m == nil {
+		return
+	}
+
+	m.liveSlowConsumers.Inc()
+}
+
+// PositionAccepted records one persisted position update.
+func (m *Metrics) PositionAccepted() {
+	if m == nil {
+		return
+	}
+
+	m.positionUpdates.WithLabelValues("accepted", "").Inc()
+}
+
+// PositionRejected records one refused position update.
+func (m *Metrics) PositionRejected(reason string) {
+	if m == nil {
+		return
+	}
+
+	m.positionUpdates.WithLabelValues("rejected", reason).Inc()
+}
+
+// ObserveSnapshot records the encoded size and point count of one route snapshot response.
+func (m *Metrics) ObserveSnapshot(bytes, points int) {
+	if m == nil {
+		return
+	}
+
+	m.snapshotBytes.Observe(float64(bytes))
+	m.snapshotPoints.Observe(float64(points))
+}
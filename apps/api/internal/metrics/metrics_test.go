@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandlerExposesRecordedValues(t *testing.T) {
+	t.Parallel()
+
+	m := New()
+	m.ObserveHTTPRequest(http.MethodGet, "GET /routes/{code}", http.StatusOK, 25*time.Millisecond)
+	m.ObserveHTTPRequest(http.MethodGet, "GET /ws", http.StatusSwitchingProtocols, time.Minute)
+	m.LiveConnectionOpened()
+	m.LiveConnectionOpened()
+	m.LiveConnectionClosed()
+	m.LiveConnectionRejected("unauthorized")
+	m.LiveEventBroadcast("position_updated")
+	m.LiveEventDelivered("position_updated", 3, 1)
+	m.PositionAccepted()
+	m.PositionRejected("impossible_speed")
+	m.ObserveSnapshot(2048, 120)
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`keepup_http_requests_total{method="GET",route="GET /routes/{code}",status="200"} 1`,
+		`keepup_http_requests_total{method="GET",route="GET /ws",status="101"} 1`,
+		`keepup_http_request_duration_seconds_count{method="GET",route="GET /routes/{code}"} 1`,
+		`keepup_live_connections 1`,
+		`keepup_live_connections_rejected_total{reason="unauthorized"} 1`,
+		`keepup_live_events_broadcast_total{type="position_updated"} 1`,
+		`keepup_live_event_deliveries_total{type="position_updated"} 3`,
+		`keepup_live_event_deliveries_dropped_total{type="position_updated"} 1`,
+		`keepup_position_updates_total{reason="",result="accepted"} 1`,
+		`keepup_position_updates_total{reason="impossible_speed",result="rejected"} 1`,
+		`keepup_route_snapshot_bytes_sum 2048`,
+		`keepup_route_snapshot_points_sum 120`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics output missing %q", want)
+		}
+	}
+
+	if strings.Contains(body, `keepup_http_request_duration_seconds_count{method="GET",route="GET /ws"}`) {
+		t.Fatal("metrics output includes websocket session duration in request latency")
+	}
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	t.Parallel()
+
+	var m *Metrics
+	m.ObserveHTTPRequest(http.MethodGet, "/", http.StatusOK, time.Millisecond)
+	m.LiveConnectionOpened()
+	m.LiveEventDelivered("member_joined", 1, 1)
+	m.PositionRejected("accuracy_too_low")
+	m.ObserveSnapshot(1, 1)
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("nil metrics handler status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("metrics handler status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	body, err := io.ReadAll(recorder.Body)
+	if err != nil {
+		t.Fatalf("read metrics body: %v", err)
+	}
+
+	return string(body)
+}
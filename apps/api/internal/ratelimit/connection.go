@@ -0,0 +1,116 @@
+package ratelimit
+
+import "time"
+
+const (
+	defaultConnectionMessagesPerSecond  = 20
+	defaultConnectionPositionsPerSecond = 5
+	defaultConnectionMaxViolations      = 20
+	// defaultConnectionBatchPositions lets a connection replay one full position_batch a minute.
+	defaultConnectionBatchPositions = 1000
+	connectionBatchWindow           = time.Minute
+	connectionViolationWindow       = 10 * time.Second
+)
+
+// ConnectionPolicy configures the limits of one live connection. Zero fields fall back to the
+// defaults.
+type ConnectionPolicy struct {
+	MessagesPerSecond       int
+	PositionsPerSecond      int
+	BatchPositionsPerMinute int
+	MaxViolations           int
+}
+
+// ConnectionLimiter throttles the messages one live connection sends. Every message uses a message
+// token, live positions use one position token each, and batched positions use one token each from a
+// separate per-minute batch budget, so replaying buffered samples neither waits on nor starves the
+// live position rate. Refused messages use no tokens. A connection that is refused MaxViolations
+// times within ten seconds is abusive. It keeps its state in memory and is not safe for concurrent
+// use; each connection's read loop owns one.
+type ConnectionLimiter struct {
+	messages        Bucket
+	positions       Bucket
+	batchPositions  Bucket
+	maxViolations   int
+	messageState    memoryBucket
+	positionState   memoryBucket
+	batchState      memoryBucket
+	violations      int
+	violationsSince time.Time
+	now             func() time.Time
+}
+
+// NewConnectionLimiter builds a limiter for one live connection.
+func NewConnectionLimiter(policy ConnectionPolicy) *ConnectionLimiter {
+	if policy.MessagesPerSecond <= 0 {
+		policy.MessagesPerSecond = defaultConnectionMessagesPerSecond
+	}
+	if policy.PositionsPerSecond <= 0 {
+		policy.PositionsPerSecond = defaultConnectionPositionsPerSecond
+	}
+	if policy.BatchPositionsPerMinute <= 0 {
+		policy.BatchPositionsPerMinute = defaultConnectionBatchPositions
+	}
+	if policy.MaxViolations <= 0 {
+		policy.MaxViolations = defaultConnectionMaxViolations
+	}
+
+	limiter := &ConnectionLimiter{
+		messages:       Bucket{Burst: policy.MessagesPerSecond, Window: time.Second},
+		positions:      Bucket{Burst: policy.PositionsPerSecond, Window: time.Second},
+		batchPositions: Bucket{Burst: policy.BatchPositionsPerMinute, Window: connectionBatchWindow},
+		maxViolations:  policy.MaxViolations,
+		now:            time.Now,
+	}
+	now := limiter.now()
+	limiter.messageState = memoryBucket{tokens: float64(policy.MessagesPerSecond), updatedAt: now}
+	limiter.positionState = memoryBucket{tokens: float64(policy.PositionsPerSecond), updatedAt: now}
+	limiter.batchState = memoryBucket{tokens: float64(policy.BatchPositionsPerMinute), updatedAt: now}
+
+	return limiter
+}
+
+// PositionsPerSecond returns the live position rate of the connection.
+func (l *ConnectionLimiter) PositionsPerSecond() int {
+	return l.positions.Burst
+}
+
+// BatchPositions returns the most samples one position batch may carry.
+func (l *ConnectionLimiter) BatchPositions() int {
+	return l.batchPositions.Burst
+}
+
+// Allow reports whether the next message, carrying the given number of live positions, may be
+// handled and, when it may not, whether the connection has now been refused often enough to be
+// closed.
+func (l *ConnectionLimiter) Allow(positions int) (allowed, abusive bool) {
+	return l.allow(&l.positionState, l.positions, positions)
+}
+
+// AllowBatch is Allow for a message replaying the given number of buffered positions, which are
+// charged against the batch budget instead of the live position rate.
+func (l *ConnectionLimiter) AllowBatch(positions int) (allowed, abusive bool) {
+	return l.allow(&l.batchState, l.batchPositions, positions)
+}
+
+// allow takes one message token and the positions from state only when both are available, so a
+// refused message costs nothing but a violation.
+func (l *ConnectionLimiter) allow(state *memoryBucket, bucket Bucket, positions int) (allowed, abusive bool) {
+	now := l.now()
+
+	if l.messageState.wait(l.messages, 1, now) == 0 && (positions <= 0 || state.wait(bucket, positions, now) == 0) {
+		l.messageState.remove(l.messages, 1, now)
+		if positions > 0 {
+			state.remove(bucket, positions, now)
+		}
+		return true, false
+	}
+
+	if now.Sub(l.violationsSince) > connectionViolationWindow {
+		l.violations = 0
+		l.violationsSince = now
+	}
+	l.violations++
+
+	return false, l.violations >= l.maxViolations
+}
@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const memoryPruneInterval = time.Minute
+
+// MemoryStore is an in-process Store. Limits only hold within one API instance.
+type MemoryStore struct {
+	mu         sync.Mutex
+	buckets    map[string]memoryBucket
+	failures   map[string]memoryFailures
+	lastPruned time.Time
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+type memoryFailures struct {
+	count       int
+	lockedUntil time.Time
+	forgetAt    time.Time
+}
+
+// NewMemoryStore builds an empty in-process store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]memoryBucket),
+		failures: make(map[string]memoryFailures),
+	}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ context.Context, key string, bucket Bucket, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+
+	state, ok := s.buckets[key]
+	if !ok {
+		state = memoryBucket{tokens: float64(bucket.Burst), updatedAt: now}
+	}
+	retryAfter := state.take(bucket, now)
+	s.buckets[key] = state
+
+	return retryAfter, nil
+}
+
+// take refills the bucket up to now and removes one token, or returns how long until one is
+// available.
+func (b *memoryBucket) take(bucket Bucket, now time.Time) time.Duration {
+	return b.takeN(bucket, 1, now)
+}
+
+// takeN refills the bucket up to now and removes n tokens, or returns how long until n are
+// available.
+func (b *memoryBucket) takeN(bucket Bucket, n int, now time.Time) time.Duration {
+	if retryAfter := b.wait(bucket, n, now); retryAfter > 0 {
+		return retryAfter
+	}
+
+	b.remove(bucket, n, now)
+	return 0
+}
+
+// wait refills the bucket up to now and returns how long until n tokens are available. Requests for
+// more than Burst tokens can never succeed and return the full Window.
+func (b *memoryBucket) wait(bucket Bucket, n int, now time.Time) time.Duration {
+	perToken := bucket.Window / time.Duration(bucket.Burst)
+
+	if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens = min(float64(bucket.Burst), b.tokens+float64(elapsed)/float64(perToken))
+		b.updatedAt = now
+	}
+
+	if n > bucket.Burst {
+		return bucket.Window
+	}
+	if b.tokens < float64(n) {
+		return time.Duration((float64(n) - b.tokens) * float64(perToken))
+	}
+
+	return 0
+}
+
+// remove takes n tokens that wait reported available.
+func (b *memoryBucket) remove(bucket Bucket, n int, now time.Time) {
+	perToken := bucket.Window / time.Duration(bucket.Burst)
+
+	b.tokens -= float64(n)
+	b.fullAt = now.Add(time.Duration((float64(bucket.Burst) - b.tokens) * float64(perToken)))
+}
+
+// LockedFor implements Store.
+func (s *MemoryStore) LockedFor(_ context.Context, key string, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.failures[key]
+	if !ok || !now.Before(state.lockedUntil) {
+		return 0, nil
+	}
+
+	return state.lockedUntil.Sub(now), nil
+}
+
+// Fail implements Store.
+func (s *MemoryStore) Fail(_ context.Context, key string, lockout Lockout, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+
+	state := s.failures[key]
+	if !now.Before(state.forgetAt) {
+		state = memoryFailures{}
+	}
+
+	state.count++
+	lockedFor := lockoutDuration(lockout, state.count)
+	if lockedFor > 0 {
+		state.lockedUntil = now.Add(lockedFor)
+	}
+	state.forgetAt = now.Add(lockout.Max)
+	s.failures[key] = state
+
+	return lockedFor, nil
+}
+
+// Reset implements Store.
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+// prune drops full buckets and forgotten failures so the maps do not grow with every client seen.
+// Callers hold s.mu.
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPruned) < memoryPruneInterval {
+		return
+	}
+	s.lastPruned = now
+
+	for key, state := range s.buckets {
+		if !now.Before(state.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, state := range s.failures {
+		if !now.Before(state.forgetAt) && !now.Before(state.lockedUntil) {
+			delete(s.failures, key)
+		}
+	}
+}
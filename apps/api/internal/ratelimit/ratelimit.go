@@ -0,0 +1,165 @@
+// Package ratelimit throttles abusive request patterns with token buckets and failure lockouts.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultJoinIPBurst        = 10
+	defaultJoinIPWindow       = time.Minute
+	defaultJoinRouteBurst     = 30
+	defaultJoinRouteWindow    = time.Minute
+	defaultLockoutFreeFailure = 5
+	defaultLockoutBase        = 30 * time.Second
+	defaultLockoutMax         = time.Hour
+)
+
+// Bucket is a token bucket holding up to Burst tokens and refilling them evenly over Window.
+type Bucket struct {
+	Burst  int
+	Window time.Duration
+}
+
+// Lockout locks a key after more than FreeFailures consecutive failures. The first lockout lasts
+// Base and each further failure doubles it, up to Max. Failures are forgotten Max after the last one.
+type Lockout struct {
+	FreeFailures int
+	Base         time.Duration
+	Max          time.Duration
+}
+
+// Store keeps limiter state by key. MemoryStore serves a single API instance; a shared store lets
+// several instances enforce the same limits. Durations returned as zero mean "not limited".
+type Store interface {
+	// Take removes one token from the bucket at key, or returns how long until one is available.
+	Take(ctx context.Context, key string, bucket Bucket, now time.Time) (time.Duration, error)
+	// LockedFor returns the remaining lockout of key.
+	LockedFor(ctx context.Context, key string, now time.Time) (time.Duration, error)
+	// Fail records a failure for key and returns the lockout it started, if any.
+	Fail(ctx context.Context, key string, lockout Lockout, now time.Time) (time.Duration, error)
+	// Reset forgets the failures recorded for key.
+	Reset(ctx context.Context, key string) error
+}
+
+// JoinPolicy configures the route join limiter. Zero fields fall back to the defaults.
+type JoinPolicy struct {
+	PerIP    Bucket
+	PerRoute Bucket
+	Lockout  Lockout
+}
+
+// JoinLimiter guards route joins, where route passwords are checked. Each client IP and each route
+// has its own token bucket, and wrong passwords lock the client IP out of that route for
+// exponentially growing periods.
+type JoinLimiter struct {
+	store  Store
+	policy JoinPolicy
+	now    func() time.Time
+}
+
+// NewJoinLimiter builds a join limiter on top of store.
+func NewJoinLimiter(store Store, policy JoinPolicy) *JoinLimiter {
+	if policy.PerIP.Burst <= 0 {
+		policy.PerIP.Burst = defaultJoinIPBurst
+	}
+	if policy.PerIP.Window <= 0 {
+		policy.PerIP.Window = defaultJoinIPWindow
+	}
+	if policy.PerRoute.Burst <= 0 {
+		policy.PerRoute.Burst = defaultJoinRouteBurst
+	}
+	if policy.PerRoute.Window <= 0 {
+		policy.PerRoute.Window = defaultJoinRouteWindow
+	}
+	if policy.Lockout.FreeFailures <= 0 {
+		policy.Lockout.FreeFailures = defaultLockoutFreeFailure
+	}
+	if policy.Lockout.Base <= 0 {
+		policy.Lockout.Base = defaultLockoutBase
+	}
+	if policy.Lockout.Max <= 0 {
+		policy.Lockout.Max = defaultLockoutMax
+	}
+	if policy.Lockout.Max < policy.Lockout.Base {
+		policy.Lockout.Max = policy.Lockout.Base
+	}
+
+	return &JoinLimiter{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// Allow reports how long the client must wait before attempting to join the route; zero allows the
+// attempt. A locked-out client is refused without using tokens.
+func (l *JoinLimiter) Allow(ctx context.Context, clientIP, routeCode string) (time.Duration, error) {
+	now := l.now()
+
+	lockedFor, err := l.store.LockedFor(ctx, lockoutKey(clientIP, routeCode), now)
+	if err != nil {
+		return 0, fmt.Errorf("check join lockout: %w", err)
+	}
+	if lockedFor > 0 {
+		return lockedFor, nil
+	}
+
+	retryAfter, err := l.store.Take(ctx, "join:ip:"+clientIP, l.policy.PerIP, now)
+	if err != nil {
+		return 0, fmt.Errorf("take join ip token: %w", err)
+	}
+	if retryAfter > 0 {
+		return retryAfter, nil
+	}
+
+	retryAfter, err = l.store.Take(ctx, "join:route:"+routeCode, l.policy.PerRoute, now)
+	if err != nil {
+		return 0, fmt.Errorf("take join route token: %w", err)
+	}
+
+	return retryAfter, nil
+}
+
+// PasswordFailed records a wrong route password and returns the lockout it started, if any.
+func (l *JoinLimiter) PasswordFailed(ctx context.Context, clientIP, routeCode string) (time.Duration, error) {
+	lockedFor, err := l.store.Fail(ctx, lockoutKey(clientIP, routeCode), l.policy.Lockout, l.now())
+	if err != nil {
+		return 0, fmt.Errorf("record join failure: %w", err)
+	}
+
+	return lockedFor, nil
+}
+
+// Joined clears the client's failed password attempts for the route.
+func (l *JoinLimiter) Joined(ctx context.Context, clientIP, routeCode string) error {
+	if err := l.store.Reset(ctx, lockoutKey(clientIP, routeCode)); err != nil {
+		return fmt.Errorf("reset join failures: %w", err)
+	}
+
+	return nil
+}
+
+func lockoutKey(clientIP, routeCode string) string {
+	return "join:lockout:" + routeCode + ":" + clientIP
+}
+
+// lockoutDuration returns the lockout after the given number of consecutive failures.
+func lockoutDuration(lockout Lockout, failures int) time.Duration {
+	excess := failures - lockout.FreeFailures
+	if excess <= 0 {
+		return 0
+	}
+
+	duration := lockout.Base
+	for range excess - 1 {
+		duration *= 2
+		if duration >= lockout.Max {
+			return lockout.Max
+		}
+	}
+
+	return min(duration, lockout.Max)
+}
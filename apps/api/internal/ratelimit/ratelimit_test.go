@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTakeRefillsBucket(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore()
+	bucket := Bucket{Burst: 2, Window: 10 * time.Second}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	for attempt := range 2 {
+		if retryAfter, err := store.Take(ctx, "key", bucket, now); err != nil || retryAfter != 0 {
+			t.Fatalf("Take() attempt %d = %s, %v; want allowed", attempt, retryAfter, err)
+		}
+	}
+
+	retryAfter, err := store.Take(ctx, "key", bucket, now.Add(time.Second))
+	if err != nil || retryAfter != 4*time.Second {
+		t.Fatalf("Take() on empty bucket = %s, %v; want 4s", retryAfter, err)
+	}
+
+	if retryAfter, err := store.Take(ctx, "other", bucket, now.Add(time.Second)); err != nil || retryAfter != 0 {
+		t.Fatalf("Take() other key = %s, %v; want allowed", retryAfter, err)
+	}
+
+	if retryAfter, err := store.Take(ctx, "key", bucket, now.Add(5*time.Second)); err != nil || retryAfter != 0 {
+		t.Fatalf("Take() after refill = %s, %v; want allowed", retryAfter, err)
+	}
+}
+
+func TestJoinLimiterLocksOutRepeatedPasswordFailures(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewJoinLimiter(NewMemoryStore(), JoinPolicy{
+		PerIP:    Bucket{Burst: 100, Window: time.Minute},
+		PerRoute: Bucket{Burst: 100, Window: time.Minute},
+		Lockout:  Lockout{FreeFailures: 2, Base: 10 * time.Second, Max: 30 * time.Second},
+	})
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	var lockouts []time.Duration
+	for range 5 {
+		lockedFor, err := limiter.PasswordFailed(ctx, "203.0.113.7", "K7P9QD")
+		if err != nil {
+			t.Fatalf("PasswordFailed() error = %v", err)
+		}
+		lockouts = append(lockouts, lockedFor)
+	}
+
+	want := []time.Duration{0, 0, 10 * time.Second, 20 * time.Second, 30 * time.Second}
+	for index := range want {
+		if lockouts[index] != want[index] {
+			t.Fatalf("PasswordFailed() lockouts = %v, want %v", lockouts, want)
+		}
+	}
+
+	if retryAfter, err := limiter.Allow(ctx, "203.0.113.7", "K7P9QD"); err != nil || retryAfter != 30*time.Second {
+		t.Fatalf("Allow() while locked = %s, %v; want 30s", retryAfter, err)
+	}
+
+	if retryAfter, err := limiter.Allow(ctx, "198.51.100.4", "K7P9QD"); err != nil || retryAfter != 0 {
+		t.Fatalf("Allow() other client = %s, %v; want allowed", retryAfter, err)
+	}
+
+	if err := limiter.Joined(ctx, "203.0.113.7", "K7P9QD"); err != nil {
+		t.Fatalf("Joined() error = %v", err)
+	}
+	if retryAfter, err := limiter.Allow(ctx, "203.0.113.7", "K7P9QD"); err != nil || retryAfter != 0 {
+		t.Fatalf("Allow() after join = %s, %v; want allowed", retryAfter, err)
+	}
+}
+
+func TestJoinLimiterAppliesPerIPAndPerRouteBuckets(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewJoinLimiter(NewMemoryStore(), JoinPolicy{
+		PerIP:    Bucket{Burst: 2, Window: time.Minute},
+		PerRoute: Bucket{Burst: 3, Window: time.Minute},
+	})
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, clientIP := range []string{"203.0.113.7", "203.0.113.7"} {
+		if retryAfter, err := limiter.Allow(ctx, clientIP, "K7P9QD"); err != nil || retryAfter != 0 {
+			t.Fatalf("Allow(%s) = %s, %v; want allowed", clientIP, retryAfter, err)
+		}
+	}
+
+	if retryAfter, err := limiter.Allow(ctx, "203.0.113.7", "ABCDEF"); err != nil || retryAfter != 30*time.Second {
+		t.Fatalf("Allow() over ip limit = %s, %v; want 30s", retryAfter, err)
+	}
+
+	if retryAfter, err := limiter.Allow(ctx, "198.51.100.4", "K7P9QD"); err != nil || retryAfter != 0 {
+		t.Fatalf("Allow() third route attempt = %s, %v; want allowed", retryAfter, err)
+	}
+
+	if retryAfter, err := limiter.Allow(ctx, "192.0.2.1", "K7P9QD"); err != nil || retryAfter != 20*time.Second {
+		t.Fatalf("Allow() over route limit = %s, %v; want 20s", retryAfter, err)
+	}
+}
+
+func TestConnectionLimiterFlagsRepeatedViolations(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewConnectionLimiter(ConnectionPolicy{MessagesPerSecond: 3, PositionsPerSecond: 1, MaxViolations: 2})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	if allowed, _ := limiter.Allow(1); !allowed {
+		t.Fatal("Allow(position) = false, want first position allowed")
+	}
+	if allowed, abusive := limiter.Allow(1); allowed || abusive {
+		t.Fatalf("Allow(second position) = %v, %v; want refused, not abusive", allowed, abusive)
+	}
+
+	// The refused position did not spend a message token, so two messages remain.
+	for range 2 {
+		if allowed, _ := limiter.Allow(0); !allowed {
+			t.Fatal("Allow(message) = false, want message allowed while message tokens remain")
+		}
+	}
+	if allowed, abusive := limiter.Allow(0); allowed || !abusive {
+		t.Fatalf("Allow(message over limit) = %v, %v; want refused and abusive", allowed, abusive)
+	}
+
+	now = now.Add(time.Second)
+	if allowed, _ := limiter.Allow(1); !allowed {
+		t.Fatal("Allow(position) after a second = false, want refilled tokens")
+	}
+}
+
+func TestConnectionLimiterChargesBatchesAgainstBatchBudget(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewConnectionLimiter(ConnectionPolicy{MessagesPerSecond: 10, PositionsPerSecond: 2, BatchPositionsPerMinute: 10, MaxViolations: 10})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	if allowed, _ := limiter.AllowBatch(11); allowed {
+		t.Fatal("AllowBatch(11) = true, want batch larger than the batch budget refused")
+	}
+	if allowed, _ := limiter.AllowBatch(8); !allowed {
+		t.Fatal("AllowBatch(8) = false, want batch larger than the live position rate allowed")
+	}
+	if allowed, _ := limiter.Allow(2); !allowed {
+		t.Fatal("Allow(2) = false, want live positions unaffected by the batch")
+	}
+	if allowed, _ := limiter.AllowBatch(3); allowed {
+		t.Fatal("AllowBatch(3) = true, want batch refused after the budget was spent")
+	}
+
+	// The budget refills one sample every six seconds.
+	now = now.Add(6 * time.Second)
+	if allowed, _ := limiter.AllowBatch(3); !allowed {
+		t.Fatal("AllowBatch(3) after refill = false, want allowed")
+	}
+}
@@ -0,0 +1,252 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const maxPositionBatchSamples = 1000
+
+// PositionBatchRejection reports one batch sample that failed validation.
+type PositionBatchRejection struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// PositionBatchSegment holds the backfilled points stored in one path segment.
+type PositionBatchSegment struct {
+	SegmentID string       `json:"segmentId"`
+	Points    []RoutePoint `json:"points"`
+}
+
+// PositionBatchResult contains the backfilled points plus per-sample outcomes for one batch upload.
+type PositionBatchResult struct {
+	RouteID         string                   `json:"-"`
+	MemberID        string                   `json:"memberId"`
+	Segments        []PositionBatchSegment   `json:"segments"`
+	Duplicates      int                      `json:"duplicates"`
+	Rejected        []PositionBatchRejection `json:"rejected,omitempty"`
+	RecoveredMember *Member                  `json:"member,omitempty"`
+}
+
+// Accepted returns the number of points stored across all segments.
+func (r PositionBatchResult) Accepted() int {
+	accepted := 0
+	for _, segment := range r.Segments {
+		accepted += len(segment.Points)
+	}
+
+	return accepted
+}
+
+// RecordPositionBatchRepoParams contains the validated samples persisted by one bulk insert.
+type RecordPositionBatchRepoParams struct {
+	RouteID  string
+	MemberID string
+	Samples  []PositionUpdateInput
+}
+
+// RecordPositionBatchRepoResult contains the stored points by segment and the samples that no
+// segment covers, as indexes into RecordPositionBatchRepoParams.Samples.
+type RecordPositionBatchRepoResult struct {
+	Segments        []PositionBatchSegment
+	Unplaced        []int
+	RecoveredMember *Member
+}
+
+// RecordPositionBatch validates client-buffered samples in client time order and stores the accepted
+// ones in the member's path segments in one insert. Every sample must carry its client timestamp, which
+// is also the deduplication key: repeats inside the batch and samples already stored are skipped.
+// Each sample is validated against the stored or accepted point just before it, so a backfilled
+// stretch is checked against what the member actually recorded around it. The member does not have
+// to be tracking anymore: a batch that arrives after the member went offline still lands in the
+// segment that was open when the samples were recorded.
+// Rejected samples are reported by their index in the request instead of failing the whole batch.
+func (s *Service) RecordPositionBatch(ctx context.Context, code, memberToken string, samples []PositionUpdateInput) (PositionBatchResult, error) {
+	if strings.TrimSpace(memberToken) == "" {
+		return PositionBatchResult{}, ErrUnauthorized
+	}
+	if len(samples) == 0 || len(samples) > maxPositionBatchSamples {
+		return PositionBatchResult{}, ErrInvalidInput
+	}
+
+	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
+	if err != nil {
+		return PositionBatchResult{}, err
+	}
+
+	if normalizeCode(code) != authorized.Route.Code {
+		return PositionBatchResult{}, ErrUnauthorized
+	}
+
+	if authorized.Route.Status != RouteStatusActive {
+		return PositionBatchResult{}, ErrRouteClosed
+	}
+
+	if authorized.Member.Status == MemberStatusLeft || authorized.Member.Status == MemberStatusRemoved {
+		return PositionBatchResult{}, ErrInvalidInput
+	}
+
+	order := positionBatchOrder(samples)
+	var stored []RoutePoint
+	if from, to, ok := positionBatchWindow(samples, order); ok && len(s.positionValidators) > 0 {
+		stored, err = s.repo.ListPositionPointsAround(ctx, authorized.Route.ID, authorized.Member.ID, from, to)
+		if err != nil {
+			return PositionBatchResult{}, fmt.Errorf("record position batch load stored points: %w", err)
+		}
+	}
+
+	result := PositionBatchResult{
+		RouteID:  authorized.Route.ID,
+		MemberID: authorized.Member.ID,
+		Segments: []PositionBatchSegment{},
+	}
+	receivedAt := s.now().UTC()
+	seen := make(map[int64]struct{}, len(samples))
+	accepted := make([]PositionUpdateInput, 0, len(samples))
+	acceptedIndexes := make([]int, 0, len(samples))
+	var storedPrevious, acceptedPrevious *RoutePoint
+	for _, index := range order {
+		normalized, err := normalizePositionUpdateInput(samples[index])
+		if err == nil && normalized.ClientRecordedAt == nil {
+			err = ErrInvalidInput
+		}
+		if err == nil {
+			clientRecordedAt := normalized.ClientRecordedAt.UnixNano()
+			if _, duplicate := seen[clientRecordedAt]; duplicate {
+				result.Duplicates++
+				continue
+			}
+			seen[clientRecordedAt] = struct{}{}
+
+			for len(stored) > 0 && !pointTime(stored[0]).After(*normalized.ClientRecordedAt) {
+				storedPrevious = &stored[0]
+				stored = stored[1:]
+			}
+			previous := storedPrevious
+			if acceptedPrevious != nil && (previous == nil || !pointTime(*acceptedPrevious).Before(pointTime(*previous))) {
+				previous = acceptedPrevious
+			}
+
+			err = validatePosition(s.positionValidators, PositionCandidate{
+				Member:     authorized.Member,
+				Input:      normalized,
+				Previous:   previous,
+				ReceivedAt: receivedAt,
+			})
+		}
+		var rejected *PositionRejectedError
+		if errors.As(err, &rejected) && rejected.Reason == PositionRejectionDuplicateTimestamp {
+			result.Duplicates++
+			continue
+		}
+		if err != nil {
+			reason := positionRejectionReason(err)
+			s.metrics.PositionRejected(reason)
+			result.Rejected = append(result.Rejected, PositionBatchRejection{Index: index, Reason: reason})
+			continue
+		}
+
+		accepted = append(accepted, normalized)
+		acceptedIndexes = append(acceptedIndexes, index)
+		acceptedPrevious = &RoutePoint{
+			Latitude:         normalized.Latitude,
+			Longitude:        normalized.Longitude,
+			ClientRecordedAt: normalized.ClientRecordedAt,
+			RecordedAt:       receivedAt,
+		}
+	}
+	if len(accepted) == 0 {
+		return result, nil
+	}
+
+	repoResult, err := s.repo.RecordPositionBatch(ctx, RecordPositionBatchRepoParams{
+		RouteID:  authorized.Route.ID,
+		MemberID: authorized.Member.ID,
+		Samples:  accepted,
+	})
+	if err != nil {
+		return PositionBatchResult{}, fmt.Errorf("record position batch: %w", err)
+	}
+	for _, unplaced := range repoResult.Unplaced {
+		s.metrics.PositionRejected(PositionRejectionOutsideSegment)
+		result.Rejected = append(result.Rejected, PositionBatchRejection{Index: acceptedIndexes[unplaced], Reason: PositionRejectionOutsideSegment})
+	}
+
+	result.Segments = repoResult.Segments
+	result.RecoveredMember = repoResult.RecoveredMember
+	for range result.Accepted() {
+		s.metrics.PositionAccepted()
+	}
+	result.Duplicates += len(accepted) - len(repoResult.Unplaced) - result.Accepted()
+
+	return result, nil
+}
+
+// placeBatchSamples returns, for each sample, the index of the segment that was open at its client
+// timestamp, or -1 when none was. Segments must be ordered by start. A segment closed by a disconnect
+// also takes samples recorded after it ended, up to the start of the member's next segment, because
+// that is the stretch the client buffered while it could not reach the server.
+func placeBatchSamples(segments []PathSegment, samples []PositionUpdateInput) []int {
+	placement := make([]int, len(samples))
+	for i, sample := range samples {
+		placement[i] = -1
+		at := *sample.ClientRecordedAt
+		for index := len(segments) - 1; index >= 0; index-- {
+			segment := segments[index]
+			if segment.StartedAt == nil || segment.StartedAt.After(at) {
+				continue
+			}
+			if segment.EndedAt == nil || !at.After(*segment.EndedAt) || segment.EndReason == PathSegmentEndReasonDisconnected {
+				placement[i] = index
+			}
+			break
+		}
+	}
+
+	return placement
+}
+
+// positionBatchWindow returns the earliest and latest client timestamps among ordered samples.
+func positionBatchWindow(samples []PositionUpdateInput, order []int) (time.Time, time.Time, bool) {
+	var from *time.Time
+	for _, index := range order {
+		if samples[index].ClientRecordedAt != nil {
+			from = samples[index].ClientRecordedAt
+			break
+		}
+	}
+	if from == nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return *from, *samples[order[len(order)-1]].ClientRecordedAt, true
+}
+
+// positionBatchOrder returns sample indexes sorted by client timestamp; samples without one sort
+// first and are rejected during validation.
+func positionBatchOrder(samples []PositionUpdateInput) []int {
+	order := make([]int, len(samples))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		left, right := samples[a].ClientRecordedAt, samples[b].ClientRecordedAt
+		switch {
+		case left == nil && right == nil:
+			return 0
+		case left == nil:
+			return -1
+		case right == nil:
+			return 1
+		default:
+			return left.Compare(*right)
+		}
+	})
+
+	return order
+}
@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultChatMessageMaxLength = 500
+	defaultChatPageLimit        = 50
+	maxChatPageLimit            = 200
+)
+
+// ChatMessageQuery pages backwards through route chat from an optional message cursor.
+type ChatMessageQuery struct {
+	Before string
+	Limit  int
+}
+
+// ChatMessagePage contains chat messages in posting order plus the cursor for older messages.
+type ChatMessagePage struct {
+	Messages   []ChatMessage `json:"messages"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// PostChatMessage validates, rate-limits, and persists one chat message for an authenticated member.
+func (s *Service) PostChatMessage(ctx context.Context, memberToken, body string) (ChatMessage, error) {
+	if strings.TrimSpace(memberToken) == "" {
+		return ChatMessage{}, ErrUnauthorized
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" || !utf8.ValidString(body) {
+		return ChatMessage{}, ErrInvalidInput
+	}
+	if utf8.RuneCountInString(body) > s.chatMessageMaxLength {
+		return ChatMessage{}, ErrChatMessageTooLong
+	}
+
+	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	if authorized.Member.Status == MemberStatusLeft {
+		return ChatMessage{}, ErrUnauthorized
+	}
+
+	if authorized.Route.Status != RouteStatusActive {
+		return ChatMessage{}, ErrRouteClosed
+	}
+
+	params := CreateChatMessageRepoParams{
+		RouteID:  authorized.Route.ID,
+		MemberID: authorized.Member.ID,
+		Body:     body,
+	}
+	// The repository checks the limit in the insert transaction, so concurrent posts cannot both
+	// pass a count taken before either is stored.
+	if s.chatRateLimitMessages > 0 && s.chatRateLimitWindow > 0 {
+		params.RateLimitMessages = s.chatRateLimitMessages
+		params.RateLimitSince = s.now().UTC().Add(-s.chatRateLimitWindow)
+	}
+
+	message, err := s.repo.CreateChatMessage(ctx, params)
+	if errors.Is(err, ErrChatRateLimited) {
+		return ChatMessage{}, err
+	}
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("create chat message: %w", err)
+	}
+
+	return message, nil
+}
+
+// ListChatMessages returns one page of route chat, newest page first, for an authenticated member.
+func (s *Service) ListChatMessages(ctx context.Context, code, memberToken string, query ChatMessageQuery) (ChatMessagePage, error) {
+	if strings.TrimSpace(memberToken) == "" {
+		return ChatMessagePage{}, ErrUnauthorized
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultChatPageLimit
+	}
+	if limit < 0 || limit > maxChatPageLimit {
+		return ChatMessagePage{}, ErrInvalidInput
+	}
+
+	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
+	if err != nil {
+		return ChatMessagePage{}, err
+	}
+	if authorized.Member.Status == MemberStatusLeft {
+		return ChatMessagePage{}, ErrUnauthorized
+	}
+
+	if normalizeCode(code) != authorized.Route.Code {
+		return ChatMessagePage{}, ErrUnauthorized
+	}
+
+	messages, err := s.repo.ListChatMessages(ctx, ListChatMessagesRepoParams{
+		RouteID:  authorized.Route.ID,
+		BeforeID: strings.TrimSpace(query.Before),
+		Limit:    limit + 1,
+	})
+	if err != nil {
+		return ChatMessagePage{}, fmt.Errorf("list chat messages: %w", err)
+	}
+
+	page := ChatMessagePage{}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		page.NextCursor = messages[limit-1].ID
+	}
+
+	// The repository pages newest-first; clients render each page oldest-first.
+	slices.Reverse(messages)
+	page.Messages = messages
+	if page.Messages == nil {
+		page.Messages = []ChatMessage{}
+	}
+
+	return page, nil
+}
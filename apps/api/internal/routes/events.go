@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultEventCatchUpMaxEvents = 500
+	defaultEventCatchUpMaxAge    = 10 * time.Minute
+)
+
+// RouteEventsSince returns the logged route events after afterSeq in broadcast order, so a reconnecting
+// live client can catch up without reloading the snapshot. It returns ErrEventCatchUpUnavailable when
+// the gap holds more events than the configured limit, reaches further back than the configured age,
+// or starts at an event PruneRouteEvents already deleted.
+func (s *Service) RouteEventsSince(ctx context.Context, routeID string, afterSeq int64) ([]RouteEvent, error) {
+	if afterSeq <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	events, err := s.repo.ListRouteEventsAfter(ctx, ListRouteEventsAfterRepoParams{
+		RouteID: routeID,
+		AfterID: afterSeq,
+		Limit:   s.eventCatchUpMaxEvents + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list route events: %w", err)
+	}
+	if len(events) > s.eventCatchUpMaxEvents {
+		return nil, ErrEventCatchUpUnavailable
+	}
+	if len(events) > 0 && events[0].OccurredAt.Before(s.now().UTC().Add(-s.eventCatchUpMaxAge)) {
+		return nil, ErrEventCatchUpUnavailable
+	}
+
+	// Pruning deletes oldest first, so a cursor event that is still stored after the listing means
+	// none of the listed gap was pruned before it was read.
+	exists, err := s.repo.RouteEventExists(ctx, routeID, afterSeq)
+	if err != nil {
+		return nil, fmt.Errorf("check route event cursor: %w", err)
+	}
+	if !exists {
+		return nil, ErrEventCatchUpUnavailable
+	}
+
+	return events, nil
+}
+
+// PruneRouteEvents deletes route events older than the catch-up window; nothing reads them back once
+// they are past it. It returns the number of deleted events.
+func (s *Service) PruneRouteEvents(ctx context.Context) (int64, error) {
+	deleted, err := s.repo.DeleteRouteEventsBefore(ctx, s.now().UTC().Add(-s.eventCatchUpMaxAge))
+	if err != nil {
+		return 0, fmt.Errorf("prune route events: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,477 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ExportFormatGPX     = "gpx"
+	ExportFormatGeoJSON = "geojson"
+	ExportFormatKML     = "kml"
+)
+
+var exportContentTypes = map[string]string{
+	ExportFormatGPX:     "application/gpx+xml",
+	ExportFormatGeoJSON: "application/geo+json",
+	ExportFormatKML:     "application/vnd.google-earth.kml+xml",
+}
+
+// ParseExportFormat normalizes a requested track file format.
+func ParseExportFormat(format string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(format))
+	if normalized == "" {
+		normalized = ExportFormatGPX
+	}
+
+	if _, ok := exportContentTypes[normalized]; !ok {
+		return "", ErrInvalidInput
+	}
+
+	return normalized, nil
+}
+
+// ExportContentType returns the MIME type for a parsed export format.
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// WriteRouteExport encodes the route history as one track per member and one track segment per path segment.
+// Each member's points are loaded just before their track is written, so only one track is held in memory.
+func WriteRouteExport(ctx context.Context, w io.Writer, export RouteExport, format string) error {
+	switch format {
+	case ExportFormatGPX:
+		return writeGPX(ctx, w, export)
+	case ExportFormatGeoJSON:
+		return writeGeoJSON(ctx, w, export)
+	case ExportFormatKML:
+		return writeKML(ctx, w, export)
+	default:
+		return ErrInvalidInput
+	}
+}
+
+// memberPaths returns a member's path segments with their points.
+func (e RouteExport) memberPaths(ctx context.Context, member SnapshotMember) ([]PathSegment, error) {
+	if e.loadPaths == nil {
+		return member.Paths, nil
+	}
+
+	return e.loadPaths(ctx, member)
+}
+
+type gpxMetadata struct {
+	XMLName xml.Name `xml:"metadata"`
+	Name    string   `xml:"name"`
+	Desc    string   `xml:"desc,omitempty"`
+	Time    string   `xml:"time"`
+}
+
+type gpxTrack struct {
+	XMLName  xml.Name     `xml:"trk"`
+	Name     string       `xml:"name"`
+	Type     string       `xml:"type,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Latitude   float64        `xml:"lat,attr"`
+	Longitude  float64        `xml:"lon,attr"`
+	Elevation  *float64       `xml:"ele,omitempty"`
+	Time       string         `xml:"time"`
+	Extensions *gpxExtensions `xml:"extensions,omitempty"`
+}
+
+type gpxExtensions struct {
+	TrackPoint gpxTrackPointExtension `xml:"gpxtpx:TrackPointExtension"`
+}
+
+type gpxTrackPointExtension struct {
+	Speed  *float64 `xml:"gpxtpx:speed,omitempty"`
+	Course *float64 `xml:"gpxtpx:course,omitempty"`
+}
+
+func writeGPX(ctx context.Context, w io.Writer, export RouteExport) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("write gpx header: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	root := xml.StartElement{
+		Name: xml.Name{Local: "gpx"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "version"}, Value: "1.1"},
+			{Name: xml.Name{Local: "creator"}, Value: "KeepUp"},
+			{Name: xml.Name{Local: "xmlns"}, Value: "http://www.topografix.com/GPX/1/1"},
+			{Name: xml.Name{Local: "xmlns:gpxtpx"}, Value: "http://www.garmin.com/xmlschemas/TrackPointExtension/v2"},
+		},
+	}
+	if err := encoder.EncodeToken(root); err != nil {
+		return fmt.Errorf("write gpx root: %w", err)
+	}
+
+	metadata := gpxMetadata{
+		Name: export.Route.Name,
+		Desc: export.Route.Description,
+		Time: formatExportTime(export.Route.CreatedAt),
+	}
+	if err := encoder.Encode(metadata); err != nil {
+		return fmt.Errorf("write gpx metadata: %w", err)
+	}
+
+	for _, member := range export.Members {
+		paths, err := export.memberPaths(ctx, member)
+		if err != nil {
+			return fmt.Errorf("load gpx track: %w", err)
+		}
+
+		track := gpxTrack{
+			Name:     member.DisplayName,
+			Type:     member.TransportMode,
+			Segments: make([]gpxSegment, 0, len(paths)),
+		}
+		for _, path := range paths {
+			segment := gpxSegment{Points: make([]gpxPoint, 0, len(path.Points))}
+			for _, point := range path.Points {
+				trackPoint := gpxPoint{
+					Latitude:  point.Latitude,
+					Longitude: point.Longitude,
+					Elevation: point.AltitudeM,
+					Time:      formatExportTime(pointTime(point)),
+				}
+				if point.SpeedMPS != nil || point.HeadingDeg != nil {
+					trackPoint.Extensions = &gpxExtensions{
+						TrackPoint: gpxTrackPointExtension{
+							Speed:  point.SpeedMPS,
+							Course: point.HeadingDeg,
+						},
+					}
+				}
+				segment.Points = append(segment.Points, trackPoint)
+			}
+			track.Segments = append(track.Segments, segment)
+		}
+
+		if err := encoder.Encode(track); err != nil {
+			return fmt.Errorf("write gpx track: %w", err)
+		}
+	}
+
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return fmt.Errorf("write gpx root end: %w", err)
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return fmt.Errorf("flush gpx: %w", err)
+	}
+
+	return nil
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONGeometry   `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string        `json:"type"`
+	Coordinates [][][]float64 `json:"coordinates"`
+}
+
+type geoJSONProperties struct {
+	MemberID             string                     `json:"memberId"`
+	DisplayName          string                     `json:"displayName"`
+	TransportMode        string                     `json:"transportMode"`
+	Color                string                     `json:"color"`
+	Segments             []geoJSONSegmentProperties `json:"segments"`
+	CoordinateProperties geoJSONCoordinateProps     `json:"coordinateProperties"`
+}
+
+type geoJSONSegmentProperties struct {
+	ID        string     `json:"id"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+}
+
+type geoJSONCoordinateProps struct {
+	Times      [][]string   `json:"times"`
+	AccuracyM  [][]*float64 `json:"accuracyM"`
+	SpeedMPS   [][]*float64 `json:"speedMps"`
+	HeadingDeg [][]*float64 `json:"headingDeg"`
+}
+
+func writeGeoJSON(ctx context.Context, w io.Writer, export RouteExport) error {
+	header, err := json.Marshal(map[string]any{
+		"name":        export.Route.Name,
+		"description": export.Route.Description,
+		"code":        export.Route.Code,
+	})
+	if err != nil {
+		return fmt.Errorf("encode geojson route properties: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, `{"type":"FeatureCollection","properties":%s,"features":[`, header); err != nil {
+		return fmt.Errorf("write geojson header: %w", err)
+	}
+
+	for index, member := range export.Members {
+		paths, err := export.memberPaths(ctx, member)
+		if err != nil {
+			return fmt.Errorf("load geojson feature: %w", err)
+		}
+
+		feature := geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "MultiLineString",
+				Coordinates: make([][][]float64, 0, len(paths)),
+			},
+			Properties: geoJSONProperties{
+				MemberID:      member.ID,
+				DisplayName:   member.DisplayName,
+				TransportMode: member.TransportMode,
+				Color:         member.Color,
+				Segments:      make([]geoJSONSegmentProperties, 0, len(paths)),
+			},
+		}
+
+		for _, path := range paths {
+			line := make([][]float64, 0, len(path.Points))
+			times := make([]string, 0, len(path.Points))
+			accuracies := make([]*float64, 0, len(path.Points))
+			speeds := make([]*float64, 0, len(path.Points))
+			headings := make([]*float64, 0, len(path.Points))
+			for _, point := range path.Points {
+				position := []float64{point.Longitude, point.Latitude}
+				if point.AltitudeM != nil {
+					position = append(position, *point.AltitudeM)
+				}
+				line = append(line, position)
+				times = append(times, formatExportTime(pointTime(point)))
+				accuracies = append(accuracies, point.AccuracyM)
+				speeds = append(speeds, point.SpeedMPS)
+				headings = append(headings, point.HeadingDeg)
+			}
+
+			feature.Geometry.Coordinates = append(feature.Geometry.Coordinates, line)
+			feature.Properties.Segments = append(feature.Properties.Segments, geoJSONSegmentProperties{
+				ID:        path.ID,
+				StartedAt: path.StartedAt,
+				EndedAt:   path.EndedAt,
+			})
+			feature.Properties.CoordinateProperties.Times = append(feature.Properties.CoordinateProperties.Times, times)
+			feature.Properties.CoordinateProperties.AccuracyM = append(feature.Properties.CoordinateProperties.AccuracyM, accuracies)
+			feature.Properties.CoordinateProperties.SpeedMPS = append(feature.Properties.CoordinateProperties.SpeedMPS, speeds)
+			feature.Properties.CoordinateProperties.HeadingDeg = append(feature.Properties.CoordinateProperties.HeadingDeg, headings)
+		}
+
+		encoded, err := json.Marshal(feature)
+		if err != nil {
+			return fmt.Errorf("encode geojson feature: %w", err)
+		}
+
+		if index > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return fmt.Errorf("write geojson separator: %w", err)
+			}
+		}
+		if _, err := w.Write(encoded); err != nil {
+			return fmt.Errorf("write geojson feature: %w", err)
+		}
+	}
+
+	if _, err := io.WriteString(w, "]}\n"); err != nil {
+		return fmt.Errorf("write geojson footer: %w", err)
+	}
+
+	return nil
+}
+
+type kmlSchema struct {
+	XMLName xml.Name              `xml:"Schema"`
+	ID      string                `xml:"id,attr"`
+	Fields  []kmlSimpleArrayField `xml:"gx:SimpleArrayField"`
+}
+
+type kmlSimpleArrayField struct {
+	Name        string `xml:"name,attr"`
+	Type        string `xml:"type,attr"`
+	DisplayName string `xml:"displayName"`
+}
+
+type kmlPlacemark struct {
+	XMLName    xml.Name       `xml:"Placemark"`
+	Name       string         `xml:"name"`
+	MultiTrack kmlMultiTrack  `xml:"gx:MultiTrack"`
+	Extended   kmlPlacemarkEx `xml:"ExtendedData"`
+}
+
+type kmlPlacemarkEx struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlMultiTrack struct {
+	Tracks []kmlTrack `xml:"gx:Track"`
+}
+
+type kmlTrack struct {
+	When     []string        `xml:"when"`
+	Coords   []string        `xml:"gx:coord"`
+	Extended kmlTrackExtData `xml:"ExtendedData"`
+}
+
+type kmlTrackExtData struct {
+	SchemaData kmlSchemaData `xml:"SchemaData"`
+}
+
+type kmlSchemaData struct {
+	SchemaURL string         `xml:"schemaUrl,attr"`
+	Arrays    []kmlArrayData `xml:"gx:SimpleArrayData"`
+}
+
+type kmlArrayData struct {
+	Name   string   `xml:"name,attr"`
+	Values []string `xml:"gx:value"`
+}
+
+func writeKML(ctx context.Context, w io.Writer, export RouteExport) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("write kml header: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	root := xml.StartElement{
+		Name: xml.Name{Local: "kml"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: "http://www.opengis.net/kml/2.2"},
+			{Name: xml.Name{Local: "xmlns:gx"}, Value: "http://www.google.com/kml/ext/2.2"},
+		},
+	}
+	document := xml.StartElement{Name: xml.Name{Local: "Document"}}
+	if err := encoder.EncodeToken(root); err != nil {
+		return fmt.Errorf("write kml root: %w", err)
+	}
+	if err := encoder.EncodeToken(document); err != nil {
+		return fmt.Errorf("write kml document: %w", err)
+	}
+
+	if err := encoder.EncodeElement(export.Route.Name, xml.StartElement{Name: xml.Name{Local: "name"}}); err != nil {
+		return fmt.Errorf("write kml name: %w", err)
+	}
+
+	schema := kmlSchema{ID: "keepup_point"}
+	for _, field := range []string{"accuracy_m", "speed_mps", "heading_deg"} {
+		schema.Fields = append(schema.Fields, kmlSimpleArrayField{Name: field, Type: "float", DisplayName: field})
+	}
+	if err := encoder.Encode(schema); err != nil {
+		return fmt.Errorf("write kml schema: %w", err)
+	}
+
+	for _, member := range export.Members {
+		paths, err := export.memberPaths(ctx, member)
+		if err != nil {
+			return fmt.Errorf("load kml placemark: %w", err)
+		}
+
+		placemark := kmlPlacemark{
+			Name: member.DisplayName,
+			MultiTrack: kmlMultiTrack{
+				Tracks: make([]kmlTrack, 0, len(paths)),
+			},
+			Extended: kmlPlacemarkEx{
+				Data: []kmlData{
+					{Name: "memberId", Value: member.ID},
+					{Name: "transportMode", Value: member.TransportMode},
+					{Name: "color", Value: member.Color},
+				},
+			},
+		}
+
+		for _, path := range paths {
+			track := kmlTrack{
+				When:   make([]string, 0, len(path.Points)),
+				Coords: make([]string, 0, len(path.Points)),
+			}
+			accuracies := kmlArrayData{Name: "accuracy_m"}
+			speeds := kmlArrayData{Name: "speed_mps"}
+			headings := kmlArrayData{Name: "heading_deg"}
+			for _, point := range path.Points {
+				altitude := 0.0
+				if point.AltitudeM != nil {
+					altitude = *point.AltitudeM
+				}
+				track.When = append(track.When, formatExportTime(pointTime(point)))
+				track.Coords = append(track.Coords, fmt.Sprintf("%s %s %s", formatExportFloat(point.Longitude), formatExportFloat(point.Latitude), formatExportFloat(altitude)))
+				accuracies.Values = append(accuracies.Values, formatOptionalExportFloat(point.AccuracyM))
+				speeds.Values = append(speeds.Values, formatOptionalExportFloat(point.SpeedMPS))
+				headings.Values = append(headings.Values, formatOptionalExportFloat(point.HeadingDeg))
+			}
+			track.Extended.SchemaData = kmlSchemaData{
+				SchemaURL: "#keepup_point",
+				Arrays:    []kmlArrayData{accuracies, speeds, headings},
+			}
+			placemark.MultiTrack.Tracks = append(placemark.MultiTrack.Tracks, track)
+		}
+
+		if err := encoder.Encode(placemark); err != nil {
+			return fmt.Errorf("write kml placemark: %w", err)
+		}
+	}
+
+	if err := encoder.EncodeToken(document.End()); err != nil {
+		return fmt.Errorf("write kml document end: %w", err)
+	}
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return fmt.Errorf("write kml root end: %w", err)
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return fmt.Errorf("flush kml: %w", err)
+	}
+
+	return nil
+}
+
+func pointTime(point RoutePoint) time.Time {
+	if point.ClientRecordedAt != nil {
+		return *point.ClientRecordedAt
+	}
+
+	return point.RecordedAt
+}
+
+func formatExportTime(value time.Time) string {
+	return value.UTC().Format(time.RFC3339Nano)
+}
+
+func formatExportFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func formatOptionalExportFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+
+	return formatExportFloat(*value)
+}
@@ -0,0 +1,263 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRouteExport() RouteExport {
+	startedAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	altitude := 295.5
+	speed := 6.25
+	heading := 90.0
+
+	return RouteExport{
+		Route: Route{
+			ID:        "route-1",
+			Code:      "K7P9QD",
+			Name:      "Morning convoy",
+			CreatedAt: startedAt,
+		},
+		Members: []SnapshotMember{
+			{
+				ID:            "member-1",
+				DisplayName:   "Ana",
+				TransportMode: "bicycle",
+				Paths: []PathSegment{
+					{
+						ID:        "segment-1",
+						StartedAt: &startedAt,
+						Points: []RoutePoint{
+							{Seq: 1, Latitude: 46.0569, Longitude: 14.5058, AltitudeM: &altitude, SpeedMPS: &speed, HeadingDeg: &heading, RecordedAt: startedAt},
+							{Seq: 2, Latitude: 46.0571, Longitude: 14.5062, RecordedAt: startedAt.Add(5 * time.Second)},
+						},
+					},
+					{
+						ID:        "segment-2",
+						StartedAt: &startedAt,
+						Points: []RoutePoint{
+							{Seq: 1, Latitude: 46.0600, Longitude: 14.5100, RecordedAt: startedAt.Add(time.Minute)},
+						},
+					},
+				},
+			},
+			{
+				ID:            "member-2",
+				DisplayName:   "Matej",
+				TransportMode: "car",
+				Paths:         []PathSegment{},
+			},
+		},
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	t.Parallel()
+
+	if format, err := ParseExportFormat(" GeoJSON "); err != nil || format != ExportFormatGeoJSON {
+		t.Fatalf("ParseExportFormat() = %q, %v, want geojson", format, err)
+	}
+
+	if format, err := ParseExportFormat(""); err != nil || format != ExportFormatGPX {
+		t.Fatalf("ParseExportFormat() = %q, %v, want gpx default", format, err)
+	}
+
+	if _, err := ParseExportFormat("shp"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("ParseExportFormat() error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestWriteRouteExportGPX(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	if err := WriteRouteExport(context.Background(), &buffer, testRouteExport(), ExportFormatGPX); err != nil {
+		t.Fatalf("WriteRouteExport() error = %v", err)
+	}
+
+	var document struct {
+		Tracks []struct {
+			Name     string `xml:"name"`
+			Segments []struct {
+				Points []struct {
+					Latitude  float64  `xml:"lat,attr"`
+					Elevation *float64 `xml:"ele"`
+				} `xml:"trkpt"`
+			} `xml:"trkseg"`
+		} `xml:"trk"`
+	}
+	if err := xml.Unmarshal(buffer.Bytes(), &document); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+
+	if len(document.Tracks) != 2 {
+		t.Fatalf("gpx tracks = %d, want one per member", len(document.Tracks))
+	}
+
+	if len(document.Tracks[0].Segments) != 2 || len(document.Tracks[0].Segments[0].Points) != 2 {
+		t.Fatalf("gpx first track = %#v, want two segments with two points in the first", document.Tracks[0])
+	}
+
+	if elevation := document.Tracks[0].Segments[0].Points[0].Elevation; elevation == nil || *elevation != 295.5 {
+		t.Fatalf("gpx first point elevation = %v, want 295.5", elevation)
+	}
+
+	if !strings.Contains(buffer.String(), "<gpxtpx:speed>6.25</gpxtpx:speed>") {
+		t.Fatalf("gpx body = %s, want speed extension", buffer.String())
+	}
+}
+
+func TestWriteRouteExportGeoJSON(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	if err := WriteRouteExport(context.Background(), &buffer, testRouteExport(), ExportFormatGeoJSON); err != nil {
+		t.Fatalf("WriteRouteExport() error = %v", err)
+	}
+
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string        `json:"type"`
+				Coordinates [][][]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				MemberID             string `json:"memberId"`
+				CoordinateProperties struct {
+					SpeedMPS [][]*float64 `json:"speedMps"`
+				} `json:"coordinateProperties"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &collection); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatalf("geojson = %#v, want feature per member", collection)
+	}
+
+	first := collection.Features[0]
+	if first.Geometry.Type != "MultiLineString" || len(first.Geometry.Coordinates) != 2 {
+		t.Fatalf("geojson geometry = %#v, want one line per segment", first.Geometry)
+	}
+
+	if position := first.Geometry.Coordinates[0][0]; len(position) != 3 || position[0] != 14.5058 || position[2] != 295.5 {
+		t.Fatalf("geojson first position = %v, want lon/lat/alt", position)
+	}
+
+	if speed := first.Properties.CoordinateProperties.SpeedMPS[0][0]; speed == nil || *speed != 6.25 {
+		t.Fatalf("geojson first speed = %v, want 6.25", speed)
+	}
+}
+
+func TestWriteRouteExportKML(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	if err := WriteRouteExport(context.Background(), &buffer, testRouteExport(), ExportFormatKML); err != nil {
+		t.Fatalf("WriteRouteExport() error = %v", err)
+	}
+
+	body := buffer.String()
+	if count := strings.Count(body, "<Placemark>"); count != 2 {
+		t.Fatalf("kml placemarks = %d, want 2", count)
+	}
+
+	if count := strings.Count(body, "<gx:Track>"); count != 2 {
+		t.Fatalf("kml tracks = %d, want 2", count)
+	}
+
+	if !strings.Contains(body, "<gx:coord>14.5058 46.0569 295.5</gx:coord>") {
+		t.Fatalf("kml body = %s, want first coordinate", body)
+	}
+}
+
+func TestExportRouteLoadsPointsOneMemberAtATime(t *testing.T) {
+	t.Parallel()
+
+	startedAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	route := Route{ID: "route-1", Code: "K7P9QD", Name: "Morning convoy", Status: RouteStatusActive, CreatedAt: startedAt}
+	ana := Member{ID: "member-1", RouteID: "route-1", DisplayName: "Ana", Status: MemberStatusTracking, JoinedAt: startedAt}
+	matej := Member{ID: "member-2", RouteID: "route-1", DisplayName: "Matej", Status: MemberStatusSpectating, JoinedAt: startedAt}
+
+	// Ana's points span more than one page, so the export must follow the keyset cursor.
+	anaPoints := make([]SegmentPoint, 0, maxPointPageLimit+1)
+	for seq := int64(1); seq <= maxPointPageLimit+1; seq++ {
+		segmentID := "segment-1"
+		if seq > maxPointPageLimit/2 {
+			segmentID = "segment-2"
+		}
+		anaPoints = append(anaPoints, SegmentPoint{SegmentID: segmentID, MemberID: ana.ID, RoutePoint: RoutePoint{Seq: seq, Latitude: 46.05, Longitude: 14.5, RecordedAt: startedAt}})
+	}
+
+	var buffer bytes.Buffer
+	var pageParams []ListRoutePointsRepoParams
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{Route: route, Member: ana}, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{ana, matej}, nil
+		},
+		getPathSegmentHeadersFn: func(context.Context, string) (map[string][]PathSegment, error) {
+			return map[string][]PathSegment{
+				ana.ID:   {{ID: "segment-1", StartedAt: &startedAt}, {ID: "segment-2", StartedAt: &startedAt}},
+				matej.ID: {{ID: "segment-3", StartedAt: &startedAt}},
+			}, nil
+		},
+		listRoutePointsFn: func(_ context.Context, params ListRoutePointsRepoParams) ([]SegmentPoint, error) {
+			pageParams = append(pageParams, params)
+			if params.MemberID == matej.ID {
+				if !strings.Contains(buffer.String(), "<name>Ana</name>") {
+					t.Fatal("ListRoutePoints() for the second member ran before the first track was written")
+				}
+				return []SegmentPoint{{SegmentID: "segment-3", MemberID: matej.ID, RoutePoint: RoutePoint{Seq: 1, RecordedAt: startedAt}}}, nil
+			}
+
+			start := 0
+			if params.AfterSegmentID != "" {
+				for index, point := range anaPoints {
+					if point.SegmentID == params.AfterSegmentID && point.Seq == params.AfterSeq {
+						start = index + 1
+					}
+				}
+			}
+			return anaPoints[start:min(start+params.Limit, len(anaPoints))], nil
+		},
+	}, testRouteConfig())
+
+	export, err := service.ExportRoute(context.Background(), "K7P9QD", "member-token")
+	if err != nil {
+		t.Fatalf("ExportRoute() error = %v", err)
+	}
+	if len(pageParams) != 0 {
+		t.Fatalf("ExportRoute() read %d point pages before writing, want none", len(pageParams))
+	}
+
+	if err := WriteRouteExport(context.Background(), &buffer, export, ExportFormatGPX); err != nil {
+		t.Fatalf("WriteRouteExport() error = %v", err)
+	}
+
+	if len(pageParams) != 3 {
+		t.Fatalf("ListRoutePoints() calls = %d, want two pages for Ana and one for Matej", len(pageParams))
+	}
+	if second := pageParams[1]; second.MemberID != ana.ID || second.AfterSegmentID != "segment-2" || second.AfterSeq != maxPointPageLimit {
+		t.Fatalf("ListRoutePoints() second page params = %#v, want cursor after Ana's last point", second)
+	}
+
+	body := buffer.String()
+	if count := strings.Count(body, "<trkpt "); count != maxPointPageLimit+2 {
+		t.Fatalf("gpx points = %d, want %d", count, maxPointPageLimit+2)
+	}
+	if count := strings.Count(body, "<trkseg>"); count != 3 {
+		t.Fatalf("gpx segments = %d, want 3", count)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import "math"
+
+const earthRadiusMeters = 6371008.8
+
+func haversineMeters(fromLatitude, fromLongitude, toLatitude, toLongitude float64) float64 {
+	fromLat := degreesToRadians(fromLatitude)
+	toLat := degreesToRadians(toLatitude)
+	deltaLat := toLat - fromLat
+	deltaLon := degreesToRadians(toLongitude - fromLongitude)
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(fromLat)*math.Cos(toLat)*math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func degreesToRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
@@ -0,0 +1,212 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	snapSegmentBatchLimit = 50
+	snapRetryBaseDelay    = time.Minute
+	snapRetryMaxDelay     = time.Hour
+)
+
+// ErrNoMatch is returned by a Matcher when a trace cannot be snapped, for example because the
+// transport mode has no road network or the points are too far from any road.
+var ErrNoMatch = errors.New("no map match")
+
+// MatchInput is one path segment's raw points in sequence order.
+type MatchInput struct {
+	TransportMode string
+	Points        []RoutePoint
+}
+
+// MatchResult is the snapped geometry for one path segment. Each line is one continuously matched
+// stretch of the trace; parts the matcher could not snap are left out instead of bridged.
+// StartsAtFirstPoint reports whether the first line begins at the first input point.
+type MatchResult struct {
+	Matcher            string
+	Lines              [][][2]float64
+	Confidence         *float64
+	StartsAtFirstPoint bool
+}
+
+// Matcher snaps raw GPS traces onto a road or path network.
+type Matcher interface {
+	Match(context.Context, MatchInput) (MatchResult, error)
+}
+
+// MatcherFunc adapts a plain function to the Matcher interface.
+type MatcherFunc func(context.Context, MatchInput) (MatchResult, error)
+
+// Match calls f.
+func (f MatcherFunc) Match(ctx context.Context, input MatchInput) (MatchResult, error) {
+	return f(ctx, input)
+}
+
+// SnapCandidate is a path segment whose snapped geometry is missing or older than its raw points.
+// SnappedSeq is the last point seq the stored geometry covers, 0 when nothing was matched yet; for open
+// segments Snapped carries the stored geometry so only the new tail needs matching.
+type SnapCandidate struct {
+	SegmentID      string
+	RouteID        string
+	TransportMode  string
+	LastSeq        int64
+	Open           bool
+	SnappedSeq     int64
+	Snapped        *SnappedGeometry
+	FailedAttempts int
+}
+
+// SaveSnappedGeometryRepoParams contains one derived geometry; nil Lines records a failed match.
+type SaveSnappedGeometryRepoParams struct {
+	SegmentID  string
+	RouteID    string
+	Matcher    string
+	SourceSeq  int64
+	Lines      [][][2]float64
+	Confidence *float64
+}
+
+// SaveSnapFailureRepoParams records a failed match attempt and when the segment may be retried.
+type SaveSnapFailureRepoParams struct {
+	SegmentID      string
+	RouteID        string
+	FailedAttempts int
+	RetryAfter     time.Time
+}
+
+// SnapSegments refreshes derived snapped geometry for segments whose raw points changed since the
+// last match. Raw points are never modified. Segments the matcher cannot snap are recorded without
+// geometry, so they are retried only after new points arrive. Segments whose match fails with any
+// other error are retried with a growing delay, so they cannot hold the front of every batch.
+func (s *Service) SnapSegments(ctx context.Context, matcher Matcher) (int, error) {
+	candidates, err := s.repo.ListSnapCandidates(ctx, snapSegmentBatchLimit)
+	if err != nil {
+		return 0, fmt.Errorf("list snap candidates: %w", err)
+	}
+
+	snapped := 0
+	var errs []error
+	for _, candidate := range candidates {
+		if err := s.snapSegment(ctx, matcher, candidate); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		snapped++
+	}
+
+	return snapped, errors.Join(errs...)
+}
+
+// snapSegment matches one candidate. An open segment that was matched before only sends its new
+// points, starting from the last matched one, and the result is appended to the stored lines.
+func (s *Service) snapSegment(ctx context.Context, matcher Matcher, candidate SnapCandidate) error {
+	tail := candidate.Open && candidate.SnappedSeq > 0
+	fromSeq := int64(0)
+	if tail {
+		fromSeq = candidate.SnappedSeq
+	}
+	points, err := s.repo.GetSegmentPoints(ctx, candidate.SegmentID, fromSeq, candidate.LastSeq)
+	if err != nil {
+		return fmt.Errorf("load segment %s points: %w", candidate.SegmentID, err)
+	}
+
+	result, err := matcher.Match(ctx, MatchInput{TransportMode: candidate.TransportMode, Points: points})
+	if err == nil {
+		result.StartsAtFirstPoint = result.StartsAtFirstPoint && len(result.Lines) > 0 && len(result.Lines[0]) >= 2
+		result.Lines = drawableLines(result.Lines)
+	}
+	if errors.Is(err, ErrNoMatch) || (err == nil && len(result.Lines) == 0) {
+		result.Lines = nil
+		result.Confidence = nil
+	} else if err != nil {
+		if ctx.Err() == nil {
+			if failErr := s.saveSnapFailure(ctx, candidate); failErr != nil {
+				err = errors.Join(err, failErr)
+			}
+		}
+		return fmt.Errorf("match segment %s: %w", candidate.SegmentID, err)
+	}
+
+	if tail && candidate.Snapped != nil {
+		result.Lines = appendTailLines(candidate.Snapped.Lines, result.Lines, result.StartsAtFirstPoint)
+		if result.Confidence == nil || (candidate.Snapped.Confidence != nil && *candidate.Snapped.Confidence < *result.Confidence) {
+			result.Confidence = candidate.Snapped.Confidence
+		}
+	}
+
+	if err := s.repo.SaveSnappedGeometry(ctx, SaveSnappedGeometryRepoParams{
+		SegmentID:  candidate.SegmentID,
+		RouteID:    candidate.RouteID,
+		Matcher:    result.Matcher,
+		SourceSeq:  candidate.LastSeq,
+		Lines:      result.Lines,
+		Confidence: result.Confidence,
+	}); err != nil {
+		return fmt.Errorf("save segment %s snapped geometry: %w", candidate.SegmentID, err)
+	}
+
+	return nil
+}
+
+func (s *Service) saveSnapFailure(ctx context.Context, candidate SnapCandidate) error {
+	failedAttempts := candidate.FailedAttempts + 1
+	if err := s.repo.SaveSnapFailure(ctx, SaveSnapFailureRepoParams{
+		SegmentID:      candidate.SegmentID,
+		RouteID:        candidate.RouteID,
+		FailedAttempts: failedAttempts,
+		RetryAfter:     s.now().UTC().Add(snapRetryDelay(failedAttempts)),
+	}); err != nil {
+		return fmt.Errorf("save segment %s snap failure: %w", candidate.SegmentID, err)
+	}
+
+	return nil
+}
+
+// snapRetryDelay doubles from snapRetryBaseDelay with each consecutive failure, up to snapRetryMaxDelay.
+func snapRetryDelay(failedAttempts int) time.Duration {
+	delay := snapRetryBaseDelay
+	for range failedAttempts - 1 {
+		delay *= 2
+		if delay >= snapRetryMaxDelay {
+			return snapRetryMaxDelay
+		}
+	}
+
+	return delay
+}
+
+// appendTailLines extends stored snapped lines with the lines matched from a segment's new points.
+// The tail match starts at the last point the stored geometry covers, so a tail whose first line
+// starts at that point continues the stored last line instead of opening a new one.
+func appendTailLines(stored, tail [][][2]float64, continues bool) [][][2]float64 {
+	lines := make([][][2]float64, 0, len(stored)+len(tail))
+	lines = append(lines, stored...)
+	if continues && len(lines) > 0 && len(tail) > 0 {
+		last := slices.Clone(lines[len(lines)-1])
+		first := tail[0]
+		if len(last) > 0 && first[0] == last[len(last)-1] {
+			first = first[1:]
+		}
+		lines[len(lines)-1] = append(last, first...)
+		tail = tail[1:]
+	}
+
+	return append(lines, tail...)
+}
+
+// drawableLines drops lines too short to draw, which a MultiLineString cannot store.
+func drawableLines(lines [][][2]float64) [][][2]float64 {
+	drawable := make([][][2]float64, 0, len(lines))
+	for _, line := range lines {
+		if len(line) >= 2 {
+			drawable = append(drawable, line)
+		}
+	}
+
+	return drawable
+}
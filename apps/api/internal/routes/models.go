@@ -2,6 +2,7 @@
 package routes
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 )
@@ -18,14 +19,18 @@ const (
 	MemberStatusStale      = "stale"
 	MemberStatusOffline    = "offline"
 	MemberStatusLeft       = "left"
+	MemberStatusRemoved    = "removed"
 
-	RoleOwner  = "owner"
-	RoleMember = "member"
+	RoleOwner     = "owner"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
 
-	PathSegmentEndReasonStopped      = "stopped"
-	PathSegmentEndReasonDisconnected = "disconnected"
-	PathSegmentEndReasonLeft         = "left"
-	PathSegmentEndReasonRouteClosed  = "route_closed"
+	PathSegmentEndReasonStopped           = "stopped"
+	PathSegmentEndReasonDisconnected      = "disconnected"
+	PathSegmentEndReasonLeft              = "left"
+	PathSegmentEndReasonRouteClosed       = "route_closed"
+	PathSegmentEndReasonPermissionRevoked = "permission_revoked"
+	PathSegmentEndReasonRemoved           = "removed"
 )
 
 var validTransportModes = map[string]struct{}{
@@ -65,6 +70,8 @@ type Member struct {
 	DisplayName   string     `json:"displayName"`
 	TransportMode string     `json:"transportMode"`
 	IsOwner       bool       `json:"isOwner"`
+	IsModerator   bool       `json:"isModerator"`
+	CanTrack      bool       `json:"canTrack"`
 	Status        string     `json:"status"`
 	Color         string     `json:"color"`
 	JoinedAt      time.Time  `json:"joinedAt"`
@@ -103,6 +110,12 @@ type UpdateRouteInput struct {
 	Status      string
 }
 
+// UpdateRouteResult contains the updated route and the owner who changed it.
+type UpdateRouteResult struct {
+	Route Route
+	Actor Member
+}
+
 // AccessRouteResult contains non-sensitive route metadata for a join screen.
 type AccessRouteResult struct {
 	Code             string `json:"code"`
@@ -165,32 +178,72 @@ type PositionUpdateResult struct {
 	RecoveredMember *Member    `json:"member,omitempty"`
 }
 
+// RouteEvent is one broadcast live event kept in the durable route event log.
+type RouteEvent struct {
+	ID            int64           `json:"id"`
+	RouteID       string          `json:"routeId"`
+	ActorMemberID string          `json:"actorMemberId,omitempty"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurredAt"`
+}
+
 // Snapshot contains the full route page bootstrap payload.
 type Snapshot struct {
-	Route   Route              `json:"route"`
-	Members []SnapshotMember   `json:"members"`
-	Viewer  ViewerCapabilities `json:"viewer"`
+	Route        Route              `json:"route"`
+	Members      []SnapshotMember   `json:"members"`
+	Messages     []ChatMessage      `json:"messages,omitempty"`
+	Viewer       ViewerCapabilities `json:"viewer"`
+	LastEventSeq int64              `json:"lastEventSeq,omitempty"`
+	Detail       string             `json:"detail"`
+	ToleranceM   float64            `json:"toleranceM,omitempty"`
+}
+
+// RouteExport contains the route history used to encode track files. Members returned by
+// ExportRoute carry path segment headers only; their points are read one member at a time while
+// the file is written.
+type RouteExport struct {
+	Route   Route
+	Members []SnapshotMember
+
+	loadPaths func(context.Context, SnapshotMember) ([]PathSegment, error)
 }
 
 // SnapshotMember contains a member and their persisted path history.
 type SnapshotMember struct {
-	ID            string        `json:"id"`
-	DisplayName   string        `json:"displayName"`
-	TransportMode string        `json:"transportMode"`
-	Role          string        `json:"role"`
-	Status        string        `json:"status"`
-	Color         string        `json:"color"`
-	JoinedAt      time.Time     `json:"joinedAt"`
-	LeftAt        *time.Time    `json:"leftAt"`
-	Paths         []PathSegment `json:"paths"`
+	ID               string            `json:"id"`
+	DisplayName      string            `json:"displayName"`
+	TransportMode    string            `json:"transportMode"`
+	Role             string            `json:"role"`
+	CanTrack         bool              `json:"canTrack"`
+	Status           string            `json:"status"`
+	Color            string            `json:"color"`
+	JoinedAt         time.Time         `json:"joinedAt"`
+	LeftAt           *time.Time        `json:"leftAt"`
+	TransportChanges []TransportChange `json:"transportChanges,omitempty"`
+	Paths            []PathSegment     `json:"paths"`
 }
 
 // PathSegment is the historical path representation in snapshot responses.
 type PathSegment struct {
-	ID        string       `json:"id,omitempty"`
-	StartedAt *time.Time   `json:"startedAt,omitempty"`
-	EndedAt   *time.Time   `json:"endedAt,omitempty"`
-	Points    []RoutePoint `json:"points"`
+	ID             string             `json:"id,omitempty"`
+	StartedAt      *time.Time         `json:"startedAt,omitempty"`
+	EndedAt        *time.Time         `json:"endedAt,omitempty"`
+	EndReason      string             `json:"endReason,omitempty"`
+	Points         []RoutePoint       `json:"points"`
+	PointCount     int                `json:"pointCount,omitempty"`
+	LastSeq        int64              `json:"lastSeq,omitempty"`
+	TransportModes []TransportStretch `json:"transportModes,omitempty"`
+	Snapped        *SnappedGeometry   `json:"snapped,omitempty"`
+}
+
+// SnappedGeometry is the map-matched polyline derived from a segment's raw points.
+type SnappedGeometry struct {
+	Matcher    string         `json:"matcher"`
+	Lines      [][][2]float64 `json:"lines"`
+	Confidence *float64       `json:"confidence,omitempty"`
+	SourceSeq  int64          `json:"sourceSeq"`
+	MatchedAt  time.Time      `json:"matchedAt"`
 }
 
 // RoutePoint is a historical route point representation.
@@ -199,19 +252,38 @@ type RoutePoint struct {
 	Latitude         float64    `json:"latitude"`
 	Longitude        float64    `json:"longitude"`
 	AccuracyM        *float64   `json:"accuracyM,omitempty"`
+	AltitudeM        *float64   `json:"altitudeM,omitempty"`
+	SpeedMPS         *float64   `json:"speedMps,omitempty"`
+	HeadingDeg       *float64   `json:"headingDeg,omitempty"`
 	ClientRecordedAt *time.Time `json:"clientRecordedAt,omitempty"`
 	RecordedAt       time.Time  `json:"recordedAt"`
 }
 
-// ViewerCapabilities contains caller-specific permission booleans.
+// ChatMessage is one route chat message posted by a member.
+type ChatMessage struct {
+	ID       string    `json:"id"`
+	MemberID string    `json:"memberId"`
+	Body     string    `json:"body"`
+	PostedAt time.Time `json:"postedAt"`
+}
+
+// ViewerCapabilities contains caller-specific permission booleans. CanJoinTrackingWaitlist is true when
+// the viewer may track but every slot is taken; the waitlist fields describe the route's queue.
 type ViewerCapabilities struct {
-	MemberID        string `json:"memberId"`
-	Role            string `json:"role"`
-	Status          string `json:"status"`
-	CanStartSharing bool   `json:"canStartSharing"`
-	CanStopSharing  bool   `json:"canStopSharing"`
-	CanLeaveRoute   bool   `json:"canLeaveRoute"`
-	CanCloseRoute   bool   `json:"canCloseRoute"`
-	CanDeleteRoute  bool   `json:"canDeleteRoute"`
-	CanEditRoute    bool   `json:"canEditRoute"`
+	MemberID                 string                  `json:"memberId"`
+	Role                     string                  `json:"role"`
+	Status                   string                  `json:"status"`
+	CanStartSharing          bool                    `json:"canStartSharing"`
+	CanStopSharing           bool                    `json:"canStopSharing"`
+	CanLeaveRoute            bool                    `json:"canLeaveRoute"`
+	CanCloseRoute            bool                    `json:"canCloseRoute"`
+	CanDeleteRoute           bool                    `json:"canDeleteRoute"`
+	CanEditRoute             bool                    `json:"canEditRoute"`
+	CanGrantTracking         bool                    `json:"canGrantTracking"`
+	CanRemoveMembers         bool                    `json:"canRemoveMembers"`
+	CanAssignRoles           bool                    `json:"canAssignRoles"`
+	CanJoinTrackingWaitlist  bool                    `json:"canJoinTrackingWaitlist"`
+	TrackingWaitlistPosition int                     `json:"trackingWaitlistPosition,omitempty"`
+	TrackingReservedUntil    *time.Time              `json:"trackingReservedUntil,omitempty"`
+	TrackingWaitlist         []TrackingWaitlistEntry `json:"trackingWaitlist"`
 }
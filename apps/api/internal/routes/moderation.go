@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RemoveMemberResult contains the removed member and whether their client was banned.
+type RemoveMemberResult struct {
+	Member Member `json:"member"`
+	Banned bool   `json:"banned"`
+	Actor  Member `json:"-"`
+}
+
+// RemoveMemberRepoParams contains one owner removal.
+type RemoveMemberRepoParams struct {
+	RouteID   string
+	MemberID  string
+	RemovedBy string
+	Ban       bool
+}
+
+// RemoveMember lets the route owner or a moderator remove a member: the member moves to removed, their
+// tokens are revoked, and their open path segment is closed. With ban set, their client ID cannot
+// rejoin. Moderators can only remove regular members.
+func (s *Service) RemoveMember(ctx context.Context, code, token, memberID string, ban bool) (RemoveMemberResult, error) {
+	authorized, role, err := s.authorizeRouteAction(ctx, code, token, PermissionRemoveMembers)
+	if err != nil {
+		return RemoveMemberResult{}, err
+	}
+
+	if authorized.Route.Status != RouteStatusActive {
+		return RemoveMemberResult{}, ErrRouteClosed
+	}
+
+	target, err := s.routeMember(ctx, authorized.Route.ID, memberID)
+	if err != nil {
+		return RemoveMemberResult{}, err
+	}
+
+	if !outranks(role, target) || target.Status == MemberStatusLeft || target.Status == MemberStatusRemoved {
+		return RemoveMemberResult{}, ErrInvalidInput
+	}
+
+	member, err := s.repo.RemoveMember(ctx, RemoveMemberRepoParams{
+		RouteID:   authorized.Route.ID,
+		MemberID:  target.ID,
+		RemovedBy: authorized.Member.ID,
+		Ban:       ban,
+	})
+	if err != nil {
+		return RemoveMemberResult{}, fmt.Errorf("remove member: %w", err)
+	}
+
+	return RemoveMemberResult{Member: member, Banned: ban, Actor: authorized.Member}, nil
+}
+
+// routeMember finds one member of the route for management actions.
+func (s *Service) routeMember(ctx context.Context, routeID, memberID string) (Member, error) {
+	memberID = strings.TrimSpace(memberID)
+	if memberID == "" {
+		return Member{}, ErrInvalidInput
+	}
+
+	members, err := s.repo.GetMembersByRouteID(ctx, routeID)
+	if err != nil {
+		return Member{}, fmt.Errorf("load route members: %w", err)
+	}
+
+	for _, member := range members {
+		if member.ID == memberID {
+			return member, nil
+		}
+	}
+
+	return Member{}, ErrMemberNotFound
+}
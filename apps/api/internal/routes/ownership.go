@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TransferOwnershipResult contains both members after an ownership transfer. OwnerToken is the
+// recipient's new owner token; it is delivered to the recipient only, never to the previous owner.
+type TransferOwnershipResult struct {
+	PreviousOwner Member `json:"previousOwner"`
+	Owner         Member `json:"owner"`
+	OwnerToken    string `json:"-"`
+}
+
+// TransferOwnershipRepoParams contains one ownership transfer.
+type TransferOwnershipRepoParams struct {
+	RouteID        string
+	FromMemberID   string
+	ToMemberID     string
+	OwnerTokenHash string
+}
+
+// TransferOwnershipRepoResult contains both members after the transfer.
+type TransferOwnershipRepoResult struct {
+	PreviousOwner Member
+	Owner         Member
+}
+
+// TransferOwnership hands an active route to another member who has not left. Every existing owner
+// token is revoked and a new one is issued for the recipient, so the previous owner loses owner
+// access immediately and may then leave the route.
+func (s *Service) TransferOwnership(ctx context.Context, code, ownerToken, memberID string) (TransferOwnershipResult, error) {
+	authorized, _, err := s.authorizeRouteAction(ctx, code, ownerToken, PermissionTransferOwnership)
+	if err != nil {
+		return TransferOwnershipResult{}, err
+	}
+
+	if authorized.Route.Status != RouteStatusActive {
+		return TransferOwnershipResult{}, ErrRouteClosed
+	}
+
+	target, err := s.routeMember(ctx, authorized.Route.ID, memberID)
+	if err != nil {
+		return TransferOwnershipResult{}, err
+	}
+
+	if target.ID == authorized.Member.ID || target.Status == MemberStatusLeft || target.Status == MemberStatusRemoved {
+		return TransferOwnershipResult{}, ErrInvalidInput
+	}
+
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return TransferOwnershipResult{}, fmt.Errorf("transfer ownership token: %w", err)
+	}
+
+	result, err := s.repo.TransferOwnership(ctx, TransferOwnershipRepoParams{
+		RouteID:        authorized.Route.ID,
+		FromMemberID:   authorized.Member.ID,
+		ToMemberID:     target.ID,
+		OwnerTokenHash: hash,
+	})
+	if err != nil {
+		return TransferOwnershipResult{}, fmt.Errorf("transfer ownership: %w", err)
+	}
+
+	return TransferOwnershipResult{
+		PreviousOwner: result.PreviousOwner,
+		Owner:         result.Owner,
+		OwnerToken:    token,
+	}, nil
+}
+
+// IssueOwnerToken rotates the owner token for a member who owns the route, for example after
+// ownership was transferred while the recipient was not connected.
+func (s *Service) IssueOwnerToken(ctx context.Context, code, memberToken string) (string, error) {
+	if strings.TrimSpace(memberToken) == "" {
+		return "", ErrUnauthorized
+	}
+
+	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
+	if err != nil {
+		return "", err
+	}
+
+	if normalizeCode(code) != authorized.Route.Code || !authorized.Member.IsOwner {
+		return "", ErrUnauthorized
+	}
+
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return "", fmt.Errorf("issue owner token: %w", err)
+	}
+
+	if err := s.repo.RotateOwnerToken(ctx, authorized.Route.ID, authorized.Member.ID, hash); err != nil {
+		return "", fmt.Errorf("rotate owner token: %w", err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TrackingPermissionChange is one persisted owner grant or revocation of a member's tracking permission.
+type TrackingPermissionChange struct {
+	MemberID  string    `json:"memberId"`
+	Granted   bool      `json:"granted"`
+	ChangedBy string    `json:"changedBy"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// SetTrackingPermissionResult contains the updated member and the recorded change. Change is nil when
+// the member already had the requested permission. StoppedSharing reports that a revocation ended the
+// member's live sharing.
+type SetTrackingPermissionResult struct {
+	Member         Member                    `json:"member"`
+	Change         *TrackingPermissionChange `json:"change,omitempty"`
+	StoppedSharing bool                      `json:"stoppedSharing"`
+	Actor          Member                    `json:"-"`
+}
+
+// SetTrackingPermissionRepoParams contains one grant or revocation.
+type SetTrackingPermissionRepoParams struct {
+	RouteID   string
+	MemberID  string
+	ChangedBy string
+	Granted   bool
+}
+
+// SetTrackingPermissionRepoResult contains the persisted permission state.
+type SetTrackingPermissionRepoResult struct {
+	Member         Member
+	Change         *TrackingPermissionChange
+	StoppedSharing bool
+}
+
+// SetTrackingPermission lets the owner or a moderator of a view-only route grant or revoke tracking for
+// one member.
+// Revoking from a member who is currently tracking or stale also stops their sharing and closes the
+// open path segment with the permission_revoked end reason.
+func (s *Service) SetTrackingPermission(ctx context.Context, code, token, memberID string, granted bool) (SetTrackingPermissionResult, error) {
+	authorized, _, err := s.authorizeRouteAction(ctx, code, token, PermissionGrantTracking)
+	if err != nil {
+		return SetTrackingPermissionResult{}, err
+	}
+
+	if authorized.Route.Status != RouteStatusActive {
+		return SetTrackingPermissionResult{}, ErrRouteClosed
+	}
+
+	if authorized.Route.SharingPolicy != SharingPolicyJoinersViewOnly {
+		return SetTrackingPermissionResult{}, ErrInvalidInput
+	}
+
+	target, err := s.routeMember(ctx, authorized.Route.ID, memberID)
+	if err != nil {
+		return SetTrackingPermissionResult{}, err
+	}
+
+	if target.IsOwner || target.Status == MemberStatusLeft || target.Status == MemberStatusRemoved {
+		return SetTrackingPermissionResult{}, ErrInvalidInput
+	}
+
+	result, err := s.repo.SetMemberTrackingPermission(ctx, SetTrackingPermissionRepoParams{
+		RouteID:   authorized.Route.ID,
+		MemberID:  target.ID,
+		ChangedBy: authorized.Member.ID,
+		Granted:   granted,
+	})
+	if err != nil {
+		return SetTrackingPermissionResult{}, fmt.Errorf("set tracking permission: %w", err)
+	}
+
+	return SetTrackingPermissionResult{
+		Member:         result.Member,
+		Change:         result.Change,
+		StoppedSharing: result.StoppedSharing,
+		Actor:          authorized.Member,
+	}, nil
+}
+
+// mayTrack reports whether the member's role, the route sharing policy, or a tracking grant lets the
+// member track.
+func mayTrack(authorized AuthorizedMember) bool {
+	return authorized.Member.IsOwner ||
+		authorized.Member.IsModerator ||
+		authorized.Route.SharingPolicy == SharingPolicyEveryoneCanShare ||
+		authorized.Member.CanTrack
+}
@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultPointPageLimit = 500
+	maxPointPageLimit     = 5000
+)
+
+// PointPageQuery pages through persisted points in (segment, seq) order. Segment and AfterSeq
+// form the cursor: the page starts after that position, or at the start of Segment when
+// AfterSeq is zero. Member optionally restricts the page to one member's segments.
+type PointPageQuery struct {
+	Member   string
+	Segment  string
+	AfterSeq int64
+	Limit    int
+}
+
+// SegmentPoint is one persisted point tagged with the segment and member it belongs to.
+type SegmentPoint struct {
+	SegmentID string `json:"segmentId"`
+	MemberID  string `json:"memberId"`
+	RoutePoint
+}
+
+// PointPage contains one page of points plus the cursor for the next page.
+type PointPage struct {
+	Points       []SegmentPoint `json:"points"`
+	NextSegment  string         `json:"nextSegment,omitempty"`
+	NextAfterSeq int64          `json:"nextAfterSeq,omitempty"`
+}
+
+// ListRoutePointsRepoParams contains one keyset page request over position_points.
+type ListRoutePointsRepoParams struct {
+	RouteID        string
+	MemberID       string
+	AfterSegmentID string
+	AfterSeq       int64
+	Limit          int
+}
+
+// ListRoutePoints returns one page of route history for progressive loading after a header snapshot.
+func (s *Service) ListRoutePoints(ctx context.Context, code, memberToken string, query PointPageQuery) (PointPage, error) {
+	if strings.TrimSpace(memberToken) == "" {
+		return PointPage{}, ErrUnauthorized
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultPointPageLimit
+	}
+	segment := strings.ToLower(strings.TrimSpace(query.Segment))
+	member := strings.ToLower(strings.TrimSpace(query.Member))
+	if limit < 0 || limit > maxPointPageLimit || query.AfterSeq < 0 || (query.AfterSeq > 0 && segment == "") {
+		return PointPage{}, ErrInvalidInput
+	}
+	if (segment != "" && !isCanonicalUUID(segment)) || (member != "" && !isCanonicalUUID(member)) {
+		return PointPage{}, ErrInvalidInput
+	}
+
+	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
+	if err != nil {
+		return PointPage{}, err
+	}
+	if authorized.Member.Status == MemberStatusLeft {
+		return PointPage{}, ErrUnauthorized
+	}
+
+	if normalizeCode(code) != authorized.Route.Code {
+		return PointPage{}, ErrUnauthorized
+	}
+
+	points, err := s.repo.ListRoutePoints(ctx, ListRoutePointsRepoParams{
+		RouteID:        authorized.Route.ID,
+		MemberID:       member,
+		AfterSegmentID: segment,
+		AfterSeq:       query.AfterSeq,
+		Limit:          limit + 1,
+	})
+	if err != nil {
+		return PointPage{}, fmt.Errorf("list route points: %w", err)
+	}
+
+	page := PointPage{Points: points}
+	if len(points) > limit {
+		page.Points = points[:limit]
+		last := page.Points[limit-1]
+		page.NextSegment = last.SegmentID
+		page.NextAfterSeq = last.Seq
+	}
+
+	return page, nil
+}
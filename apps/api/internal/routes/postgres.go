@@ -3,6 +3,7 @@ package routes
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -13,6 +14,12 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	// presenceSweepLockKey is the pg advisory lock key serializing presence sweeps across replicas.
+	presenceSweepLockKey       int64 = 0x6b7570_0001
+	presenceSweepUnlockTimeout       = 2 * time.Second
+)
+
 // PostgresRepository stores routes in PostgreSQL.
 type PostgresRepository struct {
 	db *pgxpool.Pool
@@ -72,7 +79,7 @@ func (r *PostgresRepository) CreateRoute(ctx context.Context, params CreateRoute
 			status,
 			color
 		) VALUES ($1, $2, $3, $4, TRUE, $5, $6)
-		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, status, color, joined_at, left_at
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
 	`, route.ID, params.Owner.ClientID, params.Owner.DisplayName, params.Owner.TransportMode, params.Owner.Status, params.Owner.Color).
 		Scan(
 			&owner.ID,
@@ -81,6 +88,8 @@ func (r *PostgresRepository) CreateRoute(ctx context.Context, params CreateRoute
 			&owner.DisplayName,
 			&owner.TransportMode,
 			&owner.IsOwner,
+			&owner.IsModerator,
+			&owner.CanTrack,
 			&owner.Status,
 			&owner.Color,
 			&owner.JoinedAt,
@@ -134,7 +143,7 @@ func (r *PostgresRepository) CreateMember(ctx context.Context, params CreateMemb
 			status,
 			color
 		) VALUES ($1, $2, $3, $4, FALSE, $5, $6)
-		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, status, color, joined_at, left_at
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
 	`, params.RouteID, params.ClientID, params.DisplayName, params.TransportMode, params.Status, params.Color).
 		Scan(
 			&member.ID,
@@ -143,6 +152,8 @@ func (r *PostgresRepository) CreateMember(ctx context.Context, params CreateMemb
 			&member.DisplayName,
 			&member.TransportMode,
 			&member.IsOwner,
+			&member.IsModerator,
+			&member.CanTrack,
 			&member.Status,
 			&member.Color,
 			&member.JoinedAt,
@@ -219,6 +230,8 @@ func (r *PostgresRepository) GetAuthorizedMemberByTokenHash(ctx context.Context,
 			m.display_name,
 			m.transport_mode,
 			m.is_owner,
+			m.is_moderator,
+			m.can_track,
 			m.status,
 			m.color,
 			m.joined_at,
@@ -244,6 +257,8 @@ func (r *PostgresRepository) GetAuthorizedMemberByTokenHash(ctx context.Context,
 		&result.Member.DisplayName,
 		&result.Member.TransportMode,
 		&result.Member.IsOwner,
+		&result.Member.IsModerator,
+		&result.Member.CanTrack,
 		&result.Member.Status,
 		&result.Member.Color,
 		&result.Member.JoinedAt,
@@ -281,6 +296,8 @@ func (r *PostgresRepository) GetAuthorizedOwnerByTokenHash(ctx context.Context,
 			m.display_name,
 			m.transport_mode,
 			m.is_owner,
+			m.is_moderator,
+			m.can_track,
 			m.status,
 			m.color,
 			m.joined_at,
@@ -306,6 +323,8 @@ func (r *PostgresRepository) GetAuthorizedOwnerByTokenHash(ctx context.Context,
 		&result.Member.DisplayName,
 		&result.Member.TransportMode,
 		&result.Member.IsOwner,
+		&result.Member.IsModerator,
+		&result.Member.CanTrack,
 		&result.Member.Status,
 		&result.Member.Color,
 		&result.Member.JoinedAt,
@@ -325,7 +344,7 @@ func (r *PostgresRepository) GetAuthorizedOwnerByTokenHash(ctx context.Context,
 // GetMembersByRouteID loads the route member list.
 func (r *PostgresRepository) GetMembersByRouteID(ctx context.Context, routeID string) ([]Member, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, route_id, client_id, display_name, transport_mode, is_owner, status, color, joined_at, left_at
+		SELECT id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
 		FROM route_members
 		WHERE route_id = $1
 		ORDER BY joined_at ASC
@@ -345,6 +364,8 @@ func (r *PostgresRepository) GetMembersByRouteID(ctx context.Context, routeID st
 			&member.DisplayName,
 			&member.TransportMode,
 			&member.IsOwner,
+			&member.IsModerator,
+			&member.CanTrack,
 			&member.Status,
 			&member.Color,
 			&member.JoinedAt,
@@ -365,11 +386,55 @@ func (r *PostgresRepository) GetMembersByRouteID(ctx context.Context, routeID st
 
 // GetPathSegmentsByRouteID loads persisted route paths grouped by member.
 func (r *PostgresRepository) GetPathSegmentsByRouteID(ctx context.Context, routeID string) (map[string][]PathSegment, error) {
-	segmentRows, err := r.db.Query(ctx, `
-		SELECT id, member_id, started_at, ended_at
-		FROM path_segments
+	pathsByMemberID, err := r.GetPathSegmentHeadersByRouteID(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	pointRows, err := r.db.Query(ctx, `
+		SELECT segment_id, seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
+		FROM position_points
 		WHERE route_id = $1
-		ORDER BY started_at ASC, id ASC
+		ORDER BY segment_id ASC, seq ASC
+	`, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("query position points: %w", err)
+	}
+
+	pointsBySegmentID, err := collectSegmentPoints(pointRows)
+	if err != nil {
+		return nil, err
+	}
+	for _, paths := range pathsByMemberID {
+		for index := range paths {
+			if points, ok := pointsBySegmentID[paths[index].ID]; ok {
+				paths[index].Points = points
+			}
+		}
+	}
+
+	return pathsByMemberID, nil
+}
+
+// GetPathSegmentHeadersByRouteID loads route path segments grouped by member, with their snapped
+// geometry but without points.
+func (r *PostgresRepository) GetPathSegmentHeadersByRouteID(ctx context.Context, routeID string) (map[string][]PathSegment, error) {
+	segmentRows, err := r.db.Query(ctx, `
+		SELECT
+			s.id,
+			s.member_id,
+			s.started_at,
+			s.ended_at,
+			COALESCE(s.end_reason, ''),
+			g.matcher,
+			g.source_seq,
+			g.confidence,
+			g.matched_at,
+			ST_AsGeoJSON(g.geometry::geometry)
+		FROM path_segments s
+		LEFT JOIN path_segment_snapped_geometries g ON g.segment_id = s.id AND g.geometry IS NOT NULL
+		WHERE s.route_id = $1
+		ORDER BY s.started_at ASC, s.id ASC
 	`, routeID)
 	if err != nil {
 		return nil, fmt.Errorf("query path segments: %w", err)
@@ -377,82 +442,443 @@ func (r *PostgresRepository) GetPathSegmentsByRouteID(ctx context.Context, route
 	defer segmentRows.Close()
 
 	pathsByMemberID := map[string][]PathSegment{}
-	segmentMemberIDs := map[string]string{}
-	segmentIndexes := map[string]int{}
-
 	for segmentRows.Next() {
 		var memberID string
 		var segment PathSegment
+		var snapped snappedGeometryColumns
 		if err := segmentRows.Scan(
 			&segment.ID,
 			&memberID,
 			&segment.StartedAt,
 			&segment.EndedAt,
+			&segment.EndReason,
+			&snapped.matcher,
+			&snapped.sourceSeq,
+			&snapped.confidence,
+			&snapped.matchedAt,
+			&snapped.geoJSON,
 		); err != nil {
 			return nil, fmt.Errorf("scan path segment: %w", err)
 		}
+		segment.Snapped, err = snapped.geometry()
+		if err != nil {
+			return nil, fmt.Errorf("decode snapped geometry for segment %s: %w", segment.ID, err)
+		}
 
 		segment.Points = []RoutePoint{}
 		pathsByMemberID[memberID] = append(pathsByMemberID[memberID], segment)
-		segmentMemberIDs[segment.ID] = memberID
-		segmentIndexes[segment.ID] = len(pathsByMemberID[memberID]) - 1
 	}
 	if err := segmentRows.Err(); err != nil {
 		return nil, fmt.Errorf("iterate path segments: %w", err)
 	}
 
+	return pathsByMemberID, nil
+}
+
+// GetPointsBySegmentIDs loads the points of the given route segments, keyed by segment ID and in
+// sequence order.
+func (r *PostgresRepository) GetPointsBySegmentIDs(ctx context.Context, routeID string, segmentIDs []string) (map[string][]RoutePoint, error) {
+	if len(segmentIDs) == 0 {
+		return map[string][]RoutePoint{}, nil
+	}
+
 	pointRows, err := r.db.Query(ctx, `
-		SELECT segment_id, seq, latitude, longitude, accuracy_m, client_recorded_at, recorded_at
+		SELECT segment_id, seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
 		FROM position_points
-		WHERE route_id = $1
+		WHERE route_id = $1 AND segment_id = ANY($2::UUID[])
 		ORDER BY segment_id ASC, seq ASC
-	`, routeID)
+	`, routeID, segmentIDs)
 	if err != nil {
-		return nil, fmt.Errorf("query position points: %w", err)
+		return nil, fmt.Errorf("query segment points: %w", err)
 	}
+
+	return collectSegmentPoints(pointRows)
+}
+
+// collectSegmentPoints scans segment_id-prefixed point rows into points keyed by segment ID and
+// closes rows.
+func collectSegmentPoints(pointRows pgx.Rows) (map[string][]RoutePoint, error) {
 	defer pointRows.Close()
 
+	pointsBySegmentID := map[string][]RoutePoint{}
 	for pointRows.Next() {
 		var segmentID string
 		var point RoutePoint
-		var accuracy sql.NullFloat64
-		var clientRecordedAt sql.NullTime
+		var optional routePointOptionalColumns
 
 		if err := pointRows.Scan(
 			&segmentID,
 			&point.Seq,
 			&point.Latitude,
 			&point.Longitude,
-			&accuracy,
-			&clientRecordedAt,
+			&optional.accuracy,
+			&optional.altitude,
+			&optional.speed,
+			&optional.heading,
+			&optional.clientRecordedAt,
 			&point.RecordedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scan position point: %w", err)
 		}
 
-		memberID, ok := segmentMemberIDs[segmentID]
-		if !ok {
-			continue
+		optional.apply(&point)
+		pointsBySegmentID[segmentID] = append(pointsBySegmentID[segmentID], point)
+	}
+	if err := pointRows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate position points: %w", err)
+	}
+
+	return pointsBySegmentID, nil
+}
+
+// GetPathSegmentSummariesByRouteID returns path segments grouped by member, with point counts
+// instead of points.
+func (r *PostgresRepository) GetPathSegmentSummariesByRouteID(ctx context.Context, routeID string) (map[string][]PathSegment, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			s.id,
+			s.member_id,
+			s.started_at,
+			s.ended_at,
+			COALESCE(s.end_reason, ''),
+			summary.points,
+			COALESCE(summary.last_seq, 0)
+		FROM path_segments s
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS points, MAX(seq) AS last_seq
+			FROM position_points
+			WHERE segment_id = s.id
+		) summary
+		WHERE s.route_id = $1
+		ORDER BY s.started_at ASC, s.id ASC
+	`, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("query path segment summaries: %w", err)
+	}
+	defer rows.Close()
+
+	pathsByMemberID := make(map[string][]PathSegment)
+	for rows.Next() {
+		var memberID string
+		var segment PathSegment
+		if err := rows.Scan(
+			&segment.ID,
+			&memberID,
+			&segment.StartedAt,
+			&segment.EndedAt,
+			&segment.EndReason,
+			&segment.PointCount,
+			&segment.LastSeq,
+		); err != nil {
+			return nil, fmt.Errorf("scan path segment summary: %w", err)
+		}
+
+		segment.Points = []RoutePoint{}
+		pathsByMemberID[memberID] = append(pathsByMemberID[memberID], segment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate path segment summaries: %w", err)
+	}
+
+	return pathsByMemberID, nil
+}
+
+// ListRoutePoints returns one keyset page of route points ordered by the (segment_id, seq) unique index.
+// A cursor segment that does not belong to the route is ErrInvalidInput rather than an empty page.
+func (r *PostgresRepository) ListRoutePoints(ctx context.Context, params ListRoutePointsRepoParams) ([]SegmentPoint, error) {
+	if params.AfterSegmentID != "" {
+		var exists bool
+		err := r.db.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1
+				FROM path_segments
+				WHERE id = $1::UUID AND route_id = $2
+			)
+		`, params.AfterSegmentID, params.RouteID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("check point cursor segment: %w", err)
+		}
+		if !exists {
+			return nil, ErrInvalidInput
+		}
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT segment_id, member_id, seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
+		FROM position_points
+		WHERE route_id = $1
+			AND (NULLIF($2, '') IS NULL OR member_id = NULLIF($2, '')::UUID)
+			AND (NULLIF($3, '') IS NULL OR (segment_id, seq) > (NULLIF($3, '')::UUID, $4::BIGINT))
+		ORDER BY segment_id ASC, seq ASC
+		LIMIT $5
+	`, params.RouteID, params.MemberID, params.AfterSegmentID, params.AfterSeq, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("query route points: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSegmentPoints(rows)
+}
+
+// ListReplayPoints returns the next route points after a keyset position in replay order, served by
+// position_points_route_replay_idx.
+func (r *PostgresRepository) ListReplayPoints(ctx context.Context, params ListReplayPointsRepoParams) ([]SegmentPoint, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT segment_id, member_id, seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
+		FROM position_points
+		WHERE route_id = $1
+			AND (COALESCE(client_recorded_at, recorded_at), segment_id, seq) > ($2, $3::UUID, $4::BIGINT)
+		ORDER BY COALESCE(client_recorded_at, recorded_at) ASC, segment_id ASC, seq ASC
+		LIMIT $5
+	`, params.RouteID, params.AfterAt, params.AfterSegmentID, params.AfterSeq, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("query replay points: %w", err)
+	}
+
+	return scanSegmentPoints(rows)
+}
+
+func scanSegmentPoints(rows pgx.Rows) ([]SegmentPoint, error) {
+	defer rows.Close()
+
+	points := make([]SegmentPoint, 0)
+	for rows.Next() {
+		var point SegmentPoint
+		var optional routePointOptionalColumns
+		if err := rows.Scan(
+			&point.SegmentID,
+			&point.MemberID,
+			&point.Seq,
+			&point.Latitude,
+			&point.Longitude,
+			&optional.accuracy,
+			&optional.altitude,
+			&optional.speed,
+			&optional.heading,
+			&optional.clientRecordedAt,
+			&point.RecordedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan route point: %w", err)
+		}
+		optional.apply(&point.RoutePoint)
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate route points: %w", err)
+	}
+
+	return points, nil
+}
+
+type snappedGeometryColumns struct {
+	matcher    sql.NullString
+	sourceSeq  sql.NullInt64
+	confidence sql.NullFloat64
+	matchedAt  sql.NullTime
+	geoJSON    sql.NullString
+}
+
+func (c snappedGeometryColumns) geometry() (*SnappedGeometry, error) {
+	if !c.geoJSON.Valid {
+		return nil, nil
+	}
+
+	var multiLine struct {
+		Coordinates [][][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal([]byte(c.geoJSON.String), &multiLine); err != nil {
+		return nil, err
+	}
+
+	geometry := &SnappedGeometry{
+		Matcher:   c.matcher.String,
+		Lines:     multiLine.Coordinates,
+		SourceSeq: c.sourceSeq.Int64,
+		MatchedAt: c.matchedAt.Time,
+	}
+	if c.confidence.Valid {
+		confidence := c.confidence.Float64
+		geometry.Confidence = &confidence
+	}
+
+	return geometry, nil
+}
+
+// ListSnapCandidates returns segments with at least two points whose snapped geometry is missing or
+// stale, skipping segments still waiting out a failed match. Open segments carry their stored geometry.
+func (r *PostgresRepository) ListSnapCandidates(ctx context.Context, limit int) ([]SnapCandidate, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			s.id,
+			s.route_id,
+			m.transport_mode,
+			latest.seq,
+			s.ended_at IS NULL,
+			COALESCE(g.source_seq, 0),
+			COALESCE(g.failed_attempts, 0),
+			g.matcher,
+			g.source_seq,
+			g.confidence,
+			g.matched_at,
+			CASE WHEN s.ended_at IS NULL THEN ST_AsGeoJSON(g.geometry::geometry) END
+		FROM path_segments s
+		JOIN route_members m ON m.id = s.member_id
+		JOIN LATERAL (
+			SELECT MAX(seq) AS seq, COUNT(*) AS points
+			FROM position_points
+			WHERE segment_id = s.id
+		) latest ON latest.points >= 2
+		LEFT JOIN path_segment_snapped_geometries g ON g.segment_id = s.id
+		WHERE (g.segment_id IS NULL OR g.source_seq < latest.seq)
+			AND (g.retry_after IS NULL OR g.retry_after <= NOW())
+		ORDER BY s.started_at ASC, s.id ASC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query snap candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []SnapCandidate
+	for rows.Next() {
+		var candidate SnapCandidate
+		var snapped snappedGeometryColumns
+		if err := rows.Scan(
+			&candidate.SegmentID,
+			&candidate.RouteID,
+			&candidate.TransportMode,
+			&candidate.LastSeq,
+			&candidate.Open,
+			&candidate.SnappedSeq,
+			&candidate.FailedAttempts,
+			&snapped.matcher,
+			&snapped.sourceSeq,
+			&snapped.confidence,
+			&snapped.matchedAt,
+			&snapped.geoJSON,
+		); err != nil {
+			return nil, fmt.Errorf("scan snap candidate: %w", err)
+		}
+		candidate.Snapped, err = snapped.geometry()
+		if err != nil {
+			return nil, fmt.Errorf("decode snapped geometry for segment %s: %w", candidate.SegmentID, err)
 		}
-		segmentIndex := segmentIndexes[segmentID]
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate snap candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// GetSegmentPoints loads a segment's raw points from fromSeq through throughSeq, in sequence order.
+func (r *PostgresRepository) GetSegmentPoints(ctx context.Context, segmentID string, fromSeq, throughSeq int64) ([]RoutePoint, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
+		FROM position_points
+		WHERE segment_id = $1 AND seq >= $2 AND seq <= $3
+		ORDER BY seq ASC
+	`, segmentID, fromSeq, throughSeq)
+	if err != nil {
+		return nil, fmt.Errorf("query segment points: %w", err)
+	}
+	defer rows.Close()
 
-		if accuracy.Valid {
-			point.AccuracyM = &accuracy.Float64
+	var points []RoutePoint
+	for rows.Next() {
+		var point RoutePoint
+		var optional routePointOptionalColumns
+		if err := rows.Scan(
+			&point.Seq,
+			&point.Latitude,
+			&point.Longitude,
+			&optional.accuracy,
+			&optional.altitude,
+			&optional.speed,
+			&optional.heading,
+			&optional.clientRecordedAt,
+			&point.RecordedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan segment point: %w", err)
 		}
-		if clientRecordedAt.Valid {
-			point.ClientRecordedAt = &clientRecordedAt.Time
+		optional.apply(&point)
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate segment points: %w", err)
+	}
+
+	return points, nil
+}
+
+// SaveSnappedGeometry upserts a segment's derived geometry; raw position points are left untouched.
+func (r *PostgresRepository) SaveSnappedGeometry(ctx context.Context, params SaveSnappedGeometryRepoParams) error {
+	var geoJSON *string
+	if len(params.Lines) > 0 {
+		encoded, err := json.Marshal(map[string]any{
+			"type":        "MultiLineString",
+			"coordinates": params.Lines,
+		})
+		if err != nil {
+			return fmt.Errorf("encode snapped geometry: %w", err)
 		}
+		multiLine := string(encoded)
+		geoJSON = &multiLine
+	}
 
-		pathsByMemberID[memberID][segmentIndex].Points = append(
-			pathsByMemberID[memberID][segmentIndex].Points,
-			point,
+	if _, err := r.db.Exec(ctx, `
+		INSERT INTO path_segment_snapped_geometries (
+			segment_id,
+			route_id,
+			matcher,
+			source_seq,
+			geometry,
+			confidence,
+			matched_at
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			ST_SetSRID(ST_GeomFromGeoJSON($5::TEXT), 4326)::geography,
+			$6,
+			NOW()
 		)
+		ON CONFLICT (segment_id) DO UPDATE
+		SET matcher = EXCLUDED.matcher,
+			source_seq = EXCLUDED.source_seq,
+			geometry = EXCLUDED.geometry,
+			confidence = EXCLUDED.confidence,
+			matched_at = EXCLUDED.matched_at,
+			failed_attempts = 0,
+			retry_after = NULL
+	`, params.SegmentID, params.RouteID, params.Matcher, params.SourceSeq, geoJSON, params.Confidence); err != nil {
+		return fmt.Errorf("upsert snapped geometry: %w", err)
 	}
-	if err := pointRows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate position points: %w", err)
+
+	return nil
+}
+
+// SaveSnapFailure records a failed match attempt, keeping any geometry matched before.
+func (r *PostgresRepository) SaveSnapFailure(ctx context.Context, params SaveSnapFailureRepoParams) error {
+	if _, err := r.db.Exec(ctx, `
+		INSERT INTO path_segment_snapped_geometries (
+			segment_id,
+			route_id,
+			matcher,
+			source_seq,
+			failed_attempts,
+			retry_after
+		) VALUES ($1, $2, '', 0, $3, $4)
+		ON CONFLICT (segment_id) DO UPDATE
+		SET failed_attempts = EXCLUDED.failed_attempts,
+			retry_after = EXCLUDED.retry_after
+	`, params.SegmentID, params.RouteID, params.FailedAttempts, params.RetryAfter); err != nil {
+		return fmt.Errorf("upsert snap failure: %w", err)
 	}
 
-	return pathsByMemberID, nil
+	return nil
 }
 
 // CountTrackingMembers returns the number of active tracking members for a route.
@@ -483,7 +909,11 @@ func (r *PostgresRepository) CountTrackingMembers(ctx context.Context, routeID s
 	return count, nil
 }
 
-// StartTrackingMember marks a member as tracking and opens a path segment.
+// StartTrackingMember claims a tracking slot, marks the member as tracking, and opens a path segment.
+// The route row is locked for the whole transaction, so concurrent starts on one route take slots one
+// at a time and can never exceed max_tracking_members. Stale members already hold their slot, slots
+// reserved for waitlisted members count as taken, and a member claiming their own reservation leaves
+// the waitlist.
 func (r *PostgresRepository) StartTrackingMember(ctx context.Context, routeID, memberID string) (StartSharingRepoResult, error) {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -493,12 +923,73 @@ func (r *PostgresRepository) StartTrackingMember(ctx context.Context, routeID, m
 		_ = tx.Rollback(ctx)
 	}()
 
+	// NO KEY UPDATE serializes slot claims without blocking foreign-key checks from point inserts.
+	var maxTrackingMembers int
+	if err := tx.QueryRow(ctx, `
+		SELECT max_tracking_members
+		FROM routes
+		WHERE id = $1
+		FOR NO KEY UPDATE
+	`, routeID).Scan(&maxTrackingMembers); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return StartSharingRepoResult{}, ErrRouteNotFound
+		}
+
+		return StartSharingRepoResult{}, fmt.Errorf("lock route tracking slots: %w", err)
+	}
+
+	var currentStatus string
+	if err := tx.QueryRow(ctx, `
+		SELECT status
+		FROM route_members
+		WHERE id = $1 AND route_id = $2
+		FOR UPDATE
+	`, memberID, routeID).Scan(&currentStatus); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return StartSharingRepoResult{}, ErrUnauthorized
+		}
+
+		return StartSharingRepoResult{}, fmt.Errorf("lock tracking member: %w", err)
+	}
+
+	switch currentStatus {
+	case MemberStatusLeft, MemberStatusRemoved, MemberStatusOffline:
+		return StartSharingRepoResult{}, ErrInvalidInput
+	case MemberStatusSpectating:
+		var takenSlots int
+		var holdsReservation bool
+		if err := tx.QueryRow(ctx, `
+			SELECT
+				(SELECT COUNT(*) FROM route_members WHERE route_id = $1 AND status IN ($3, $4)) +
+				(
+					SELECT COUNT(*)
+					FROM tracking_waitlist w
+					INNER JOIN route_members m ON m.id = w.member_id
+					WHERE w.route_id = $1 AND w.member_id <> $2 AND w.reserved_until > NOW() AND m.status IN ($5, $6)
+				),
+				EXISTS (SELECT 1 FROM tracking_waitlist WHERE route_id = $1 AND member_id = $2 AND reserved_until > NOW())
+		`, routeID, memberID, MemberStatusTracking, MemberStatusStale, MemberStatusSpectating, MemberStatusOffline).Scan(&takenSlots, &holdsReservation); err != nil {
+			return StartSharingRepoResult{}, fmt.Errorf("count tracking slots: %w", err)
+		}
+
+		if !holdsReservation && takenSlots >= maxTrackingMembers {
+			return StartSharingRepoResult{}, ErrTrackingLimitReached
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM tracking_waitlist
+		WHERE route_id = $1 AND member_id = $2
+	`, routeID, memberID); err != nil {
+		return StartSharingRepoResult{}, fmt.Errorf("leave tracking waitlist: %w", err)
+	}
+
 	var member Member
 	if err := tx.QueryRow(ctx, `
 		UPDATE route_members
-		SET status = $3
+		SET status = $3, status_changed_at = CASE WHEN status = $3 THEN status_changed_at ELSE NOW() END
 		WHERE id = $1 AND route_id = $2
-		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, status, color, joined_at, left_at
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
 	`, memberID, routeID, MemberStatusTracking).Scan(
 		&member.ID,
 		&member.RouteID,
@@ -506,6 +997,8 @@ func (r *PostgresRepository) StartTrackingMember(ctx context.Context, routeID, m
 		&member.DisplayName,
 		&member.TransportMode,
 		&member.IsOwner,
+		&member.IsModerator,
+		&member.CanTrack,
 		&member.Status,
 		&member.Color,
 		&member.JoinedAt,
@@ -559,17 +1052,122 @@ func (r *PostgresRepository) StartTrackingMember(ctx context.Context, routeID, m
 
 // MarkMemberOnline marks an offline member as spectating.
 func (r *PostgresRepository) MarkMemberOnline(ctx context.Context, routeID, memberID string) (Member, bool, error) {
-	return r.updateMemberStatus(ctx, routeID, memberID, []string{MemberStatusOffline}, MemberStatusSpectating, "")
+	return r.updateMemberStatus(ctx, routeID, memberID, []string{MemberStatusOffline}, MemberStatusSpectating, "", nil)
 }
 
 // MarkMemberStale marks a tracking member as stale.
 func (r *PostgresRepository) MarkMemberStale(ctx context.Context, routeID, memberID string) (Member, bool, error) {
-	return r.updateMemberStatus(ctx, routeID, memberID, []string{MemberStatusTracking}, MemberStatusStale, "")
+	return r.updateMemberStatus(ctx, routeID, memberID, []string{MemberStatusTracking}, MemberStatusStale, "", nil)
 }
 
 // MarkMemberOffline marks an interrupted member offline and closes open segments.
 func (r *PostgresRepository) MarkMemberOffline(ctx context.Context, routeID, memberID string) (Member, bool, error) {
-	return r.updateMemberStatus(ctx, routeID, memberID, []string{MemberStatusSpectating, MemberStatusStale}, MemberStatusOffline, PathSegmentEndReasonDisconnected)
+	return r.updateMemberStatus(ctx, routeID, memberID, []string{MemberStatusSpectating, MemberStatusStale}, MemberStatusOffline, PathSegmentEndReasonDisconnected, nil)
+}
+
+// MarkOverdueMemberStale marks a tracking member as stale if its stale deadline still holds.
+func (r *PostgresRepository) MarkOverdueMemberStale(ctx context.Context, routeID, memberID string, deadlines PresenceDeadlines) (Member, bool, error) {
+	return r.updateMemberStatus(ctx, routeID, memberID, []string{MemberStatusTracking}, MemberStatusStale, "", &deadlines)
+}
+
+// MarkOverdueMemberOffline marks a stale or spectating member offline if its offline deadline still holds.
+func (r *PostgresRepository) MarkOverdueMemberOffline(ctx context.Context, routeID, memberID string, deadlines PresenceDeadlines) (Member, bool, error) {
+	return r.updateMemberStatus(ctx, routeID, memberID, []string{MemberStatusSpectating, MemberStatusStale}, MemberStatusOffline, PathSegmentEndReasonDisconnected, &deadlines)
+}
+
+// RecordMemberSeen stamps the member's last live connection activity.
+func (r *PostgresRepository) RecordMemberSeen(ctx context.Context, routeID, memberID string) error {
+	if _, err := r.db.Exec(ctx, `
+		UPDATE route_members
+		SET last_seen_at = NOW()
+		WHERE id = $1 AND route_id = $2
+	`, memberID, routeID); err != nil {
+		return fmt.Errorf("update member last seen: %w", err)
+	}
+
+	return nil
+}
+
+// ListPresenceCandidates loads members of active routes whose stale or offline deadline has passed.
+// Tracking members are measured from their last accepted point, spectators from their last live activity.
+func (r *PostgresRepository) ListPresenceCandidates(ctx context.Context, params PresenceDeadlines) ([]PresenceCandidate, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT m.route_id, m.id, m.status
+		FROM route_members m
+		JOIN routes r ON r.id = m.route_id
+		LEFT JOIN LATERAL (
+			SELECT p.recorded_at
+			FROM position_points p
+			WHERE p.route_id = m.route_id AND p.member_id = m.id
+			ORDER BY p.recorded_at DESC
+			LIMIT 1
+		) last_point ON m.status = $2
+		WHERE r.status = $1
+			AND (
+				(m.status = $2 AND GREATEST(m.status_changed_at, last_point.recorded_at) < $5)
+				OR (m.status = $3 AND m.status_changed_at < $6)
+				OR (m.status = $4 AND GREATEST(m.status_changed_at, m.last_seen_at) < $7)
+			)
+		ORDER BY m.route_id, m.id
+	`,
+		RouteStatusActive,
+		MemberStatusTracking,
+		MemberStatusStale,
+		MemberStatusSpectating,
+		params.TrackingStaleBefore,
+		params.StaleOfflineBefore,
+		params.SpectatorOfflineBefore,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query presence candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make([]PresenceCandidate, 0)
+	for rows.Next() {
+		var candidate PresenceCandidate
+		if err := rows.Scan(&candidate.RouteID, &candidate.MemberID, &candidate.Status); err != nil {
+			return nil, fmt.Errorf("scan presence candidate: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate presence candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// WithPresenceSweepLock runs fn while holding the cluster-wide presence sweep advisory lock.
+// It reports false without running fn when another replica holds the lock.
+func (r *PostgresRepository) WithPresenceSweepLock(ctx context.Context, fn func(context.Context) error) (bool, error) {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("acquire presence sweep connection: %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, presenceSweepLockKey).Scan(&locked); err != nil {
+		conn.Release()
+		return false, fmt.Errorf("try presence sweep lock: %w", err)
+	}
+	if !locked {
+		conn.Release()
+		return false, nil
+	}
+
+	fnErr := fn(ctx)
+
+	unlockCtx, cancel := context.WithTimeout(context.Background(), presenceSweepUnlockTimeout)
+	defer cancel()
+	if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, presenceSweepLockKey); err != nil {
+		// A session lock that cannot be released must not go back to the pool with its connection.
+		_ = conn.Hijack().Close(unlockCtx)
+		return true, errors.Join(fnErr, fmt.Errorf("release presence sweep lock: %w", err))
+	}
+	conn.Release()
+
+	return true, fnErr
 }
 
 // StopTrackingMember marks a member as spectating and ends open path segments.
@@ -585,9 +1183,9 @@ func (r *PostgresRepository) StopTrackingMember(ctx context.Context, routeID, me
 	var member Member
 	if err := tx.QueryRow(ctx, `
 		UPDATE route_members
-		SET status = $3
+		SET status = $3, status_changed_at = CASE WHEN status = $3 THEN status_changed_at ELSE NOW() END
 		WHERE id = $1 AND route_id = $2
-		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, status, color, joined_at, left_at
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
 	`, memberID, routeID, MemberStatusSpectating).Scan(
 		&member.ID,
 		&member.RouteID,
@@ -595,6 +1193,8 @@ func (r *PostgresRepository) StopTrackingMember(ctx context.Context, routeID, me
 		&member.DisplayName,
 		&member.TransportMode,
 		&member.IsOwner,
+		&member.IsModerator,
+		&member.CanTrack,
 		&member.Status,
 		&member.Color,
 		&member.JoinedAt,
@@ -622,60 +1222,308 @@ func (r *PostgresRepository) StopTrackingMember(ctx context.Context, routeID, me
 	return member, nil
 }
 
-// RecordPosition appends a point to the member's current open path segment.
-func (r *PostgresRepository) RecordPosition(ctx context.Context, params RecordPositionRepoParams) (PositionUpdateResult, error) {
+// ChangeMemberTransportMode updates the member's transport mode and appends the change to the
+// transport history in one transaction.
+func (r *PostgresRepository) ChangeMemberTransportMode(ctx context.Context, params ChangeTransportModeRepoParams) (Member, TransportChange, error) {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return PositionUpdateResult{}, fmt.Errorf("begin record position tx: %w", err)
+		return Member{}, TransportChange{}, fmt.Errorf("begin transport change tx: %w", err)
 	}
 	defer func() {
 		_ = tx.Rollback(ctx)
 	}()
 
-	var segmentID string
-	var recoveredMember *Member
+	change := TransportChange{
+		MemberID:      params.MemberID,
+		TransportMode: params.TransportMode,
+	}
 	if err := tx.QueryRow(ctx, `
-		SELECT id
-		FROM path_segments
-		WHERE route_id = $1 AND member_id = $2 AND ended_at IS NULL
-		ORDER BY started_at DESC
-		LIMIT 1
+		SELECT transport_mode
+		FROM route_members
+		WHERE id = $1 AND route_id = $2
 		FOR UPDATE
-	`, params.RouteID, params.MemberID).Scan(&segmentID); err != nil {
+	`, params.MemberID, params.RouteID).Scan(&change.FromTransportMode); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return PositionUpdateResult{}, ErrInvalidInput
+			return Member{}, TransportChange{}, ErrUnauthorized
 		}
 
-		return PositionUpdateResult{}, fmt.Errorf("load open path segment: %w", err)
+		return Member{}, TransportChange{}, fmt.Errorf("lock member transport mode: %w", err)
 	}
 
-	var staleMember Member
-	err = tx.QueryRow(ctx, `
+	var member Member
+	if err := tx.QueryRow(ctx, `
 		UPDATE route_members
-		SET status = $3
-		WHERE id = $1 AND route_id = $2 AND status = $4
-		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, status, color, joined_at, left_at
-	`, params.MemberID, params.RouteID, MemberStatusTracking, MemberStatusStale).Scan(
-		&staleMember.ID,
-		&staleMember.RouteID,
-		&staleMember.ClientID,
-		&staleMember.DisplayName,
-		&staleMember.TransportMode,
-		&staleMember.IsOwner,
-		&staleMember.Status,
-		&staleMember.Color,
-		&staleMember.JoinedAt,
-		&staleMember.LeftAt,
+		SET transport_mode = $3
+		WHERE id = $1 AND route_id = $2
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
+	`, params.MemberID, params.RouteID, params.TransportMode).Scan(
+		&member.ID,
+		&member.RouteID,
+		&member.ClientID,
+		&member.DisplayName,
+		&member.TransportMode,
+		&member.IsOwner,
+		&member.IsModerator,
+		&member.CanTrack,
+		&member.Status,
+		&member.Color,
+		&member.JoinedAt,
+		&member.LeftAt,
+	); err != nil {
+		return Member{}, TransportChange{}, fmt.Errorf("update member transport mode: %w", err)
+	}
+
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO member_transport_changes (route_id, member_id, from_transport_mode, to_transport_mode)
+		VALUES ($1, $2, $3, $4)
+		RETURNING changed_at
+	`, params.RouteID, params.MemberID, change.FromTransportMode, params.TransportMode).Scan(&change.ChangedAt); err != nil {
+		return Member{}, TransportChange{}, fmt.Errorf("insert transport change: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Member{}, TransportChange{}, fmt.Errorf("commit transport change tx: %w", err)
+	}
+
+	return member, change, nil
+}
+
+// GetTransportChangesByRouteID returns each member's transport mode changes in time order.
+func (r *PostgresRepository) GetTransportChangesByRouteID(ctx context.Context, routeID string) (map[string][]TransportChange, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT member_id, from_transport_mode, to_transport_mode, changed_at
+		FROM member_transport_changes
+		WHERE route_id = $1
+		ORDER BY changed_at ASC, id ASC
+	`, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("query transport changes: %w", err)
+	}
+	defer rows.Close()
+
+	changesByMemberID := make(map[string][]TransportChange)
+	for rows.Next() {
+		var change TransportChange
+		if err := rows.Scan(&change.MemberID, &change.FromTransportMode, &change.TransportMode, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("scan transport change: %w", err)
+		}
+		changesByMemberID[change.MemberID] = append(changesByMemberID[change.MemberID], change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate transport changes: %w", err)
+	}
+
+	return changesByMemberID, nil
+}
+
+// SetMemberTrackingPermission stores a member's tracking grant and appends the change to the grant
+// history in one transaction. A revocation also returns a tracking or stale member to spectating and
+// closes their open path segment.
+func (r *PostgresRepository) SetMemberTrackingPermission(ctx context.Context, params SetTrackingPermissionRepoParams) (SetTrackingPermissionRepoResult, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return SetTrackingPermissionRepoResult{}, fmt.Errorf("begin tracking permission tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var canTrack bool
+	var status string
+	if err := tx.QueryRow(ctx, `
+		SELECT can_track, status
+		FROM route_members
+		WHERE id = $1 AND route_id = $2
+		FOR UPDATE
+	`, params.MemberID, params.RouteID).Scan(&canTrack, &status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SetTrackingPermissionRepoResult{}, ErrMemberNotFound
+		}
+
+		return SetTrackingPermissionRepoResult{}, fmt.Errorf("lock member tracking permission: %w", err)
+	}
+
+	if canTrack == params.Granted {
+		member, err := r.getMemberByID(ctx, tx, params.RouteID, params.MemberID)
+		if err != nil {
+			return SetTrackingPermissionRepoResult{}, err
+		}
+
+		return SetTrackingPermissionRepoResult{Member: member}, nil
+	}
+
+	stopSharing := !params.Granted && (status == MemberStatusTracking || status == MemberStatusStale)
+	nextStatus := status
+	if stopSharing {
+		nextStatus = MemberStatusSpectating
+	}
+
+	var member Member
+	if err := tx.QueryRow(ctx, `
+		UPDATE route_members
+		SET can_track = $3,
+			status = $4,
+			status_changed_at = CASE WHEN status = $4 THEN status_changed_at ELSE NOW() END
+		WHERE id = $1 AND route_id = $2
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
+	`, params.MemberID, params.RouteID, params.Granted, nextStatus).Scan(
+		&member.ID,
+		&member.RouteID,
+		&member.ClientID,
+		&member.DisplayName,
+		&member.TransportMode,
+		&member.IsOwner,
+		&member.IsModerator,
+		&member.CanTrack,
+		&member.Status,
+		&member.Color,
+		&member.JoinedAt,
+		&member.LeftAt,
+	); err != nil {
+		return SetTrackingPermissionRepoResult{}, fmt.Errorf("update member tracking permission: %w", err)
+	}
+
+	if stopSharing {
+		if _, err := tx.Exec(ctx, `
+			UPDATE path_segments
+			SET ended_at = COALESCE(ended_at, NOW()), end_reason = COALESCE(end_reason, $3)
+			WHERE route_id = $1 AND member_id = $2 AND ended_at IS NULL
+		`, params.RouteID, member.ID, PathSegmentEndReasonPermissionRevoked); err != nil {
+			return SetTrackingPermissionRepoResult{}, fmt.Errorf("end revoked path segment: %w", err)
+		}
+	}
+
+	change := TrackingPermissionChange{
+		MemberID:  member.ID,
+		Granted:   params.Granted,
+		ChangedBy: params.ChangedBy,
+	}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO member_tracking_grants (route_id, member_id, granted, changed_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING changed_at
+	`, params.RouteID, member.ID, params.Granted, params.ChangedBy).Scan(&change.ChangedAt); err != nil {
+		return SetTrackingPermissionRepoResult{}, fmt.Errorf("insert tracking permission change: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return SetTrackingPermissionRepoResult{}, fmt.Errorf("commit tracking permission tx: %w", err)
+	}
+
+	return SetTrackingPermissionRepoResult{
+		Member:         member,
+		Change:         &change,
+		StoppedSharing: stopSharing,
+	}, nil
+}
+
+// GetLastPositionPoint loads the member's most recently accepted point across all segments.
+func (r *PostgresRepository) GetLastPositionPoint(ctx context.Context, routeID, memberID string) (RoutePoint, bool, error) {
+	var point RoutePoint
+	var optional routePointOptionalColumns
+	err := r.db.QueryRow(ctx, `
+		SELECT seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
+		FROM position_points
+		WHERE route_id = $1 AND member_id = $2
+		ORDER BY recorded_at DESC, seq DESC
+		LIMIT 1
+	`, routeID, memberID).Scan(
+		&point.Seq,
+		&point.Latitude,
+		&point.Longitude,
+		&optional.accuracy,
+		&optional.altitude,
+		&optional.speed,
+		&optional.heading,
+		&optional.clientRecordedAt,
+		&point.RecordedAt,
 	)
-	if err == nil {
-		recoveredMember = &staleMember
-	} else if !errors.Is(err, pgx.ErrNoRows) {
-		return PositionUpdateResult{}, fmt.Errorf("recover stale member: %w", err)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RoutePoint{}, false, nil
+		}
+
+		return RoutePoint{}, false, fmt.Errorf("get last position point: %w", err)
+	}
+
+	optional.apply(&point)
+
+	return point, true, nil
+}
+
+// ListPositionPointsAround loads the member's stored points recorded between from and to, plus the
+// last one at or before from, ordered by client time.
+func (r *PostgresRepository) ListPositionPointsAround(ctx context.Context, routeID, memberID string, from, to time.Time) ([]RoutePoint, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
+		FROM (
+			(
+				SELECT seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
+				FROM position_points
+				WHERE route_id = $1 AND member_id = $2 AND COALESCE(client_recorded_at, recorded_at) <= $3
+				ORDER BY COALESCE(client_recorded_at, recorded_at) DESC
+				LIMIT 1
+			)
+			UNION ALL
+			(
+				SELECT seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
+				FROM position_points
+				WHERE route_id = $1 AND member_id = $2
+					AND COALESCE(client_recorded_at, recorded_at) > $3
+					AND COALESCE(client_recorded_at, recorded_at) <= $4
+			)
+		) points
+		ORDER BY COALESCE(points.client_recorded_at, points.recorded_at)
+	`, routeID, memberID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query position points around: %w", err)
+	}
+	defer rows.Close()
+
+	points := make([]RoutePoint, 0)
+	for rows.Next() {
+		var point RoutePoint
+		var optional routePointOptionalColumns
+		if err := rows.Scan(
+			&point.Seq,
+			&point.Latitude,
+			&point.Longitude,
+			&optional.accuracy,
+			&optional.altitude,
+			&optional.speed,
+			&optional.heading,
+			&optional.clientRecordedAt,
+			&point.RecordedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan position point around: %w", err)
+		}
+		optional.apply(&point)
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate position points around: %w", err)
+	}
+
+	return points, nil
+}
+
+// RecordPosition appends a point to the member's current open path segment.
+func (r *PostgresRepository) RecordPosition(ctx context.Context, params RecordPositionRepoParams) (PositionUpdateResult, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return PositionUpdateResult{}, fmt.Errorf("begin record position tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	segmentID, recoveredMember, err := lockOpenSegment(ctx, tx, params.RouteID, params.MemberID)
+	if err != nil {
+		return PositionUpdateResult{}, err
 	}
 
 	var point RoutePoint
-	var accuracy sql.NullFloat64
-	var clientRecordedAt sql.NullTime
+	var optional routePointOptionalColumns
 	if err := tx.QueryRow(ctx, `
 		WITH next_seq AS (
 			SELECT COALESCE(MAX(seq), 0) + 1 AS seq
@@ -712,36 +1560,582 @@ func (r *PostgresRepository) RecordPosition(ctx context.Context, params RecordPo
 			$9,
 			$11
 		)
-		RETURNING seq, latitude, longitude, accuracy_m, client_recorded_at, recorded_at
+		RETURNING seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
 	`, params.RouteID, params.MemberID, segmentID, params.Latitude, params.Longitude, params.AccuracyM, params.AltitudeM, params.SpeedMPS, params.HeadingDeg, params.ClientRecordedAt, params.RawPayload).Scan(
 		&point.Seq,
 		&point.Latitude,
 		&point.Longitude,
-		&accuracy,
-		&clientRecordedAt,
+		&optional.accuracy,
+		&optional.altitude,
+		&optional.speed,
+		&optional.heading,
+		&optional.clientRecordedAt,
 		&point.RecordedAt,
 	); err != nil {
 		return PositionUpdateResult{}, fmt.Errorf("insert position point: %w", err)
 	}
 
-	if accuracy.Valid {
-		point.AccuracyM = &accuracy.Float64
-	}
-	if clientRecordedAt.Valid {
-		point.ClientRecordedAt = &clientRecordedAt.Time
+	optional.apply(&point)
+
+	if err := tx.Commit(ctx); err != nil {
+		return PositionUpdateResult{}, fmt.Errorf("commit record position tx: %w", err)
+	}
+
+	return PositionUpdateResult{
+		RouteID:         params.RouteID,
+		MemberID:        params.MemberID,
+		SegmentID:       segmentID,
+		Point:           point,
+		RecoveredMember: recoveredMember,
+	}, nil
+}
+
+// lockOpenSegment locks the member's open path segment for appending points and moves a stale
+// member back to tracking, returning the recovered member when that happened.
+func lockOpenSegment(ctx context.Context, tx pgx.Tx, routeID, memberID string) (string, *Member, error) {
+	var segmentID string
+	if err := tx.QueryRow(ctx, `
+		SELECT id
+		FROM path_segments
+		WHERE route_id = $1 AND member_id = $2 AND ended_at IS NULL
+		ORDER BY started_at DESC
+		LIMIT 1
+		FOR UPDATE
+	`, routeID, memberID).Scan(&segmentID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil, ErrInvalidInput
+		}
+
+		return "", nil, fmt.Errorf("load open path segment: %w", err)
+	}
+
+	recoveredMember, err := recoverStaleMember(ctx, tx, routeID, memberID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return segmentID, recoveredMember, nil
+}
+
+// recoverStaleMember moves a stale member back to tracking, returning the member when that happened.
+func recoverStaleMember(ctx context.Context, tx pgx.Tx, routeID, memberID string) (*Member, error) {
+	var staleMember Member
+	err := tx.QueryRow(ctx, `
+		UPDATE route_members
+		SET status = $3, status_changed_at = NOW()
+		WHERE id = $1 AND route_id = $2 AND status = $4
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
+	`, memberID, routeID, MemberStatusTracking, MemberStatusStale).Scan(
+		&staleMember.ID,
+		&staleMember.RouteID,
+		&staleMember.ClientID,
+		&staleMember.DisplayName,
+		&staleMember.TransportMode,
+		&staleMember.IsOwner,
+		&staleMember.IsModerator,
+		&staleMember.CanTrack,
+		&staleMember.Status,
+		&staleMember.Color,
+		&staleMember.JoinedAt,
+		&staleMember.LeftAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("recover stale member: %w", err)
+	}
+
+	return &staleMember, nil
+}
+
+// RecordPositionBatch stores validated samples in the member's path segments with one bulk insert,
+// placing each sample by its client timestamp (see placeBatchSamples). A segment closed by a disconnect
+// is extended to cover the samples placed after its end. Segments that received samples older than
+// their newest point are renumbered so seq follows client time, and their snapped geometry is dropped
+// for the matcher to redo. Samples whose client timestamp is already stored for the member are skipped,
+// so retried uploads are idempotent.
+func (r *PostgresRepository) RecordPositionBatch(ctx context.Context, params RecordPositionBatchRepoParams) (RecordPositionBatchRepoResult, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return RecordPositionBatchRepoResult{}, fmt.Errorf("begin record position batch tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	segments, err := lockMemberSegments(ctx, tx, params.RouteID, params.MemberID)
+	if err != nil {
+		return RecordPositionBatchRepoResult{}, err
+	}
+
+	var result RecordPositionBatchRepoResult
+	placement := placeBatchSamples(segments, params.Samples)
+	segmentIDs := make([]string, 0, len(params.Samples))
+	latitudes := make([]float64, 0, len(params.Samples))
+	longitudes := make([]float64, 0, len(params.Samples))
+	accuracies := make([]*float64, 0, len(params.Samples))
+	altitudes := make([]*float64, 0, len(params.Samples))
+	speeds := make([]*float64, 0, len(params.Samples))
+	headings := make([]*float64, 0, len(params.Samples))
+	clientRecordedAts := make([]*time.Time, 0, len(params.Samples))
+	rawPayloads := make([]string, 0, len(params.Samples))
+	segmentEnds := make(map[int]time.Time)
+	touchesOpenSegment := false
+	for i, sample := range params.Samples {
+		index := placement[i]
+		if index < 0 {
+			result.Unplaced = append(result.Unplaced, i)
+			continue
+		}
+
+		segment := segments[index]
+		if segment.EndedAt == nil {
+			touchesOpenSegment = true
+		} else if sample.ClientRecordedAt.After(*segment.EndedAt) && sample.ClientRecordedAt.After(segmentEnds[index]) {
+			segmentEnds[index] = *sample.ClientRecordedAt
+		}
+
+		segmentIDs = append(segmentIDs, segment.ID)
+		latitudes = append(latitudes, sample.Latitude)
+		longitudes = append(longitudes, sample.Longitude)
+		accuracies = append(accuracies, sample.AccuracyM)
+		altitudes = append(altitudes, sample.AltitudeM)
+		speeds = append(speeds, sample.SpeedMPS)
+		headings = append(headings, sample.HeadingDeg)
+		clientRecordedAts = append(clientRecordedAts, sample.ClientRecordedAt)
+		rawPayloads = append(rawPayloads, string(sample.RawPayload))
+	}
+	if len(segmentIDs) == 0 {
+		return result, nil
+	}
+
+	for index, endedAt := range segmentEnds {
+		if _, err := tx.Exec(ctx, `
+			UPDATE path_segments
+			SET ended_at = $2
+			WHERE id = $1
+		`, segments[index].ID, endedAt); err != nil {
+			return RecordPositionBatchRepoResult{}, fmt.Errorf("extend disconnected path segment: %w", err)
+		}
+	}
+
+	rows, err := tx.Query(ctx, `
+		WITH samples AS (
+			SELECT *
+			FROM UNNEST(
+				$3::UUID[],
+				$4::DOUBLE PRECISION[],
+				$5::DOUBLE PRECISION[],
+				$6::DOUBLE PRECISION[],
+				$7::DOUBLE PRECISION[],
+				$8::DOUBLE PRECISION[],
+				$9::DOUBLE PRECISION[],
+				$10::TIMESTAMPTZ[],
+				$11::TEXT[]
+			) WITH ORDINALITY AS sample(segment_id, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, raw_payload, ord)
+		),
+		fresh AS (
+			SELECT samples.*, ROW_NUMBER() OVER (PARTITION BY samples.segment_id ORDER BY samples.ord) AS batch_index
+			FROM samples
+			WHERE NOT EXISTS (
+				SELECT 1
+				FROM position_points
+				WHERE member_id = $2 AND client_recorded_at = samples.client_recorded_at
+			)
+		)
+		INSERT INTO position_points (
+			route_id,
+			member_id,
+			segment_id,
+			seq,
+			client_recorded_at,
+			location,
+			latitude,
+			longitude,
+			accuracy_m,
+			altitude_m,
+			speed_mps,
+			heading_deg,
+			raw_payload
+		)
+		SELECT
+			$1,
+			$2,
+			fresh.segment_id,
+			last_seq.seq + fresh.batch_index,
+			fresh.client_recorded_at,
+			ST_SetSRID(ST_MakePoint(fresh.longitude, fresh.latitude), 4326)::geography,
+			fresh.latitude,
+			fresh.longitude,
+			fresh.accuracy_m,
+			fresh.altitude_m,
+			fresh.speed_mps,
+			fresh.heading_deg,
+			fresh.raw_payload::JSONB
+		FROM fresh
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(MAX(seq), 0) AS seq
+			FROM position_points
+			WHERE segment_id = fresh.segment_id
+		) last_seq
+		RETURNING id
+	`, params.RouteID, params.MemberID, segmentIDs, latitudes, longitudes, accuracies, altitudes, speeds, headings, clientRecordedAts, rawPayloads)
+	if err != nil {
+		return RecordPositionBatchRepoResult{}, fmt.Errorf("insert position batch: %w", err)
+	}
+	insertedIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return RecordPositionBatchRepoResult{}, fmt.Errorf("insert position batch: %w", err)
+	}
+	if len(insertedIDs) == 0 {
+		return result, nil
+	}
+
+	// The seq constraint is deferrable, so shifting points up is checked once the statement ends.
+	if _, err := tx.Exec(ctx, `
+		WITH renumbered AS (
+			UPDATE position_points p
+			SET seq = ordered.seq
+			FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY segment_id ORDER BY COALESCE(client_recorded_at, recorded_at), seq) AS seq
+				FROM position_points
+				WHERE segment_id IN (SELECT segment_id FROM position_points WHERE id = ANY($1::UUID[]))
+			) ordered
+			WHERE p.id = ordered.id AND p.seq <> ordered.seq
+			RETURNING p.segment_id
+		)
+		DELETE FROM path_segment_snapped_geometries
+		WHERE segment_id IN (SELECT segment_id FROM renumbered)
+	`, insertedIDs); err != nil {
+		return RecordPositionBatchRepoResult{}, fmt.Errorf("renumber backfilled segments: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT segment_id, seq, latitude, longitude, accuracy_m, altitude_m, speed_mps, heading_deg, client_recorded_at, recorded_at
+		FROM position_points
+		WHERE id = ANY($1::UUID[])
+		ORDER BY segment_id, seq
+	`, insertedIDs)
+	if err != nil {
+		return RecordPositionBatchRepoResult{}, fmt.Errorf("load backfilled points: %w", err)
+	}
+	pointsBySegment := make(map[string][]RoutePoint, len(segments))
+	for rows.Next() {
+		var segmentID string
+		var point RoutePoint
+		var optional routePointOptionalColumns
+		if err := rows.Scan(
+			&segmentID,
+			&point.Seq,
+			&point.Latitude,
+			&point.Longitude,
+			&optional.accuracy,
+			&optional.altitude,
+			&optional.speed,
+			&optional.heading,
+			&optional.clientRecordedAt,
+			&point.RecordedAt,
+		); err != nil {
+			rows.Close()
+			return RecordPositionBatchRepoResult{}, fmt.Errorf("scan backfilled point: %w", err)
+		}
+		optional.apply(&point)
+		pointsBySegment[segmentID] = append(pointsBySegment[segmentID], point)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return RecordPositionBatchRepoResult{}, fmt.Errorf("load backfilled points: %w", err)
+	}
+	for _, segment := range segments {
+		if points := pointsBySegment[segment.ID]; len(points) > 0 {
+			result.Segments = append(result.Segments, PositionBatchSegment{SegmentID: segment.ID, Points: points})
+		}
+	}
+
+	if touchesOpenSegment {
+		result.RecoveredMember, err = recoverStaleMember(ctx, tx, params.RouteID, params.MemberID)
+		if err != nil {
+			return RecordPositionBatchRepoResult{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return RecordPositionBatchRepoResult{}, fmt.Errorf("commit record position batch tx: %w", err)
+	}
+
+	return result, nil
+}
+
+// lockMemberSegments locks all of the member's path segments, oldest first, so batch placement and
+// renumbering cannot race a live point or a status change closing a segment.
+func lockMemberSegments(ctx context.Context, tx pgx.Tx, routeID, memberID string) ([]PathSegment, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, started_at, ended_at, COALESCE(end_reason, '')
+		FROM path_segments
+		WHERE route_id = $1 AND member_id = $2
+		ORDER BY started_at, id
+		FOR UPDATE
+	`, routeID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("lock member path segments: %w", err)
+	}
+	defer rows.Close()
+
+	segments := make([]PathSegment, 0)
+	for rows.Next() {
+		var segment PathSegment
+		var startedAt time.Time
+		if err := rows.Scan(&segment.ID, &startedAt, &segment.EndedAt, &segment.EndReason); err != nil {
+			return nil, fmt.Errorf("scan member path segment: %w", err)
+		}
+		segment.StartedAt = &startedAt
+		segments = append(segments, segment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate member path segments: %w", err)
+	}
+
+	return segments, nil
+}
+
+// RecordRouteEvent appends one entry to the route event log.
+func (r *PostgresRepository) RecordRouteEvent(ctx context.Context, params RecordRouteEventRepoParams) (RouteEvent, error) {
+	var event RouteEvent
+	var actorMemberID sql.NullString
+	if err := r.db.QueryRow(ctx, `
+		INSERT INTO route_events (
+			route_id,
+			actor_member_id,
+			event_type,
+			payload,
+			occurred_at
+		) VALUES ($1, NULLIF($2, '')::UUID, $3, $4, $5)
+		RETURNING id, route_id, actor_member_id::TEXT, event_type, payload, occurred_at
+	`, params.RouteID, params.ActorMemberID, params.Type, params.Payload, params.OccurredAt).Scan(
+		&event.ID,
+		&event.RouteID,
+		&actorMemberID,
+		&event.Type,
+		&event.Payload,
+		&event.OccurredAt,
+	); err != nil {
+		return RouteEvent{}, fmt.Errorf("insert route event: %w", err)
+	}
+	event.ActorMemberID = actorMemberID.String
+
+	return event, nil
+}
+
+// ListRouteEventsAfter loads route event log entries after a cursor, oldest first.
+func (r *PostgresRepository) ListRouteEventsAfter(ctx context.Context, params ListRouteEventsAfterRepoParams) ([]RouteEvent, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, route_id, actor_member_id::TEXT, event_type, payload, occurred_at
+		FROM route_events
+		WHERE route_id = $1 AND id > $2
+		ORDER BY id ASC
+		LIMIT $3
+	`, params.RouteID, params.AfterID, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("query route events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []RouteEvent
+	for rows.Next() {
+		var event RouteEvent
+		var actorMemberID sql.NullString
+		if err := rows.Scan(
+			&event.ID,
+			&event.RouteID,
+			&actorMemberID,
+			&event.Type,
+			&event.Payload,
+			&event.OccurredAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan route event: %w", err)
+		}
+		event.ActorMemberID = actorMemberID.String
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate route events: %w", err)
+	}
+
+	return events, nil
+}
+
+// GetLatestRouteEventID returns the newest route event log id, or zero when the route has none.
+func (r *PostgresRepository) GetLatestRouteEventID(ctx context.Context, routeID string) (int64, error) {
+	var id int64
+	if err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(MAX(id), 0)
+		FROM route_events
+		WHERE route_id = $1
+	`, routeID).Scan(&id); err != nil {
+		return 0, fmt.Errorf("query latest route event: %w", err)
+	}
+
+	return id, nil
+}
+
+// RouteEventExists reports whether the route event log still holds the event with the given id.
+func (r *PostgresRepository) RouteEventExists(ctx context.Context, routeID string, id int64) (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM route_events
+			WHERE route_id = $1 AND id = $2
+		)
+	`, routeID, id).Scan(&exists); err != nil {
+		return false, fmt.Errorf("check route event: %w", err)
+	}
+
+	return exists, nil
+}
+
+// DeleteRouteEventsBefore deletes route events that occurred before the cutoff across all routes.
+func (r *PostgresRepository) DeleteRouteEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	commandTag, err := r.db.Exec(ctx, `
+		DELETE FROM route_events
+		WHERE occurred_at < $1
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete route events: %w", err)
+	}
+
+	return commandTag.RowsAffected(), nil
+}
+
+// CreateChatMessage stores one route chat message. The rate limit is counted under the member row
+// lock, so concurrent posts from one member are checked one at a time.
+func (r *PostgresRepository) CreateChatMessage(ctx context.Context, params CreateChatMessageRepoParams) (ChatMessage, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("begin create chat message tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if params.RateLimitMessages > 0 {
+		// NO KEY UPDATE serializes posts without blocking foreign-key checks from point inserts.
+		if _, err := tx.Exec(ctx, `
+			SELECT 1
+			FROM route_members
+			WHERE id = $1
+			FOR NO KEY UPDATE
+		`, params.MemberID); err != nil {
+			return ChatMessage{}, fmt.Errorf("lock chat member: %w", err)
+		}
+
+		var recent int
+		if err := tx.QueryRow(ctx, `
+			SELECT COUNT(*)
+			FROM chat_messages
+			WHERE member_id = $1 AND posted_at >= $2
+		`, params.MemberID, params.RateLimitSince).Scan(&recent); err != nil {
+			return ChatMessage{}, fmt.Errorf("count chat messages: %w", err)
+		}
+		if recent >= params.RateLimitMessages {
+			return ChatMessage{}, ErrChatRateLimited
+		}
+	}
+
+	var message ChatMessage
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO chat_messages (route_id, member_id, body)
+		VALUES ($1, $2, $3)
+		RETURNING id, member_id, body, posted_at
+	`, params.RouteID, params.MemberID, params.Body).Scan(
+		&message.ID,
+		&message.MemberID,
+		&message.Body,
+		&message.PostedAt,
+	); err != nil {
+		return ChatMessage{}, fmt.Errorf("insert chat message: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ChatMessage{}, fmt.Errorf("commit create chat message tx: %w", err)
+	}
+
+	return message, nil
+}
+
+// ListChatMessages returns route chat newest-first, optionally older than a cursor message.
+func (r *PostgresRepository) ListChatMessages(ctx context.Context, params ListChatMessagesRepoParams) ([]ChatMessage, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, member_id, body, posted_at
+		FROM chat_messages
+		WHERE route_id = $1
+			AND (
+				$2 = ''
+				OR (posted_at, id) < (
+					SELECT cursor.posted_at, cursor.id
+					FROM chat_messages cursor
+					WHERE cursor.route_id = $1 AND cursor.id::TEXT = $2
+				)
+			)
+		ORDER BY posted_at DESC, id DESC
+		LIMIT $3
+	`, params.RouteID, params.BeforeID, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("query chat messages: %w", err)
+	}
+
+	return scanChatMessages(rows)
+}
+
+// GetChatMessagesByRouteID returns the full route chat in posting order.
+func (r *PostgresRepository) GetChatMessagesByRouteID(ctx context.Context, routeID string) ([]ChatMessage, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, member_id, body, posted_at
+		FROM chat_messages
+		WHERE route_id = $1
+		ORDER BY posted_at ASC, id ASC
+	`, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("query route chat messages: %w", err)
+	}
+
+	return scanChatMessages(rows)
+}
+
+// ListReplayChatMessages returns the next route chat messages after a keyset position in posting
+// order.
+func (r *PostgresRepository) ListReplayChatMessages(ctx context.Context, params ListReplayChatMessagesRepoParams) ([]ChatMessage, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, member_id, body, posted_at
+		FROM chat_messages
+		WHERE route_id = $1 AND (posted_at, id) > ($2, $3::UUID)
+		ORDER BY posted_at ASC, id ASC
+		LIMIT $4
+	`, params.RouteID, params.AfterAt, params.AfterID, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("query replay chat messages: %w", err)
+	}
+
+	return scanChatMessages(rows)
+}
+
+func scanChatMessages(rows pgx.Rows) ([]ChatMessage, error) {
+	defer rows.Close()
+
+	messages := make([]ChatMessage, 0)
+	for rows.Next() {
+		var message ChatMessage
+		if err := rows.Scan(&message.ID, &message.MemberID, &message.Body, &message.PostedAt); err != nil {
+			return nil, fmt.Errorf("scan chat message: %w", err)
+		}
+		messages = append(messages, message)
 	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return PositionUpdateResult{}, fmt.Errorf("commit record position tx: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate chat messages: %w", err)
 	}
 
-	return PositionUpdateResult{
-		RouteID:         params.RouteID,
-		MemberID:        params.MemberID,
-		SegmentID:       segmentID,
-		Point:           point,
-		RecoveredMember: recoveredMember,
-	}, nil
+	return messages, nil
 }
 
 // UpdateRoute mutates route name, description, and/or status.
@@ -799,7 +2193,7 @@ func (r *PostgresRepository) UpdateRoute(ctx context.Context, routeID string, pa
 
 		if _, err := tx.Exec(ctx, `
 			UPDATE route_members
-			SET status = $2
+			SET status = $2, status_changed_at = NOW()
 			WHERE route_id = $1 AND status IN ($3, $4)
 		`, routeID, MemberStatusSpectating, MemberStatusTracking, MemberStatusStale); err != nil {
 			return Route{}, fmt.Errorf("close route active members: %w", err)
@@ -827,9 +2221,9 @@ func (r *PostgresRepository) LeaveMember(ctx context.Context, memberID string) (
 
 	err = tx.QueryRow(ctx, `
 		UPDATE route_members
-		SET status = $2, left_at = COALESCE(left_at, NOW())
+		SET status = $2, status_changed_at = NOW(), left_at = COALESCE(left_at, NOW())
 		WHERE id = $1
-		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, status, color, joined_at, left_at
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
 	`, memberID, MemberStatusLeft).Scan(
 		&member.ID,
 		&member.RouteID,
@@ -837,6 +2231,8 @@ func (r *PostgresRepository) LeaveMember(ctx context.Context, memberID string) (
 		&member.DisplayName,
 		&member.TransportMode,
 		&member.IsOwner,
+		&member.IsModerator,
+		&member.CanTrack,
 		&member.Status,
 		&member.Color,
 		&member.JoinedAt,
@@ -874,6 +2270,13 @@ func (r *PostgresRepository) LeaveMember(ctx context.Context, memberID string) (
 		return Member{}, fmt.Errorf("revoke owner tokens: %w", err)
 	}
 
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM tracking_waitlist
+		WHERE member_id = $1
+	`, memberID); err != nil {
+		return Member{}, fmt.Errorf("leave tracking waitlist: %w", err)
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return Member{}, fmt.Errorf("commit leave member tx: %w", err)
 	}
@@ -881,7 +2284,428 @@ func (r *PostgresRepository) LeaveMember(ctx context.Context, memberID string) (
 	return member, nil
 }
 
-func (r *PostgresRepository) updateMemberStatus(ctx context.Context, routeID, memberID string, fromStatuses []string, toStatus, closeReason string) (Member, bool, error) {
+// RemoveMember moves a member who has not left to removed, closes their open path segments, revokes their
+// tokens, drops their tracking waitlist entry, and optionally bans their client ID from the route in one transaction. A member who left or was
+// removed in the meantime is ErrInvalidInput.
+func (r *PostgresRepository) RemoveMember(ctx context.Context, params RemoveMemberRepoParams) (Member, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Member{}, fmt.Errorf("begin remove member tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var member Member
+	if err := tx.QueryRow(ctx, `
+		UPDATE route_members
+		SET status = $3, status_changed_at = NOW(), left_at = COALESCE(left_at, NOW())
+		WHERE id = $1 AND route_id = $2 AND status NOT IN ($4, $3)
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
+	`, params.MemberID, params.RouteID, MemberStatusRemoved, MemberStatusLeft).Scan(
+		&member.ID,
+		&member.RouteID,
+		&member.ClientID,
+		&member.DisplayName,
+		&member.TransportMode,
+		&member.IsOwner,
+		&member.IsModerator,
+		&member.CanTrack,
+		&member.Status,
+		&member.Color,
+		&member.JoinedAt,
+		&member.LeftAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Member{}, ErrInvalidInput
+		}
+
+		return Member{}, fmt.Errorf("remove member row: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE path_segments
+		SET ended_at = COALESCE(ended_at, NOW()), end_reason = COALESCE(end_reason, $3)
+		WHERE route_id = $1 AND member_id = $2 AND ended_at IS NULL
+	`, params.RouteID, params.MemberID, PathSegmentEndReasonRemoved); err != nil {
+		return Member{}, fmt.Errorf("remove member path segments: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE member_tokens
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE member_id = $1
+	`, params.MemberID); err != nil {
+		return Member{}, fmt.Errorf("revoke removed member tokens: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM tracking_waitlist
+		WHERE route_id = $1 AND member_id = $2
+	`, params.RouteID, params.MemberID); err != nil {
+		return Member{}, fmt.Errorf("drop removed member from tracking waitlist: %w", err)
+	}
+
+	if params.Ban {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO route_member_bans (route_id, client_id, member_id, banned_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (route_id, client_id) DO NOTHING
+		`, params.RouteID, member.ClientID, member.ID, params.RemovedBy); err != nil {
+			return Member{}, fmt.Errorf("ban removed member: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Member{}, fmt.Errorf("commit remove member tx: %w", err)
+	}
+
+	return member, nil
+}
+
+// SetMemberModerator stores whether a non-owner member who has not left is a route moderator.
+func (r *PostgresRepository) SetMemberModerator(ctx context.Context, routeID, memberID string, moderator bool) (Member, error) {
+	var member Member
+	if err := scanMember(r.db.QueryRow(ctx, `
+		UPDATE route_members
+		SET is_moderator = $3
+		WHERE id = $1 AND route_id = $2 AND NOT is_owner AND status NOT IN ($4, $5)
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
+	`, memberID, routeID, moderator, MemberStatusLeft, MemberStatusRemoved), &member); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Member{}, ErrInvalidInput
+		}
+
+		return Member{}, fmt.Errorf("set member moderator: %w", err)
+	}
+
+	return member, nil
+}
+
+// JoinTrackingWaitlist appends the member to the route's tracking waitlist unless already queued.
+func (r *PostgresRepository) JoinTrackingWaitlist(ctx context.Context, routeID, memberID string) error {
+	if _, err := r.db.Exec(ctx, `
+		INSERT INTO tracking_waitlist (route_id, member_id)
+		VALUES ($1, $2)
+		ON CONFLICT (route_id, member_id) DO NOTHING
+	`, routeID, memberID); err != nil {
+		return fmt.Errorf("insert tracking waitlist entry: %w", err)
+	}
+
+	return nil
+}
+
+// LeaveTrackingWaitlist removes the member's waitlist entry and any reservation it held.
+func (r *PostgresRepository) LeaveTrackingWaitlist(ctx context.Context, routeID, memberID string) error {
+	if _, err := r.db.Exec(ctx, `
+		DELETE FROM tracking_waitlist
+		WHERE route_id = $1 AND member_id = $2
+	`, routeID, memberID); err != nil {
+		return fmt.Errorf("delete tracking waitlist entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetTrackingWaitlist loads the route's queue in FIFO order. Entries of members who no longer wait
+// for a slot and reservations that ran out are left for cleanup and not returned.
+func (r *PostgresRepository) GetTrackingWaitlist(ctx context.Context, routeID string) ([]TrackingWaitlistEntry, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT w.member_id, w.queued_at, w.reserved_until
+		FROM tracking_waitlist w
+		INNER JOIN route_members m ON m.id = w.member_id
+		WHERE w.route_id = $1
+			AND m.status IN ($2, $3)
+			AND (w.reserved_until IS NULL OR w.reserved_until > NOW())
+		ORDER BY w.id
+	`, routeID, MemberStatusSpectating, MemberStatusOffline)
+	if err != nil {
+		return nil, fmt.Errorf("query tracking waitlist: %w", err)
+	}
+	defer rows.Close()
+
+	waitlist := []TrackingWaitlistEntry{}
+	for rows.Next() {
+		var entry TrackingWaitlistEntry
+		if err := rows.Scan(&entry.MemberID, &entry.QueuedAt, &entry.ReservedUntil); err != nil {
+			return nil, fmt.Errorf("scan tracking waitlist entry: %w", err)
+		}
+		waitlist = append(waitlist, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tracking waitlist: %w", err)
+	}
+
+	return waitlist, nil
+}
+
+// ReserveTrackingSlots holds each free tracking slot for the next queued spectator until reservedUntil.
+// It runs under the same route row lock as StartTrackingMember, first dropping entries of members who
+// started tracking, left, or let their reservation run out.
+func (r *PostgresRepository) ReserveTrackingSlots(ctx context.Context, routeID string, reservedUntil time.Time) ([]TrackingReservation, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("begin reserve tracking slots tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var maxTrackingMembers int
+	var routeStatus string
+	if err := tx.QueryRow(ctx, `
+		SELECT max_tracking_members, status
+		FROM routes
+		WHERE id = $1
+		FOR NO KEY UPDATE
+	`, routeID).Scan(&maxTrackingMembers, &routeStatus); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRouteNotFound
+		}
+
+		return nil, fmt.Errorf("lock route tracking slots: %w", err)
+	}
+
+	if routeStatus != RouteStatusActive {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM tracking_waitlist w
+		USING route_members m
+		WHERE m.id = w.member_id
+			AND w.route_id = $1
+			AND (m.status NOT IN ($2, $3) OR w.reserved_until <= NOW())
+	`, routeID, MemberStatusSpectating, MemberStatusOffline); err != nil {
+		return nil, fmt.Errorf("clean tracking waitlist: %w", err)
+	}
+
+	var takenSlots int
+	if err := tx.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM route_members WHERE route_id = $1 AND status IN ($2, $3)) +
+			(SELECT COUNT(*) FROM tracking_waitlist WHERE route_id = $1 AND reserved_until IS NOT NULL)
+	`, routeID, MemberStatusTracking, MemberStatusStale).Scan(&takenSlots); err != nil {
+		return nil, fmt.Errorf("count tracking slots: %w", err)
+	}
+
+	freeSlots := maxTrackingMembers - takenSlots
+	if freeSlots <= 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE tracking_waitlist
+		SET reserved_until = $2
+		WHERE id IN (
+			SELECT w.id
+			FROM tracking_waitlist w
+			INNER JOIN route_members m ON m.id = w.member_id
+			WHERE w.route_id = $1 AND w.reserved_until IS NULL AND m.status = $3
+			ORDER BY w.id
+			LIMIT $4
+		)
+		RETURNING member_id, reserved_until
+	`, routeID, reservedUntil, MemberStatusSpectating, freeSlots)
+	if err != nil {
+		return nil, fmt.Errorf("reserve tracking slots: %w", err)
+	}
+
+	var reservations []TrackingReservation
+	for rows.Next() {
+		reservation := TrackingReservation{RouteID: routeID}
+		if err := rows.Scan(&reservation.MemberID, &reservation.ReservedUntil); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan tracking reservation: %w", err)
+		}
+		reservations = append(reservations, reservation)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tracking reservations: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit reserve tracking slots tx: %w", err)
+	}
+
+	return reservations, nil
+}
+
+// ExpireTrackingReservations removes waitlist entries whose reservation ran out unclaimed and returns
+// the affected route IDs.
+func (r *PostgresRepository) ExpireTrackingReservations(ctx context.Context) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH expired AS (
+			DELETE FROM tracking_waitlist
+			WHERE reserved_until <= NOW()
+			RETURNING route_id
+		)
+		SELECT DISTINCT route_id FROM expired
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("delete expired tracking reservations: %w", err)
+	}
+	defer rows.Close()
+
+	var routeIDs []string
+	for rows.Next() {
+		var routeID string
+		if err := rows.Scan(&routeID); err != nil {
+			return nil, fmt.Errorf("scan expired reservation route: %w", err)
+		}
+		routeIDs = append(routeIDs, routeID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate expired reservation routes: %w", err)
+	}
+
+	return routeIDs, nil
+}
+
+// TransferOwnership moves the owner flag to another member, revokes every owner token for the route,
+// and stores the recipient's new owner token in one transaction.
+func (r *PostgresRepository) TransferOwnership(ctx context.Context, params TransferOwnershipRepoParams) (TransferOwnershipRepoResult, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return TransferOwnershipRepoResult{}, fmt.Errorf("begin transfer ownership tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var result TransferOwnershipRepoResult
+	if err := scanMember(tx.QueryRow(ctx, `
+		UPDATE route_members
+		SET is_owner = FALSE
+		WHERE id = $1 AND route_id = $2 AND is_owner
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
+	`, params.FromMemberID, params.RouteID), &result.PreviousOwner); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TransferOwnershipRepoResult{}, ErrUnauthorized
+		}
+
+		return TransferOwnershipRepoResult{}, fmt.Errorf("demote previous owner: %w", err)
+	}
+
+	if err := scanMember(tx.QueryRow(ctx, `
+		UPDATE route_members
+		SET is_owner = TRUE, is_moderator = FALSE
+		WHERE id = $1 AND route_id = $2 AND status NOT IN ($3, $4)
+		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
+	`, params.ToMemberID, params.RouteID, MemberStatusLeft, MemberStatusRemoved), &result.Owner); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TransferOwnershipRepoResult{}, ErrInvalidInput
+		}
+
+		return TransferOwnershipRepoResult{}, fmt.Errorf("promote new owner: %w", err)
+	}
+
+	if err := replaceOwnerToken(ctx, tx, params.RouteID, params.ToMemberID, params.OwnerTokenHash); err != nil {
+		return TransferOwnershipRepoResult{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return TransferOwnershipRepoResult{}, fmt.Errorf("commit transfer ownership tx: %w", err)
+	}
+
+	return result, nil
+}
+
+// RotateOwnerToken revokes every owner token for the route and stores a new one for its owner.
+func (r *PostgresRepository) RotateOwnerToken(ctx context.Context, routeID, memberID, tokenHash string) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin rotate owner token tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var isOwner bool
+	if err := tx.QueryRow(ctx, `
+		SELECT is_owner
+		FROM route_members
+		WHERE id = $1 AND route_id = $2
+		FOR UPDATE
+	`, memberID, routeID).Scan(&isOwner); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUnauthorized
+		}
+
+		return fmt.Errorf("lock route owner: %w", err)
+	}
+
+	if !isOwner {
+		return ErrUnauthorized
+	}
+
+	if err := replaceOwnerToken(ctx, tx, routeID, memberID, tokenHash); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit rotate owner token tx: %w", err)
+	}
+
+	return nil
+}
+
+func replaceOwnerToken(ctx context.Context, tx pgx.Tx, routeID, memberID, tokenHash string) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE owner_tokens
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE route_id = $1 AND revoked_at IS NULL
+	`, routeID); err != nil {
+		return fmt.Errorf("revoke owner tokens: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO owner_tokens (route_id, member_id, token_hash)
+		VALUES ($1, $2, $3)
+	`, routeID, memberID, tokenHash); err != nil {
+		return mapDatabaseError(fmt.Errorf("insert owner token: %w", err))
+	}
+
+	return nil
+}
+
+func scanMember(row pgx.Row, member *Member) error {
+	return row.Scan(
+		&member.ID,
+		&member.RouteID,
+		&member.ClientID,
+		&member.DisplayName,
+		&member.TransportMode,
+		&member.IsOwner,
+		&member.IsModerator,
+		&member.CanTrack,
+		&member.Status,
+		&member.Color,
+		&member.JoinedAt,
+		&member.LeftAt,
+	)
+}
+
+// IsClientBanned reports whether the owner banned a client ID from the route.
+func (r *PostgresRepository) IsClientBanned(ctx context.Context, routeID, clientID string) (bool, error) {
+	var banned bool
+	if err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM route_member_bans
+			WHERE route_id = $1 AND client_id = $2
+		)
+	`, routeID, clientID).Scan(&banned); err != nil {
+		return false, fmt.Errorf("check client ban: %w", err)
+	}
+
+	return banned, nil
+}
+
+// updateMemberStatus moves a member between statuses. With deadlines set, the update also requires
+// the same overdue condition as ListPresenceCandidates, measured against the row as it is now.
+func (r *PostgresRepository) updateMemberStatus(ctx context.Context, routeID, memberID string, fromStatuses []string, toStatus, closeReason string, deadlines *PresenceDeadlines) (Member, bool, error) {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return Member{}, false, fmt.Errorf("begin update member status tx: %w", err)
@@ -890,19 +2714,49 @@ func (r *PostgresRepository) updateMemberStatus(ctx context.Context, routeID, me
 		_ = tx.Rollback(ctx)
 	}()
 
+	var staleBefore, staleOfflineBefore, spectatorOfflineBefore *time.Time
+	if deadlines != nil {
+		staleBefore = &deadlines.TrackingStaleBefore
+		staleOfflineBefore = &deadlines.StaleOfflineBefore
+		spectatorOfflineBefore = &deadlines.SpectatorOfflineBefore
+	}
+
 	var member Member
 	err = tx.QueryRow(ctx, `
-		UPDATE route_members
-		SET status = $3
-		WHERE id = $1 AND route_id = $2 AND status = ANY($4)
-		RETURNING id, route_id, client_id, display_name, transport_mode, is_owner, status, color, joined_at, left_at
-	`, memberID, routeID, toStatus, fromStatuses).Scan(
+		UPDATE route_members m
+		SET status = $3, status_changed_at = NOW()
+		WHERE m.id = $1 AND m.route_id = $2 AND m.status = ANY($4)
+			AND (
+				$5::TIMESTAMPTZ IS NULL
+				OR (m.status = $8 AND GREATEST(m.status_changed_at, (
+					SELECT MAX(p.recorded_at)
+					FROM position_points p
+					WHERE p.route_id = m.route_id AND p.member_id = m.id
+				)) < $5)
+				OR (m.status = $9 AND m.status_changed_at < $6)
+				OR (m.status = $10 AND GREATEST(m.status_changed_at, m.last_seen_at) < $7)
+			)
+		RETURNING m.id, m.route_id, m.client_id, m.display_name, m.transport_mode, m.is_owner, m.is_moderator, m.can_track, m.status, m.color, m.joined_at, m.left_at
+	`,
+		memberID,
+		routeID,
+		toStatus,
+		fromStatuses,
+		staleBefore,
+		staleOfflineBefore,
+		spectatorOfflineBefore,
+		MemberStatusTracking,
+		MemberStatusStale,
+		MemberStatusSpectating,
+	).Scan(
 		&member.ID,
 		&member.RouteID,
 		&member.ClientID,
 		&member.DisplayName,
 		&member.TransportMode,
 		&member.IsOwner,
+		&member.IsModerator,
+		&member.CanTrack,
 		&member.Status,
 		&member.Color,
 		&member.JoinedAt,
@@ -937,7 +2791,7 @@ func (r *PostgresRepository) updateMemberStatus(ctx context.Context, routeID, me
 func (r *PostgresRepository) getMemberByID(ctx context.Context, tx pgx.Tx, routeID, memberID string) (Member, error) {
 	var member Member
 	if err := tx.QueryRow(ctx, `
-		SELECT id, route_id, client_id, display_name, transport_mode, is_owner, status, color, joined_at, left_at
+		SELECT id, route_id, client_id, display_name, transport_mode, is_owner, is_moderator, can_track, status, color, joined_at, left_at
 		FROM route_members
 		WHERE id = $1 AND route_id = $2
 	`, memberID, routeID).Scan(
@@ -947,6 +2801,8 @@ func (r *PostgresRepository) getMemberByID(ctx context.Context, tx pgx.Tx, route
 		&member.DisplayName,
 		&member.TransportMode,
 		&member.IsOwner,
+		&member.IsModerator,
+		&member.CanTrack,
 		&member.Status,
 		&member.Color,
 		&member.JoinedAt,
@@ -979,6 +2835,32 @@ func (r *PostgresRepository) DeleteRoute(ctx context.Context, routeID string) er
 	return nil
 }
 
+type routePointOptionalColumns struct {
+	accuracy         sql.NullFloat64
+	altitude         sql.NullFloat64
+	speed            sql.NullFloat64
+	heading          sql.NullFloat64
+	clientRecordedAt sql.NullTime
+}
+
+func (c routePointOptionalColumns) apply(point *RoutePoint) {
+	if c.accuracy.Valid {
+		point.AccuracyM = &c.accuracy.Float64
+	}
+	if c.altitude.Valid {
+		point.AltitudeM = &c.altitude.Float64
+	}
+	if c.speed.Valid {
+		point.SpeedMPS = &c.speed.Float64
+	}
+	if c.heading.Valid {
+		point.HeadingDeg = &c.heading.Float64
+	}
+	if c.clientRecordedAt.Valid {
+		point.ClientRecordedAt = &c.clientRecordedAt.Time
+	}
+}
+
 func mapDatabaseError(err error) error {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
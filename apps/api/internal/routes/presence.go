@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// PresenceEventMemberBecameStale is emitted when a tracking member stops sending points.
+	PresenceEventMemberBecameStale = "member_became_stale"
+	// PresenceEventMemberWentOffline is emitted when a stale or disconnected spectating member goes offline.
+	PresenceEventMemberWentOffline = "member_went_offline"
+)
+
+// PresenceRules holds the presence timeouts enforced by the sweeper.
+type PresenceRules struct {
+	TrackingStaleAfter    time.Duration
+	TrackingOfflineAfter  time.Duration
+	SpectatorOfflineAfter time.Duration
+}
+
+// ConnectionChecker reports whether a member currently holds a live connection on any API instance.
+type ConnectionChecker interface {
+	HasMemberConnection(ctx context.Context, routeID, memberID string) (bool, error)
+}
+
+// PresenceCandidate is a member whose presence deadline has passed according to persisted timestamps.
+type PresenceCandidate struct {
+	RouteID  string
+	MemberID string
+	Status   string
+}
+
+// PresenceTransition is one status change applied by the presence sweeper.
+type PresenceTransition struct {
+	RouteID string
+	Type    string
+	Member  Member
+}
+
+// RecordMemberSeen stamps the member's last live connection activity.
+func (s *Service) RecordMemberSeen(ctx context.Context, routeID, memberID string) error {
+	if err := s.repo.RecordMemberSeen(ctx, routeID, memberID); err != nil {
+		return fmt.Errorf("record member seen: %w", err)
+	}
+
+	return nil
+}
+
+// SweepPresence applies overdue stale and offline transitions from persisted timestamps, so presence
+// converges even when the connection-scoped timers died with an API process. Only one replica sweeps
+// at a time; the others return no transitions.
+func (s *Service) SweepPresence(ctx context.Context, rules PresenceRules, connections ConnectionChecker) ([]PresenceTransition, error) {
+	var transitions []PresenceTransition
+	_, err := s.repo.WithPresenceSweepLock(ctx, func(ctx context.Context) error {
+		now := s.now().UTC()
+		deadlines := PresenceDeadlines{
+			TrackingStaleBefore:    now.Add(-rules.TrackingStaleAfter),
+			StaleOfflineBefore:     now.Add(-rules.TrackingOfflineAfter),
+			SpectatorOfflineBefore: now.Add(-rules.SpectatorOfflineAfter),
+		}
+		candidates, err := s.repo.ListPresenceCandidates(ctx, deadlines)
+		if err != nil {
+			return fmt.Errorf("list presence candidates: %w", err)
+		}
+
+		var errs []error
+		for _, candidate := range candidates {
+			transition, changed, err := s.applyPresenceCandidate(ctx, candidate, deadlines, connections)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if changed {
+				transitions = append(transitions, transition)
+			}
+		}
+
+		return errors.Join(errs...)
+	})
+	if err != nil {
+		return transitions, fmt.Errorf("sweep presence: %w", err)
+	}
+
+	return transitions, nil
+}
+
+// applyPresenceCandidate re-checks the deadlines in the status update itself, because a point or
+// live activity can land between listing a candidate and updating it.
+func (s *Service) applyPresenceCandidate(ctx context.Context, candidate PresenceCandidate, deadlines PresenceDeadlines, connections ConnectionChecker) (PresenceTransition, bool, error) {
+	switch candidate.Status {
+	case MemberStatusTracking:
+		member, changed, err := s.repo.MarkOverdueMemberStale(ctx, candidate.RouteID, candidate.MemberID, deadlines)
+		if err != nil {
+			return PresenceTransition{}, false, fmt.Errorf("mark member %s stale: %w", candidate.MemberID, err)
+		}
+
+		return PresenceTransition{RouteID: candidate.RouteID, Type: PresenceEventMemberBecameStale, Member: member}, changed, nil
+	case MemberStatusSpectating:
+		connected, err := connections.HasMemberConnection(ctx, candidate.RouteID, candidate.MemberID)
+		if err != nil {
+			return PresenceTransition{}, false, fmt.Errorf("check member %s connection: %w", candidate.MemberID, err)
+		}
+		if connected {
+			return PresenceTransition{}, false, nil
+		}
+		fallthrough
+	case MemberStatusStale:
+		member, changed, err := s.repo.MarkOverdueMemberOffline(ctx, candidate.RouteID, candidate.MemberID, deadlines)
+		if err != nil {
+			return PresenceTransition{}, false, fmt.Errorf("mark member %s offline: %w", candidate.MemberID, err)
+		}
+
+		return PresenceTransition{RouteID: candidate.RouteID, Type: PresenceEventMemberWentOffline, Member: member}, changed, nil
+	default:
+		return PresenceTransition{}, false, nil
+	}
+}
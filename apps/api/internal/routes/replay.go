@@ -0,0 +1,521 @@
+package routes
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReplayLimit = 500
+	maxReplayLimit     = 2000
+)
+
+var validReplaySpeeds = map[int]struct{}{
+	1:  {},
+	2:  {},
+	4:  {},
+	8:  {},
+	16: {},
+}
+
+const (
+	replayOrderJoined = iota
+	replayOrderStarted
+	replayOrderTransportChanged
+	replayOrderPosition
+	replayOrderChat
+	replayOrderStopped
+	replayOrderLeft
+	replayOrderClosed
+)
+
+// ReplayQuery contains replay seek, paging, and playback speed controls.
+type ReplayQuery struct {
+	From   *time.Time
+	Speed  int
+	Cursor string
+	Limit  int
+}
+
+// ReplayPage contains one chronological page of a closed route timeline.
+type ReplayPage struct {
+	RouteID    string
+	StartedAt  time.Time
+	EndedAt    *time.Time
+	Speed      int
+	Events     []ReplayEvent
+	NextCursor string
+}
+
+// ReplayEvent is one historical fact in the shape of the matching live event.
+type ReplayEvent struct {
+	Type             string
+	At               time.Time
+	PlaybackOffsetMS int64
+	MemberID         string
+	SegmentID        string
+	Member           *Member
+	Segment          *PathSegment
+	Point            *RoutePoint
+	Route            *Route
+	Message          *ChatMessage
+	TransportChange  *TransportChange
+
+	order  int
+	keyID  string
+	keySeq int64
+}
+
+// replayCursor is the position of one event in timeline order: time, event kind, then the ID (and
+// point seq) of the row the event came from.
+type replayCursor struct {
+	at    time.Time
+	order int
+	id    string
+	seq   int64
+}
+
+// ListReplayPointsRepoParams requests the next points of a route in replay order, which is client
+// time (or server time when the client sent none), then segment and seq.
+type ListReplayPointsRepoParams struct {
+	RouteID        string
+	AfterAt        time.Time
+	AfterSegmentID string
+	AfterSeq       int64
+	Limit          int
+}
+
+// ListReplayChatMessagesRepoParams requests the next chat messages of a route in (posted_at, id)
+// order.
+type ListReplayChatMessagesRepoParams struct {
+	RouteID string
+	AfterAt time.Time
+	AfterID string
+	Limit   int
+}
+
+// UUID bounds used to turn "at or after" and "strictly after" a time into keyset conditions.
+const (
+	minReplayUUID = "00000000-0000-0000-0000-000000000000"
+	maxReplayUUID = "ffffffff-ffff-ffff-ffff-ffffffffffff"
+)
+
+// Replay returns closed route history as chronological live-shaped events. Members, path segment
+// headers, and transport changes grow with member actions and are loaded whole; position points and
+// chat messages grow with the length of the route and are read only from the page's cursor onward.
+func (s *Service) Replay(ctx context.Context, code, memberToken string, query ReplayQuery) (ReplayPage, error) {
+	if strings.TrimSpace(memberToken) == "" {
+		return ReplayPage{}, ErrUnauthorized
+	}
+
+	normalized, cursor, err := normalizeReplayQuery(query)
+	if err != nil {
+		return ReplayPage{}, err
+	}
+
+	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
+	if err != nil {
+		return ReplayPage{}, err
+	}
+	if authorized.Member.Status == MemberStatusLeft {
+		return ReplayPage{}, ErrUnauthorized
+	}
+
+	if normalizeCode(code) != authorized.Route.Code {
+		return ReplayPage{}, ErrUnauthorized
+	}
+
+	if authorized.Route.Status != RouteStatusClosed {
+		return ReplayPage{}, ErrRouteActive
+	}
+
+	members, err := s.repo.GetMembersByRouteID(ctx, authorized.Route.ID)
+	if err != nil {
+		return ReplayPage{}, fmt.Errorf("load replay members: %w", err)
+	}
+
+	pathsByMemberID, err := s.repo.GetPathSegmentHeadersByRouteID(ctx, authorized.Route.ID)
+	if err != nil {
+		return ReplayPage{}, fmt.Errorf("load replay paths: %w", err)
+	}
+
+	changesByMemberID, err := s.repo.GetTransportChangesByRouteID(ctx, authorized.Route.ID)
+	if err != nil {
+		return ReplayPage{}, fmt.Errorf("load replay transport changes: %w", err)
+	}
+
+	afterSegmentID, afterSeq := cursor.boundFor(replayOrderPosition)
+	points, err := s.repo.ListReplayPoints(ctx, ListReplayPointsRepoParams{
+		RouteID:        authorized.Route.ID,
+		AfterAt:        cursor.at,
+		AfterSegmentID: afterSegmentID,
+		AfterSeq:       afterSeq,
+		Limit:          normalized.Limit + 1,
+	})
+	if err != nil {
+		return ReplayPage{}, fmt.Errorf("load replay points: %w", err)
+	}
+
+	afterMessageID, _ := cursor.boundFor(replayOrderChat)
+	messages, err := s.repo.ListReplayChatMessages(ctx, ListReplayChatMessagesRepoParams{
+		RouteID: authorized.Route.ID,
+		AfterAt: cursor.at,
+		AfterID: afterMessageID,
+		Limit:   normalized.Limit + 1,
+	})
+	if err != nil {
+		return ReplayPage{}, fmt.Errorf("load replay chat: %w", err)
+	}
+
+	timeline := buildReplayTimeline(authorized.Route, members, pathsByMemberID, changesByMemberID)
+	timeline = append(timeline, replayPointEvents(points)...)
+	timeline = append(timeline, replayChatEvents(messages)...)
+	events := make([]ReplayEvent, 0, len(timeline))
+	for _, event := range timeline {
+		if replayEventAfter(event, cursor) {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(left, right int) bool {
+		return replayEventBefore(events[left], events[right])
+	})
+
+	page := ReplayPage{
+		RouteID:   authorized.Route.ID,
+		StartedAt: authorized.Route.CreatedAt,
+		EndedAt:   authorized.Route.ClosedAt,
+		Speed:     normalized.Speed,
+	}
+	if len(events) > normalized.Limit {
+		events = events[:normalized.Limit]
+		page.NextCursor = encodeReplayCursor(events[len(events)-1])
+	}
+
+	origin := authorized.Route.CreatedAt
+	if normalized.From != nil {
+		origin = *normalized.From
+	}
+	for index := range events {
+		events[index].PlaybackOffsetMS = max(events[index].At.Sub(origin), 0).Milliseconds() / int64(normalized.Speed)
+	}
+	page.Events = events
+
+	return page, nil
+}
+
+func normalizeReplayQuery(query ReplayQuery) (ReplayQuery, replayCursor, error) {
+	normalized := query
+	if normalized.Speed == 0 {
+		normalized.Speed = 1
+	}
+	if _, ok := validReplaySpeeds[normalized.Speed]; !ok {
+		return ReplayQuery{}, replayCursor{}, ErrInvalidInput
+	}
+
+	if normalized.Limit == 0 {
+		normalized.Limit = defaultReplayLimit
+	}
+	if normalized.Limit < 0 || normalized.Limit > maxReplayLimit {
+		return ReplayQuery{}, replayCursor{}, ErrInvalidInput
+	}
+
+	// Without a cursor the page starts with every event at or after from (or the beginning).
+	cursor := replayCursor{order: -1}
+	if normalized.From != nil {
+		cursor.at = *normalized.From
+	}
+	normalized.Cursor = strings.TrimSpace(normalized.Cursor)
+	if normalized.Cursor != "" {
+		decoded, err := decodeReplayCursor(normalized.Cursor)
+		if err != nil {
+			return ReplayQuery{}, replayCursor{}, ErrInvalidInput
+		}
+		cursor = decoded
+	}
+
+	return normalized, cursor, nil
+}
+
+func encodeReplayCursor(event ReplayEvent) string {
+	raw := strconv.FormatInt(event.At.UnixNano(), 10) + "|" + strconv.Itoa(event.order) + "|" + event.keyID + "|" + strconv.FormatInt(event.keySeq, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeReplayCursor(value string) (replayCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return replayCursor{}, err
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 4 {
+		return replayCursor{}, errors.New("replay cursor needs four parts")
+	}
+	at, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return replayCursor{}, err
+	}
+	order, err := strconv.Atoi(parts[1])
+	if err != nil || order < replayOrderJoined || order > replayOrderClosed {
+		return replayCursor{}, errors.New("replay cursor has an unknown event order")
+	}
+	if !isCanonicalUUID(parts[2]) {
+		return replayCursor{}, errors.New("replay cursor needs a row ID")
+	}
+	seq, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return replayCursor{}, err
+	}
+
+	return replayCursor{at: time.Unix(0, at).UTC(), order: order, id: parts[2], seq: seq}, nil
+}
+
+// isCanonicalUUID reports whether value is a UUID in the lowercase form Postgres prints, so a
+// tampered cursor is rejected before it reaches a UUID cast.
+func isCanonicalUUID(value string) bool {
+	if len(value) != len(minReplayUUID) {
+		return false
+	}
+	for index, char := range value {
+		switch index {
+		case 8, 13, 18, 23:
+			if char != '-' {
+				return false
+			}
+		default:
+			if (char < '0' || char > '9') && (char < 'a' || char > 'f') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// boundFor returns the (id, seq) keyset bound for a source whose events all have the given order:
+// the cursor's own key when the cursor sits in that source, otherwise a bound that includes (order
+// after the cursor) or excludes (order before it) every row at the cursor's time.
+func (c replayCursor) boundFor(order int) (string, int64) {
+	switch {
+	case c.order < order:
+		return minReplayUUID, -1
+	case c.order > order:
+		return maxReplayUUID, math.MaxInt64
+	default:
+		return c.id, c.seq
+	}
+}
+
+func replayEventAfter(event ReplayEvent, cursor replayCursor) bool {
+	return compareReplayKeys(event.At, event.order, event.keyID, event.keySeq, cursor.at, cursor.order, cursor.id, cursor.seq) > 0
+}
+
+func replayEventBefore(left, right ReplayEvent) bool {
+	return compareReplayKeys(left.At, left.order, left.keyID, left.keySeq, right.At, right.order, right.keyID, right.keySeq) < 0
+}
+
+func compareReplayKeys(leftAt time.Time, leftOrder int, leftID string, leftSeq int64, rightAt time.Time, rightOrder int, rightID string, rightSeq int64) int {
+	if c := leftAt.Compare(rightAt); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(leftOrder, rightOrder); c != 0 {
+		return c
+	}
+	if c := strings.Compare(leftID, rightID); c != 0 {
+		return c
+	}
+
+	return cmp.Compare(leftSeq, rightSeq)
+}
+
+// buildReplayTimeline returns the events derived from memberships, path segment headers, transport
+// changes, and the route itself. Position and chat events come from their own paged queries.
+func buildReplayTimeline(route Route, members []Member, pathsByMemberID map[string][]PathSegment, changesByMemberID map[string][]TransportChange) []ReplayEvent {
+	timeline := make([]ReplayEvent, 0)
+
+	for _, member := range members {
+		changes := changesByMemberID[member.ID]
+		initialMode := initialTransportMode(member.TransportMode, changes)
+		modeAt := func(at time.Time) string {
+			return transportModeAt(initialMode, changes, at)
+		}
+
+		joined := member
+		joined.Status = MemberStatusSpectating
+		joined.TransportMode = initialMode
+		joined.LeftAt = nil
+		timeline = append(timeline, ReplayEvent{
+			Type:     "member_joined",
+			At:       member.JoinedAt,
+			MemberID: member.ID,
+			Member:   &joined,
+			order:    replayOrderJoined,
+			keyID:    member.ID,
+		})
+
+		for _, path := range pathsByMemberID[member.ID] {
+			timeline = append(timeline, replaySegmentEvents(member, path, modeAt)...)
+		}
+
+		for index := range changes {
+			change := changes[index]
+			changed := member
+			changed.Status = replayStatusAt(pathsByMemberID[member.ID], change.ChangedAt)
+			changed.TransportMode = change.TransportMode
+			changed.LeftAt = nil
+			timeline = append(timeline, ReplayEvent{
+				Type:            "member_transport_changed",
+				At:              change.ChangedAt,
+				MemberID:        member.ID,
+				Member:          &changed,
+				TransportChange: &change,
+				order:           replayOrderTransportChanged,
+				keyID:           member.ID,
+				keySeq:          int64(index),
+			})
+		}
+
+		if member.LeftAt != nil {
+			left := member
+			left.Status = MemberStatusLeft
+			eventType := "member_left"
+			if member.Status == MemberStatusRemoved {
+				left.Status = MemberStatusRemoved
+				eventType = "member_removed"
+			}
+			timeline = append(timeline, ReplayEvent{
+				Type:     eventType,
+				At:       *member.LeftAt,
+				MemberID: member.ID,
+				Member:   &left,
+				order:    replayOrderLeft,
+				keyID:    member.ID,
+			})
+		}
+	}
+
+	if route.ClosedAt != nil {
+		closed := route
+		timeline = append(timeline, ReplayEvent{
+			Type:  "route_closed",
+			At:    *route.ClosedAt,
+			Route: &closed,
+			order: replayOrderClosed,
+			keyID: route.ID,
+		})
+	}
+
+	return timeline
+}
+
+// replayPointEvents places backfilled points at the moment the client recorded them, since they
+// were stored at upload time; ListReplayPoints pages in the same order.
+func replayPointEvents(points []SegmentPoint) []ReplayEvent {
+	events := make([]ReplayEvent, 0, len(points))
+	for index := range points {
+		point := points[index].RoutePoint
+		events = append(events, ReplayEvent{
+			Type:      "position_updated",
+			At:        pointTime(point),
+			MemberID:  points[index].MemberID,
+			SegmentID: points[index].SegmentID,
+			Point:     &point,
+			order:     replayOrderPosition,
+			keyID:     points[index].SegmentID,
+			keySeq:    point.Seq,
+		})
+	}
+
+	return events
+}
+
+func replayChatEvents(messages []ChatMessage) []ReplayEvent {
+	events := make([]ReplayEvent, 0, len(messages))
+	for index := range messages {
+		message := messages[index]
+		events = append(events, ReplayEvent{
+			Type:     "chat_message_posted",
+			At:       message.PostedAt,
+			MemberID: message.MemberID,
+			Message:  &message,
+			order:    replayOrderChat,
+			keyID:    message.ID,
+		})
+	}
+
+	return events
+}
+
+// replayStatusAt approximates a member's status at a moment from their path segments: tracking
+// inside a segment, spectating otherwise.
+func replayStatusAt(paths []PathSegment, at time.Time) string {
+	for _, path := range paths {
+		if path.StartedAt != nil && !path.StartedAt.After(at) && (path.EndedAt == nil || path.EndedAt.After(at)) {
+			return MemberStatusTracking
+		}
+	}
+
+	return MemberStatusSpectating
+}
+
+func replaySegmentEvents(member Member, path PathSegment, modeAt func(time.Time) string) []ReplayEvent {
+	events := make([]ReplayEvent, 0, 2)
+
+	if path.StartedAt != nil {
+		started := member
+		started.Status = MemberStatusTracking
+		started.TransportMode = modeAt(*path.StartedAt)
+		segment := PathSegment{
+			ID:        path.ID,
+			StartedAt: path.StartedAt,
+			Points:    []RoutePoint{},
+		}
+		events = append(events, ReplayEvent{
+			Type:      "member_started_sharing",
+			At:        *path.StartedAt,
+			MemberID:  member.ID,
+			SegmentID: path.ID,
+			Member:    &started,
+			Segment:   &segment,
+			order:     replayOrderStarted,
+			keyID:     path.ID,
+		})
+	}
+
+	if path.EndedAt == nil {
+		return events
+	}
+
+	ended := member
+	ended.TransportMode = modeAt(*path.EndedAt)
+	eventType := ""
+	switch path.EndReason {
+	case PathSegmentEndReasonStopped, PathSegmentEndReasonPermissionRevoked:
+		eventType = "member_stopped_sharing"
+		ended.Status = MemberStatusSpectating
+	case PathSegmentEndReasonDisconnected:
+		eventType = "member_went_offline"
+		ended.Status = MemberStatusOffline
+	default:
+		// Left, removed, and route_closed endings are replayed through member_left, member_removed, and route_closed.
+		return events
+	}
+
+	return append(events, ReplayEvent{
+		Type:      eventType,
+		At:        *path.EndedAt,
+		MemberID:  member.ID,
+		SegmentID: path.ID,
+		Member:    &ended,
+		order:     replayOrderStopped,
+		keyID:     path.ID,
+	})
+}
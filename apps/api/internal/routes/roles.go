@@ -0,0 +1,134 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Permission names one route management action.
+type Permission string
+
+const (
+	PermissionEditRoute         Permission = "edit_route"
+	PermissionCloseRoute        Permission = "close_route"
+	PermissionDeleteRoute       Permission = "delete_route"
+	PermissionRemoveMembers     Permission = "remove_members"
+	PermissionGrantTracking     Permission = "grant_tracking"
+	PermissionAssignRoles       Permission = "assign_roles"
+	PermissionTransferOwnership Permission = "transfer_ownership"
+)
+
+var rolePermissions = map[string]map[Permission]bool{
+	RoleOwner: {
+		PermissionEditRoute:         true,
+		PermissionCloseRoute:        true,
+		PermissionDeleteRoute:       true,
+		PermissionRemoveMembers:     true,
+		PermissionGrantTracking:     true,
+		PermissionAssignRoles:       true,
+		PermissionTransferOwnership: true,
+	},
+	RoleModerator: {
+		PermissionEditRoute:     true,
+		PermissionRemoveMembers: true,
+		PermissionGrantTracking: true,
+	},
+}
+
+var roleRanks = map[string]int{
+	RoleMember:    0,
+	RoleModerator: 1,
+	RoleOwner:     2,
+}
+
+// SetModeratorResult contains the member after a role change. Changed is false when the member
+// already had the requested role.
+type SetModeratorResult struct {
+	Member  Member `json:"member"`
+	Changed bool   `json:"changed"`
+	Actor   Member `json:"-"`
+}
+
+// memberRole returns the management role stored on the membership.
+func memberRole(member Member) string {
+	switch {
+	case member.IsOwner:
+		return RoleOwner
+	case member.IsModerator:
+		return RoleModerator
+	default:
+		return RoleMember
+	}
+}
+
+// roleAllows reports whether the role grants the permission.
+func roleAllows(role string, permission Permission) bool {
+	return rolePermissions[role][permission]
+}
+
+// outranks reports whether a member with actorRole may act on the target member.
+func outranks(actorRole string, target Member) bool {
+	return roleRanks[actorRole] > roleRanks[memberRole(target)]
+}
+
+// authorizeRouteAction resolves a bearer token for a management action. Owner tokens act with the
+// owner role; member tokens act with the member's stored role, so moderators use their member token
+// while owner-only actions keep requiring the owner token.
+func (s *Service) authorizeRouteAction(ctx context.Context, code, token string, permission Permission) (AuthorizedMember, string, error) {
+	if strings.TrimSpace(token) == "" {
+		return AuthorizedMember{}, "", ErrUnauthorized
+	}
+
+	role := RoleOwner
+	authorized, err := s.repo.GetAuthorizedOwnerByTokenHash(ctx, tokenHash(token))
+	if errors.Is(err, ErrUnauthorized) {
+		authorized, err = s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(token))
+		role = RoleMember
+		if authorized.Member.IsModerator {
+			role = RoleModerator
+		}
+	}
+	if err != nil {
+		return AuthorizedMember{}, "", err
+	}
+
+	if normalizeCode(code) != authorized.Route.Code || !roleAllows(role, permission) {
+		return AuthorizedMember{}, "", ErrUnauthorized
+	}
+
+	return authorized, role, nil
+}
+
+// SetModerator lets the route owner promote a member to moderator or demote them back to member.
+func (s *Service) SetModerator(ctx context.Context, code, ownerToken, memberID string, moderator bool) (SetModeratorResult, error) {
+	authorized, _, err := s.authorizeRouteAction(ctx, code, ownerToken, PermissionAssignRoles)
+	if err != nil {
+		return SetModeratorResult{}, err
+	}
+
+	if authorized.Route.Status != RouteStatusActive {
+		return SetModeratorResult{}, ErrRouteClosed
+	}
+
+	target, err := s.routeMember(ctx, authorized.Route.ID, memberID)
+	if err != nil {
+		return SetModeratorResult{}, err
+	}
+
+	if target.IsOwner || target.Status == MemberStatusLeft || target.Status == MemberStatusRemoved {
+		return SetModeratorResult{}, ErrInvalidInput
+	}
+
+	if target.IsModerator == moderator {
+		return SetModeratorResult{Member: target, Actor: authorized.Member}, nil
+	}
+
+	member, err := s.repo.SetMemberModerator(ctx, authorized.Route.ID, target.ID, moderator)
+	if err != nil {
+		return SetModeratorResult{}, fmt.Errorf("set moderator: %w", err)
+	}
+
+	return SetModeratorResult{Member: member, Changed: true, Actor: authorized.Member}, nil
+}
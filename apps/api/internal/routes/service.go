@@ -13,6 +13,9 @@ import (
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
+
+	"keepup/apps/api/internal/config"
+	"keepup/apps/api/internal/metrics"
 )
 
 const (
@@ -33,10 +36,24 @@ var (
 	ErrInvalidInput = errors.New("invalid input")
 	// ErrRouteClosed is returned when a route no longer accepts live mutations.
 	ErrRouteClosed = errors.New("route closed")
+	// ErrRouteActive is returned when an operation needs a closed route archive.
+	ErrRouteActive = errors.New("route still active")
 	// ErrSharingNotAllowed is returned when the route sharing policy forbids tracking.
 	ErrSharingNotAllowed = errors.New("sharing not allowed")
+	// ErrMemberNotFound is returned when a member does not belong to the route.
+	ErrMemberNotFound = errors.New("member not found")
+	// ErrMemberBanned is returned when a client the owner banned tries to rejoin the route.
+	ErrMemberBanned = errors.New("member banned")
 	// ErrTrackingLimitReached is returned when all active tracking slots are occupied.
 	ErrTrackingLimitReached = errors.New("tracking limit reached")
+	// ErrChatMessageTooLong is returned when a chat message exceeds the configured length.
+	ErrChatMessageTooLong = errors.New("chat message too long")
+	// ErrChatRateLimited is returned when a member posts chat messages faster than allowed.
+	ErrChatRateLimited = errors.New("chat rate limited")
+	// ErrPositionRejected is returned when a position sample fails GPS validation.
+	ErrPositionRejected = errors.New("position rejected")
+	// ErrEventCatchUpUnavailable is returned when missed route events can no longer be replayed.
+	ErrEventCatchUpUnavailable = errors.New("event catch-up unavailable")
 )
 
 var palette = []string{
@@ -61,6 +78,24 @@ type Repository interface {
 	GetAuthorizedOwnerByTokenHash(context.Context, string) (AuthorizedMember, error)
 	GetMembersByRouteID(context.Context, string) ([]Member, error)
 	GetPathSegmentsByRouteID(context.Context, string) (map[string][]PathSegment, error)
+	GetPathSegmentSummariesByRouteID(context.Context, string) (map[string][]PathSegment, error)
+	GetPathSegmentHeadersByRouteID(context.Context, string) (map[string][]PathSegment, error)
+	GetPointsBySegmentIDs(context.Context, string, []string) (map[string][]RoutePoint, error)
+	ListRoutePoints(context.Context, ListRoutePointsRepoParams) ([]SegmentPoint, error)
+	ListReplayPoints(context.Context, ListReplayPointsRepoParams) ([]SegmentPoint, error)
+	ChangeMemberTransportMode(context.Context, ChangeTransportModeRepoParams) (Member, TransportChange, error)
+	GetTransportChangesByRouteID(context.Context, string) (map[string][]TransportChange, error)
+	SetMemberTrackingPermission(context.Context, SetTrackingPermissionRepoParams) (SetTrackingPermissionRepoResult, error)
+	RemoveMember(context.Context, RemoveMemberRepoParams) (Member, error)
+	IsClientBanned(context.Context, string, string) (bool, error)
+	SetMemberModerator(context.Context, string, string, bool) (Member, error)
+	JoinTrackingWaitlist(context.Context, string, string) error
+	LeaveTrackingWaitlist(context.Context, string, string) error
+	GetTrackingWaitlist(context.Context, string) ([]TrackingWaitlistEntry, error)
+	ReserveTrackingSlots(context.Context, string, time.Time) ([]TrackingReservation, error)
+	ExpireTrackingReservations(context.Context) ([]string, error)
+	TransferOwnership(context.Context, TransferOwnershipRepoParams) (TransferOwnershipRepoResult, error)
+	RotateOwnerToken(context.Context, string, string, string) error
 	CountMembersByRouteID(context.Context, string) (int, error)
 	CountTrackingMembers(context.Context, string) (int, error)
 	StartTrackingMember(context.Context, string, string) (StartSharingRepoResult, error)
@@ -68,8 +103,29 @@ type Repository interface {
 	MarkMemberOnline(context.Context, string, string) (Member, bool, error)
 	MarkMemberStale(context.Context, string, string) (Member, bool, error)
 	MarkMemberOffline(context.Context, string, string) (Member, bool, error)
+	MarkOverdueMemberStale(context.Context, string, string, PresenceDeadlines) (Member, bool, error)
+	MarkOverdueMemberOffline(context.Context, string, string, PresenceDeadlines) (Member, bool, error)
+	RecordMemberSeen(context.Context, string, string) error
+	ListPresenceCandidates(context.Context, PresenceDeadlines) ([]PresenceCandidate, error)
+	WithPresenceSweepLock(context.Context, func(context.Context) error) (bool, error)
+	GetLastPositionPoint(context.Context, string, string) (RoutePoint, bool, error)
+	ListPositionPointsAround(context.Context, string, string, time.Time, time.Time) ([]RoutePoint, error)
+	ListSnapCandidates(context.Context, int) ([]SnapCandidate, error)
+	GetSegmentPoints(context.Context, string, int64, int64) ([]RoutePoint, error)
+	SaveSnappedGeometry(context.Context, SaveSnappedGeometryRepoParams) error
+	SaveSnapFailure(context.Context, SaveSnapFailureRepoParams) error
 	RecordPosition(context.Context, RecordPositionRepoParams) (PositionUpdateResult, error)
+	RecordPositionBatch(context.Context, RecordPositionBatchRepoParams) (RecordPositionBatchRepoResult, error)
 	UpdateRoute(context.Context, string, UpdateRouteRepoParams) (Route, error)
+	RecordRouteEvent(context.Context, RecordRouteEventRepoParams) (RouteEvent, error)
+	ListRouteEventsAfter(context.Context, ListRouteEventsAfterRepoParams) ([]RouteEvent, error)
+	GetLatestRouteEventID(context.Context, string) (int64, error)
+	RouteEventExists(context.Context, string, int64) (bool, error)
+	DeleteRouteEventsBefore(context.Context, time.Time) (int64, error)
+	CreateChatMessage(context.Context, CreateChatMessageRepoParams) (ChatMessage, error)
+	ListChatMessages(context.Context, ListChatMessagesRepoParams) ([]ChatMessage, error)
+	GetChatMessagesByRouteID(context.Context, string) ([]ChatMessage, error)
+	ListReplayChatMessages(context.Context, ListReplayChatMessagesRepoParams) ([]ChatMessage, error)
 	LeaveMember(context.Context, string) (Member, error)
 	DeleteRoute(context.Context, string) error
 }
@@ -77,15 +133,68 @@ type Repository interface {
 // Service coordinates route business logic.
 type Service struct {
 	defaultMaxTrackingMembers int
+	positionValidators        []PositionValidator
+	chatMessageMaxLength      int
+	chatRateLimitMessages     int
+	chatRateLimitWindow       time.Duration
+	eventCatchUpMaxEvents     int
+	eventCatchUpMaxAge        time.Duration
+	trackingReservationWindow time.Duration
+	simplifiedSegments        *simplifiedSegmentCache
+	metrics                   *metrics.Metrics
+	now                       func() time.Time
 	repo                      Repository
 }
 
+// ServiceOption customizes optional route service collaborators.
+type ServiceOption func(*Service)
+
+// WithPositionValidators replaces the configured GPS validator chain.
+func WithPositionValidators(validators ...PositionValidator) ServiceOption {
+	return func(s *Service) {
+		s.positionValidators = validators
+	}
+}
+
+// WithMetrics records accepted and rejected position updates.
+func WithMetrics(m *metrics.Metrics) ServiceOption {
+	return func(s *Service) {
+		s.metrics = m
+	}
+}
+
 // NewService builds the route service.
-func NewService(repo Repository, defaultMaxTrackingMembers int) *Service {
-	return &Service{
-		defaultMaxTrackingMembers: defaultMaxTrackingMembers,
+func NewService(repo Repository, cfg config.RouteConfig, opts ...ServiceOption) *Service {
+	service := &Service{
+		defaultMaxTrackingMembers: cfg.DefaultMaxTrackingMembers,
+		positionValidators:        DefaultPositionValidators(cfg),
+		chatMessageMaxLength:      cfg.ChatMessageMaxLength,
+		chatRateLimitMessages:     cfg.ChatRateLimitMessages,
+		chatRateLimitWindow:       cfg.ChatRateLimitWindow,
+		eventCatchUpMaxEvents:     cfg.EventCatchUpMaxEvents,
+		eventCatchUpMaxAge:        cfg.EventCatchUpMaxAge,
+		trackingReservationWindow: cfg.TrackingReservationWindow,
+		simplifiedSegments:        newSimplifiedSegmentCache(simplifiedSegmentCacheLimit),
+		now:                       time.Now,
 		repo:                      repo,
 	}
+	if service.chatMessageMaxLength <= 0 {
+		service.chatMessageMaxLength = defaultChatMessageMaxLength
+	}
+	if service.eventCatchUpMaxEvents <= 0 {
+		service.eventCatchUpMaxEvents = defaultEventCatchUpMaxEvents
+	}
+	if service.eventCatchUpMaxAge <= 0 {
+		service.eventCatchUpMaxAge = defaultEventCatchUpMaxAge
+	}
+	if service.trackingReservationWindow <= 0 {
+		service.trackingReservationWindow = defaultTrackingReservationWindow
+	}
+	for _, opt := range opts {
+		opt(service)
+	}
+
+	return service
 }
 
 // CreateRouteRepoParams contains repository route create data.
@@ -145,6 +254,47 @@ type UpdateRouteRepoParams struct {
 	Status      *string
 }
 
+// RecordRouteEventRepoParams contains persistence fields for one route event log entry.
+type RecordRouteEventRepoParams struct {
+	RouteID       string
+	ActorMemberID string
+	Type          string
+	Payload       json.RawMessage
+	OccurredAt    time.Time
+}
+
+// ListRouteEventsAfterRepoParams selects one oldest-first page of the route event log.
+type ListRouteEventsAfterRepoParams struct {
+	RouteID string
+	AfterID int64
+	Limit   int
+}
+
+// CreateChatMessageRepoParams contains persistence fields for one chat message. When
+// RateLimitMessages is positive, the insert fails with ErrChatRateLimited if the member already
+// posted that many messages at or after RateLimitSince.
+type CreateChatMessageRepoParams struct {
+	RouteID           string
+	MemberID          string
+	Body              string
+	RateLimitMessages int
+	RateLimitSince    time.Time
+}
+
+// ListChatMessagesRepoParams selects one newest-first page of route chat.
+type ListChatMessagesRepoParams struct {
+	RouteID  string
+	BeforeID string
+	Limit    int
+}
+
+// PresenceDeadlines contains the presence deadlines for one sweep.
+type PresenceDeadlines struct {
+	TrackingStaleBefore    time.Time
+	StaleOfflineBefore     time.Time
+	SpectatorOfflineBefore time.Time
+}
+
 // StartSharingRepoResult contains the member and opened path segment.
 type StartSharingRepoResult struct {
 	Member  Member
@@ -270,6 +420,15 @@ func (s *Service) JoinRoute(ctx context.Context, code string, input JoinRouteInp
 		}
 	}
 
+	banned, err := s.repo.IsClientBanned(ctx, route.ID, normalized.ClientID)
+	if err != nil {
+		return JoinRouteResult{}, fmt.Errorf("join route check ban: %w", err)
+	}
+
+	if banned {
+		return JoinRouteResult{}, ErrMemberBanned
+	}
+
 	memberCount, err := s.repo.CountMembersByRouteID(ctx, route.ID)
 	if err != nil {
 		return JoinRouteResult{}, fmt.Errorf("join route count all members: %w", err)
@@ -300,19 +459,12 @@ func (s *Service) JoinRoute(ctx context.Context, code string, input JoinRouteInp
 	}, nil
 }
 
-// UpdateRoute updates owner-managed route fields.
-func (s *Service) UpdateRoute(ctx context.Context, code, ownerToken string, input UpdateRouteInput) (Route, error) {
-	if strings.TrimSpace(ownerToken) == "" {
-		return Route{}, ErrUnauthorized
-	}
-
-	authorized, err := s.repo.GetAuthorizedOwnerByTokenHash(ctx, tokenHash(ownerToken))
+// UpdateRoute updates managed route fields. Owners and moderators may edit the name and description;
+// closing the route stays with the owner.
+func (s *Service) UpdateRoute(ctx context.Context, code, token string, input UpdateRouteInput) (UpdateRouteResult, error) {
+	authorized, role, err := s.authorizeRouteAction(ctx, code, token, PermissionEditRoute)
 	if err != nil {
-		return Route{}, err
-	}
-
-	if normalizeCode(code) != authorized.Route.Code {
-		return Route{}, ErrUnauthorized
+		return UpdateRouteResult{}, err
 	}
 
 	params := UpdateRouteRepoParams{}
@@ -329,26 +481,30 @@ func (s *Service) UpdateRoute(ctx context.Context, code, ownerToken string, inpu
 	if input.Status != "" {
 		status := strings.TrimSpace(input.Status)
 		if status != RouteStatusClosed {
-			return Route{}, ErrInvalidInput
+			return UpdateRouteResult{}, ErrInvalidInput
+		}
+
+		if !roleAllows(role, PermissionCloseRoute) {
+			return UpdateRouteResult{}, ErrUnauthorized
 		}
 
 		if authorized.Route.Status == RouteStatusClosed {
-			return Route{}, ErrRouteClosed
+			return UpdateRouteResult{}, ErrRouteClosed
 		}
 
 		params.Status = &status
 	}
 
 	if params.Name == nil && params.Description == nil && params.Status == nil {
-		return Route{}, ErrInvalidInput
+		return UpdateRouteResult{}, ErrInvalidInput
 	}
 
 	updated, err := s.repo.UpdateRoute(ctx, authorized.Route.ID, params)
 	if err != nil {
-		return Route{}, fmt.Errorf("update route: %w", err)
+		return UpdateRouteResult{}, fmt.Errorf("update route: %w", err)
 	}
 
-	return updated, nil
+	return UpdateRouteResult{Route: updated, Actor: authorized.Member}, nil
 }
 
 // LeaveRoute marks the authenticated member as left.
@@ -405,19 +561,11 @@ func (s *Service) StartSharing(ctx context.Context, code, memberToken string) (S
 		return StartSharingResult{Member: authorized.Member}, nil
 	}
 
-	if !authorized.Member.IsOwner && authorized.Route.SharingPolicy != SharingPolicyEveryoneCanShare {
+	if !mayTrack(authorized) {
 		return StartSharingResult{}, ErrSharingNotAllowed
 	}
 
-	trackingCount, err := s.repo.CountTrackingMembers(ctx, authorized.Route.ID)
-	if err != nil {
-		return StartSharingResult{}, fmt.Errorf("start sharing count tracking members: %w", err)
-	}
-
-	if authorized.Member.Status != MemberStatusStale && trackingCount >= authorized.Route.MaxTrackingMembers {
-		return StartSharingResult{}, ErrTrackingLimitReached
-	}
-
+	// The repository checks and claims the tracking slot in one transaction.
 	result, err := s.repo.StartTrackingMember(ctx, authorized.Route.ID, authorized.Member.ID)
 	if err != nil {
 		return StartSharingResult{}, fmt.Errorf("start sharing: %w", err)
@@ -463,6 +611,17 @@ func (s *Service) StopSharing(ctx context.Context, code, memberToken string) (St
 
 // RecordPosition validates and persists one position update for an authenticated tracking member.
 func (s *Service) RecordPosition(ctx context.Context, memberToken string, input PositionUpdateInput) (PositionUpdateResult, error) {
+	result, err := s.recordPosition(ctx, memberToken, input)
+	if err != nil {
+		s.metrics.PositionRejected(positionRejectionReason(err))
+		return PositionUpdateResult{}, err
+	}
+	s.metrics.PositionAccepted()
+
+	return result, nil
+}
+
+func (s *Service) recordPosition(ctx context.Context, memberToken string, input PositionUpdateInput) (PositionUpdateResult, error) {
 	if strings.TrimSpace(memberToken) == "" {
 		return PositionUpdateResult{}, ErrUnauthorized
 	}
@@ -485,6 +644,26 @@ func (s *Service) RecordPosition(ctx context.Context, memberToken string, input
 		return PositionUpdateResult{}, ErrInvalidInput
 	}
 
+	if len(s.positionValidators) > 0 {
+		candidate := PositionCandidate{
+			Member:     authorized.Member,
+			Input:      normalized,
+			ReceivedAt: s.now().UTC(),
+		}
+
+		previous, found, err := s.repo.GetLastPositionPoint(ctx, authorized.Route.ID, authorized.Member.ID)
+		if err != nil {
+			return PositionUpdateResult{}, fmt.Errorf("record position load previous point: %w", err)
+		}
+		if found {
+			candidate.Previous = &previous
+		}
+
+		if err := validatePosition(s.positionValidators, candidate); err != nil {
+			return PositionUpdateResult{}, err
+		}
+	}
+
 	result, err := s.repo.RecordPosition(ctx, RecordPositionRepoParams{
 		RouteID:          authorized.Route.ID,
 		MemberID:         authorized.Member.ID,
@@ -504,6 +683,32 @@ func (s *Service) RecordPosition(ctx context.Context, memberToken string, input
 	return result, nil
 }
 
+// RecordRouteEvent appends one broadcast live event to the durable route event log.
+func (s *Service) RecordRouteEvent(ctx context.Context, routeID, actorMemberID string, payload map[string]any) (RouteEvent, error) {
+	eventType, _ := payload["type"].(string)
+	if strings.TrimSpace(routeID) == "" || eventType == "" {
+		return RouteEvent{}, ErrInvalidInput
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return RouteEvent{}, fmt.Errorf("encode route event payload: %w", err)
+	}
+
+	event, err := s.repo.RecordRouteEvent(ctx, RecordRouteEventRepoParams{
+		RouteID:       routeID,
+		ActorMemberID: actorMemberID,
+		Type:          eventType,
+		Payload:       encoded,
+		OccurredAt:    s.now().UTC(),
+	})
+	if err != nil {
+		return RouteEvent{}, fmt.Errorf("record route event: %w", err)
+	}
+
+	return event, nil
+}
+
 // MarkMemberOnline marks an offline member as spectating after live authentication.
 func (s *Service) MarkMemberOnline(ctx context.Context, routeID, memberID string) (Member, bool, error) {
 	member, changed, err := s.repo.MarkMemberOnline(ctx, routeID, memberID)
@@ -536,19 +741,11 @@ func (s *Service) MarkMemberOffline(ctx context.Context, routeID, memberID strin
 
 // DeleteRoute removes a route owned by the authenticated owner token.
 func (s *Service) DeleteRoute(ctx context.Context, code, ownerToken string) error {
-	if strings.TrimSpace(ownerToken) == "" {
-		return ErrUnauthorized
-	}
-
-	authorized, err := s.repo.GetAuthorizedOwnerByTokenHash(ctx, tokenHash(ownerToken))
+	authorized, _, err := s.authorizeRouteAction(ctx, code, ownerToken, PermissionDeleteRoute)
 	if err != nil {
 		return err
 	}
 
-	if normalizeCode(code) != authorized.Route.Code {
-		return ErrUnauthorized
-	}
-
 	if err := s.repo.DeleteRoute(ctx, authorized.Route.ID); err != nil {
 		return fmt.Errorf("delete route: %w", err)
 	}
@@ -574,11 +771,18 @@ func (s *Service) AuthorizeMember(ctx context.Context, memberToken string) (Auth
 }
 
 // Snapshot returns the full route bootstrap payload for an authenticated member.
-func (s *Service) Snapshot(ctx context.Context, code, memberToken string) (Snapshot, error) {
+// Simplified snapshots apply Douglas–Peucker to each path segment; header snapshots list segments
+// without points, which clients then page through ListRoutePoints. Full detail stays the default.
+func (s *Service) Snapshot(ctx context.Context, code, memberToken string, query SnapshotQuery) (Snapshot, error) {
 	if strings.TrimSpace(memberToken) == "" {
 		return Snapshot{}, ErrUnauthorized
 	}
 
+	query, err := normalizeSnapshotQuery(query)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
 	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
 	if err != nil {
 		return Snapshot{}, err
@@ -591,14 +795,27 @@ func (s *Service) Snapshot(ctx context.Context, code, memberToken string) (Snaps
 		return Snapshot{}, ErrUnauthorized
 	}
 
-	members, err := s.repo.GetMembersByRouteID(ctx, authorized.Route.ID)
+	// Read the event cursor before the members, so replaying from it never skips a change.
+	lastEventSeq, err := s.repo.GetLatestRouteEventID(ctx, authorized.Route.ID)
 	if err != nil {
-		return Snapshot{}, fmt.Errorf("load snapshot members: %w", err)
+		return Snapshot{}, fmt.Errorf("load event cursor: %w", err)
 	}
 
-	pathsByMemberID, err := s.repo.GetPathSegmentsByRouteID(ctx, authorized.Route.ID)
+	loadPaths := s.repo.GetPathSegmentsByRouteID
+	var simplified *simplifiedPathLoader
+	switch query.Detail {
+	case SnapshotDetailHeader:
+		loadPaths = s.repo.GetPathSegmentSummariesByRouteID
+	case SnapshotDetailSimplified:
+		simplified = s.newSimplifiedPathLoader(query.ToleranceM)
+		loadPaths = simplified.load
+	}
+	snapshotMembers, err := s.loadMembersWithPaths(ctx, authorized.Route.ID, loadPaths)
 	if err != nil {
-		return Snapshot{}, fmt.Errorf("load snapshot paths: %w", err)
+		return Snapshot{}, fmt.Errorf("load snapshot: %w", err)
+	}
+	if simplified != nil {
+		simplified.simplify(snapshotMembers)
 	}
 
 	trackingCount, err := s.repo.CountTrackingMembers(ctx, authorized.Route.ID)
@@ -606,60 +823,203 @@ func (s *Service) Snapshot(ctx context.Context, code, memberToken string) (Snaps
 		return Snapshot{}, fmt.Errorf("load tracking count: %w", err)
 	}
 
+	var waitlist []TrackingWaitlistEntry
+	if authorized.Route.Status == RouteStatusActive {
+		waitlist, err = s.trackingWaitlist(ctx, authorized.Route.ID)
+		if err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	snapshot := Snapshot{
+		Route:        authorized.Route,
+		Members:      snapshotMembers,
+		Viewer:       buildViewerCapabilities(authorized, trackingCount, waitlist),
+		LastEventSeq: lastEventSeq,
+		Detail:       query.Detail,
+		ToleranceM:   query.ToleranceM,
+	}
+	if authorized.Route.Status == RouteStatusClosed {
+		messages, err := s.repo.GetChatMessagesByRouteID(ctx, authorized.Route.ID)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("load archived chat: %w", err)
+		}
+		snapshot.Messages = messages
+	}
+
+	return snapshot, nil
+}
+
+// ExportRoute returns every member's persisted path history for track file export.
+func (s *Service) ExportRoute(ctx context.Context, code, memberToken string) (RouteExport, error) {
+	if strings.TrimSpace(memberToken) == "" {
+		return RouteExport{}, ErrUnauthorized
+	}
+
+	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
+	if err != nil {
+		return RouteExport{}, err
+	}
+	if authorized.Member.Status == MemberStatusLeft {
+		return RouteExport{}, ErrUnauthorized
+	}
+
+	if normalizeCode(code) != authorized.Route.Code {
+		return RouteExport{}, ErrUnauthorized
+	}
+
+	members, err := s.loadMembersWithPaths(ctx, authorized.Route.ID, s.repo.GetPathSegmentHeadersByRouteID)
+	if err != nil {
+		return RouteExport{}, fmt.Errorf("load export: %w", err)
+	}
+
+	return RouteExport{
+		Route:     authorized.Route,
+		Members:   members,
+		loadPaths: s.exportMemberPaths(authorized.Route.ID),
+	}, nil
+}
+
+// exportMemberPaths returns a loader that fills one member's path segment headers with their
+// points, reading them in keyset pages of (segment_id, seq).
+func (s *Service) exportMemberPaths(routeID string) func(context.Context, SnapshotMember) ([]PathSegment, error) {
+	return func(ctx context.Context, member SnapshotMember) ([]PathSegment, error) {
+		if len(member.Paths) == 0 {
+			return member.Paths, nil
+		}
+
+		paths := make([]PathSegment, len(member.Paths))
+		indexBySegmentID := make(map[string]int, len(member.Paths))
+		for index, path := range member.Paths {
+			paths[index] = path
+			paths[index].Points = []RoutePoint{}
+			indexBySegmentID[path.ID] = index
+		}
+
+		params := ListRoutePointsRepoParams{RouteID: routeID, MemberID: member.ID, Limit: maxPointPageLimit}
+		for {
+			points, err := s.repo.ListRoutePoints(ctx, params)
+			if err != nil {
+				return nil, fmt.Errorf("load export points: %w", err)
+			}
+
+			for _, point := range points {
+				if index, ok := indexBySegmentID[point.SegmentID]; ok {
+					paths[index].Points = append(paths[index].Points, point.RoutePoint)
+				}
+			}
+
+			if len(points) < params.Limit {
+				return paths, nil
+			}
+			last := points[len(points)-1]
+			params.AfterSegmentID = last.SegmentID
+			params.AfterSeq = last.Seq
+		}
+	}
+}
+
+func (s *Service) loadMembersWithPaths(ctx context.Context, routeID string, loadPaths func(context.Context, string) (map[string][]PathSegment, error)) ([]SnapshotMember, error) {
+	members, err := s.repo.GetMembersByRouteID(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("members: %w", err)
+	}
+
+	pathsByMemberID, err := loadPaths(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("paths: %w", err)
+	}
+
+	changesByMemberID, err := s.repo.GetTransportChangesByRouteID(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("transport changes: %w", err)
+	}
+
 	snapshotMembers := make([]SnapshotMember, 0, len(members))
 	for _, member := range members {
-		role := RoleMember
-		if member.IsOwner {
-			role = RoleOwner
-		}
+		role := memberRole(member)
 		paths := pathsByMemberID[member.ID]
 		if paths == nil {
 			paths = []PathSegment{}
 		}
+		changes := changesByMemberID[member.ID]
+		initialMode := initialTransportMode(member.TransportMode, changes)
+		for index := range paths {
+			// Cached simplified segments arrive with the stretches of their raw points.
+			if len(paths[index].Points) > 0 && paths[index].TransportModes == nil {
+				paths[index].TransportModes = transportStretches(initialMode, changes, paths[index].Points)
+			}
+		}
 
 		snapshotMembers = append(snapshotMembers, SnapshotMember{
-			ID:            member.ID,
-			DisplayName:   member.DisplayName,
-			TransportMode: member.TransportMode,
-			Role:          role,
-			Status:        member.Status,
-			Color:         member.Color,
-			JoinedAt:      member.JoinedAt,
-			LeftAt:        member.LeftAt,
-			Paths:         paths,
+			ID:               member.ID,
+			DisplayName:      member.DisplayName,
+			TransportMode:    member.TransportMode,
+			Role:             role,
+			CanTrack:         member.CanTrack,
+			Status:           member.Status,
+			Color:            member.Color,
+			JoinedAt:         member.JoinedAt,
+			LeftAt:           member.LeftAt,
+			TransportChanges: changes,
+			Paths:            paths,
 		})
 	}
 
-	return Snapshot{
-		Route:   authorized.Route,
-		Members: snapshotMembers,
-		Viewer:  buildViewerCapabilities(authorized, trackingCount),
-	}, nil
+	return snapshotMembers, nil
 }
 
-func buildViewerCapabilities(authorized AuthorizedMember, trackingCount int) ViewerCapabilities {
-	role := RoleMember
-	if authorized.Member.IsOwner {
-		role = RoleOwner
-	}
+func buildViewerCapabilities(authorized AuthorizedMember, trackingCount int, waitlist []TrackingWaitlistEntry) ViewerCapabilities {
+	role := memberRole(authorized.Member)
+	isActive := authorized.Route.Status == RouteStatusActive
 
-	hasTrackingSlot := trackingCount < authorized.Route.MaxTrackingMembers || authorized.Member.Status == MemberStatusStale
+	heldForOthers, heldForViewer := activeReservations(waitlist, authorized.Member.ID)
+	hasTrackingSlot := trackingCount+heldForOthers < authorized.Route.MaxTrackingMembers ||
+		authorized.Member.Status == MemberStatusStale ||
+		heldForViewer
 	canStartSharing := authorized.Route.Status == RouteStatusActive &&
 		(authorized.Member.Status == MemberStatusSpectating || authorized.Member.Status == MemberStatusStale) &&
 		hasTrackingSlot &&
-		(authorized.Member.IsOwner || authorized.Route.SharingPolicy == SharingPolicyEveryoneCanShare)
-
-	return ViewerCapabilities{
-		MemberID:        authorized.Member.ID,
-		Role:            role,
-		Status:          authorized.Member.Status,
-		CanStartSharing: canStartSharing,
-		CanStopSharing:  authorized.Route.Status == RouteStatusActive && (authorized.Member.Status == MemberStatusTracking || authorized.Member.Status == MemberStatusStale),
-		CanLeaveRoute:   authorized.Member.Status != MemberStatusLeft && !(authorized.Route.Status == RouteStatusActive && authorized.Member.IsOwner),
-		CanCloseRoute:   authorized.Member.IsOwner && authorized.Route.Status == RouteStatusActive,
-		CanDeleteRoute:  authorized.Member.IsOwner,
-		CanEditRoute:    authorized.Member.IsOwner,
+		mayTrack(authorized)
+
+	var viewerEntry *TrackingWaitlistEntry
+	for index := range waitlist {
+		if waitlist[index].MemberID == authorized.Member.ID {
+			viewerEntry = &waitlist[index]
+			break
+		}
 	}
+	if waitlist == nil {
+		waitlist = []TrackingWaitlistEntry{}
+	}
+	canJoinWaitlist := isActive &&
+		authorized.Member.Status == MemberStatusSpectating &&
+		!hasTrackingSlot &&
+		viewerEntry == nil &&
+		mayTrack(authorized)
+
+	capabilities := ViewerCapabilities{
+		MemberID:                authorized.Member.ID,
+		Role:                    role,
+		Status:                  authorized.Member.Status,
+		CanStartSharing:         canStartSharing,
+		CanStopSharing:          authorized.Route.Status == RouteStatusActive && (authorized.Member.Status == MemberStatusTracking || authorized.Member.Status == MemberStatusStale),
+		CanLeaveRoute:           authorized.Member.Status != MemberStatusLeft && !(authorized.Route.Status == RouteStatusActive && authorized.Member.IsOwner),
+		CanCloseRoute:           isActive && roleAllows(role, PermissionCloseRoute),
+		CanDeleteRoute:          roleAllows(role, PermissionDeleteRoute),
+		CanEditRoute:            roleAllows(role, PermissionEditRoute),
+		CanGrantTracking:        isActive && roleAllows(role, PermissionGrantTracking) && authorized.Route.SharingPolicy == SharingPolicyJoinersViewOnly,
+		CanRemoveMembers:        isActive && roleAllows(role, PermissionRemoveMembers),
+		CanAssignRoles:          isActive && roleAllows(role, PermissionAssignRoles),
+		CanJoinTrackingWaitlist: canJoinWaitlist,
+		TrackingWaitlist:        waitlist,
+	}
+	if viewerEntry != nil {
+		capabilities.TrackingWaitlistPosition = viewerEntry.Position
+		capabilities.TrackingReservedUntil = viewerEntry.ReservedUntil
+	}
+
+	return capabilities
 }
 
 func normalizeCreateInput(input CreateRouteInput) (CreateRouteInput, error) {
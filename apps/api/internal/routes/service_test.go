@@ -2,9 +2,18 @@ package routes
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"keepup/apps/api/internal/config"
 )
 
 type stubRepository struct {
@@ -22,10 +31,49 @@ type stubRepository struct {
 	markMemberOnlineFn           func(context.Context, string, string) (Member, bool, error)
 	markMemberStaleFn            func(context.Context, string, string) (Member, bool, error)
 	markMemberOfflineFn          func(context.Context, string, string) (Member, bool, error)
+	markOverdueMemberStaleFn     func(context.Context, string, string, PresenceDeadlines) (Member, bool, error)
+	markOverdueMemberOfflineFn   func(context.Context, string, string, PresenceDeadlines) (Member, bool, error)
+	recordMemberSeenFn           func(context.Context, string, string) error
+	listPresenceCandidatesFn     func(context.Context, PresenceDeadlines) ([]PresenceCandidate, error)
+	withPresenceSweepLockFn      func(context.Context, func(context.Context) error) (bool, error)
+	getLastPositionPointFn       func(context.Context, string, string) (RoutePoint, bool, error)
 	recordPositionFn             func(context.Context, RecordPositionRepoParams) (PositionUpdateResult, error)
+	listPositionPointsAroundFn   func(context.Context, string, string, time.Time, time.Time) ([]RoutePoint, error)
+	recordPositionBatchFn        func(context.Context, RecordPositionBatchRepoParams) (RecordPositionBatchRepoResult, error)
 	updateRouteFn                func(context.Context, string, UpdateRouteRepoParams) (Route, error)
+	recordRouteEventFn           func(context.Context, RecordRouteEventRepoParams) (RouteEvent, error)
+	listRouteEventsAfterFn       func(context.Context, ListRouteEventsAfterRepoParams) ([]RouteEvent, error)
+	routeEventExistsFn           func(context.Context, string, int64) (bool, error)
+	deleteRouteEventsBeforeFn    func(context.Context, time.Time) (int64, error)
+	getLatestRouteEventIDFn      func(context.Context, string) (int64, error)
+	createChatMessageFn          func(context.Context, CreateChatMessageRepoParams) (ChatMessage, error)
+	listChatMessagesFn           func(context.Context, ListChatMessagesRepoParams) ([]ChatMessage, error)
+	getChatMessagesByRouteIDFn   func(context.Context, string) ([]ChatMessage, error)
 	leaveMemberFn                func(context.Context, string) (Member, error)
 	deleteRouteFn                func(context.Context, string) error
+	listSnapCandidatesFn         func(context.Context, int) ([]SnapCandidate, error)
+	getSegmentPointsFn           func(context.Context, string, int64, int64) ([]RoutePoint, error)
+	saveSnappedGeometryFn        func(context.Context, SaveSnappedGeometryRepoParams) error
+	saveSnapFailureFn            func(context.Context, SaveSnapFailureRepoParams) error
+	getPathSegmentSummariesFn    func(context.Context, string) (map[string][]PathSegment, error)
+	getPathSegmentHeadersFn      func(context.Context, string) (map[string][]PathSegment, error)
+	getPointsBySegmentIDsFn      func(context.Context, string, []string) (map[string][]RoutePoint, error)
+	listRoutePointsFn            func(context.Context, ListRoutePointsRepoParams) ([]SegmentPoint, error)
+	listReplayPointsFn           func(context.Context, ListReplayPointsRepoParams) ([]SegmentPoint, error)
+	listReplayChatMessagesFn     func(context.Context, ListReplayChatMessagesRepoParams) ([]ChatMessage, error)
+	changeMemberTransportModeFn  func(context.Context, ChangeTransportModeRepoParams) (Member, TransportChange, error)
+	getTransportChangesFn        func(context.Context, string) (map[string][]TransportChange, error)
+	setTrackingPermissionFn      func(context.Context, SetTrackingPermissionRepoParams) (SetTrackingPermissionRepoResult, error)
+	removeMemberFn               func(context.Context, RemoveMemberRepoParams) (Member, error)
+	isClientBannedFn             func(context.Context, string, string) (bool, error)
+	transferOwnershipFn          func(context.Context, TransferOwnershipRepoParams) (TransferOwnershipRepoResult, error)
+	rotateOwnerTokenFn           func(context.Context, string, string, string) error
+	setMemberModeratorFn         func(context.Context, string, string, bool) (Member, error)
+	joinTrackingWaitlistFn       func(context.Context, string, string) error
+	leaveTrackingWaitlistFn      func(context.Context, string, string) error
+	getTrackingWaitlistFn        func(context.Context, string) ([]TrackingWaitlistEntry, error)
+	reserveTrackingSlotsFn       func(context.Context, string, time.Time) ([]TrackingReservation, error)
+	expireTrackingReservationsFn func(context.Context) ([]string, error)
 }
 
 func (s stubRepository) CreateRoute(ctx context.Context, params CreateRouteRepoParams) (CreateRouteRepoResult, error) {
@@ -96,14 +144,98 @@ func (s stubRepository) MarkMemberOffline(ctx context.Context, routeID, memberID
 	return s.markMemberOfflineFn(ctx, routeID, memberID)
 }
 
+func (s stubRepository) MarkOverdueMemberStale(ctx context.Context, routeID, memberID string, deadlines PresenceDeadlines) (Member, bool, error) {
+	return s.markOverdueMemberStaleFn(ctx, routeID, memberID, deadlines)
+}
+
+func (s stubRepository) MarkOverdueMemberOffline(ctx context.Context, routeID, memberID string, deadlines PresenceDeadlines) (Member, bool, error) {
+	return s.markOverdueMemberOfflineFn(ctx, routeID, memberID, deadlines)
+}
+
+func (s stubRepository) RecordMemberSeen(ctx context.Context, routeID, memberID string) error {
+	if s.recordMemberSeenFn == nil {
+		return nil
+	}
+
+	return s.recordMemberSeenFn(ctx, routeID, memberID)
+}
+
+func (s stubRepository) ListPresenceCandidates(ctx context.Context, params PresenceDeadlines) ([]PresenceCandidate, error) {
+	return s.listPresenceCandidatesFn(ctx, params)
+}
+
+func (s stubRepository) WithPresenceSweepLock(ctx context.Context, fn func(context.Context) error) (bool, error) {
+	if s.withPresenceSweepLockFn == nil {
+		return true, fn(ctx)
+	}
+
+	return s.withPresenceSweepLockFn(ctx, fn)
+}
+
+func (s stubRepository) GetLastPositionPoint(ctx context.Context, routeID, memberID string) (RoutePoint, bool, error) {
+	if s.getLastPositionPointFn == nil {
+		return RoutePoint{}, false, nil
+	}
+
+	return s.getLastPositionPointFn(ctx, routeID, memberID)
+}
+
 func (s stubRepository) RecordPosition(ctx context.Context, params RecordPositionRepoParams) (PositionUpdateResult, error) {
 	return s.recordPositionFn(ctx, params)
 }
 
+func (s stubRepository) ListPositionPointsAround(ctx context.Context, routeID, memberID string, from, to time.Time) ([]RoutePoint, error) {
+	return s.listPositionPointsAroundFn(ctx, routeID, memberID, from, to)
+}
+
+func (s stubRepository) RecordPositionBatch(ctx context.Context, params RecordPositionBatchRepoParams) (RecordPositionBatchRepoResult, error) {
+	return s.recordPositionBatchFn(ctx, params)
+}
+
 func (s stubRepository) UpdateRoute(ctx context.Context, routeID string, params UpdateRouteRepoParams) (Route, error) {
 	return s.updateRouteFn(ctx, routeID, params)
 }
 
+func (s stubRepository) RecordRouteEvent(ctx context.Context, params RecordRouteEventRepoParams) (RouteEvent, error) {
+	return s.recordRouteEventFn(ctx, params)
+}
+
+func (s stubRepository) ListRouteEventsAfter(ctx context.Context, params ListRouteEventsAfterRepoParams) ([]RouteEvent, error) {
+	return s.listRouteEventsAfterFn(ctx, params)
+}
+
+func (s stubRepository) RouteEventExists(ctx context.Context, routeID string, id int64) (bool, error) {
+	return s.routeEventExistsFn(ctx, routeID, id)
+}
+
+func (s stubRepository) DeleteRouteEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	return s.deleteRouteEventsBeforeFn(ctx, before)
+}
+
+func (s stubRepository) GetLatestRouteEventID(ctx context.Context, routeID string) (int64, error) {
+	if s.getLatestRouteEventIDFn == nil {
+		return 0, nil
+	}
+
+	return s.getLatestRouteEventIDFn(ctx, routeID)
+}
+
+func (s stubRepository) CreateChatMessage(ctx context.Context, params CreateChatMessageRepoParams) (ChatMessage, error) {
+	return s.createChatMessageFn(ctx, params)
+}
+
+func (s stubRepository) ListChatMessages(ctx context.Context, params ListChatMessagesRepoParams) ([]ChatMessage, error) {
+	return s.listChatMessagesFn(ctx, params)
+}
+
+func (s stubRepository) GetChatMessagesByRouteID(ctx context.Context, routeID string) ([]ChatMessage, error) {
+	if s.getChatMessagesByRouteIDFn == nil {
+		return nil, nil
+	}
+
+	return s.getChatMessagesByRouteIDFn(ctx, routeID)
+}
+
 func (s stubRepository) LeaveMember(ctx context.Context, memberID string) (Member, error) {
 	return s.leaveMemberFn(ctx, memberID)
 }
@@ -112,6 +244,149 @@ func (s stubRepository) DeleteRoute(ctx context.Context, routeID string) error {
 	return s.deleteRouteFn(ctx, routeID)
 }
 
+func (s stubRepository) GetPathSegmentSummariesByRouteID(ctx context.Context, routeID string) (map[string][]PathSegment, error) {
+	return s.getPathSegmentSummariesFn(ctx, routeID)
+}
+
+func (s stubRepository) GetPathSegmentHeadersByRouteID(ctx context.Context, routeID string) (map[string][]PathSegment, error) {
+	return s.getPathSegmentHeadersFn(ctx, routeID)
+}
+
+func (s stubRepository) GetPointsBySegmentIDs(ctx context.Context, routeID string, segmentIDs []string) (map[string][]RoutePoint, error) {
+	return s.getPointsBySegmentIDsFn(ctx, routeID, segmentIDs)
+}
+
+func (s stubRepository) ListRoutePoints(ctx context.Context, params ListRoutePointsRepoParams) ([]SegmentPoint, error) {
+	return s.listRoutePointsFn(ctx, params)
+}
+
+func (s stubRepository) ListReplayPoints(ctx context.Context, params ListReplayPointsRepoParams) ([]SegmentPoint, error) {
+	if s.listReplayPointsFn == nil {
+		return nil, nil
+	}
+
+	return s.listReplayPointsFn(ctx, params)
+}
+
+func (s stubRepository) ListReplayChatMessages(ctx context.Context, params ListReplayChatMessagesRepoParams) ([]ChatMessage, error) {
+	if s.listReplayChatMessagesFn == nil {
+		return nil, nil
+	}
+
+	return s.listReplayChatMessagesFn(ctx, params)
+}
+
+func (s stubRepository) ChangeMemberTransportMode(ctx context.Context, params ChangeTransportModeRepoParams) (Member, TransportChange, error) {
+	return s.changeMemberTransportModeFn(ctx, params)
+}
+
+func (s stubRepository) SetMemberTrackingPermission(ctx context.Context, params SetTrackingPermissionRepoParams) (SetTrackingPermissionRepoResult, error) {
+	return s.setTrackingPermissionFn(ctx, params)
+}
+
+func (s stubRepository) RemoveMember(ctx context.Context, params RemoveMemberRepoParams) (Member, error) {
+	return s.removeMemberFn(ctx, params)
+}
+
+func (s stubRepository) IsClientBanned(ctx context.Context, routeID, clientID string) (bool, error) {
+	if s.isClientBannedFn == nil {
+		return false, nil
+	}
+
+	return s.isClientBannedFn(ctx, routeID, clientID)
+}
+
+func (s stubRepository) TransferOwnership(ctx context.Context, params TransferOwnershipRepoParams) (TransferOwnershipRepoResult, error) {
+	return s.transferOwnershipFn(ctx, params)
+}
+
+func (s stubRepository) RotateOwnerToken(ctx context.Context, routeID, memberID, tokenHash string) error {
+	return s.rotateOwnerTokenFn(ctx, routeID, memberID, tokenHash)
+}
+
+func (s stubRepository) SetMemberModerator(ctx context.Context, routeID, memberID string, moderator bool) (Member, error) {
+	return s.setMemberModeratorFn(ctx, routeID, memberID, moderator)
+}
+
+func (s stubRepository) JoinTrackingWaitlist(ctx context.Context, routeID, memberID string) error {
+	return s.joinTrackingWaitlistFn(ctx, routeID, memberID)
+}
+
+func (s stubRepository) LeaveTrackingWaitlist(ctx context.Context, routeID, memberID string) error {
+	return s.leaveTrackingWaitlistFn(ctx, routeID, memberID)
+}
+
+func (s stubRepository) GetTrackingWaitlist(ctx context.Context, routeID string) ([]TrackingWaitlistEntry, error) {
+	if s.getTrackingWaitlistFn == nil {
+		return nil, nil
+	}
+
+	return s.getTrackingWaitlistFn(ctx, routeID)
+}
+
+func (s stubRepository) ReserveTrackingSlots(ctx context.Context, routeID string, reservedUntil time.Time) ([]TrackingReservation, error) {
+	return s.reserveTrackingSlotsFn(ctx, routeID, reservedUntil)
+}
+
+func (s stubRepository) ExpireTrackingReservations(ctx context.Context) ([]string, error) {
+	return s.expireTrackingReservationsFn(ctx)
+}
+
+func (s stubRepository) GetTransportChangesByRouteID(ctx context.Context, routeID string) (map[string][]TransportChange, error) {
+	if s.getTransportChangesFn == nil {
+		return nil, nil
+	}
+
+	return s.getTransportChangesFn(ctx, routeID)
+}
+
+func (s stubRepository) ListSnapCandidates(ctx context.Context, limit int) ([]SnapCandidate, error) {
+	return s.listSnapCandidatesFn(ctx, limit)
+}
+
+func (s stubRepository) GetSegmentPoints(ctx context.Context, segmentID string, fromSeq, throughSeq int64) ([]RoutePoint, error) {
+	return s.getSegmentPointsFn(ctx, segmentID, fromSeq, throughSeq)
+}
+
+func (s stubRepository) SaveSnappedGeometry(ctx context.Context, params SaveSnappedGeometryRepoParams) error {
+	return s.saveSnappedGeometryFn(ctx, params)
+}
+
+func (s stubRepository) SaveSnapFailure(ctx context.Context, params SaveSnapFailureRepoParams) error {
+	return s.saveSnapFailureFn(ctx, params)
+}
+
+// replayPointsAfter serves ListReplayPoints from points the way the keyset query does.
+func replayPointsAfter(points []SegmentPoint) func(context.Context, ListReplayPointsRepoParams) ([]SegmentPoint, error) {
+	return func(_ context.Context, params ListReplayPointsRepoParams) ([]SegmentPoint, error) {
+		sorted := append([]SegmentPoint(nil), points...)
+		sort.Slice(sorted, func(left, right int) bool {
+			return compareReplayKeys(pointTime(sorted[left].RoutePoint), 0, sorted[left].SegmentID, sorted[left].Seq, pointTime(sorted[right].RoutePoint), 0, sorted[right].SegmentID, sorted[right].Seq) < 0
+		})
+
+		page := make([]SegmentPoint, 0, params.Limit)
+		for _, point := range sorted {
+			if len(page) == params.Limit {
+				break
+			}
+			if compareReplayKeys(pointTime(point.RoutePoint), 0, point.SegmentID, point.Seq, params.AfterAt, 0, params.AfterSegmentID, params.AfterSeq) > 0 {
+				page = append(page, point)
+			}
+		}
+
+		return page, nil
+	}
+}
+
+func testRouteConfig() config.RouteConfig {
+	return config.RouteConfig{
+		DefaultMaxTrackingMembers: 10,
+		ChatMessageMaxLength:      20,
+		ChatRateLimitMessages:     2,
+		ChatRateLimitWindow:       10 * time.Second,
+	}
+}
+
 func TestCreateRoute(t *testing.T) {
 	t.Parallel()
 
@@ -143,7 +418,7 @@ func TestCreateRoute(t *testing.T) {
 		},
 	}
 
-	service := NewService(repo, 10)
+	service := NewService(repo, testRouteConfig())
 
 	result, err := service.CreateRoute(context.Background(), CreateRouteInput{
 		ClientID:      "client-1",
@@ -222,7 +497,7 @@ func TestJoinRoute(t *testing.T) {
 		},
 	}
 
-	service := NewService(repo, 10)
+	service := NewService(repo, testRouteConfig())
 
 	result, err := service.JoinRoute(context.Background(), "k7p9qd", JoinRouteInput{
 		ClientID:      "client-2",
@@ -261,7 +536,7 @@ func TestJoinRouteRejectsWrongPassword(t *testing.T) {
 				Status:      RouteStatusActive,
 			}, hashed, nil
 		},
-	}, 10)
+	}, testRouteConfig())
 
 	_, err = service.JoinRoute(context.Background(), "K7P9QD", JoinRouteInput{
 		ClientID:      "client-2",
@@ -356,9 +631,12 @@ func TestSnapshot(t *testing.T) {
 		countTrackingMembersFn: func(_ context.Context, _ string) (int, error) {
 			return 1, nil
 		},
-	}, 10)
+		getLatestRouteEventIDFn: func(_ context.Context, _ string) (int64, error) {
+			return 42, nil
+		},
+	}, testRouteConfig())
 
-	snapshot, err := service.Snapshot(context.Background(), "K7P9QD", "raw-member-token")
+	snapshot, err := service.Snapshot(context.Background(), "K7P9QD", "raw-member-token", SnapshotQuery{})
 	if err != nil {
 		t.Fatalf("Snapshot() error = %v", err)
 	}
@@ -386,6 +664,10 @@ func TestSnapshot(t *testing.T) {
 	if !snapshot.Viewer.CanCloseRoute {
 		t.Fatal("Snapshot() owner should be allowed to close route")
 	}
+
+	if snapshot.LastEventSeq != 42 {
+		t.Fatalf("Snapshot() last event seq = %d, want 42", snapshot.LastEventSeq)
+	}
 }
 
 func TestUpdateRoute(t *testing.T) {
@@ -430,7 +712,7 @@ func TestUpdateRoute(t *testing.T) {
 				SharingPolicy: SharingPolicyEveryoneCanShare,
 			}, nil
 		},
-	}, 10)
+	}, testRouteConfig())
 
 	updated, err := service.UpdateRoute(context.Background(), "K7P9QD", "owner-token", UpdateRouteInput{
 		Name:        "New name",
@@ -440,8 +722,147 @@ func TestUpdateRoute(t *testing.T) {
 		t.Fatalf("UpdateRoute() error = %v", err)
 	}
 
-	if updated.Name != "New name" {
-		t.Fatalf("UpdateRoute() name = %q, want New name", updated.Name)
+	if updated.Route.Name != "New name" {
+		t.Fatalf("UpdateRoute() name = %q, want New name", updated.Route.Name)
+	}
+}
+
+func TestRecordRouteEvent(t *testing.T) {
+	t.Parallel()
+
+	occurredAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	service := NewService(stubRepository{
+		recordRouteEventFn: func(_ context.Context, params RecordRouteEventRepoParams) (RouteEvent, error) {
+			if params.RouteID != "route-1" || params.ActorMemberID != "member-1" || params.Type != "member_joined" {
+				t.Fatalf("RecordRouteEvent() params = %#v, want member_joined by member-1", params)
+			}
+			if !params.OccurredAt.Equal(occurredAt) {
+				t.Fatalf("RecordRouteEvent() occurredAt = %s, want %s", params.OccurredAt, occurredAt)
+			}
+
+			var payload map[string]any
+			if err := json.Unmarshal(params.Payload, &payload); err != nil {
+				t.Fatalf("RecordRouteEvent() payload is not JSON: %v", err)
+			}
+			if payload["type"] != "member_joined" {
+				t.Fatalf("RecordRouteEvent() payload type = %v, want member_joined", payload["type"])
+			}
+
+			return RouteEvent{
+				ID:            1,
+				RouteID:       params.RouteID,
+				ActorMemberID: params.ActorMemberID,
+				Type:          params.Type,
+				Payload:       params.Payload,
+				OccurredAt:    params.OccurredAt,
+			}, nil
+		},
+	}, testRouteConfig())
+	service.now = func() time.Time { return occurredAt }
+
+	event, err := service.RecordRouteEvent(context.Background(), "route-1", "member-1", map[string]any{
+		"type":   "member_joined",
+		"member": Member{ID: "member-1", DisplayName: "Ana"},
+	})
+	if err != nil {
+		t.Fatalf("RecordRouteEvent() error = %v", err)
+	}
+
+	if event.ID != 1 {
+		t.Fatalf("RecordRouteEvent() id = %d, want 1", event.ID)
+	}
+
+	if _, err := service.RecordRouteEvent(context.Background(), "route-1", "", map[string]any{}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("RecordRouteEvent() without type error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestRouteEventsSince(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		available int
+		firstAge  time.Duration
+		pruned    bool
+		wantCount int
+		wantErr   error
+	}{
+		{name: "returns events inside the catch-up window", available: 2, firstAge: time.Minute, wantCount: 2},
+		{name: "returns nothing when no event was missed", available: 0},
+		{name: "rejects gap with more events than the limit", available: 4, firstAge: time.Minute, wantErr: ErrEventCatchUpUnavailable},
+		{name: "rejects gap older than the max age", available: 1, firstAge: time.Hour, wantErr: ErrEventCatchUpUnavailable},
+		{name: "rejects cursor whose event was pruned", available: 1, firstAge: time.Minute, pruned: true, wantErr: ErrEventCatchUpUnavailable},
+		{name: "rejects pruned cursor with nothing left after it", pruned: true, wantErr: ErrEventCatchUpUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := testRouteConfig()
+			cfg.EventCatchUpMaxEvents = 3
+			cfg.EventCatchUpMaxAge = 10 * time.Minute
+			service := NewService(stubRepository{
+				listRouteEventsAfterFn: func(_ context.Context, params ListRouteEventsAfterRepoParams) ([]RouteEvent, error) {
+					if params.RouteID != "route-1" || params.AfterID != 41 || params.Limit != 4 {
+						t.Fatalf("ListRouteEventsAfter() params = %#v, want route-1 after 41 limit 4", params)
+					}
+
+					events := make([]RouteEvent, 0, tc.available)
+					for i := range tc.available {
+						events = append(events, RouteEvent{
+							ID:         int64(42 + i),
+							RouteID:    params.RouteID,
+							Type:       "position_updated",
+							OccurredAt: now.Add(-tc.firstAge + time.Duration(i)*time.Second),
+						})
+					}
+
+					return events, nil
+				},
+				routeEventExistsFn: func(_ context.Context, routeID string, id int64) (bool, error) {
+					if routeID != "route-1" || id != 41 {
+						t.Fatalf("RouteEventExists() got route=%q id=%d, want route-1 41", routeID, id)
+					}
+
+					return !tc.pruned, nil
+				},
+			}, cfg)
+			service.now = func() time.Time { return now }
+
+			events, err := service.RouteEventsSince(context.Background(), "route-1", 41)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("RouteEventsSince() error = %v, want %v", err, tc.wantErr)
+			}
+			if len(events) != tc.wantCount {
+				t.Fatalf("RouteEventsSince() count = %d, want %d", len(events), tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestPruneRouteEvents(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	cfg := testRouteConfig()
+	cfg.EventCatchUpMaxAge = 10 * time.Minute
+	service := NewService(stubRepository{
+		deleteRouteEventsBeforeFn: func(_ context.Context, before time.Time) (int64, error) {
+			if !before.Equal(now.Add(-10 * time.Minute)) {
+				t.Fatalf("DeleteRouteEventsBefore() cutoff = %s, want ten minutes ago", before)
+			}
+
+			return 7, nil
+		},
+	}, cfg)
+	service.now = func() time.Time { return now }
+
+	deleted, err := service.PruneRouteEvents(context.Background())
+	if err != nil || deleted != 7 {
+		t.Fatalf("PruneRouteEvents() = %d, %v; want 7", deleted, err)
 	}
 }
 
@@ -474,7 +895,7 @@ func TestLeaveRoute(t *testing.T) {
 				LeftAt:      &now,
 			}, nil
 		},
-	}, 10)
+	}, testRouteConfig())
 
 	result, err := service.LeaveRoute(context.Background(), "K7P9QD", "member-token")
 	if err != nil {
@@ -486,111 +907,547 @@ func TestLeaveRoute(t *testing.T) {
 	}
 }
 
-func TestStartSharing(t *testing.T) {
+func TestRemoveMember(t *testing.T) {
 	t.Parallel()
 
-	now := time.Now().UTC()
-	authorized := AuthorizedMember{
-		Route: Route{
-			ID:                 "route-1",
-			Code:               "K7P9QD",
-			SharingPolicy:      SharingPolicyEveryoneCanShare,
-			Status:             RouteStatusActive,
-			MaxTrackingMembers: 10,
+	route := Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive}
+	owner := Member{ID: "owner-1", RouteID: "route-1", IsOwner: true, Status: MemberStatusSpectating}
+	joiner := Member{ID: "member-2", RouteID: "route-1", ClientID: "client-2", Status: MemberStatusTracking}
+	left := Member{ID: "member-3", RouteID: "route-1", Status: MemberStatusLeft}
+	var calls []RemoveMemberRepoParams
+	service := NewService(stubRepository{
+		getAuthorizedOwnerByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{Route: route, Member: owner}, nil
 		},
-		Member: Member{
-			ID:      "member-2",
-			RouteID: "route-1",
-			Status:  MemberStatusSpectating,
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{owner, joiner, left}, nil
+		},
+		removeMemberFn: func(_ context.Context, params RemoveMemberRepoParams) (Member, error) {
+			calls = append(calls, params)
+
+			now := time.Now().UTC()
+			member := joiner
+			member.Status = MemberStatusRemoved
+			member.LeftAt = &now
+			return member, nil
 		},
+	}, testRouteConfig())
+
+	result, err := service.RemoveMember(context.Background(), "k7p9qd", "owner-token", "member-2", true)
+	if err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+
+	want := RemoveMemberRepoParams{RouteID: "route-1", MemberID: "member-2", RemovedBy: "owner-1", Ban: true}
+	if len(calls) != 1 || calls[0] != want {
+		t.Fatalf("RemoveMember() repo calls = %#v, want %#v", calls, want)
 	}
 
+	if result.Member.Status != MemberStatusRemoved || !result.Banned || result.Actor.ID != "owner-1" {
+		t.Fatalf("RemoveMember() = %#v, want banned removed member", result)
+	}
+
+	for _, memberID := range []string{"owner-1", "member-3"} {
+		if _, err := service.RemoveMember(context.Background(), "K7P9QD", "owner-token", memberID, false); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("RemoveMember(%q) error = %v, want ErrInvalidInput", memberID, err)
+		}
+	}
+
+	if _, err := service.RemoveMember(context.Background(), "K7P9QD", "owner-token", "member-9", false); !errors.Is(err, ErrMemberNotFound) {
+		t.Fatalf("RemoveMember() unknown member error = %v, want ErrMemberNotFound", err)
+	}
+
+	if _, err := service.RemoveMember(context.Background(), "K7P9QD", "", "member-2", false); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("RemoveMember() without token error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	t.Parallel()
+
+	route := Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive}
+	owner := Member{ID: "owner-1", RouteID: "route-1", IsOwner: true, Status: MemberStatusTracking}
+	joiner := Member{ID: "member-2", RouteID: "route-1", Status: MemberStatusOffline}
+	left := Member{ID: "member-3", RouteID: "route-1", Status: MemberStatusLeft}
+	var calls []TransferOwnershipRepoParams
 	service := NewService(stubRepository{
-		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
-			return authorized, nil
+		getAuthorizedOwnerByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{Route: route, Member: owner}, nil
 		},
-		countTrackingMembersFn: func(_ context.Context, routeID string) (int, error) {
-			if routeID != "route-1" {
-				t.Fatalf("CountTrackingMembers() routeID = %q, want route-1", routeID)
-			}
-
-			return 1, nil
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{owner, joiner, left}, nil
 		},
-		startTrackingMemberFn: func(_ context.Context, routeID, memberID string) (StartSharingRepoResult, error) {
-			if routeID != "route-1" || memberID != "member-2" {
-				t.Fatalf("StartTrackingMember() got routeID=%q memberID=%q", routeID, memberID)
-			}
+		transferOwnershipFn: func(_ context.Context, params TransferOwnershipRepoParams) (TransferOwnershipRepoResult, error) {
+			calls = append(calls, params)
 
-			return StartSharingRepoResult{
-				Member: Member{
-					ID:      memberID,
-					RouteID: routeID,
-					Status:  MemberStatusTracking,
-				},
-				Segment: PathSegment{
-					ID:        "segment-1",
-					StartedAt: &now,
-					Points:    []RoutePoint{},
-				},
-			}, nil
+			previous, next := owner, joiner
+			previous.IsOwner = false
+			next.IsOwner = true
+			return TransferOwnershipRepoResult{PreviousOwner: previous, Owner: next}, nil
 		},
-	}, 10)
+	}, testRouteConfig())
 
-	result, err := service.StartSharing(context.Background(), "k7p9qd", "member-token")
+	result, err := service.TransferOwnership(context.Background(), "k7p9qd", "owner-token", "member-2")
 	if err != nil {
-		t.Fatalf("StartSharing() error = %v", err)
+		t.Fatalf("TransferOwnership() error = %v", err)
 	}
 
-	if result.Member.Status != MemberStatusTracking {
-		t.Fatalf("StartSharing() status = %q, want %q", result.Member.Status, MemberStatusTracking)
+	if len(calls) != 1 || calls[0].FromMemberID != "owner-1" || calls[0].ToMemberID != "member-2" {
+		t.Fatalf("TransferOwnership() repo calls = %#v", calls)
 	}
 
-	if result.Segment.ID == "" {
-		t.Fatal("StartSharing() segment ID must not be empty")
+	if result.OwnerToken == "" || calls[0].OwnerTokenHash != tokenHash(result.OwnerToken) {
+		t.Fatalf("TransferOwnership() token = %q, stored hash %q", result.OwnerToken, calls[0].OwnerTokenHash)
+	}
+
+	if result.PreviousOwner.IsOwner || !result.Owner.IsOwner || result.Owner.ID != "member-2" {
+		t.Fatalf("TransferOwnership() = %#v, want member-2 as owner", result)
+	}
+
+	for _, memberID := range []string{"owner-1", "member-3"} {
+		if _, err := service.TransferOwnership(context.Background(), "K7P9QD", "owner-token", memberID); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("TransferOwnership(%q) error = %v, want ErrInvalidInput", memberID, err)
+		}
+	}
+
+	if _, err := service.TransferOwnership(context.Background(), "K7P9QD", "", "member-2"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("TransferOwnership() without token error = %v, want ErrUnauthorized", err)
 	}
 }
 
-func TestStartSharingRejectsTrackingLimit(t *testing.T) {
+func TestIssueOwnerTokenRequiresOwner(t *testing.T) {
 	t.Parallel()
 
+	member := Member{ID: "member-2", RouteID: "route-1", IsOwner: true}
+	var rotated []string
 	service := NewService(stubRepository{
-		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
-			return AuthorizedMember{
-				Route: Route{
-					ID:                 "route-1",
-					Code:               "K7P9QD",
-					SharingPolicy:      SharingPolicyEveryoneCanShare,
-					Status:             RouteStatusActive,
-					MaxTrackingMembers: 1,
-				},
-				Member: Member{
-					ID:      "member-2",
-					RouteID: "route-1",
-					Status:  MemberStatusSpectating,
-				},
-			}, nil
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{Route: Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive}, Member: member}, nil
 		},
-		countTrackingMembersFn: func(_ context.Context, _ string) (int, error) {
-			return 1, nil
+		rotateOwnerTokenFn: func(_ context.Context, routeID, memberID, hash string) error {
+			rotated = append(rotated, routeID+"/"+memberID+"/"+hash)
+			return nil
 		},
-	}, 10)
+	}, testRouteConfig())
 
-	_, err := service.StartSharing(context.Background(), "K7P9QD", "member-token")
-	if !errors.Is(err, ErrTrackingLimitReached) {
-		t.Fatalf("StartSharing() error = %v, want ErrTrackingLimitReached", err)
+	token, err := service.IssueOwnerToken(context.Background(), "K7P9QD", "member-token")
+	if err != nil {
+		t.Fatalf("IssueOwnerToken() error = %v", err)
+	}
+
+	if want := "route-1/member-2/" + tokenHash(token); len(rotated) != 1 || rotated[0] != want {
+		t.Fatalf("IssueOwnerToken() rotations = %v, want [%s]", rotated, want)
+	}
+
+	member.IsOwner = false
+	if _, err := service.IssueOwnerToken(context.Background(), "K7P9QD", "member-token"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("IssueOwnerToken() for non-owner error = %v, want ErrUnauthorized", err)
 	}
 }
 
-func TestStartSharingRejectsRestrictedJoiner(t *testing.T) {
+func TestModeratorPermissions(t *testing.T) {
 	t.Parallel()
 
+	route := Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive, SharingPolicy: SharingPolicyJoinersViewOnly, MaxTrackingMembers: 5}
+	owner := Member{ID: "owner-1", RouteID: "route-1", IsOwner: true, Status: MemberStatusSpectating}
+	moderator := Member{ID: "member-2", RouteID: "route-1", IsModerator: true, Status: MemberStatusSpectating}
+	otherModerator := Member{ID: "member-3", RouteID: "route-1", IsModerator: true, Status: MemberStatusSpectating}
+	joiner := Member{ID: "member-4", RouteID: "route-1", Status: MemberStatusSpectating}
+	var removed, updated int
 	service := NewService(stubRepository{
-		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
-			return AuthorizedMember{
-				Route: Route{
-					ID:            "route-1",
-					Code:          "K7P9QD",
-					SharingPolicy: SharingPolicyJoinersViewOnly,
+		getAuthorizedOwnerByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{}, ErrUnauthorized
+		},
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{Route: route, Member: moderator}, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{owner, moderator, otherModerator, joiner}, nil
+		},
+		updateRouteFn: func(_ context.Context, _ string, params UpdateRouteRepoParams) (Route, error) {
+			updated++
+			next := route
+			next.Name = *params.Name
+			return next, nil
+		},
+		removeMemberFn: func(_ context.Context, params RemoveMemberRepoParams) (Member, error) {
+			removed++
+			member := joiner
+			member.Status = MemberStatusRemoved
+			return member, nil
+		},
+	}, testRouteConfig())
+	ctx := context.Background()
+
+	if _, err := service.UpdateRoute(ctx, "K7P9QD", "member-token", UpdateRouteInput{Name: "Renamed"}); err != nil || updated != 1 {
+		t.Fatalf("moderator UpdateRoute() error = %v, updates = %d", err, updated)
+	}
+
+	if _, err := service.UpdateRoute(ctx, "K7P9QD", "member-token", UpdateRouteInput{Status: RouteStatusClosed}); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("moderator close error = %v, want ErrUnauthorized", err)
+	}
+
+	if err := service.DeleteRoute(ctx, "K7P9QD", "member-token"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("moderator DeleteRoute() error = %v, want ErrUnauthorized", err)
+	}
+
+	if _, err := service.SetModerator(ctx, "K7P9QD", "member-token", "member-4", true); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("moderator SetModerator() error = %v, want ErrUnauthorized", err)
+	}
+
+	if result, err := service.RemoveMember(ctx, "K7P9QD", "member-token", "member-4", false); err != nil || removed != 1 || result.Actor.ID != "member-2" {
+		t.Fatalf("moderator RemoveMember() = %#v, %v; removals = %d", result, err, removed)
+	}
+
+	for _, memberID := range []string{"owner-1", "member-3"} {
+		if _, err := service.RemoveMember(ctx, "K7P9QD", "member-token", memberID, false); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("moderator RemoveMember(%q) error = %v, want ErrInvalidInput", memberID, err)
+		}
+	}
+
+	capabilities := buildViewerCapabilities(AuthorizedMember{Route: route, Member: moderator}, 0, nil)
+	if capabilities.Role != RoleModerator || !capabilities.CanEditRoute || !capabilities.CanRemoveMembers || !capabilities.CanGrantTracking ||
+		capabilities.CanDeleteRoute || capabilities.CanCloseRoute || capabilities.CanAssignRoles || !capabilities.CanStartSharing {
+		t.Fatalf("moderator capabilities = %#v", capabilities)
+	}
+}
+
+func TestSetModerator(t *testing.T) {
+	t.Parallel()
+
+	route := Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive}
+	owner := Member{ID: "owner-1", RouteID: "route-1", IsOwner: true}
+	joiner := Member{ID: "member-2", RouteID: "route-1", Status: MemberStatusSpectating}
+	var calls []string
+	service := NewService(stubRepository{
+		getAuthorizedOwnerByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{Route: route, Member: owner}, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{owner, joiner}, nil
+		},
+		setMemberModeratorFn: func(_ context.Context, routeID, memberID string, moderator bool) (Member, error) {
+			calls = append(calls, routeID+"/"+memberID)
+			member := joiner
+			member.IsModerator = moderator
+			return member, nil
+		},
+	}, testRouteConfig())
+
+	result, err := service.SetModerator(context.Background(), "K7P9QD", "owner-token", "member-2", true)
+	if err != nil {
+		t.Fatalf("SetModerator() error = %v", err)
+	}
+
+	if !result.Changed || !result.Member.IsModerator || len(calls) != 1 || calls[0] != "route-1/member-2" {
+		t.Fatalf("SetModerator() = %#v, calls = %v", result, calls)
+	}
+
+	result, err = service.SetModerator(context.Background(), "K7P9QD", "owner-token", "member-2", false)
+	if err != nil || result.Changed || len(calls) != 1 {
+		t.Fatalf("SetModerator() unchanged = %#v, %v; calls = %v", result, err, calls)
+	}
+
+	if _, err := service.SetModerator(context.Background(), "K7P9QD", "owner-token", "owner-1", true); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("SetModerator(owner) error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestJoinTrackingWaitlist(t *testing.T) {
+	t.Parallel()
+
+	route := Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive, SharingPolicy: SharingPolicyEveryoneCanShare, MaxTrackingMembers: 1}
+	joiner := Member{ID: "member-2", RouteID: "route-1", Status: MemberStatusSpectating}
+	queuedAt := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	var queued []string
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(_ context.Context, hash string) (AuthorizedMember, error) {
+			if hash == tokenHash("tracker-token") {
+				return AuthorizedMember{Route: route, Member: Member{ID: "member-3", RouteID: "route-1", Status: MemberStatusTracking}}, nil
+			}
+			return AuthorizedMember{Route: route, Member: joiner}, nil
+		},
+		joinTrackingWaitlistFn: func(_ context.Context, routeID, memberID string) error {
+			queued = append(queued, routeID+"/"+memberID)
+			return nil
+		},
+		getTrackingWaitlistFn: func(context.Context, string) ([]TrackingWaitlistEntry, error) {
+			return []TrackingWaitlistEntry{
+				{MemberID: "member-4", QueuedAt: queuedAt.Add(-time.Minute)},
+				{MemberID: "member-2", QueuedAt: queuedAt},
+			}, nil
+		},
+	}, testRouteConfig())
+
+	entry, err := service.JoinTrackingWaitlist(context.Background(), "k7p9qd", "member-token")
+	if err != nil {
+		t.Fatalf("JoinTrackingWaitlist() error = %v", err)
+	}
+
+	if entry.MemberID != "member-2" || entry.Position != 2 || len(queued) != 1 || queued[0] != "route-1/member-2" {
+		t.Fatalf("JoinTrackingWaitlist() = %#v, queued = %v", entry, queued)
+	}
+
+	if _, err := service.JoinTrackingWaitlist(context.Background(), "K7P9QD", "tracker-token"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("JoinTrackingWaitlist(tracking) error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestOfferTrackingSlotsUsesReservationWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	cfg := testRouteConfig()
+	cfg.TrackingReservationWindow = 90 * time.Second
+	var reservedUntil time.Time
+	service := NewService(stubRepository{
+		reserveTrackingSlotsFn: func(_ context.Context, routeID string, until time.Time) ([]TrackingReservation, error) {
+			reservedUntil = until
+			return []TrackingReservation{{RouteID: routeID, MemberID: "member-2", ReservedUntil: until}}, nil
+		},
+		expireTrackingReservationsFn: func(context.Context) ([]string, error) {
+			return []string{"route-1", "route-2"}, nil
+		},
+	}, cfg)
+	service.now = func() time.Time { return now }
+
+	reservations, err := service.ExpireTrackingReservations(context.Background())
+	if err != nil {
+		t.Fatalf("ExpireTrackingReservations() error = %v", err)
+	}
+
+	if len(reservations) != 2 || reservations[1].RouteID != "route-2" || !reservedUntil.Equal(now.Add(90*time.Second)) {
+		t.Fatalf("ExpireTrackingReservations() = %#v, reserved until %s", reservations, reservedUntil)
+	}
+}
+
+func TestBuildViewerCapabilitiesTrackingWaitlist(t *testing.T) {
+	t.Parallel()
+
+	route := Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive, SharingPolicy: SharingPolicyEveryoneCanShare, MaxTrackingMembers: 2}
+	reservedUntil := time.Date(2026, 5, 1, 12, 1, 0, 0, time.UTC)
+	waitlist := []TrackingWaitlistEntry{
+		{MemberID: "member-2", Position: 1, ReservedUntil: &reservedUntil},
+		{MemberID: "member-3", Position: 2},
+	}
+
+	reserved := buildViewerCapabilities(AuthorizedMember{Route: route, Member: Member{ID: "member-2", Status: MemberStatusSpectating}}, 1, waitlist)
+	if !reserved.CanStartSharing || reserved.CanJoinTrackingWaitlist || reserved.TrackingWaitlistPosition != 1 || reserved.TrackingReservedUntil == nil {
+		t.Fatalf("reserved viewer capabilities = %#v", reserved)
+	}
+
+	queued := buildViewerCapabilities(AuthorizedMember{Route: route, Member: Member{ID: "member-3", Status: MemberStatusSpectating}}, 1, waitlist)
+	if queued.CanStartSharing || queued.CanJoinTrackingWaitlist || queued.TrackingWaitlistPosition != 2 || queued.TrackingReservedUntil != nil {
+		t.Fatalf("queued viewer capabilities = %#v", queued)
+	}
+
+	other := buildViewerCapabilities(AuthorizedMember{Route: route, Member: Member{ID: "member-4", Status: MemberStatusSpectating}}, 1, waitlist)
+	if other.CanStartSharing || !other.CanJoinTrackingWaitlist || other.TrackingWaitlistPosition != 0 || len(other.TrackingWaitlist) != 2 {
+		t.Fatalf("unqueued viewer capabilities = %#v", other)
+	}
+}
+
+func TestJoinRouteRejectsBannedClient(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(stubRepository{
+		getRouteByCodeFn: func(context.Context, string) (Route, string, error) {
+			return Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive}, "", nil
+		},
+		isClientBannedFn: func(_ context.Context, routeID, clientID string) (bool, error) {
+			if routeID != "route-1" || clientID != "client-2" {
+				t.Fatalf("IsClientBanned() got routeID=%q clientID=%q", routeID, clientID)
+			}
+
+			return true, nil
+		},
+	}, testRouteConfig())
+
+	_, err := service.JoinRoute(context.Background(), "K7P9QD", JoinRouteInput{
+		ClientID:      "client-2",
+		DisplayName:   "Matej",
+		TransportMode: "train",
+	})
+	if !errors.Is(err, ErrMemberBanned) {
+		t.Fatalf("JoinRoute() error = %v, want ErrMemberBanned", err)
+	}
+}
+
+func TestStartSharing(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	authorized := AuthorizedMember{
+		Route: Route{
+			ID:                 "route-1",
+			Code:               "K7P9QD",
+			SharingPolicy:      SharingPolicyEveryoneCanShare,
+			Status:             RouteStatusActive,
+			MaxTrackingMembers: 10,
+		},
+		Member: Member{
+			ID:      "member-2",
+			RouteID: "route-1",
+			Status:  MemberStatusSpectating,
+		},
+	}
+
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
+			return authorized, nil
+		},
+		startTrackingMemberFn: func(_ context.Context, routeID, memberID string) (StartSharingRepoResult, error) {
+			if routeID != "route-1" || memberID != "member-2" {
+				t.Fatalf("StartTrackingMember() got routeID=%q memberID=%q", routeID, memberID)
+			}
+
+			return StartSharingRepoResult{
+				Member: Member{
+					ID:      memberID,
+					RouteID: routeID,
+					Status:  MemberStatusTracking,
+				},
+				Segment: PathSegment{
+					ID:        "segment-1",
+					StartedAt: &now,
+					Points:    []RoutePoint{},
+				},
+			}, nil
+		},
+	}, testRouteConfig())
+
+	result, err := service.StartSharing(context.Background(), "k7p9qd", "member-token")
+	if err != nil {
+		t.Fatalf("StartSharing() error = %v", err)
+	}
+
+	if result.Member.Status != MemberStatusTracking {
+		t.Fatalf("StartSharing() status = %q, want %q", result.Member.Status, MemberStatusTracking)
+	}
+
+	if result.Segment.ID == "" {
+		t.Fatal("StartSharing() segment ID must not be empty")
+	}
+}
+
+func TestStartSharingRejectsTrackingLimit(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
+			return AuthorizedMember{
+				Route: Route{
+					ID:                 "route-1",
+					Code:               "K7P9QD",
+					SharingPolicy:      SharingPolicyEveryoneCanShare,
+					Status:             RouteStatusActive,
+					MaxTrackingMembers: 1,
+				},
+				Member: Member{
+					ID:      "member-2",
+					RouteID: "route-1",
+					Status:  MemberStatusSpectating,
+				},
+			}, nil
+		},
+		startTrackingMemberFn: func(_ context.Context, _, _ string) (StartSharingRepoResult, error) {
+			return StartSharingRepoResult{}, ErrTrackingLimitReached
+		},
+	}, testRouteConfig())
+
+	_, err := service.StartSharing(context.Background(), "K7P9QD", "member-token")
+	if !errors.Is(err, ErrTrackingLimitReached) {
+		t.Fatalf("StartSharing() error = %v, want ErrTrackingLimitReached", err)
+	}
+}
+
+func TestStartSharingLeavesTrackingLimitToRepository(t *testing.T) {
+	t.Parallel()
+
+	const (
+		maxTrackers = 10
+		members     = 50
+	)
+
+	route := Route{
+		ID:                 "route-1",
+		Code:               "K7P9QD",
+		SharingPolicy:      SharingPolicyEveryoneCanShare,
+		Status:             RouteStatusActive,
+		MaxTrackingMembers: maxTrackers,
+	}
+
+	// Concurrent starts must rely on StartTrackingMember alone for the limit: the service never
+	// counts trackers itself and surfaces every ErrTrackingLimitReached. The stub serializes claims
+	// with a mutex, so the route row lock in PostgresRepository.StartTrackingMember is not covered.
+	var mu sync.Mutex
+	tracking := map[string]bool{}
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(_ context.Context, hash string) (AuthorizedMember, error) {
+			return AuthorizedMember{
+				Route:  route,
+				Member: Member{ID: hash, RouteID: route.ID, Status: MemberStatusSpectating},
+			}, nil
+		},
+		countTrackingMembersFn: func(context.Context, string) (int, error) {
+			t.Error("StartSharing() must not count trackers outside the repository transaction")
+			return 0, nil
+		},
+		startTrackingMemberFn: func(_ context.Context, routeID, memberID string) (StartSharingRepoResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if len(tracking) >= maxTrackers {
+				return StartSharingRepoResult{}, ErrTrackingLimitReached
+			}
+			tracking[memberID] = true
+
+			return StartSharingRepoResult{Member: Member{ID: memberID, RouteID: routeID, Status: MemberStatusTracking}}, nil
+		},
+	}, testRouteConfig())
+
+	var started, rejected atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for index := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			_, err := service.StartSharing(context.Background(), "K7P9QD", fmt.Sprintf("member-token-%d", index))
+			switch {
+			case err == nil:
+				started.Add(1)
+			case errors.Is(err, ErrTrackingLimitReached):
+				rejected.Add(1)
+			default:
+				t.Errorf("StartSharing() error = %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if started.Load() != maxTrackers || rejected.Load() != members-maxTrackers {
+		t.Fatalf("StartSharing() started = %d, rejected = %d; want %d and %d", started.Load(), rejected.Load(), maxTrackers, members-maxTrackers)
+	}
+
+	if len(tracking) != maxTrackers {
+		t.Fatalf("tracking members = %d, want %d", len(tracking), maxTrackers)
+	}
+}
+
+func TestStartSharingRejectsRestrictedJoiner(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
+			return AuthorizedMember{
+				Route: Route{
+					ID:            "route-1",
+					Code:          "K7P9QD",
+					SharingPolicy: SharingPolicyJoinersViewOnly,
 					Status:        RouteStatusActive,
 				},
 				Member: Member{
@@ -601,202 +1458,1579 @@ func TestStartSharingRejectsRestrictedJoiner(t *testing.T) {
 				},
 			}, nil
 		},
-	}, 10)
+	}, testRouteConfig())
+
+	_, err := service.StartSharing(context.Background(), "K7P9QD", "member-token")
+	if !errors.Is(err, ErrSharingNotAllowed) {
+		t.Fatalf("StartSharing() error = %v, want ErrSharingNotAllowed", err)
+	}
+}
+
+func TestStartSharingAllowsGrantedJoiner(t *testing.T) {
+	t.Parallel()
+
+	authorized := AuthorizedMember{
+		Route: Route{
+			ID:                 "route-1",
+			Code:               "K7P9QD",
+			SharingPolicy:      SharingPolicyJoinersViewOnly,
+			Status:             RouteStatusActive,
+			MaxTrackingMembers: 10,
+		},
+		Member: Member{
+			ID:       "member-2",
+			RouteID:  "route-1",
+			Status:   MemberStatusSpectating,
+			CanTrack: true,
+		},
+	}
+
+	if !buildViewerCapabilities(authorized, 1, nil).CanStartSharing {
+		t.Fatal("buildViewerCapabilities() CanStartSharing = false, want true for granted joiner")
+	}
+
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
+			return authorized, nil
+		},
+		countTrackingMembersFn: func(context.Context, string) (int, error) {
+			return 1, nil
+		},
+		startTrackingMemberFn: func(_ context.Context, routeID, memberID string) (StartSharingRepoResult, error) {
+			return StartSharingRepoResult{
+				Member: Member{ID: memberID, RouteID: routeID, Status: MemberStatusTracking, CanTrack: true},
+			}, nil
+		},
+	}, testRouteConfig())
+
+	result, err := service.StartSharing(context.Background(), "K7P9QD", "member-token")
+	if err != nil {
+		t.Fatalf("StartSharing() error = %v", err)
+	}
+
+	if result.Member.Status != MemberStatusTracking {
+		t.Fatalf("StartSharing() status = %q, want %q", result.Member.Status, MemberStatusTracking)
+	}
+}
+
+func TestSetTrackingPermission(t *testing.T) {
+	t.Parallel()
+
+	changedAt := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	route := Route{ID: "route-1", Code: "K7P9QD", SharingPolicy: SharingPolicyJoinersViewOnly, Status: RouteStatusActive}
+	owner := Member{ID: "owner-1", RouteID: "route-1", IsOwner: true, Status: MemberStatusTracking}
+	joiner := Member{ID: "member-2", RouteID: "route-1", Status: MemberStatusTracking, CanTrack: true}
+	var calls []SetTrackingPermissionRepoParams
+	service := NewService(stubRepository{
+		getAuthorizedOwnerByTokenFn: func(_ context.Context, tokenHash string) (AuthorizedMember, error) {
+			if tokenHash == "" {
+				t.Fatal("GetAuthorizedOwnerByTokenHash() token hash must not be empty")
+			}
+
+			return AuthorizedMember{Route: route, Member: owner}, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{owner, joiner}, nil
+		},
+		setTrackingPermissionFn: func(_ context.Context, params SetTrackingPermissionRepoParams) (SetTrackingPermissionRepoResult, error) {
+			calls = append(calls, params)
+
+			member := joiner
+			member.CanTrack = params.Granted
+			member.Status = MemberStatusSpectating
+			return SetTrackingPermissionRepoResult{
+				Member:         member,
+				Change:         &TrackingPermissionChange{MemberID: member.ID, Granted: params.Granted, ChangedBy: params.ChangedBy, ChangedAt: changedAt},
+				StoppedSharing: !params.Granted,
+			}, nil
+		},
+	}, testRouteConfig())
+
+	result, err := service.SetTrackingPermission(context.Background(), "k7p9qd", "owner-token", "member-2", false)
+	if err != nil {
+		t.Fatalf("SetTrackingPermission() error = %v", err)
+	}
+
+	if len(calls) != 1 || calls[0].RouteID != "route-1" || calls[0].MemberID != "member-2" || calls[0].ChangedBy != "owner-1" || calls[0].Granted {
+		t.Fatalf("SetMemberTrackingPermission() calls = %#v, want revoke of member-2 by owner-1", calls)
+	}
+
+	if !result.StoppedSharing || result.Member.CanTrack || result.Change == nil || result.Actor.ID != "owner-1" {
+		t.Fatalf("SetTrackingPermission() = %#v, want revoked member with stopped sharing", result)
+	}
+
+	if _, err := service.SetTrackingPermission(context.Background(), "K7P9QD", "owner-token", "owner-1", false); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("SetTrackingPermission() owner target error = %v, want ErrInvalidInput", err)
+	}
+
+	if _, err := service.SetTrackingPermission(context.Background(), "K7P9QD", "owner-token", "member-9", true); !errors.Is(err, ErrMemberNotFound) {
+		t.Fatalf("SetTrackingPermission() unknown member error = %v, want ErrMemberNotFound", err)
+	}
+
+	route.SharingPolicy = SharingPolicyEveryoneCanShare
+	if _, err := service.SetTrackingPermission(context.Background(), "K7P9QD", "owner-token", "member-2", true); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("SetTrackingPermission() open route error = %v, want ErrInvalidInput", err)
+	}
+
+	if len(calls) != 1 {
+		t.Fatalf("SetMemberTrackingPermission() calls = %d, want 1", len(calls))
+	}
+}
+
+func TestStopSharing(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
+			return AuthorizedMember{
+				Route: Route{
+					ID:     "route-1",
+					Code:   "K7P9QD",
+					Status: RouteStatusActive,
+				},
+				Member: Member{
+					ID:      "member-2",
+					RouteID: "route-1",
+					Status:  MemberStatusTracking,
+				},
+			}, nil
+		},
+		stopTrackingMemberFn: func(_ context.Context, routeID, memberID string) (Member, error) {
+			if routeID != "route-1" || memberID != "member-2" {
+				t.Fatalf("StopTrackingMember() got routeID=%q memberID=%q", routeID, memberID)
+			}
+
+			return Member{
+				ID:      memberID,
+				RouteID: routeID,
+				Status:  MemberStatusSpectating,
+			}, nil
+		},
+	}, testRouteConfig())
+
+	result, err := service.StopSharing(context.Background(), "K7P9QD", "member-token")
+	if err != nil {
+		t.Fatalf("StopSharing() error = %v", err)
+	}
+
+	if result.Member.Status != MemberStatusSpectating {
+		t.Fatalf("StopSharing() status = %q, want %q", result.Member.Status, MemberStatusSpectating)
+	}
+}
+
+func TestRecordPosition(t *testing.T) {
+	t.Parallel()
+
+	accuracy := 12.5
+	clientRecordedAt := time.Now().UTC()
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
+			return AuthorizedMember{
+				Route: Route{
+					ID:     "route-1",
+					Code:   "K7P9QD",
+					Status: RouteStatusActive,
+				},
+				Member: Member{
+					ID:      "member-2",
+					RouteID: "route-1",
+					Status:  MemberStatusTracking,
+				},
+			}, nil
+		},
+		recordPositionFn: func(_ context.Context, params RecordPositionRepoParams) (PositionUpdateResult, error) {
+			if params.RouteID != "route-1" || params.MemberID != "member-2" {
+				t.Fatalf("RecordPosition() got routeID=%q memberID=%q", params.RouteID, params.MemberID)
+			}
+
+			if params.Latitude != 46.0569 || params.Longitude != 14.5058 {
+				t.Fatalf("RecordPosition() got coordinates=%f,%f", params.Latitude, params.Longitude)
+			}
+
+			if params.AccuracyM == nil || *params.AccuracyM != accuracy {
+				t.Fatal("RecordPosition() expected accuracy")
+			}
+
+			if params.ClientRecordedAt == nil || !params.ClientRecordedAt.Equal(clientRecordedAt) {
+				t.Fatal("RecordPosition() expected client recorded time")
+			}
+
+			if string(params.RawPayload) != "{}" {
+				t.Fatalf("RecordPosition() raw payload = %s, want {}", string(params.RawPayload))
+			}
+
+			return PositionUpdateResult{
+				RouteID:   params.RouteID,
+				MemberID:  params.MemberID,
+				SegmentID: "segment-1",
+				Point: RoutePoint{
+					Seq:              1,
+					Latitude:         params.Latitude,
+					Longitude:        params.Longitude,
+					AccuracyM:        params.AccuracyM,
+					ClientRecordedAt: params.ClientRecordedAt,
+					RecordedAt:       time.Now().UTC(),
+				},
+			}, nil
+		},
+	}, testRouteConfig())
+
+	result, err := service.RecordPosition(context.Background(), "member-token", PositionUpdateInput{
+		Latitude:         46.0569,
+		Longitude:        14.5058,
+		AccuracyM:        &accuracy,
+		ClientRecordedAt: &clientRecordedAt,
+	})
+	if err != nil {
+		t.Fatalf("RecordPosition() error = %v", err)
+	}
+
+	if result.SegmentID != "segment-1" || result.Point.Seq != 1 {
+		t.Fatalf("RecordPosition() result segment/seq = %q/%d", result.SegmentID, result.Point.Seq)
+	}
+}
+
+func TestRecordPositionBatch(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	at := func(seconds int) *time.Time {
+		recordedAt := base.Add(time.Duration(seconds) * time.Second)
+		return &recordedAt
+	}
+	accurate := 10.0
+	inaccurate := 250.0
+	cfg := testRouteConfig()
+	cfg.PositionMaxAccuracyM = 100
+
+	var inserted []PositionUpdateInput
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			// The sweeper already marked the member offline; the buffered stretch is still accepted.
+			return AuthorizedMember{
+				Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive},
+				Member: Member{ID: "member-2", RouteID: "route-1", Status: MemberStatusOffline},
+			}, nil
+		},
+		getLastPositionPointFn: func(context.Context, string, string) (RoutePoint, bool, error) {
+			t.Fatal("GetLastPositionPoint() called; batch samples are validated against the points around them")
+			return RoutePoint{}, false, nil
+		},
+		listPositionPointsAroundFn: func(_ context.Context, routeID, memberID string, from, to time.Time) ([]RoutePoint, error) {
+			if routeID != "route-1" || memberID != "member-2" || !from.Equal(*at(0)) || !to.Equal(*at(30)) {
+				t.Fatalf("ListPositionPointsAround() got %q/%q %s..%s, want the batch's client time window", routeID, memberID, from, to)
+			}
+
+			return []RoutePoint{
+				{Seq: 4, Latitude: 46.0569, Longitude: 14.5058, ClientRecordedAt: at(0), RecordedAt: base},
+				{Seq: 6, Latitude: 46.0572, Longitude: 14.5058, ClientRecordedAt: at(25), RecordedAt: base},
+			}, nil
+		},
+		recordPositionBatchFn: func(_ context.Context, params RecordPositionBatchRepoParams) (RecordPositionBatchRepoResult, error) {
+			if params.RouteID != "route-1" || params.MemberID != "member-2" {
+				t.Fatalf("RecordPositionBatch() got routeID=%q memberID=%q", params.RouteID, params.MemberID)
+			}
+			inserted = params.Samples
+
+			// The sample at 10s falls outside every segment of the member.
+			return RecordPositionBatchRepoResult{
+				Segments: []PositionBatchSegment{{
+					SegmentID: "segment-1",
+					Points:    []RoutePoint{{Seq: 7, Latitude: params.Samples[1].Latitude, Longitude: params.Samples[1].Longitude, ClientRecordedAt: params.Samples[1].ClientRecordedAt}},
+				}},
+				Unplaced: []int{0},
+			}, nil
+		},
+	}, cfg)
+
+	result, err := service.RecordPositionBatch(context.Background(), "k7p9qd", "member-token", []PositionUpdateInput{
+		{Latitude: 46.0572, Longitude: 14.5058, AccuracyM: &accurate, ClientRecordedAt: at(30)},
+		{Latitude: 46.0570, Longitude: 14.5058, AccuracyM: &accurate, ClientRecordedAt: at(10)},
+		{Latitude: 46.0570, Longitude: 14.5058, AccuracyM: &accurate, ClientRecordedAt: at(10)},
+		{Latitude: 46.0571, Longitude: 14.5058, AccuracyM: &inaccurate, ClientRecordedAt: at(20)},
+		{Latitude: 46.0571, Longitude: 14.5058, AccuracyM: &accurate},
+		{Latitude: 46.0569, Longitude: 14.5058, AccuracyM: &accurate, ClientRecordedAt: at(0)},
+	})
+	if err != nil {
+		t.Fatalf("RecordPositionBatch() error = %v", err)
+	}
+
+	if len(inserted) != 2 || !inserted[0].ClientRecordedAt.Equal(*at(10)) || !inserted[1].ClientRecordedAt.Equal(*at(30)) {
+		t.Fatalf("RecordPositionBatch() inserted = %#v, want samples at 10s and 30s in client order", inserted)
+	}
+
+	if len(result.Segments) != 1 || result.Segments[0].SegmentID != "segment-1" || result.Accepted() != 1 {
+		t.Fatalf("RecordPositionBatch() segments = %#v, want one point in segment-1", result.Segments)
+	}
+
+	// The repeat at 10s inside the batch, and the sample at 0s that is already stored.
+	if result.Duplicates != 2 {
+		t.Fatalf("RecordPositionBatch() duplicates = %d, want 2", result.Duplicates)
+	}
+
+	wantRejected := []PositionBatchRejection{
+		{Index: 4, Reason: "invalid_input"},
+		{Index: 3, Reason: PositionRejectionAccuracyTooLow},
+		{Index: 1, Reason: PositionRejectionOutsideSegment},
+	}
+	if len(result.Rejected) != len(wantRejected) {
+		t.Fatalf("RecordPositionBatch() rejected = %#v, want %#v", result.Rejected, wantRejected)
+	}
+	for i, want := range wantRejected {
+		if result.Rejected[i] != want {
+			t.Fatalf("RecordPositionBatch() rejected[%d] = %#v, want %#v", i, result.Rejected[i], want)
+		}
+	}
+
+	if _, err := service.RecordPositionBatch(context.Background(), "K7P9QD", "member-token", nil); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("RecordPositionBatch() empty batch error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestRecordPositionBatchValidatesAgainstPointBeforeEachSample(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	at := func(seconds int) *time.Time {
+		recordedAt := base.Add(time.Duration(seconds) * time.Second)
+		return &recordedAt
+	}
+
+	cfg := testRouteConfig()
+	cfg.PositionMaxSpeedMPS = map[string]float64{"walking": 3}
+
+	var inserted []PositionUpdateInput
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{
+				Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive},
+				Member: Member{ID: "member-2", RouteID: "route-1", Status: MemberStatusTracking, TransportMode: "walking"},
+			}, nil
+		},
+		listPositionPointsAroundFn: func(context.Context, string, string, time.Time, time.Time) ([]RoutePoint, error) {
+			// Live points resumed at 600s, one kilometre north, while the gap was still buffered.
+			return []RoutePoint{
+				{Seq: 1, Latitude: 46.0000, Longitude: 14.5, ClientRecordedAt: at(0), RecordedAt: base},
+				{Seq: 2, Latitude: 46.0090, Longitude: 14.5, ClientRecordedAt: at(600), RecordedAt: base},
+			}, nil
+		},
+		recordPositionBatchFn: func(_ context.Context, params RecordPositionBatchRepoParams) (RecordPositionBatchRepoResult, error) {
+			inserted = params.Samples
+			return RecordPositionBatchRepoResult{}, nil
+		},
+	}, cfg)
+
+	result, err := service.RecordPositionBatch(context.Background(), "K7P9QD", "member-token", []PositionUpdateInput{
+		{Latitude: 46.0030, Longitude: 14.5, ClientRecordedAt: at(200)},
+		{Latitude: 46.0060, Longitude: 14.5, ClientRecordedAt: at(400)},
+		{Latitude: 46.0300, Longitude: 14.5, ClientRecordedAt: at(610)},
+	})
+	if err != nil {
+		t.Fatalf("RecordPositionBatch() error = %v", err)
+	}
+
+	// The gap samples predate the newest stored point but follow the one before them; the last sample
+	// jumps 2 km in the 10 s after the stored point at 600s.
+	if len(inserted) != 2 || !inserted[0].ClientRecordedAt.Equal(*at(200)) || !inserted[1].ClientRecordedAt.Equal(*at(400)) {
+		t.Fatalf("RecordPositionBatch() inserted = %#v, want the samples at 200s and 400s", inserted)
+	}
+	if len(result.Rejected) != 1 || result.Rejected[0] != (PositionBatchRejection{Index: 2, Reason: PositionRejectionImpossibleSpeed}) {
+		t.Fatalf("RecordPositionBatch() rejected = %#v, want the jump after the stored point", result.Rejected)
+	}
+}
+
+func TestPlaceBatchSamples(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		value := base.Add(time.Duration(minutes) * time.Minute)
+		return &value
+	}
+	segments := []PathSegment{
+		{ID: "stopped", StartedAt: at(0), EndedAt: at(10), EndReason: PathSegmentEndReasonStopped},
+		{ID: "disconnected", StartedAt: at(20), EndedAt: at(30), EndReason: PathSegmentEndReasonDisconnected},
+		{ID: "open", StartedAt: at(40)},
+	}
+
+	testCases := []struct {
+		name   string
+		minute int
+		want   int
+	}{
+		{name: "before the first segment", minute: -5, want: -1},
+		{name: "inside a stopped segment", minute: 5, want: 0},
+		{name: "at a stopped segment's end", minute: 10, want: 0},
+		{name: "after a stopped segment", minute: 15, want: -1},
+		{name: "inside a disconnected segment", minute: 25, want: 1},
+		{name: "after a disconnect before the next segment", minute: 35, want: 1},
+		{name: "inside the open segment", minute: 45, want: 2},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := placeBatchSamples(segments, []PositionUpdateInput{{ClientRecordedAt: at(testCase.minute)}})
+			if len(got) != 1 || got[0] != testCase.want {
+				t.Fatalf("placeBatchSamples() = %v, want [%d]", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestRecordPositionRejectsSpectator(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
+			return AuthorizedMember{
+				Route: Route{
+					ID:     "route-1",
+					Code:   "K7P9QD",
+					Status: RouteStatusActive,
+				},
+				Member: Member{
+					ID:      "member-2",
+					RouteID: "route-1",
+					Status:  MemberStatusSpectating,
+				},
+			}, nil
+		},
+		recordPositionFn: func(context.Context, RecordPositionRepoParams) (PositionUpdateResult, error) {
+			t.Fatal("RecordPosition() repository call should not run for a spectator")
+			return PositionUpdateResult{}, nil
+		},
+	}, testRouteConfig())
+
+	_, err := service.RecordPosition(context.Background(), "member-token", PositionUpdateInput{
+		Latitude:  46.0569,
+		Longitude: 14.5058,
+	})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("RecordPosition() error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestRecordPositionRejectsInvalidCoordinates(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			t.Fatal("RecordPosition() should validate coordinates before authorization")
+			return AuthorizedMember{}, nil
+		},
+	}, testRouteConfig())
+
+	_, err := service.RecordPosition(context.Background(), "member-token", PositionUpdateInput{
+		Latitude:  91,
+		Longitude: 14.5058,
+	})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("RecordPosition() error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestSnapshotSimplifiesPaths(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	authorized := AuthorizedMember{
+		Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive, MaxTrackingMembers: 10},
+		Member: Member{ID: "member-1", RouteID: "route-1", Status: MemberStatusTracking},
+	}
+	// A straight eastbound line with one 50 m northward detour in the middle.
+	line := func(offset float64) []RoutePoint {
+		points := make([]RoutePoint, 0, 9)
+		for i := range 9 {
+			latitude := 46.0
+			if i == 4 {
+				latitude += offset
+			}
+			points = append(points, RoutePoint{Seq: int64(i + 1), Latitude: latitude, Longitude: 14.5 + float64(i)*0.0005, RecordedAt: now})
+		}
+		return points
+	}
+	var pointLoads [][]string
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return authorized, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{authorized.Member}, nil
+		},
+		getPathSegmentHeadersFn: func(context.Context, string) (map[string][]PathSegment, error) {
+			return map[string][]PathSegment{
+				"member-1": {
+					{ID: "segment-closed", StartedAt: &now, EndedAt: &now, Points: []RoutePoint{}},
+					{ID: "segment-open", StartedAt: &now, Points: []RoutePoint{}},
+				},
+			}, nil
+		},
+		getPointsBySegmentIDsFn: func(_ context.Context, _ string, segmentIDs []string) (map[string][]RoutePoint, error) {
+			pointLoads = append(pointLoads, segmentIDs)
+			offset := 0.00045
+			if len(pointLoads) > 1 {
+				offset = 0
+			}
+			points := make(map[string][]RoutePoint, len(segmentIDs))
+			for _, segmentID := range segmentIDs {
+				points[segmentID] = line(offset)
+			}
+			return points, nil
+		},
+		countTrackingMembersFn: func(context.Context, string) (int, error) {
+			return 1, nil
+		},
+	}, testRouteConfig())
+
+	query := SnapshotQuery{Detail: SnapshotDetailSimplified, ToleranceM: 20}
+	snapshot, err := service.Snapshot(context.Background(), "K7P9QD", "raw-member-token", query)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snapshot.Detail != SnapshotDetailSimplified || snapshot.ToleranceM != 20 {
+		t.Fatalf("Snapshot() detail = %q tolerance = %v, want simplified 20", snapshot.Detail, snapshot.ToleranceM)
+	}
+	for _, segment := range snapshot.Members[0].Paths {
+		if got := len(segment.Points); got != 5 {
+			t.Fatalf("Snapshot() %s points = %d, want endpoints plus the detour corners", segment.ID, got)
+		}
+	}
+
+	snapshot, err = service.Snapshot(context.Background(), "K7P9QD", "raw-member-token", query)
+	if err != nil {
+		t.Fatalf("Snapshot() second error = %v", err)
+	}
+	closed, open := snapshot.Members[0].Paths[0], snapshot.Members[0].Paths[1]
+	if len(closed.Points) != 5 {
+		t.Fatalf("Snapshot() cached closed segment points = %d, want 5", len(closed.Points))
+	}
+	if len(open.Points) != 2 {
+		t.Fatalf("Snapshot() open segment points = %d, want 2 after re-simplifying a straight line", len(open.Points))
+	}
+	if len(pointLoads) != 2 || len(pointLoads[1]) != 1 || pointLoads[1][0] != "segment-open" {
+		t.Fatalf("Snapshot() point loads = %v, want the second snapshot to load only the open segment", pointLoads)
+	}
+	if len(closed.TransportModes) != 1 {
+		t.Fatalf("Snapshot() cached closed segment transport modes = %#v, want the cached stretch", closed.TransportModes)
+	}
+
+	if _, err := service.Snapshot(context.Background(), "K7P9QD", "raw-member-token", SnapshotQuery{Detail: "tiny"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Snapshot() unknown detail error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestListRoutePoints(t *testing.T) {
+	t.Parallel()
+
+	const (
+		memberID  = "00000000-0000-4000-8000-0000000000a2"
+		segmentID = "00000000-0000-4000-8000-0000000000b1"
+	)
+	authorized := AuthorizedMember{
+		Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusClosed},
+		Member: Member{ID: "member-1", RouteID: "route-1", Status: MemberStatusOffline},
+	}
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return authorized, nil
+		},
+		listRoutePointsFn: func(_ context.Context, params ListRoutePointsRepoParams) ([]SegmentPoint, error) {
+			if params.RouteID != "route-1" || params.MemberID != memberID || params.AfterSegmentID != segmentID || params.AfterSeq != 10 || params.Limit != 3 {
+				t.Fatalf("ListRoutePoints() params = %#v, want lowercased cursor %s/10 with limit+1", params, segmentID)
+			}
+
+			return []SegmentPoint{
+				{SegmentID: segmentID, MemberID: memberID, RoutePoint: RoutePoint{Seq: 11}},
+				{SegmentID: segmentID, MemberID: memberID, RoutePoint: RoutePoint{Seq: 12}},
+				{SegmentID: "00000000-0000-4000-8000-0000000000b2", MemberID: memberID, RoutePoint: RoutePoint{Seq: 1}},
+			}, nil
+		},
+	}, testRouteConfig())
+
+	page, err := service.ListRoutePoints(context.Background(), "k7p9qd", "raw-member-token", PointPageQuery{
+		Member:   strings.ToUpper(memberID),
+		Segment:  " " + strings.ToUpper(segmentID) + " ",
+		AfterSeq: 10,
+		Limit:    2,
+	})
+	if err != nil {
+		t.Fatalf("ListRoutePoints() error = %v", err)
+	}
+	if len(page.Points) != 2 || page.NextSegment != segmentID || page.NextAfterSeq != 12 {
+		t.Fatalf("ListRoutePoints() page = %#v, want 2 points and cursor %s/12", page, segmentID)
+	}
+
+	for _, query := range []PointPageQuery{
+		{AfterSeq: 5},
+		{Segment: segmentID, AfterSeq: -1},
+		{Segment: "segment-1", AfterSeq: 5},
+		{Member: "member-2"},
+		{Limit: maxPointPageLimit + 1},
+	} {
+		if _, err := service.ListRoutePoints(context.Background(), "K7P9QD", "raw-member-token", query); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("ListRoutePoints(%#v) error = %v, want ErrInvalidInput", query, err)
+		}
+	}
+}
+
+func TestSnapshotHeaderSkipsPoints(t *testing.T) {
+	t.Parallel()
+
+	authorized := AuthorizedMember{
+		Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive, MaxTrackingMembers: 10},
+		Member: Member{ID: "member-1", RouteID: "route-1", Status: MemberStatusTracking},
+	}
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return authorized, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{authorized.Member}, nil
+		},
+		getPathSegmentsByRouteIDFn: func(context.Context, string) (map[string][]PathSegment, error) {
+			t.Fatal("GetPathSegmentsByRouteID() called for a header snapshot")
+			return nil, nil
+		},
+		getPathSegmentSummariesFn: func(context.Context, string) (map[string][]PathSegment, error) {
+			return map[string][]PathSegment{
+				"member-1": {{ID: "segment-1", Points: []RoutePoint{}, PointCount: 1200, LastSeq: 1200}},
+			}, nil
+		},
+		countTrackingMembersFn: func(context.Context, string) (int, error) {
+			return 1, nil
+		},
+	}, testRouteConfig())
+
+	snapshot, err := service.Snapshot(context.Background(), "K7P9QD", "raw-member-token", SnapshotQuery{Detail: SnapshotDetailHeader})
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snapshot.Detail != SnapshotDetailHeader || len(snapshot.Members) != 1 {
+		t.Fatalf("Snapshot() = %#v, want header with one member", snapshot)
+	}
+	segment := snapshot.Members[0].Paths[0]
+	if len(segment.Points) != 0 || segment.PointCount != 1200 || segment.LastSeq != 1200 {
+		t.Fatalf("Snapshot() header segment = %#v, want summary without points", segment)
+	}
+}
+
+func TestChangeTransportMode(t *testing.T) {
+	t.Parallel()
+
+	changedAt := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	authorized := AuthorizedMember{
+		Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive},
+		Member: Member{ID: "member-1", RouteID: "route-1", TransportMode: "walking", Status: MemberStatusTracking},
+	}
+	changes := 0
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return authorized, nil
+		},
+		changeMemberTransportModeFn: func(_ context.Context, params ChangeTransportModeRepoParams) (Member, TransportChange, error) {
+			changes++
+			if params.RouteID != "route-1" || params.MemberID != "member-1" || params.TransportMode != "bus" {
+				t.Fatalf("ChangeMemberTransportMode() params = %#v, want member-1 to bus", params)
+			}
+
+			member := authorized.Member
+			member.TransportMode = params.TransportMode
+			return member, TransportChange{MemberID: member.ID, FromTransportMode: "walking", TransportMode: "bus", ChangedAt: changedAt}, nil
+		},
+	}, testRouteConfig())
+
+	result, err := service.ChangeTransportMode(context.Background(), "K7P9QD", "raw-member-token", " Bus ")
+	if err != nil {
+		t.Fatalf("ChangeTransportMode() error = %v", err)
+	}
+	if result.Member.TransportMode != "bus" || result.Change == nil || result.Change.FromTransportMode != "walking" {
+		t.Fatalf("ChangeTransportMode() = %#v, want walking to bus change", result)
+	}
+
+	result, err = service.ChangeTransportMode(context.Background(), "K7P9QD", "raw-member-token", "walking")
+	if err != nil || result.Change != nil || changes != 1 {
+		t.Fatalf("ChangeTransportMode() same mode = %#v, %v, want no-op", result, err)
+	}
+
+	if _, err := service.ChangeTransportMode(context.Background(), "K7P9QD", "raw-member-token", "rocket"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("ChangeTransportMode() invalid mode error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestSnapshotSplitsPathsByTransportMode(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	authorized := AuthorizedMember{
+		Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive, MaxTrackingMembers: 10},
+		Member: Member{ID: "member-1", RouteID: "route-1", TransportMode: "walking", Status: MemberStatusTracking},
+	}
+	point := func(seq int64, minutes int) RoutePoint {
+		recordedAt := start.Add(time.Duration(minutes) * time.Minute)
+		return RoutePoint{Seq: seq, Latitude: 46, Longitude: 14.5, RecordedAt: recordedAt}
+	}
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return authorized, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{authorized.Member}, nil
+		},
+		getPathSegmentsByRouteIDFn: func(context.Context, string) (map[string][]PathSegment, error) {
+			return map[string][]PathSegment{
+				"member-1": {{ID: "segment-1", StartedAt: &start, Points: []RoutePoint{point(1, 0), point(2, 5), point(3, 10), point(4, 20), point(5, 30)}}},
+			}, nil
+		},
+		getTransportChangesFn: func(context.Context, string) (map[string][]TransportChange, error) {
+			return map[string][]TransportChange{
+				"member-1": {
+					{MemberID: "member-1", FromTransportMode: "bicycle", TransportMode: "bus", ChangedAt: start.Add(8 * time.Minute)},
+					{MemberID: "member-1", FromTransportMode: "bus", TransportMode: "walking", ChangedAt: start.Add(20 * time.Minute)},
+				},
+			}, nil
+		},
+		countTrackingMembersFn: func(context.Context, string) (int, error) {
+			return 1, nil
+		},
+	}, testRouteConfig())
+
+	snapshot, err := service.Snapshot(context.Background(), "K7P9QD", "raw-member-token", SnapshotQuery{})
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	member := snapshot.Members[0]
+	if len(member.TransportChanges) != 2 {
+		t.Fatalf("Snapshot() transport changes = %#v, want 2", member.TransportChanges)
+	}
+	want := []TransportStretch{
+		{TransportMode: "bicycle", FromSeq: 1},
+		{TransportMode: "bus", FromSeq: 3},
+		{TransportMode: "walking", FromSeq: 4},
+	}
+	got := member.Paths[0].TransportModes
+	if len(got) != len(want) {
+		t.Fatalf("Snapshot() transport stretches = %#v, want %#v", got, want)
+	}
+	for index := range want {
+		if got[index] != want[index] {
+			t.Fatalf("Snapshot() transport stretch %d = %#v, want %#v", index, got[index], want[index])
+		}
+	}
+}
+
+func TestDeleteRoute(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(stubRepository{
+		getAuthorizedOwnerByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
+			return AuthorizedMember{
+				Route: Route{
+					ID:   "route-1",
+					Code: "K7P9QD",
+				},
+				Member: Member{IsOwner: true},
+			}, nil
+		},
+		deleteRouteFn: func(_ context.Context, routeID string) error {
+			if routeID != "route-1" {
+				t.Fatalf("DeleteRoute() routeID = %q, want route-1", routeID)
+			}
+
+			return nil
+		},
+	}, testRouteConfig())
+
+	if err := service.DeleteRoute(context.Background(), "K7P9QD", "owner-token"); err != nil {
+		t.Fatalf("DeleteRoute() error = %v", err)
+	}
+}
+
+func TestRecordPositionValidatorChain(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	previousRecordedAt := now.Add(-10 * time.Second)
+	backdated := previousRecordedAt.Add(-time.Minute)
+	accurate := 12.0
+	inaccurate := 250.0
+
+	testCases := []struct {
+		name       string
+		input      PositionUpdateInput
+		wantReason string
+	}{
+		{
+			name: "accepts plausible sample",
+			input: PositionUpdateInput{
+				Latitude:         46.0570,
+				Longitude:        14.5058,
+				AccuracyM:        &accurate,
+				ClientRecordedAt: &now,
+			},
+		},
+		{
+			name: "rejects inaccurate sample",
+			input: PositionUpdateInput{
+				Latitude:         46.0570,
+				Longitude:        14.5058,
+				AccuracyM:        &inaccurate,
+				ClientRecordedAt: &now,
+			},
+			wantReason: PositionRejectionAccuracyTooLow,
+		},
+		{
+			name: "rejects duplicate client timestamp",
+			input: PositionUpdateInput{
+				Latitude:         46.0570,
+				Longitude:        14.5058,
+				AccuracyM:        &accurate,
+				ClientRecordedAt: &previousRecordedAt,
+			},
+			wantReason: PositionRejectionDuplicateTimestamp,
+		},
+		{
+			name: "rejects impossible jump for transport mode",
+			input: PositionUpdateInput{
+				Latitude:         46.1569,
+				Longitude:        14.5058,
+				AccuracyM:        &accurate,
+				ClientRecordedAt: &now,
+			},
+			wantReason: PositionRejectionImpossibleSpeed,
+		},
+		{
+			name: "rejects jump backdated before the previous point",
+			input: PositionUpdateInput{
+				Latitude:         46.1569,
+				Longitude:        14.5058,
+				AccuracyM:        &accurate,
+				ClientRecordedAt: &backdated,
+			},
+			wantReason: PositionRejectionTimestampOutOfOrder,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorded := false
+			cfg := testRouteConfig()
+			cfg.PositionMaxAccuracyM = 100
+			cfg.PositionMaxSpeedMPS = map[string]float64{"bicycle": 25}
+
+			service := NewService(stubRepository{
+				getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+					return AuthorizedMember{
+						Route: Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive},
+						Member: Member{
+							ID:            "member-2",
+							RouteID:       "route-1",
+							TransportMode: "bicycle",
+							Status:        MemberStatusTracking,
+						},
+					}, nil
+				},
+				getLastPositionPointFn: func(_ context.Context, routeID, memberID string) (RoutePoint, bool, error) {
+					if routeID != "route-1" || memberID != "member-2" {
+						t.Fatalf("GetLastPositionPoint() got routeID=%q memberID=%q", routeID, memberID)
+					}
+
+					return RoutePoint{
+						Seq:              1,
+						Latitude:         46.0569,
+						Longitude:        14.5058,
+						ClientRecordedAt: &previousRecordedAt,
+						RecordedAt:       previousRecordedAt,
+					}, true, nil
+				},
+				recordPositionFn: func(_ context.Context, params RecordPositionRepoParams) (PositionUpdateResult, error) {
+					recorded = true
+					return PositionUpdateResult{RouteID: params.RouteID, MemberID: params.MemberID}, nil
+				},
+			}, cfg)
+
+			_, err := service.RecordPosition(context.Background(), "member-token", tc.input)
+			if tc.wantReason == "" {
+				if err != nil {
+					t.Fatalf("RecordPosition() error = %v", err)
+				}
+				if !recorded {
+					t.Fatal("RecordPosition() should persist an accepted sample")
+				}
+				return
+			}
+
+			var rejected *PositionRejectedError
+			if !errors.As(err, &rejected) || rejected.Reason != tc.wantReason {
+				t.Fatalf("RecordPosition() error = %v, want rejection %q", err, tc.wantReason)
+			}
+			if !errors.Is(err, ErrPositionRejected) {
+				t.Fatalf("RecordPosition() error = %v, want ErrPositionRejected", err)
+			}
+			if recorded {
+				t.Fatal("RecordPosition() should not persist a rejected sample")
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	startedAt := createdAt.Add(time.Minute)
+	stoppedAt := createdAt.Add(5 * time.Minute)
+	leftAt := createdAt.Add(6 * time.Minute)
+	closedAt := createdAt.Add(8 * time.Minute)
+	route := Route{
+		ID:        "route-1",
+		Code:      "K7P9QD",
+		Status:    RouteStatusClosed,
+		CreatedAt: createdAt,
+		ClosedAt:  &closedAt,
+	}
+	owner := Member{ID: "member-1", RouteID: "route-1", IsOwner: true, Status: MemberStatusSpectating, JoinedAt: createdAt}
+	rider := Member{ID: "member-2", RouteID: "route-1", TransportMode: "bus", Status: MemberStatusLeft, JoinedAt: createdAt, LeftAt: &leftAt}
+
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{Route: route, Member: owner}, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{owner, rider}, nil
+		},
+		getPathSegmentHeadersFn: func(context.Context, string) (map[string][]PathSegment, error) {
+			return map[string][]PathSegment{
+				"member-2": {{ID: "segment-1", StartedAt: &startedAt, EndedAt: &stoppedAt, EndReason: PathSegmentEndReasonStopped}},
+			}, nil
+		},
+		listReplayPointsFn: replayPointsAfter([]SegmentPoint{
+			{SegmentID: "segment-1", MemberID: "member-2", RoutePoint: RoutePoint{Seq: 1, RecordedAt: createdAt.Add(2 * time.Minute)}},
+			{SegmentID: "segment-1", MemberID: "member-2", RoutePoint: RoutePoint{Seq: 2, RecordedAt: createdAt.Add(4 * time.Minute)}},
+		}),
+		getTransportChangesFn: func(context.Context, string) (map[string][]TransportChange, error) {
+			return map[string][]TransportChange{
+				"member-2": {{MemberID: "member-2", FromTransportMode: "walking", TransportMode: "bus", ChangedAt: createdAt.Add(3 * time.Minute)}},
+			}, nil
+		},
+	}, testRouteConfig())
+
+	page, err := service.Replay(context.Background(), "K7P9QD", "raw-member-token", ReplayQuery{Speed: 4})
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+
+	wantTypes := []string{
+		"member_joined",
+		"member_joined",
+		"member_started_sharing",
+		"position_updated",
+		"member_transport_changed",
+		"position_updated",
+		"member_stopped_sharing",
+		"member_left",
+		"route_closed",
+	}
+	if len(page.Events) != len(wantTypes) {
+		t.Fatalf("Replay() events = %d, want %d", len(page.Events), len(wantTypes))
+	}
+	for index, want := range wantTypes {
+		if page.Events[index].Type != want {
+			t.Fatalf("Replay() event %d type = %q, want %q", index, page.Events[index].Type, want)
+		}
+	}
+
+	if got := page.Events[3].PlaybackOffsetMS; got != (2 * time.Minute / 4).Milliseconds() {
+		t.Fatalf("Replay() playback offset = %d, want quarter of two minutes", got)
+	}
+
+	if page.Events[1].Member.TransportMode != "walking" || page.Events[2].Member.TransportMode != "walking" {
+		t.Fatalf("Replay() joined/started transport modes = %q/%q, want walking before the change", page.Events[1].Member.TransportMode, page.Events[2].Member.TransportMode)
+	}
+	if changed := page.Events[4]; changed.TransportChange == nil || changed.Member.TransportMode != "bus" || changed.Member.Status != MemberStatusTracking {
+		t.Fatalf("Replay() transport change event = %#v, want tracking member switching to bus", changed)
+	}
+
+	if page.NextCursor != "" {
+		t.Fatalf("Replay() next cursor = %q, want empty", page.NextCursor)
+	}
+
+	from := createdAt.Add(3*time.Minute + 30*time.Second)
+	seeked, err := service.Replay(context.Background(), "K7P9QD", "raw-member-token", ReplayQuery{From: &from, Limit: 1})
+	if err != nil {
+		t.Fatalf("Replay() seek error = %v", err)
+	}
+
+	if len(seeked.Events) != 1 || seeked.Events[0].Point == nil || seeked.Events[0].Point.Seq != 2 {
+		t.Fatalf("Replay() seek events = %#v, want second position", seeked.Events)
+	}
+	if seeked.Events[0].PlaybackOffsetMS != (30 * time.Second).Milliseconds() {
+		t.Fatalf("Replay() seek playback offset = %d, want thirty seconds", seeked.Events[0].PlaybackOffsetMS)
+	}
+	if seeked.NextCursor == "" {
+		t.Fatalf("Replay() seek next cursor is empty, want a cursor after the second position")
+	}
+}
+
+func TestReplayPlacesBackfilledPointsAtClientTime(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	startedAt := createdAt.Add(time.Minute)
+	uploadedAt := createdAt.Add(10 * time.Minute)
+	closedAt := createdAt.Add(12 * time.Minute)
+	firstSample := createdAt.Add(2 * time.Minute)
+	secondSample := createdAt.Add(6 * time.Minute)
+	route := Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusClosed, CreatedAt: createdAt, ClosedAt: &closedAt}
+	owner := Member{ID: "member-1", RouteID: "route-1", IsOwner: true, Status: MemberStatusSpectating, JoinedAt: createdAt}
+
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{Route: route, Member: owner}, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{owner}, nil
+		},
+		getPathSegmentHeadersFn: func(context.Context, string) (map[string][]PathSegment, error) {
+			return map[string][]PathSegment{
+				"member-1": {{ID: "segment-1", StartedAt: &startedAt, EndReason: PathSegmentEndReasonRouteClosed}},
+			}, nil
+		},
+		listReplayPointsFn: replayPointsAfter([]SegmentPoint{
+			{SegmentID: "segment-1", MemberID: "member-1", RoutePoint: RoutePoint{Seq: 1, RecordedAt: uploadedAt, ClientRecordedAt: &firstSample}},
+			{SegmentID: "segment-1", MemberID: "member-1", RoutePoint: RoutePoint{Seq: 2, RecordedAt: uploadedAt, ClientRecordedAt: &secondSample}},
+		}),
+	}, testRouteConfig())
 
-	_, err := service.StartSharing(context.Background(), "K7P9QD", "member-token")
-	if !errors.Is(err, ErrSharingNotAllowed) {
-		t.Fatalf("StartSharing() error = %v, want ErrSharingNotAllowed", err)
+	page, err := service.Replay(context.Background(), "K7P9QD", "raw-member-token", ReplayQuery{})
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+
+	var positions []ReplayEvent
+	for _, event := range page.Events {
+		if event.Type == "position_updated" {
+			positions = append(positions, event)
+		}
+	}
+	if len(positions) != 2 {
+		t.Fatalf("Replay() position events = %d, want 2", len(positions))
+	}
+	if !positions[0].At.Equal(firstSample) || !positions[1].At.Equal(secondSample) {
+		t.Fatalf("Replay() position times = %s, %s; want client times %s, %s", positions[0].At, positions[1].At, firstSample, secondSample)
+	}
+	if got := positions[1].PlaybackOffsetMS; got != (6 * time.Minute).Milliseconds() {
+		t.Fatalf("Replay() playback offset = %d, want six minutes", got)
 	}
 }
 
-func TestStopSharing(t *testing.T) {
+func TestReplayPagesWithKeysetCursor(t *testing.T) {
 	t.Parallel()
 
+	const (
+		routeID   = "00000000-0000-0000-0000-0000000000a1"
+		ownerID   = "00000000-0000-0000-0000-0000000000b1"
+		riderID   = "00000000-0000-0000-0000-0000000000b2"
+		segmentID = "00000000-0000-0000-0000-0000000000c1"
+		messageID = "00000000-0000-0000-0000-0000000000d1"
+	)
+	createdAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	startedAt := createdAt.Add(time.Minute)
+	stoppedAt := createdAt.Add(5 * time.Minute)
+	closedAt := createdAt.Add(8 * time.Minute)
+	route := Route{ID: routeID, Code: "K7P9QD", Status: RouteStatusClosed, CreatedAt: createdAt, ClosedAt: &closedAt}
+	owner := Member{ID: ownerID, RouteID: routeID, IsOwner: true, Status: MemberStatusSpectating, JoinedAt: createdAt}
+	rider := Member{ID: riderID, RouteID: routeID, Status: MemberStatusSpectating, JoinedAt: createdAt}
+	// Two points share a timestamp with the chat message so pages must split ties on the row key.
+	points := []SegmentPoint{
+		{SegmentID: segmentID, MemberID: riderID, RoutePoint: RoutePoint{Seq: 1, RecordedAt: createdAt.Add(2 * time.Minute)}},
+		{SegmentID: segmentID, MemberID: riderID, RoutePoint: RoutePoint{Seq: 2, RecordedAt: createdAt.Add(3 * time.Minute)}},
+		{SegmentID: segmentID, MemberID: riderID, RoutePoint: RoutePoint{Seq: 3, RecordedAt: createdAt.Add(3 * time.Minute)}},
+		{SegmentID: segmentID, MemberID: riderID, RoutePoint: RoutePoint{Seq: 4, RecordedAt: createdAt.Add(4 * time.Minute)}},
+	}
+	message := ChatMessage{ID: messageID, MemberID: ownerID, Body: "Nearly there", PostedAt: createdAt.Add(3 * time.Minute)}
+
+	var pointParams []ListReplayPointsRepoParams
+	var mu sync.Mutex
 	service := NewService(stubRepository{
-		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
-			return AuthorizedMember{
-				Route: Route{
-					ID:     "route-1",
-					Code:   "K7P9QD",
-					Status: RouteStatusActive,
-				},
-				Member: Member{
-					ID:      "member-2",
-					RouteID: "route-1",
-					Status:  MemberStatusTracking,
-				},
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{Route: route, Member: owner}, nil
+		},
+		getMembersByRouteIDFn: func(context.Context, string) ([]Member, error) {
+			return []Member{owner, rider}, nil
+		},
+		getPathSegmentHeadersFn: func(context.Context, string) (map[string][]PathSegment, error) {
+			return map[string][]PathSegment{
+				riderID: {{ID: segmentID, StartedAt: &startedAt, EndedAt: &stoppedAt, EndReason: PathSegmentEndReasonStopped}},
 			}, nil
 		},
-		stopTrackingMemberFn: func(_ context.Context, routeID, memberID string) (Member, error) {
-			if routeID != "route-1" || memberID != "member-2" {
-				t.Fatalf("StopTrackingMember() got routeID=%q memberID=%q", routeID, memberID)
+		listReplayPointsFn: func(ctx context.Context, params ListReplayPointsRepoParams) ([]SegmentPoint, error) {
+			mu.Lock()
+			pointParams = append(pointParams, params)
+			mu.Unlock()
+
+			return replayPointsAfter(points)(ctx, params)
+		},
+		listReplayChatMessagesFn: func(_ context.Context, params ListReplayChatMessagesRepoParams) ([]ChatMessage, error) {
+			if compareReplayKeys(message.PostedAt, 0, message.ID, 0, params.AfterAt, 0, params.AfterID, 0) > 0 {
+				return []ChatMessage{message}, nil
 			}
 
-			return Member{
-				ID:      memberID,
-				RouteID: routeID,
-				Status:  MemberStatusSpectating,
-			}, nil
+			return []ChatMessage{}, nil
 		},
-	}, 10)
+	}, testRouteConfig())
 
-	result, err := service.StopSharing(context.Background(), "K7P9QD", "member-token")
+	full, err := service.Replay(context.Background(), "K7P9QD", "raw-member-token", ReplayQuery{})
 	if err != nil {
-		t.Fatalf("StopSharing() error = %v", err)
+		t.Fatalf("Replay() error = %v", err)
 	}
 
-	if result.Member.Status != MemberStatusSpectating {
-		t.Fatalf("StopSharing() status = %q, want %q", result.Member.Status, MemberStatusSpectating)
+	var paged []ReplayEvent
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > len(full.Events) {
+			t.Fatalf("Replay() did not finish paging after %d pages", pages)
+		}
+
+		page, err := service.Replay(context.Background(), "K7P9QD", "raw-member-token", ReplayQuery{Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("Replay() page %d error = %v", pages, err)
+		}
+		if len(page.Events) > 2 {
+			t.Fatalf("Replay() page %d events = %d, want at most 2", pages, len(page.Events))
+		}
+		paged = append(paged, page.Events...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(paged) != len(full.Events) {
+		t.Fatalf("Replay() paged events = %d, want %d", len(paged), len(full.Events))
+	}
+	for index := range full.Events {
+		if paged[index].Type != full.Events[index].Type || !paged[index].At.Equal(full.Events[index].At) || paged[index].keyID != full.Events[index].keyID || paged[index].keySeq != full.Events[index].keySeq {
+			t.Fatalf("Replay() paged event %d = %s at %s, want %s at %s", index, paged[index].Type, paged[index].At, full.Events[index].Type, full.Events[index].At)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, params := range pointParams[1:] {
+		if params.Limit != 3 {
+			t.Fatalf("ListReplayPoints() limit = %d, want one past the page size", params.Limit)
+		}
+	}
+	if last := pointParams[len(pointParams)-1]; last.AfterAt.IsZero() {
+		t.Fatalf("ListReplayPoints() last page params = %#v, want a window after the cursor", last)
+	}
+
+	for _, cursor := range []string{"6", "not a cursor", encodeReplayCursor(ReplayEvent{At: createdAt, order: replayOrderPosition, keyID: "segment-1"})} {
+		if _, err := service.Replay(context.Background(), "K7P9QD", "raw-member-token", ReplayQuery{Cursor: cursor}); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("Replay(cursor %q) error = %v, want ErrInvalidInput", cursor, err)
+		}
 	}
 }
 
-func TestRecordPosition(t *testing.T) {
+func TestReplayRejectsActiveRouteAndInvalidSpeed(t *testing.T) {
 	t.Parallel()
 
-	accuracy := 12.5
-	clientRecordedAt := time.Now().UTC()
 	service := NewService(stubRepository{
-		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
 			return AuthorizedMember{
-				Route: Route{
-					ID:     "route-1",
-					Code:   "K7P9QD",
-					Status: RouteStatusActive,
-				},
-				Member: Member{
-					ID:      "member-2",
-					RouteID: "route-1",
-					Status:  MemberStatusTracking,
-				},
+				Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive},
+				Member: Member{ID: "member-1", Status: MemberStatusSpectating},
 			}, nil
 		},
-		recordPositionFn: func(_ context.Context, params RecordPositionRepoParams) (PositionUpdateResult, error) {
-			if params.RouteID != "route-1" || params.MemberID != "member-2" {
-				t.Fatalf("RecordPosition() got routeID=%q memberID=%q", params.RouteID, params.MemberID)
-			}
+	}, testRouteConfig())
 
-			if params.Latitude != 46.0569 || params.Longitude != 14.5058 {
-				t.Fatalf("RecordPosition() got coordinates=%f,%f", params.Latitude, params.Longitude)
+	if _, err := service.Replay(context.Background(), "K7P9QD", "raw-member-token", ReplayQuery{}); !errors.Is(err, ErrRouteActive) {
+		t.Fatalf("Replay() error = %v, want ErrRouteActive", err)
+	}
+
+	if _, err := service.Replay(context.Background(), "K7P9QD", "raw-member-token", ReplayQuery{Speed: 3}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("Replay() error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestPostChatMessage(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	authorized := AuthorizedMember{
+		Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive},
+		Member: Member{ID: "member-1", RouteID: "route-1", Status: MemberStatusSpectating},
+	}
+
+	testCases := []struct {
+		name    string
+		body    string
+		recent  int
+		wantErr error
+	}{
+		{name: "stores trimmed message", body: "  On my way  "},
+		{name: "rejects empty message", body: "   ", wantErr: ErrInvalidInput},
+		{name: "rejects message over max length", body: strings.Repeat("ž", 21), wantErr: ErrChatMessageTooLong},
+		{name: "rejects member over rate limit", body: "Again", recent: 2, wantErr: ErrChatRateLimited},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			service := NewService(stubRepository{
+				getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+					return authorized, nil
+				},
+				createChatMessageFn: func(_ context.Context, params CreateChatMessageRepoParams) (ChatMessage, error) {
+					if params.MemberID != "member-1" || params.RateLimitMessages != 2 || !params.RateLimitSince.Equal(now.Add(-10*time.Second)) {
+						t.Fatalf("CreateChatMessage() rate limit = %d since %s for %q, want 2 since ten seconds ago", params.RateLimitMessages, params.RateLimitSince, params.MemberID)
+					}
+					if tc.recent >= params.RateLimitMessages {
+						return ChatMessage{}, ErrChatRateLimited
+					}
+					if params.Body != "On my way" {
+						t.Fatalf("CreateChatMessage() body = %q, want trimmed body", params.Body)
+					}
+
+					return ChatMessage{ID: "message-1", MemberID: params.MemberID, Body: params.Body, PostedAt: now}, nil
+				},
+			}, testRouteConfig())
+			service.now = func() time.Time { return now }
+
+			message, err := service.PostChatMessage(context.Background(), "raw-member-token", tc.body)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("PostChatMessage() error = %v, want %v", err, tc.wantErr)
+				}
+
+				return
 			}
 
-			if params.AccuracyM == nil || *params.AccuracyM != accuracy {
-				t.Fatal("RecordPosition() expected accuracy")
+			if err != nil {
+				t.Fatalf("PostChatMessage() error = %v", err)
 			}
 
-			if params.ClientRecordedAt == nil || !params.ClientRecordedAt.Equal(clientRecordedAt) {
-				t.Fatal("RecordPosition() expected client recorded time")
+			if message.ID != "message-1" {
+				t.Fatalf("PostChatMessage() id = %q, want message-1", message.ID)
 			}
+		})
+	}
+}
 
-			if string(params.RawPayload) != "{}" {
-				t.Fatalf("RecordPosition() raw payload = %s, want {}", string(params.RawPayload))
+func TestListChatMessages(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	service := NewService(stubRepository{
+		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
+			return AuthorizedMember{
+				Route:  Route{ID: "route-1", Code: "K7P9QD", Status: RouteStatusActive},
+				Member: Member{ID: "member-1", Status: MemberStatusSpectating},
+			}, nil
+		},
+		listChatMessagesFn: func(_ context.Context, params ListChatMessagesRepoParams) ([]ChatMessage, error) {
+			if params.RouteID != "route-1" || params.BeforeID != "message-4" || params.Limit != 3 {
+				t.Fatalf("ListChatMessages() params = %#v, want route-1 before message-4 limit 3", params)
 			}
 
-			return PositionUpdateResult{
-				RouteID:   params.RouteID,
-				MemberID:  params.MemberID,
-				SegmentID: "segment-1",
-				Point: RoutePoint{
-					Seq:              1,
-					Latitude:         params.Latitude,
-					Longitude:        params.Longitude,
-					AccuracyM:        params.AccuracyM,
-					ClientRecordedAt: params.ClientRecordedAt,
-					RecordedAt:       time.Now().UTC(),
-				},
+			return []ChatMessage{
+				{ID: "message-3", PostedAt: now.Add(3 * time.Minute)},
+				{ID: "message-2", PostedAt: now.Add(2 * time.Minute)},
+				{ID: "message-1", PostedAt: now.Add(time.Minute)},
 			}, nil
 		},
-	}, 10)
+	}, testRouteConfig())
 
-	result, err := service.RecordPosition(context.Background(), "member-token", PositionUpdateInput{
-		Latitude:         46.0569,
-		Longitude:        14.5058,
-		AccuracyM:        &accuracy,
-		ClientRecordedAt: &clientRecordedAt,
-	})
+	page, err := service.ListChatMessages(context.Background(), "k7p9qd", "raw-member-token", ChatMessageQuery{Before: "message-4", Limit: 2})
 	if err != nil {
-		t.Fatalf("RecordPosition() error = %v", err)
+		t.Fatalf("ListChatMessages() error = %v", err)
 	}
 
-	if result.SegmentID != "segment-1" || result.Point.Seq != 1 {
-		t.Fatalf("RecordPosition() result segment/seq = %q/%d", result.SegmentID, result.Point.Seq)
+	if len(page.Messages) != 2 || page.Messages[0].ID != "message-2" || page.Messages[1].ID != "message-3" {
+		t.Fatalf("ListChatMessages() messages = %#v, want message-2 then message-3", page.Messages)
+	}
+
+	if page.NextCursor != "message-2" {
+		t.Fatalf("ListChatMessages() next cursor = %q, want message-2", page.NextCursor)
 	}
 }
 
-func TestRecordPositionRejectsSpectator(t *testing.T) {
+type connectionCheckerFunc func(context.Context, string, string) (bool, error)
+
+func (f connectionCheckerFunc) HasMemberConnection(ctx context.Context, routeID, memberID string) (bool, error) {
+	return f(ctx, routeID, memberID)
+}
+
+func TestSweepPresence(t *testing.T) {
 	t.Parallel()
 
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	rules := PresenceRules{
+		TrackingStaleAfter:    20 * time.Second,
+		TrackingOfflineAfter:  5 * time.Minute,
+		SpectatorOfflineAfter: 30 * time.Second,
+	}
+	wantDeadlines := PresenceDeadlines{
+		TrackingStaleBefore:    now.Add(-20 * time.Second),
+		StaleOfflineBefore:     now.Add(-5 * time.Minute),
+		SpectatorOfflineBefore: now.Add(-30 * time.Second),
+	}
 	service := NewService(stubRepository{
-		getAuthorizedMemberByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
-			return AuthorizedMember{
-				Route: Route{
-					ID:     "route-1",
-					Code:   "K7P9QD",
-					Status: RouteStatusActive,
-				},
-				Member: Member{
-					ID:      "member-2",
-					RouteID: "route-1",
-					Status:  MemberStatusSpectating,
-				},
+		listPresenceCandidatesFn: func(_ context.Context, params PresenceDeadlines) ([]PresenceCandidate, error) {
+			if params != wantDeadlines {
+				t.Fatalf("ListPresenceCandidates() params = %#v, want deadlines derived from rules", params)
+			}
+
+			return []PresenceCandidate{
+				{RouteID: "route-1", MemberID: "tracking-1", Status: MemberStatusTracking},
+				{RouteID: "route-1", MemberID: "stale-1", Status: MemberStatusStale},
+				{RouteID: "route-1", MemberID: "spectator-connected", Status: MemberStatusSpectating},
+				{RouteID: "route-2", MemberID: "spectator-gone", Status: MemberStatusSpectating},
+				{RouteID: "route-2", MemberID: "tracking-raced", Status: MemberStatusTracking},
 			}, nil
 		},
-		recordPositionFn: func(context.Context, RecordPositionRepoParams) (PositionUpdateResult, error) {
-			t.Fatal("RecordPosition() repository call should not run for a spectator")
-			return PositionUpdateResult{}, nil
+		markOverdueMemberStaleFn: func(_ context.Context, routeID, memberID string, deadlines PresenceDeadlines) (Member, bool, error) {
+			if deadlines != wantDeadlines {
+				t.Fatalf("MarkOverdueMemberStale() deadlines = %#v, want the sweep deadlines", deadlines)
+			}
+			// A point accepted after listing moves the deadline, so the update matches no row.
+			if memberID == "tracking-raced" {
+				return Member{ID: memberID, RouteID: routeID, Status: MemberStatusTracking}, false, nil
+			}
+
+			return Member{ID: memberID, RouteID: routeID, Status: MemberStatusStale}, true, nil
+		},
+		markOverdueMemberOfflineFn: func(_ context.Context, routeID, memberID string, deadlines PresenceDeadlines) (Member, bool, error) {
+			if memberID == "spectator-connected" {
+				t.Fatal("MarkOverdueMemberOffline() called for a connected spectator")
+			}
+			if deadlines != wantDeadlines {
+				t.Fatalf("MarkOverdueMemberOffline() deadlines = %#v, want the sweep deadlines", deadlines)
+			}
+
+			return Member{ID: memberID, RouteID: routeID, Status: MemberStatusOffline}, true, nil
 		},
-	}, 10)
+	}, testRouteConfig())
+	service.now = func() time.Time { return now }
 
-	_, err := service.RecordPosition(context.Background(), "member-token", PositionUpdateInput{
-		Latitude:  46.0569,
-		Longitude: 14.5058,
-	})
-	if !errors.Is(err, ErrInvalidInput) {
-		t.Fatalf("RecordPosition() error = %v, want ErrInvalidInput", err)
+	transitions, err := service.SweepPresence(context.Background(), rules, connectionCheckerFunc(func(_ context.Context, _, memberID string) (bool, error) {
+		return memberID == "spectator-connected", nil
+	}))
+	if err != nil {
+		t.Fatalf("SweepPresence() error = %v", err)
+	}
+
+	want := []PresenceTransition{
+		{RouteID: "route-1", Type: PresenceEventMemberBecameStale, Member: Member{ID: "tracking-1", RouteID: "route-1", Status: MemberStatusStale}},
+		{RouteID: "route-1", Type: PresenceEventMemberWentOffline, Member: Member{ID: "stale-1", RouteID: "route-1", Status: MemberStatusOffline}},
+		{RouteID: "route-2", Type: PresenceEventMemberWentOffline, Member: Member{ID: "spectator-gone", RouteID: "route-2", Status: MemberStatusOffline}},
+	}
+	if len(transitions) != len(want) {
+		t.Fatalf("SweepPresence() transitions = %#v, want %#v", transitions, want)
+	}
+	for index := range want {
+		if transitions[index].RouteID != want[index].RouteID || transitions[index].Type != want[index].Type || transitions[index].Member.ID != want[index].Member.ID || transitions[index].Member.Status != want[index].Member.Status {
+			t.Fatalf("SweepPresence() transition %d = %#v, want %#v", index, transitions[index], want[index])
+		}
 	}
 }
 
-func TestRecordPositionRejectsInvalidCoordinates(t *testing.T) {
+func TestSweepPresenceSkipsWhenAnotherReplicaHoldsLock(t *testing.T) {
 	t.Parallel()
 
 	service := NewService(stubRepository{
-		getAuthorizedMemberByTokenFn: func(context.Context, string) (AuthorizedMember, error) {
-			t.Fatal("RecordPosition() should validate coordinates before authorization")
-			return AuthorizedMember{}, nil
+		withPresenceSweepLockFn: func(context.Context, func(context.Context) error) (bool, error) {
+			return false, nil
 		},
-	}, 10)
+		listPresenceCandidatesFn: func(context.Context, PresenceDeadlines) ([]PresenceCandidate, error) {
+			t.Fatal("ListPresenceCandidates() called without the sweep lock")
+			return nil, nil
+		},
+	}, testRouteConfig())
 
-	_, err := service.RecordPosition(context.Background(), "member-token", PositionUpdateInput{
-		Latitude:  91,
-		Longitude: 14.5058,
-	})
-	if !errors.Is(err, ErrInvalidInput) {
-		t.Fatalf("RecordPosition() error = %v, want ErrInvalidInput", err)
+	transitions, err := service.SweepPresence(context.Background(), PresenceRules{}, connectionCheckerFunc(func(context.Context, string, string) (bool, error) {
+		return false, nil
+	}))
+	if err != nil {
+		t.Fatalf("SweepPresence() error = %v", err)
+	}
+	if len(transitions) != 0 {
+		t.Fatalf("SweepPresence() transitions = %#v, want none", transitions)
 	}
 }
 
-func TestDeleteRoute(t *testing.T) {
+func TestSnapSegments(t *testing.T) {
 	t.Parallel()
 
+	confidence := 0.9
+	var saved []SaveSnappedGeometryRepoParams
 	service := NewService(stubRepository{
-		getAuthorizedOwnerByTokenFn: func(_ context.Context, _ string) (AuthorizedMember, error) {
-			return AuthorizedMember{
-				Route: Route{
-					ID:   "route-1",
-					Code: "K7P9QD",
-				},
-				Member: Member{IsOwner: true},
+		listSnapCandidatesFn: func(context.Context, int) ([]SnapCandidate, error) {
+			return []SnapCandidate{
+				{SegmentID: "segment-1", RouteID: "route-1", TransportMode: "walking", LastSeq: 3},
+				{SegmentID: "segment-2", RouteID: "route-1", TransportMode: "boat", LastSeq: 2},
 			}, nil
 		},
-		deleteRouteFn: func(_ context.Context, routeID string) error {
-			if routeID != "route-1" {
-				t.Fatalf("DeleteRoute() routeID = %q, want route-1", routeID)
+		getSegmentPointsFn: func(_ context.Context, segmentID string, fromSeq, throughSeq int64) ([]RoutePoint, error) {
+			if fromSeq != 0 {
+				t.Fatalf("GetSegmentPoints(%s) fromSeq = %d, want the whole closed segment", segmentID, fromSeq)
+			}
+			points := make([]RoutePoint, 0, throughSeq)
+			for seq := int64(1); seq <= throughSeq; seq++ {
+				points = append(points, RoutePoint{Seq: seq, Latitude: 52.52, Longitude: 13.40 + float64(seq)/1000})
 			}
 
+			return points, nil
+		},
+		saveSnappedGeometryFn: func(_ context.Context, params SaveSnappedGeometryRepoParams) error {
+			saved = append(saved, params)
 			return nil
 		},
-	}, 10)
+	}, testRouteConfig())
+
+	matcher := MatcherFunc(func(_ context.Context, input MatchInput) (MatchResult, error) {
+		if input.TransportMode != "walking" {
+			return MatchResult{Matcher: "test"}, ErrNoMatch
+		}
+		if len(input.Points) != 3 {
+			t.Fatalf("Match() points = %d, want 3", len(input.Points))
+		}
+
+		return MatchResult{
+			Matcher:    "test",
+			Lines:      [][][2]float64{{{13.401, 52.5201}, {13.403, 52.5201}}, {{13.404, 52.5201}}},
+			Confidence: &confidence,
+		}, nil
+	})
 
-	if err := service.DeleteRoute(context.Background(), "K7P9QD", "owner-token"); err != nil {
-		t.Fatalf("DeleteRoute() error = %v", err)
+	snapped, err := service.SnapSegments(context.Background(), matcher)
+	if err != nil {
+		t.Fatalf("SnapSegments() error = %v", err)
+	}
+	if snapped != 2 || len(saved) != 2 {
+		t.Fatalf("SnapSegments() = %d saved %#v, want 2 saved", snapped, saved)
+	}
+	if saved[0].SegmentID != "segment-1" || saved[0].SourceSeq != 3 || len(saved[0].Lines) != 1 || saved[0].Confidence == nil {
+		t.Fatalf("saved snapped geometry = %#v, want one drawable matched line", saved[0])
+	}
+	if saved[1].SegmentID != "segment-2" || saved[1].Lines != nil || saved[1].Confidence != nil {
+		t.Fatalf("saved unmatched geometry = %#v, want empty geometry", saved[1])
+	}
+}
+
+func TestSnapSegmentsBacksOffFailedMatches(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	var failures []SaveSnapFailureRepoParams
+	service := NewService(stubRepository{
+		listSnapCandidatesFn: func(context.Context, int) ([]SnapCandidate, error) {
+			return []SnapCandidate{
+				{SegmentID: "segment-1", RouteID: "route-1", TransportMode: "walking", LastSeq: 2},
+				{SegmentID: "segment-2", RouteID: "route-1", TransportMode: "walking", LastSeq: 2, FailedAttempts: 3},
+				{SegmentID: "segment-3", RouteID: "route-1", TransportMode: "walking", LastSeq: 2, FailedAttempts: 20},
+			}, nil
+		},
+		getSegmentPointsFn: func(context.Context, string, int64, int64) ([]RoutePoint, error) {
+			return []RoutePoint{{Seq: 1}, {Seq: 2}}, nil
+		},
+		saveSnappedGeometryFn: func(context.Context, SaveSnappedGeometryRepoParams) error {
+			t.Fatal("SaveSnappedGeometry() called for a failed match")
+			return nil
+		},
+		saveSnapFailureFn: func(_ context.Context, params SaveSnapFailureRepoParams) error {
+			failures = append(failures, params)
+			return nil
+		},
+	}, testRouteConfig())
+	service.now = func() time.Time { return now }
+
+	matchErr := errors.New("osrm match failed with TooBig")
+	snapped, err := service.SnapSegments(context.Background(), MatcherFunc(func(context.Context, MatchInput) (MatchResult, error) {
+		return MatchResult{}, matchErr
+	}))
+	if !errors.Is(err, matchErr) || snapped != 0 {
+		t.Fatalf("SnapSegments() = %d, %v, want 0 and the match error", snapped, err)
+	}
+
+	want := []SaveSnapFailureRepoParams{
+		{SegmentID: "segment-1", RouteID: "route-1", FailedAttempts: 1, RetryAfter: now.Add(time.Minute)},
+		{SegmentID: "segment-2", RouteID: "route-1", FailedAttempts: 4, RetryAfter: now.Add(8 * time.Minute)},
+		{SegmentID: "segment-3", RouteID: "route-1", FailedAttempts: 21, RetryAfter: now.Add(time.Hour)},
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("SaveSnapFailure() calls = %#v, want %#v", failures, want)
+	}
+	for i := range want {
+		if failures[i] != want[i] {
+			t.Fatalf("SaveSnapFailure() call %d = %#v, want %#v", i, failures[i], want[i])
+		}
+	}
+}
+
+func TestSnapSegmentsMatchesOpenSegmentTail(t *testing.T) {
+	t.Parallel()
+
+	storedConfidence := 0.8
+	tailConfidence := 0.9
+	var saved SaveSnappedGeometryRepoParams
+	service := NewService(stubRepository{
+		listSnapCandidatesFn: func(context.Context, int) ([]SnapCandidate, error) {
+			return []SnapCandidate{{
+				SegmentID:     "segment-1",
+				RouteID:       "route-1",
+				TransportMode: "walking",
+				LastSeq:       12,
+				Open:          true,
+				SnappedSeq:    10,
+				Snapped: &SnappedGeometry{
+					Matcher:    "test",
+					Lines:      [][][2]float64{{{13.400, 52.52}, {13.401, 52.52}}, {{13.405, 52.52}, {13.410, 52.52}}},
+					Confidence: &storedConfidence,
+					SourceSeq:  10,
+				},
+			}}, nil
+		},
+		getSegmentPointsFn: func(_ context.Context, _ string, fromSeq, throughSeq int64) ([]RoutePoint, error) {
+			if fromSeq != 10 || throughSeq != 12 {
+				t.Fatalf("GetSegmentPoints() range = %d..%d, want the tail 10..12", fromSeq, throughSeq)
+			}
+			return []RoutePoint{{Seq: 10}, {Seq: 11}, {Seq: 12}}, nil
+		},
+		saveSnappedGeometryFn: func(_ context.Context, params SaveSnappedGeometryRepoParams) error {
+			saved = params
+			return nil
+		},
+	}, testRouteConfig())
+
+	if _, err := service.SnapSegments(context.Background(), MatcherFunc(func(context.Context, MatchInput) (MatchResult, error) {
+		return MatchResult{
+			Matcher:            "test",
+			Lines:              [][][2]float64{{{13.410, 52.52}, {13.412, 52.52}}, {{13.420, 52.52}, {13.421, 52.52}}},
+			Confidence:         &tailConfidence,
+			StartsAtFirstPoint: true,
+		}, nil
+	})); err != nil {
+		t.Fatalf("SnapSegments() error = %v", err)
+	}
+
+	// The tail's first line starts at seq 10, where the stored geometry ends, so it continues that line.
+	wantLines := [][][2]float64{
+		{{13.400, 52.52}, {13.401, 52.52}},
+		{{13.405, 52.52}, {13.410, 52.52}, {13.412, 52.52}},
+		{{13.420, 52.52}, {13.421, 52.52}},
+	}
+	if saved.SourceSeq != 12 || !slices.EqualFunc(saved.Lines, wantLines, slices.Equal[[][2]float64]) {
+		t.Fatalf("saved snapped geometry = %#v, want the tail appended to the stored lines through seq 12", saved)
+	}
+	if saved.Confidence == nil || *saved.Confidence != storedConfidence {
+		t.Fatalf("saved confidence = %v, want the lower stored confidence", saved.Confidence)
 	}
 }
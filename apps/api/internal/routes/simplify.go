@@ -0,0 +1,247 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+const (
+	// SnapshotDetailFull returns every persisted point.
+	SnapshotDetailFull = "full"
+	// SnapshotDetailSimplified returns Douglas–Peucker simplified paths.
+	SnapshotDetailSimplified = "simplified"
+	// SnapshotDetailHeader returns path segment summaries without points.
+	SnapshotDetailHeader = "header"
+
+	defaultSimplifyToleranceM   = 10
+	maxSimplifyToleranceM       = 1000
+	simplifiedSegmentCacheLimit = 4096
+)
+
+// SnapshotQuery selects how much path detail a snapshot carries.
+type SnapshotQuery struct {
+	Detail     string
+	ToleranceM float64
+}
+
+func normalizeSnapshotQuery(query SnapshotQuery) (SnapshotQuery, error) {
+	normalized := SnapshotQuery{
+		Detail:     strings.ToLower(strings.TrimSpace(query.Detail)),
+		ToleranceM: query.ToleranceM,
+	}
+	if normalized.Detail == "" {
+		normalized.Detail = SnapshotDetailFull
+	}
+
+	switch normalized.Detail {
+	case SnapshotDetailFull, SnapshotDetailHeader:
+		normalized.ToleranceM = 0
+	case SnapshotDetailSimplified:
+		if normalized.ToleranceM == 0 {
+			normalized.ToleranceM = defaultSimplifyToleranceM
+		}
+		if math.IsNaN(normalized.ToleranceM) || normalized.ToleranceM < 0 || normalized.ToleranceM > maxSimplifyToleranceM {
+			return SnapshotQuery{}, ErrInvalidInput
+		}
+	default:
+		return SnapshotQuery{}, ErrInvalidInput
+	}
+
+	return normalized, nil
+}
+
+type simplifiedSegmentKey struct {
+	segmentID  string
+	toleranceM float64
+}
+
+// simplifiedSegment is the cached snapshot form of a closed segment. Transport stretches are kept
+// because they are computed from the raw points, which cached segments no longer load.
+type simplifiedSegment struct {
+	points         []RoutePoint
+	transportModes []TransportStretch
+}
+
+// simplifiedSegmentCache keeps simplified points for closed segments, which never change again.
+// Open segments are simplified on every request because new points keep arriving.
+type simplifiedSegmentCache struct {
+	mu      sync.Mutex
+	entries map[simplifiedSegmentKey]simplifiedSegment
+	order   []simplifiedSegmentKey
+	limit   int
+}
+
+func newSimplifiedSegmentCache(limit int) *simplifiedSegmentCache {
+	return &simplifiedSegmentCache{
+		entries: make(map[simplifiedSegmentKey]simplifiedSegment),
+		limit:   limit,
+	}
+}
+
+func (c *simplifiedSegmentCache) get(key simplifiedSegmentKey) (simplifiedSegment, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	segment, ok := c.entries[key]
+	return segment, ok
+}
+
+func (c *simplifiedSegmentCache) put(key simplifiedSegmentKey, segment simplifiedSegment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	if len(c.order) >= c.limit {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.entries[key] = segment
+	c.order = append(c.order, key)
+}
+
+// simplifiedPathLoader loads snapshot paths for one simplified snapshot. Closed segments found in
+// the cache are filled from it without reading their points; only open and uncached segments load
+// raw points, which simplify then thins and caches.
+type simplifiedPathLoader struct {
+	service    *Service
+	toleranceM float64
+	cached     map[string]struct{}
+}
+
+func (s *Service) newSimplifiedPathLoader(toleranceM float64) *simplifiedPathLoader {
+	return &simplifiedPathLoader{
+		service:    s,
+		toleranceM: toleranceM,
+		cached:     make(map[string]struct{}),
+	}
+}
+
+func (l *simplifiedPathLoader) load(ctx context.Context, routeID string) (map[string][]PathSegment, error) {
+	pathsByMemberID, err := l.service.repo.GetPathSegmentHeadersByRouteID(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var uncachedIDs []string
+	for _, paths := range pathsByMemberID {
+		for index := range paths {
+			segment := &paths[index]
+			if !isClosedSegment(*segment) {
+				uncachedIDs = append(uncachedIDs, segment.ID)
+				continue
+			}
+
+			cached, ok := l.service.simplifiedSegments.get(simplifiedSegmentKey{segmentID: segment.ID, toleranceM: l.toleranceM})
+			if !ok {
+				uncachedIDs = append(uncachedIDs, segment.ID)
+				continue
+			}
+			segment.Points = cached.points
+			segment.TransportModes = cached.transportModes
+			l.cached[segment.ID] = struct{}{}
+		}
+	}
+
+	pointsBySegmentID, err := l.service.repo.GetPointsBySegmentIDs(ctx, routeID, uncachedIDs)
+	if err != nil {
+		return nil, fmt.Errorf("load uncached segment points: %w", err)
+	}
+	for _, paths := range pathsByMemberID {
+		for index := range paths {
+			if points, ok := pointsBySegmentID[paths[index].ID]; ok {
+				paths[index].Points = points
+			}
+		}
+	}
+
+	return pathsByMemberID, nil
+}
+
+// simplify thins the raw points load returned and caches closed segments.
+func (l *simplifiedPathLoader) simplify(members []SnapshotMember) {
+	for memberIndex := range members {
+		for segmentIndex := range members[memberIndex].Paths {
+			segment := &members[memberIndex].Paths[segmentIndex]
+			if _, ok := l.cached[segment.ID]; ok {
+				continue
+			}
+
+			segment.Points = simplifyPoints(segment.Points, l.toleranceM)
+			if isClosedSegment(*segment) {
+				l.service.simplifiedSegments.put(simplifiedSegmentKey{segmentID: segment.ID, toleranceM: l.toleranceM}, simplifiedSegment{
+					points:         segment.Points,
+					transportModes: segment.TransportModes,
+				})
+			}
+		}
+	}
+}
+
+func isClosedSegment(segment PathSegment) bool {
+	return segment.EndedAt != nil && segment.ID != ""
+}
+
+// simplifyPoints applies Douglas–Peucker to a path, keeping both endpoints and every point that
+// deviates more than toleranceM from the simplified line. Distances use a local equirectangular
+// projection, which is accurate enough at the scale of one route.
+func simplifyPoints(points []RoutePoint, toleranceM float64) []RoutePoint {
+	if len(points) <= 2 || toleranceM <= 0 {
+		return points
+	}
+
+	originLatitude := degreesToRadians(points[0].Latitude)
+	projected := make([][2]float64, len(points))
+	for i, point := range points {
+		projected[i] = [2]float64{
+			degreesToRadians(point.Longitude-points[0].Longitude) * math.Cos(originLatitude) * earthRadiusMeters,
+			degreesToRadians(point.Latitude-points[0].Latitude) * earthRadiusMeters,
+		}
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		span := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		farthest, farthestDistance := -1, toleranceM
+		for i := span[0] + 1; i < span[1]; i++ {
+			if distance := segmentDistance(projected[i], projected[span[0]], projected[span[1]]); distance > farthestDistance {
+				farthest, farthestDistance = i, distance
+			}
+		}
+		if farthest < 0 {
+			continue
+		}
+
+		keep[farthest] = true
+		stack = append(stack, [2]int{span[0], farthest}, [2]int{farthest, span[1]})
+	}
+
+	simplified := make([]RoutePoint, 0, len(points))
+	for i, point := range points {
+		if keep[i] {
+			simplified = append(simplified, point)
+		}
+	}
+
+	return simplified
+}
+
+// segmentDistance returns the distance from point p to the line segment a-b in projected meters.
+func segmentDistance(p, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1])
+	}
+
+	t := math.Max(0, math.Min(1, ((p[0]-a[0])*dx+(p[1]-a[1])*dy)/lengthSquared))
+	return math.Hypot(p[0]-(a[0]+t*dx), p[1]-(a[1]+t*dy))
+}
@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TransportChange is one persisted mid-route transport mode switch.
+type TransportChange struct {
+	MemberID          string    `json:"memberId"`
+	FromTransportMode string    `json:"fromTransportMode"`
+	TransportMode     string    `json:"transportMode"`
+	ChangedAt         time.Time `json:"changedAt"`
+}
+
+// TransportStretch marks the transport mode that applies from one point of a path segment onward.
+type TransportStretch struct {
+	TransportMode string `json:"transportMode"`
+	FromSeq       int64  `json:"fromSeq"`
+}
+
+// ChangeTransportModeResult contains the updated member and the recorded change. Change is nil when
+// the member already used the requested mode.
+type ChangeTransportModeResult struct {
+	Member Member           `json:"member"`
+	Change *TransportChange `json:"change,omitempty"`
+}
+
+// ChangeTransportModeRepoParams contains one transport mode switch.
+type ChangeTransportModeRepoParams struct {
+	RouteID       string
+	MemberID      string
+	TransportMode string
+}
+
+// ChangeTransportMode switches the authenticated member to another transport mode and records the
+// change with its timestamp, so snapshots and replay can tell which mode applied to each stretch.
+func (s *Service) ChangeTransportMode(ctx context.Context, code, memberToken, transportMode string) (ChangeTransportModeResult, error) {
+	if strings.TrimSpace(memberToken) == "" {
+		return ChangeTransportModeResult{}, ErrUnauthorized
+	}
+
+	transportMode = strings.ToLower(strings.TrimSpace(transportMode))
+	if _, ok := validTransportModes[transportMode]; !ok {
+		return ChangeTransportModeResult{}, ErrInvalidInput
+	}
+
+	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
+	if err != nil {
+		return ChangeTransportModeResult{}, err
+	}
+
+	if normalizeCode(code) != authorized.Route.Code {
+		return ChangeTransportModeResult{}, ErrUnauthorized
+	}
+
+	if authorized.Route.Status != RouteStatusActive {
+		return ChangeTransportModeResult{}, ErrRouteClosed
+	}
+
+	if authorized.Member.Status == MemberStatusLeft {
+		return ChangeTransportModeResult{}, ErrInvalidInput
+	}
+
+	if authorized.Member.TransportMode == transportMode {
+		return ChangeTransportModeResult{Member: authorized.Member}, nil
+	}
+
+	member, change, err := s.repo.ChangeMemberTransportMode(ctx, ChangeTransportModeRepoParams{
+		RouteID:       authorized.Route.ID,
+		MemberID:      authorized.Member.ID,
+		TransportMode: transportMode,
+	})
+	if err != nil {
+		return ChangeTransportModeResult{}, fmt.Errorf("change transport mode: %w", err)
+	}
+
+	return ChangeTransportModeResult{Member: member, Change: &change}, nil
+}
+
+// initialTransportMode returns the mode a member joined with, given their changes in time order.
+func initialTransportMode(current string, changes []TransportChange) string {
+	if len(changes) == 0 {
+		return current
+	}
+
+	return changes[0].FromTransportMode
+}
+
+// transportModeAt returns the mode that applied at a moment, given the member's changes in time order.
+func transportModeAt(initial string, changes []TransportChange, at time.Time) string {
+	mode := initial
+	for _, change := range changes {
+		if change.ChangedAt.After(at) {
+			break
+		}
+		mode = change.TransportMode
+	}
+
+	return mode
+}
+
+// transportStretches splits a segment's points into runs that share one transport mode. Points are
+// placed by their client timestamp when present, because batch uploads are stored long after the
+// samples were taken.
+func transportStretches(initial string, changes []TransportChange, points []RoutePoint) []TransportStretch {
+	stretches := make([]TransportStretch, 0, 1)
+	for _, point := range points {
+		at := point.RecordedAt
+		if point.ClientRecordedAt != nil {
+			at = *point.ClientRecordedAt
+		}
+
+		mode := transportModeAt(initial, changes, at)
+		if len(stretches) == 0 || stretches[len(stretches)-1].TransportMode != mode {
+			stretches = append(stretches, TransportStretch{TransportMode: mode, FromSeq: point.Seq})
+		}
+	}
+
+	return stretches
+}
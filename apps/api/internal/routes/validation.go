@@ -0,0 +1,173 @@
+package routes
+
+import (
+	"errors"
+	"time"
+
+	"keepup/apps/api/internal/config"
+)
+
+const (
+	// PositionRejectionAccuracyTooLow is reported when the sample's accuracy radius exceeds the threshold.
+	PositionRejectionAccuracyTooLow = "accuracy_too_low"
+	// PositionRejectionDuplicateTimestamp is reported when the sample repeats the previous client timestamp.
+	PositionRejectionDuplicateTimestamp = "duplicate_timestamp"
+	// PositionRejectionImpossibleSpeed is reported when reaching the sample needs more than the mode's max speed.
+	PositionRejectionImpossibleSpeed = "impossible_speed"
+	// PositionRejectionOutsideSegment is reported when no path segment of the member covers a batch sample's timestamp.
+	PositionRejectionOutsideSegment = "outside_segment"
+	// PositionRejectionTimestampOutOfOrder is reported when the sample is timestamped before the previous point.
+	PositionRejectionTimestampOutOfOrder = "timestamp_out_of_order"
+)
+
+// PositionRejectedError reports why a well-formed position sample failed GPS validation.
+type PositionRejectedError struct {
+	Reason string
+}
+
+func (e *PositionRejectedError) Error() string {
+	return "position rejected: " + e.Reason
+}
+
+// Is lets callers match any typed rejection with errors.Is(err, ErrPositionRejected).
+func (e *PositionRejectedError) Is(target error) bool {
+	return target == ErrPositionRejected
+}
+
+// PositionCandidate contains one normalized sample plus the context validators compare it against.
+type PositionCandidate struct {
+	Member     Member
+	Input      PositionUpdateInput
+	Previous   *RoutePoint
+	ReceivedAt time.Time
+}
+
+// PositionValidator checks one position sample before it is persisted.
+type PositionValidator interface {
+	ValidatePosition(PositionCandidate) error
+}
+
+// PositionValidatorFunc adapts a plain function to the PositionValidator interface.
+type PositionValidatorFunc func(PositionCandidate) error
+
+// ValidatePosition calls f.
+func (f PositionValidatorFunc) ValidatePosition(candidate PositionCandidate) error {
+	return f(candidate)
+}
+
+// DefaultPositionValidators builds the accuracy, duplicate timestamp, and speed validator chain from route config.
+func DefaultPositionValidators(cfg config.RouteConfig) []PositionValidator {
+	validators := make([]PositionValidator, 0, 3)
+	if cfg.PositionMaxAccuracyM > 0 {
+		validators = append(validators, AccuracyValidator{MaxAccuracyM: cfg.PositionMaxAccuracyM})
+	}
+	validators = append(validators, DuplicateTimestampValidator{})
+	if len(cfg.PositionMaxSpeedMPS) > 0 {
+		validators = append(validators, SpeedValidator{MaxSpeedMPS: cfg.PositionMaxSpeedMPS})
+	}
+
+	return validators
+}
+
+// AccuracyValidator rejects samples whose reported accuracy radius is wider than the threshold.
+type AccuracyValidator struct {
+	MaxAccuracyM float64
+}
+
+// ValidatePosition implements PositionValidator.
+func (v AccuracyValidator) ValidatePosition(candidate PositionCandidate) error {
+	if candidate.Input.AccuracyM != nil && *candidate.Input.AccuracyM > v.MaxAccuracyM {
+		return &PositionRejectedError{Reason: PositionRejectionAccuracyTooLow}
+	}
+
+	return nil
+}
+
+// DuplicateTimestampValidator rejects samples that repeat the previous accepted client timestamp.
+type DuplicateTimestampValidator struct{}
+
+// ValidatePosition implements PositionValidator.
+func (DuplicateTimestampValidator) ValidatePosition(candidate PositionCandidate) error {
+	if candidate.Previous == nil || candidate.Previous.ClientRecordedAt == nil || candidate.Input.ClientRecordedAt == nil {
+		return nil
+	}
+
+	if candidate.Input.ClientRecordedAt.Equal(*candidate.Previous.ClientRecordedAt) {
+		return &PositionRejectedError{Reason: PositionRejectionDuplicateTimestamp}
+	}
+
+	return nil
+}
+
+// SpeedValidator rejects jumps from the previous accepted point that exceed the member's transport mode ceiling.
+// Samples timestamped before the previous point are rejected rather than skipped, so backdating cannot
+// hide a jump.
+type SpeedValidator struct {
+	MaxSpeedMPS map[string]float64
+}
+
+// ValidatePosition implements PositionValidator.
+func (v SpeedValidator) ValidatePosition(candidate PositionCandidate) error {
+	if candidate.Previous == nil {
+		return nil
+	}
+
+	maxSpeed, ok := v.MaxSpeedMPS[candidate.Member.TransportMode]
+	if !ok || maxSpeed <= 0 {
+		return nil
+	}
+
+	elapsed := candidate.ReceivedAt.Sub(candidate.Previous.RecordedAt)
+	if candidate.Input.ClientRecordedAt != nil && candidate.Previous.ClientRecordedAt != nil {
+		elapsed = candidate.Input.ClientRecordedAt.Sub(*candidate.Previous.ClientRecordedAt)
+	}
+	if elapsed < 0 {
+		return &PositionRejectedError{Reason: PositionRejectionTimestampOutOfOrder}
+	}
+
+	distance := haversineMeters(
+		candidate.Previous.Latitude,
+		candidate.Previous.Longitude,
+		candidate.Input.Latitude,
+		candidate.Input.Longitude,
+	)
+	if elapsed == 0 {
+		if distance > 0 {
+			return &PositionRejectedError{Reason: PositionRejectionImpossibleSpeed}
+		}
+
+		return nil
+	}
+	if distance/elapsed.Seconds() > maxSpeed {
+		return &PositionRejectedError{Reason: PositionRejectionImpossibleSpeed}
+	}
+
+	return nil
+}
+
+func validatePosition(validators []PositionValidator, candidate PositionCandidate) error {
+	for _, validator := range validators {
+		if err := validator.ValidatePosition(candidate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// positionRejectionReason maps a position recording error to a bounded reason used for metrics and batch rejections.
+func positionRejectionReason(err error) string {
+	var rejected *PositionRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		return rejected.Reason
+	case errors.Is(err, ErrInvalidInput):
+		return "invalid_input"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrRouteClosed):
+		return "route_closed"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultTrackingReservationWindow = time.Minute
+
+// TrackingWaitlistEntry is one member queued for a tracking slot. ReservedUntil is set while a freed
+// slot is held for the member.
+type TrackingWaitlistEntry struct {
+	MemberID      string     `json:"memberId"`
+	Position      int        `json:"position"`
+	QueuedAt      time.Time  `json:"queuedAt"`
+	ReservedUntil *time.Time `json:"reservedUntil,omitempty"`
+}
+
+// TrackingReservation is a freed tracking slot held for the next queued member.
+type TrackingReservation struct {
+	RouteID       string    `json:"routeId"`
+	MemberID      string    `json:"memberId"`
+	ReservedUntil time.Time `json:"reservedUntil"`
+}
+
+// JoinTrackingWaitlist queues a spectating member for the next free tracking slot. Joining again
+// returns the member's current entry.
+func (s *Service) JoinTrackingWaitlist(ctx context.Context, code, memberToken string) (TrackingWaitlistEntry, error) {
+	authorized, err := s.authorizeWaitlistMember(ctx, code, memberToken)
+	if err != nil {
+		return TrackingWaitlistEntry{}, err
+	}
+
+	if authorized.Member.Status != MemberStatusSpectating {
+		return TrackingWaitlistEntry{}, ErrInvalidInput
+	}
+
+	if !mayTrack(authorized) {
+		return TrackingWaitlistEntry{}, ErrSharingNotAllowed
+	}
+
+	if err := s.repo.JoinTrackingWaitlist(ctx, authorized.Route.ID, authorized.Member.ID); err != nil {
+		return TrackingWaitlistEntry{}, fmt.Errorf("join tracking waitlist: %w", err)
+	}
+
+	waitlist, err := s.trackingWaitlist(ctx, authorized.Route.ID)
+	if err != nil {
+		return TrackingWaitlistEntry{}, err
+	}
+
+	for _, entry := range waitlist {
+		if entry.MemberID == authorized.Member.ID {
+			return entry, nil
+		}
+	}
+
+	return TrackingWaitlistEntry{}, fmt.Errorf("join tracking waitlist: entry for member %s not found", authorized.Member.ID)
+}
+
+// LeaveTrackingWaitlist removes the member from the waitlist and gives up any slot held for them.
+func (s *Service) LeaveTrackingWaitlist(ctx context.Context, code, memberToken string) error {
+	authorized, err := s.authorizeWaitlistMember(ctx, code, memberToken)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.LeaveTrackingWaitlist(ctx, authorized.Route.ID, authorized.Member.ID); err != nil {
+		return fmt.Errorf("leave tracking waitlist: %w", err)
+	}
+
+	return nil
+}
+
+// OfferTrackingSlots holds every free tracking slot of the route for the next queued spectators, in
+// queue order, for the reservation window. It returns only the reservations made by this call.
+func (s *Service) OfferTrackingSlots(ctx context.Context, routeID string) ([]TrackingReservation, error) {
+	reservations, err := s.repo.ReserveTrackingSlots(ctx, routeID, s.now().UTC().Add(s.trackingReservationWindow))
+	if err != nil {
+		return nil, fmt.Errorf("reserve tracking slots: %w", err)
+	}
+
+	return reservations, nil
+}
+
+// ExpireTrackingReservations drops queued members whose reservation ran out unclaimed and offers the
+// released slots to the next members in line.
+func (s *Service) ExpireTrackingReservations(ctx context.Context) ([]TrackingReservation, error) {
+	routeIDs, err := s.repo.ExpireTrackingReservations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("expire tracking reservations: %w", err)
+	}
+
+	var reservations []TrackingReservation
+	for _, routeID := range routeIDs {
+		offered, err := s.OfferTrackingSlots(ctx, routeID)
+		if err != nil {
+			return reservations, err
+		}
+		reservations = append(reservations, offered...)
+	}
+
+	return reservations, nil
+}
+
+func (s *Service) authorizeWaitlistMember(ctx context.Context, code, memberToken string) (AuthorizedMember, error) {
+	if strings.TrimSpace(memberToken) == "" {
+		return AuthorizedMember{}, ErrUnauthorized
+	}
+
+	authorized, err := s.repo.GetAuthorizedMemberByTokenHash(ctx, tokenHash(memberToken))
+	if err != nil {
+		return AuthorizedMember{}, err
+	}
+
+	if normalizeCode(code) != authorized.Route.Code {
+		return AuthorizedMember{}, ErrUnauthorized
+	}
+
+	if authorized.Route.Status != RouteStatusActive {
+		return AuthorizedMember{}, ErrRouteClosed
+	}
+
+	return authorized, nil
+}
+
+// trackingWaitlist loads the route queue in order and numbers the entries from 1.
+func (s *Service) trackingWaitlist(ctx context.Context, routeID string) ([]TrackingWaitlistEntry, error) {
+	waitlist, err := s.repo.GetTrackingWaitlist(ctx, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("load tracking waitlist: %w", err)
+	}
+
+	for index := range waitlist {
+		waitlist[index].Position = index + 1
+	}
+
+	return waitlist, nil
+}
+
+// activeReservations counts the slots held for queued members other than memberID, and whether one is
+// held for memberID itself.
+func activeReservations(waitlist []TrackingWaitlistEntry, memberID string) (int, bool) {
+	held := 0
+	ownReservation := false
+	for _, entry := range waitlist {
+		if entry.ReservedUntil == nil {
+			continue
+		}
+		if entry.MemberID == memberID {
+			ownReservation = true
+			continue
+		}
+		held++
+	}
+
+	return held, ownReservation
+}
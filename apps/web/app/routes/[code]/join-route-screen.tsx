@@ -12,6 +12,7 @@ import {
 } from "../../../lib/identity-storage";
 import {
   appendLiveRoutePoint,
+  mergeBackfilledRoutePoints,
   mergeSnapshotIntoMapState,
   routeSnapshotToMapState,
   updateMapMemberStatus,
@@ -27,14 +28,24 @@ import {
   joinRoute,
   routeWebSocketUrl,
   type RouteAccess,
+  type MemberRole,
   type MemberSummary,
   type PathSegment,
   type RoutePoint,
   type RouteSnapshot,
   type SnapshotMember,
+  type TrackingPermissionChange,
+  type TransportChange,
+  type ViewerCapabilities,
 } from "../../../lib/routes-api";
 import { RouteMap } from "../../components/route-map";
 
+// Matches the server's position_batch limit.
+const maxPendingPositions = 1000;
+// Matches the server's default per-connection batch budget; connection_established reports the
+// configured one. Buffered positions are uploaded in batches of at most this size.
+const defaultBatchPositions = 1000;
+
 const transportLabels: Record<TransportMode, string> = {
   walking: "Walking",
   bicycle: "Bicycle",
@@ -321,26 +332,37 @@ function RouteSnapshotShell({
   onSnapshotChange: (snapshot: RouteSnapshot) => void;
   snapshot: RouteSnapshot;
 }) {
-  const [sharingAction, setSharingAction] = useState<"start" | "stop" | null>(
+  const [sharingAction, setSharingAction] = useState<SharingAction | null>(
     null,
   );
   const [sharingError, setSharingError] = useState("");
+  const [trackingSlotNotice, setTrackingSlotNotice] = useState("");
   const [liveTrackingError, setLiveTrackingError] = useState("");
   const [liveConnectionRejected, setLiveConnectionRejected] = useState(false);
   const websocketRef = useRef<WebSocket | null>(null);
   const snapshotRef = useRef(snapshot);
+  const lastEventSeqRef = useRef(snapshot.lastEventSeq ?? 0);
+  const pendingPositionsRef = useRef<NavigationPosition[]>([]);
+  const inFlightPositionsRef = useRef<NavigationPosition[]>([]);
+  const batchPositionsRef = useRef(defaultBatchPositions);
   const [mapState, setMapState] = useState(() => routeSnapshotToMapState(snapshot));
   const sortedMembers = [...snapshot.members].sort(compareMembers);
+  const waitlistPosition = snapshot.viewer.trackingWaitlistPosition ?? 0;
+  const nextSharingAction = viewerSharingAction(snapshot.viewer);
   const canUseSharingControl =
     memberToken !== "" &&
     snapshot.route.status === "active" &&
     !sharingAction &&
-    (snapshot.viewer.canStartSharing || snapshot.viewer.canStopSharing);
-  const sharingControlLabel = snapshot.viewer.canStopSharing
-    ? "Stop sharing"
-    : "Start sharing";
-  const sharingControlBusyLabel =
-    sharingAction === "stop" ? "Stopping..." : "Starting...";
+    nextSharingAction !== null;
+  const sharingControlLabel =
+    nextSharingAction === "stop"
+      ? "Stop sharing"
+      : nextSharingAction === "leave_waitlist"
+        ? `Leave waitlist (#${waitlistPosition})`
+        : nextSharingAction === "join_waitlist"
+          ? "Join waitlist"
+          : "Start sharing";
+  const sharingControlBusyLabel = sharingActionBusyLabels[sharingAction ?? "start"];
   const isViewerTracking =
     memberToken !== "" &&
     snapshot.route.status === "active" &&
@@ -348,6 +370,10 @@ function RouteSnapshotShell({
 
   useEffect(() => {
     snapshotRef.current = snapshot;
+    lastEventSeqRef.current = Math.max(
+      lastEventSeqRef.current,
+      snapshot.lastEventSeq ?? 0,
+    );
     setMapState((current) => mergeSnapshotIntoMapState(current, snapshot));
   }, [snapshot]);
 
@@ -365,15 +391,49 @@ function RouteSnapshotShell({
         JSON.stringify({
           type: "authenticate",
           memberToken,
+          ...(lastEventSeqRef.current > 0
+            ? { lastEventSeq: lastEventSeqRef.current }
+            : {}),
         }),
       );
+      if (
+        snapshotRef.current.viewer.role === "owner" &&
+        !getRouteAuth(code)?.ownerToken
+      ) {
+        // Ownership was transferred while this browser was away.
+        socket.send(JSON.stringify({ type: "claim_owner_token" }));
+      }
     });
+    const flushTimer = window.setInterval(() => {
+      if (socket.readyState === WebSocket.OPEN) {
+        flushPendingPositions(socket);
+      }
+    }, 1000);
+
+    function refreshSnapshot() {
+      getRouteSnapshot(code, memberToken)
+        .then((routeSnapshot) => {
+          if (isCurrent) {
+            onSnapshotChange(routeSnapshot);
+          }
+        })
+        .catch(() => {
+          if (isCurrent) {
+            setLiveTrackingError(
+              "Live updates were interrupted. Reload to refresh the map.",
+            );
+          }
+        });
+    }
 
     socket.addEventListener("message", (event) => {
       const liveEvent = parseLiveEvent(event.data);
       if (!liveEvent || !isCurrent) {
         return;
       }
+      if (liveEvent.eventSeq && liveEvent.eventSeq > lastEventSeqRef.current) {
+        lastEventSeqRef.current = liveEvent.eventSeq;
+      }
 
       if (liveEvent.type === "position_updated") {
         setMapState((current) =>
@@ -386,18 +446,108 @@ function RouteSnapshotShell({
         return;
       }
 
+      if (liveEvent.type === "positions_backfilled") {
+        setMapState((current) =>
+          mergeBackfilledRoutePoints(current, {
+            memberId: liveEvent.memberId,
+            segmentId: liveEvent.segmentId,
+            points: liveEvent.points,
+          }),
+        );
+        return;
+      }
+
+      if (liveEvent.type === "connection_established") {
+        batchPositionsRef.current =
+          liveEvent.limits?.batchPositions ?? defaultBatchPositions;
+        flushPendingPositions(socket);
+        return;
+      }
+
       if (liveEvent.type === "position_rejected") {
         setLiveTrackingError(positionRejectedMessage(liveEvent.error));
         return;
       }
 
+      if (
+        (liveEvent.type === "command_ack" ||
+          liveEvent.type === "command_rejected") &&
+        liveEvent.command === "position_batch"
+      ) {
+        if (
+          liveEvent.type === "command_rejected" &&
+          liveEvent.reason === "rate_limited"
+        ) {
+          requeueInFlightPositions();
+        } else {
+          inFlightPositionsRef.current = [];
+          if (liveEvent.type === "command_rejected") {
+            setLiveTrackingError(positionRejectedMessage(liveEvent.reason));
+          }
+        }
+        return;
+      }
+
       if (liveEvent.type === "live_connection_rejected") {
         setLiveConnectionRejected(true);
         return;
       }
 
+      if (
+        liveEvent.type === "member_removed" &&
+        liveEvent.member.id === snapshotRef.current.viewer.memberId
+      ) {
+        setLiveTrackingError("The route owner removed you from this route.");
+      }
+
+      if (liveEvent.type === "owner_token_issued") {
+        saveRouteAuth(code, { ownerToken: liveEvent.ownerToken });
+        return;
+      }
+
+      if (liveEvent.type === "route_owner_changed") {
+        onSnapshotChange(
+          snapshotWithMemberRoles(snapshotRef.current, [
+            liveEvent.previousOwner,
+            liveEvent.owner,
+          ]),
+        );
+        return;
+      }
+
+      if (liveEvent.type === "member_role_changed") {
+        onSnapshotChange(
+          snapshotWithMemberRoles(snapshotRef.current, [liveEvent.member]),
+        );
+        return;
+      }
+
+      if (liveEvent.type === "resync_required") {
+        refreshSnapshot();
+        return;
+      }
+
+      if (liveEvent.type === "tracking_slot_available") {
+        setTrackingSlotNotice(
+          `A tracking slot is held for you until ${new Date(
+            liveEvent.reservedUntil,
+          ).toLocaleTimeString()}.`,
+        );
+        refreshSnapshot();
+        return;
+      }
+
       if (liveEvent.type === "command_ack") {
         setSharingAction(null);
+        if (
+          liveEvent.command === "join_tracking_waitlist" ||
+          liveEvent.command === "leave_tracking_waitlist"
+        ) {
+          refreshSnapshot();
+        }
+        if (liveEvent.command === "start_sharing") {
+          setTrackingSlotNotice("");
+        }
         return;
       }
 
@@ -410,6 +560,9 @@ function RouteSnapshotShell({
       if (
         liveEvent.type === "member_started_sharing" ||
         liveEvent.type === "member_stopped_sharing" ||
+        liveEvent.type === "member_transport_changed" ||
+        liveEvent.type === "member_tracking_permission_changed" ||
+        liveEvent.type === "member_removed" ||
         liveEvent.type === "member_became_stale" ||
         liveEvent.type === "member_back_online" ||
         liveEvent.type === "member_went_offline"
@@ -434,6 +587,7 @@ function RouteSnapshotShell({
     });
 
     socket.addEventListener("close", () => {
+      requeueInFlightPositions();
       if (websocketRef.current === socket) {
         websocketRef.current = null;
       }
@@ -447,6 +601,7 @@ function RouteSnapshotShell({
 
     return () => {
       isCurrent = false;
+      window.clearInterval(flushTimer);
       if (websocketRef.current === socket) {
         websocketRef.current = null;
       }
@@ -463,7 +618,17 @@ function RouteSnapshotShell({
     return navigationService.watchPosition(
       (position) => {
         const socket = websocketRef.current;
-        if (!socket || socket.readyState !== WebSocket.OPEN) {
+        // Live samples queue behind buffered ones so the server sees them in order.
+        if (
+          !socket ||
+          socket.readyState !== WebSocket.OPEN ||
+          pendingPositionsRef.current.length > 0 ||
+          inFlightPositionsRef.current.length > 0
+        ) {
+          pendingPositionsRef.current = [
+            ...pendingPositionsRef.current,
+            position,
+          ].slice(-maxPendingPositions);
           return;
         }
 
@@ -481,16 +646,17 @@ function RouteSnapshotShell({
     }
 
     setSharingError("");
-    const shouldStartSharing = snapshot.viewer.canStartSharing;
-    setSharingAction(shouldStartSharing ? "start" : "stop");
+    const action = nextSharingAction;
+    if (!action) {
+      return;
+    }
+    setSharingAction(action);
 
     try {
-      if (shouldStartSharing) {
+      if (action === "start") {
         await navigationService.requestPermission();
-        sendLiveCommand("start_sharing");
-      } else {
-        sendLiveCommand("stop_sharing");
       }
+      sendLiveCommand(sharingActionCommands[action]);
     } catch (caughtError) {
       setSharingAction(null);
       if (
@@ -504,7 +670,35 @@ function RouteSnapshotShell({
     }
   }
 
-  function sendLiveCommand(type: "start_sharing" | "stop_sharing") {
+  function flushPendingPositions(socket: WebSocket) {
+    if (
+      pendingPositionsRef.current.length === 0 ||
+      inFlightPositionsRef.current.length > 0
+    ) {
+      return;
+    }
+
+    const batchSize = Math.max(1, batchPositionsRef.current);
+    const positions = pendingPositionsRef.current.slice(0, batchSize);
+    pendingPositionsRef.current = pendingPositionsRef.current.slice(batchSize);
+    inFlightPositionsRef.current = positions;
+    socket.send(
+      JSON.stringify({
+        type: "position_batch",
+        positions: positions.map(positionSamplePayload),
+      }),
+    );
+  }
+
+  function requeueInFlightPositions() {
+    pendingPositionsRef.current = [
+      ...inFlightPositionsRef.current,
+      ...pendingPositionsRef.current,
+    ].slice(-maxPendingPositions);
+    inFlightPositionsRef.current = [];
+  }
+
+  function sendLiveCommand(type: SharingCommand) {
     const socket = websocketRef.current;
     if (!socket || socket.readyState !== WebSocket.OPEN) {
       setSharingAction(null);
@@ -602,6 +796,12 @@ function RouteSnapshotShell({
             {sharingAction ? sharingControlBusyLabel : sharingControlLabel}
           </button>
 
+          {trackingSlotNotice && snapshot.viewer.canStartSharing ? (
+            <p className="route-status" role="status">
+              {trackingSlotNotice}
+            </p>
+          ) : null}
+
           {sharingError ? (
             <p className="form-error" role="alert">
               {sharingError}
@@ -659,9 +859,59 @@ function MemberRow({ member }: { member: SnapshotMember }) {
   );
 }
 
-type LiveEvent =
+type SharingAction = "start" | "stop" | "join_waitlist" | "leave_waitlist";
+
+type SharingCommand =
+  | "start_sharing"
+  | "stop_sharing"
+  | "join_tracking_waitlist"
+  | "leave_tracking_waitlist";
+
+const sharingActionCommands: Record<SharingAction, SharingCommand> = {
+  start: "start_sharing",
+  stop: "stop_sharing",
+  join_waitlist: "join_tracking_waitlist",
+  leave_waitlist: "leave_tracking_waitlist",
+};
+
+const sharingActionBusyLabels: Record<SharingAction, string> = {
+  start: "Starting...",
+  stop: "Stopping...",
+  join_waitlist: "Joining waitlist...",
+  leave_waitlist: "Leaving waitlist...",
+};
+
+// viewerSharingAction picks what the sharing button does: share when a slot is free or reserved for
+// the viewer, otherwise queue for or leave the tracking waitlist.
+function viewerSharingAction(viewer: ViewerCapabilities): SharingAction | null {
+  if (viewer.canStopSharing) {
+    return "stop";
+  }
+  if (viewer.canStartSharing) {
+    return "start";
+  }
+  if ((viewer.trackingWaitlistPosition ?? 0) > 0) {
+    return "leave_waitlist";
+  }
+  if (viewer.canJoinTrackingWaitlist) {
+    return "join_waitlist";
+  }
+
+  return null;
+}
+
+type LiveEvent = LiveEventPayload & {
+  eventSeq?: number;
+  replayed?: boolean;
+};
+
+type LiveEventPayload =
   | {
       type: "connection_established";
+      limits?: {
+        positionsPerSecond?: number;
+        batchPositions?: number;
+      };
     }
   | {
       type: "member_started_sharing";
@@ -671,12 +921,46 @@ type LiveEvent =
   | {
       type: "member_stopped_sharing";
       member: MemberSummary;
+      reason?: string;
+    }
+  | {
+      type: "member_transport_changed";
+      member: MemberSummary;
+      change?: TransportChange;
+    }
+  | {
+      type: "member_removed";
+      member: MemberSummary;
+      banned?: boolean;
+    }
+  | {
+      type: "member_tracking_permission_changed";
+      member: MemberSummary;
+      change?: TrackingPermissionChange;
+    }
+  | {
+      type: "route_owner_changed";
+      previousOwner: MemberSummary;
+      owner: MemberSummary;
+    }
+  | {
+      type: "member_role_changed";
+      member: MemberSummary;
+    }
+  | {
+      type: "owner_token_issued";
+      requestId?: string;
+      ownerToken: string;
     }
   | {
       type: "member_became_stale" | "member_back_online" | "member_went_offline";
       member: MemberSummary;
       segment?: PathSegment;
     }
+  | {
+      type: "tracking_slot_available";
+      reservedUntil: string;
+    }
   | {
       type: "command_ack";
       requestId?: string;
@@ -698,6 +982,12 @@ type LiveEvent =
       segmentId?: string;
       point: RoutePoint;
     }
+  | {
+      type: "positions_backfilled";
+      memberId: string;
+      segmentId?: string;
+      points: RoutePoint[];
+    }
   | {
       type: "position_rejected";
       error?: string;
@@ -705,6 +995,12 @@ type LiveEvent =
   | {
       type: "message_rejected";
       error?: string;
+    }
+  | {
+      type: "resync_required";
+      seq?: number;
+      missed?: number;
+      reason?: string;
     };
 
 function parseLiveEvent(payload: string | ArrayBufferLike | Blob): LiveEvent | null {
@@ -718,6 +1014,13 @@ function parseLiveEvent(payload: string | ArrayBufferLike | Blob): LiveEvent | n
       return event;
     }
 
+    if (
+      event.type === "positions_backfilled" &&
+      isPositionsBackfilledEvent(event)
+    ) {
+      return event;
+    }
+
     if (
       event.type === "member_started_sharing" &&
       isSharingStartedEvent(event)
@@ -732,6 +1035,49 @@ function parseLiveEvent(payload: string | ArrayBufferLike | Blob): LiveEvent | n
       return event;
     }
 
+    if (
+      event.type === "member_transport_changed" &&
+      isTransportChangedEvent(event)
+    ) {
+      return event;
+    }
+
+    if (
+      event.type === "member_tracking_permission_changed" &&
+      isTrackingPermissionChangedEvent(event)
+    ) {
+      return event;
+    }
+
+    if (event.type === "member_removed" && isMemberRemovedEvent(event)) {
+      return event;
+    }
+
+    if (
+      event.type === "route_owner_changed" &&
+      isRouteOwnerChangedEvent(event)
+    ) {
+      return event;
+    }
+
+    if (event.type === "member_role_changed" && isMemberRoleChangedEvent(event)) {
+      return event;
+    }
+
+    if (
+      event.type === "owner_token_issued" &&
+      typeof event.ownerToken === "string"
+    ) {
+      return event as LiveEvent;
+    }
+
+    if (
+      event.type === "tracking_slot_available" &&
+      typeof event.reservedUntil === "string"
+    ) {
+      return event as LiveEvent;
+    }
+
     if (
       (event.type === "member_became_stale" ||
         event.type === "member_back_online" ||
@@ -747,6 +1093,7 @@ function parseLiveEvent(payload: string | ArrayBufferLike | Blob): LiveEvent | n
       event.type === "command_ack" ||
       event.type === "command_rejected" ||
       event.type === "live_connection_rejected" ||
+      event.type === "resync_required" ||
       event.type === "connection_established"
     ) {
       return event as LiveEvent;
@@ -758,6 +1105,25 @@ function parseLiveEvent(payload: string | ArrayBufferLike | Blob): LiveEvent | n
   return null;
 }
 
+function isPositionsBackfilledEvent(
+  event: Partial<LiveEvent>,
+): event is Extract<LiveEvent, { type: "positions_backfilled" }> {
+  if (
+    event.type !== "positions_backfilled" ||
+    typeof event.memberId !== "string" ||
+    !Array.isArray(event.points)
+  ) {
+    return false;
+  }
+
+  return event.points.every(
+    (point) =>
+      typeof point.latitude === "number" &&
+      typeof point.longitude === "number" &&
+      typeof point.recordedAt === "string",
+  );
+}
+
 function isPositionUpdatedEvent(
   event: Partial<LiveEvent>,
 ): event is Extract<LiveEvent, { type: "position_updated" }> {
@@ -792,6 +1158,45 @@ function isSharingStoppedEvent(
   return event.type === "member_stopped_sharing" && isMemberSummary(event.member);
 }
 
+function isTransportChangedEvent(
+  event: Partial<LiveEvent>,
+): event is Extract<LiveEvent, { type: "member_transport_changed" }> {
+  return (
+    event.type === "member_transport_changed" && isMemberSummary(event.member)
+  );
+}
+
+function isTrackingPermissionChangedEvent(
+  event: Partial<LiveEvent>,
+): event is Extract<LiveEvent, { type: "member_tracking_permission_changed" }> {
+  return (
+    event.type === "member_tracking_permission_changed" &&
+    isMemberSummary(event.member)
+  );
+}
+
+function isMemberRemovedEvent(
+  event: Partial<LiveEvent>,
+): event is Extract<LiveEvent, { type: "member_removed" }> {
+  return event.type === "member_removed" && isMemberSummary(event.member);
+}
+
+function isMemberRoleChangedEvent(
+  event: Partial<LiveEvent>,
+): event is Extract<LiveEvent, { type: "member_role_changed" }> {
+  return event.type === "member_role_changed" && isMemberSummary(event.member);
+}
+
+function isRouteOwnerChangedEvent(
+  event: Partial<LiveEvent>,
+): event is Extract<LiveEvent, { type: "route_owner_changed" }> {
+  return (
+    event.type === "route_owner_changed" &&
+    isMemberSummary(event.previousOwner) &&
+    isMemberSummary(event.owner)
+  );
+}
+
 function isMemberStatusEvent(
 	event: Partial<LiveEvent>,
 ): event is Extract<LiveEvent, { type: "member_became_stale" | "member_back_online" | "member_went_offline" }> {
@@ -838,6 +1243,64 @@ function snapshotWithUpdatedSharingMember(
   };
 }
 
+function snapshotWithMemberRoles(
+  snapshot: RouteSnapshot,
+  changedMembers: MemberSummary[],
+): RouteSnapshot {
+  const members = snapshot.members.map((snapshotMember) => {
+    const changed = changedMembers.find(
+      (member) => member.id === snapshotMember.id,
+    );
+
+    return changed
+      ? { ...snapshotMember, role: memberRole(changed) }
+      : snapshotMember;
+  });
+
+  const viewerMember = changedMembers.find(
+    (member) => member.id === snapshot.viewer.memberId,
+  );
+  if (!viewerMember) {
+    return { ...snapshot, members };
+  }
+
+  const role = memberRole(viewerMember);
+  const isOwner = role === "owner";
+  const canManage = role !== "member";
+  const isActive = snapshot.route.status === "active";
+  const nextSnapshot: RouteSnapshot = {
+    ...snapshot,
+    members,
+    viewer: {
+      ...snapshot.viewer,
+      role,
+      canLeaveRoute: viewerMember.status !== "left" && !(isActive && isOwner),
+      canCloseRoute: isOwner && isActive,
+      canDeleteRoute: isOwner,
+      canEditRoute: canManage,
+      canGrantTracking:
+        canManage &&
+        isActive &&
+        snapshot.route.sharingPolicy === "joiners_can_view_only",
+      canRemoveMembers: canManage && isActive,
+      canAssignRoles: isOwner && isActive,
+    },
+  };
+
+  return {
+    ...nextSnapshot,
+    viewer: viewerCapabilitiesForSharingMember(nextSnapshot, viewerMember),
+  };
+}
+
+function memberRole(member: MemberSummary): MemberRole {
+  if (member.isOwner) {
+    return "owner";
+  }
+
+  return member.isModerator ? "moderator" : "member";
+}
+
 function snapshotMemberFromMemberSummary(
   snapshotMember: SnapshotMember,
   member: MemberSummary,
@@ -852,6 +1315,7 @@ function snapshotMemberFromMemberSummary(
     ...snapshotMember,
     displayName: member.displayName,
     transportMode: member.transportMode,
+    canTrack: member.canTrack ?? snapshotMember.canTrack,
     status: member.status,
     color: member.color,
     joinedAt: member.joinedAt,
@@ -865,13 +1329,17 @@ function viewerCapabilitiesForSharingMember(
   member: MemberSummary,
 ) {
   const canUseSharingPolicy =
-    snapshot.viewer.role === "owner" ||
-    snapshot.route.sharingPolicy === "everyone_can_share";
+    snapshot.viewer.role !== "member" ||
+    snapshot.route.sharingPolicy === "everyone_can_share" ||
+    member.canTrack === true;
   const canShare =
     snapshot.route.status === "active" &&
     member.status !== "left" &&
     canUseSharingPolicy;
 
+  // The server drops a member from the tracking waitlist once they share or leave.
+  const isWaiting = member.status === "spectating" || member.status === "offline";
+
   return {
     ...snapshot.viewer,
     status: member.status,
@@ -879,12 +1347,25 @@ function viewerCapabilitiesForSharingMember(
       canShare && (member.status === "spectating" || member.status === "stale"),
     canStopSharing:
       canShare && (member.status === "tracking" || member.status === "stale"),
+    ...(isWaiting
+      ? {}
+      : {
+          canJoinTrackingWaitlist: false,
+          trackingWaitlistPosition: undefined,
+          trackingReservedUntil: undefined,
+        }),
   };
 }
 
 function positionUpdatePayload(position: NavigationPosition) {
   return {
     type: "position_update",
+    ...positionSamplePayload(position),
+  };
+}
+
+function positionSamplePayload(position: NavigationPosition) {
+  return {
     latitude: position.latitude,
     longitude: position.longitude,
     accuracyM: position.accuracyM,
@@ -908,12 +1389,16 @@ function positionRejectedMessage(error?: string) {
     return "The latest location update could not be used.";
   }
 
+  if (error === "rate_limited") {
+    return "Location updates are arriving too quickly. Some were skipped.";
+  }
+
   return "The latest location update was rejected.";
 }
 
 function commandRejectedMessage(reason?: string) {
   if (reason === "tracking_limit_reached") {
-    return "All tracking slots are currently in use.";
+    return "All tracking slots are currently in use. Join the waitlist to get the next free slot.";
   }
   if (reason === "sharing_not_allowed") {
     return "This route only allows the owner to share location.";
@@ -921,6 +1406,9 @@ function commandRejectedMessage(reason?: string) {
   if (reason === "route_closed") {
     return "This route is closed.";
   }
+  if (reason === "rate_limited") {
+    return "Too many requests. Wait a moment and try again.";
+  }
 
   return "Could not update sharing.";
 }
@@ -934,8 +1422,14 @@ function compareMembers(first: SnapshotMember, second: SnapshotMember) {
     left: 5,
   };
 
+  const roleOrder: Record<MemberRole, number> = {
+    owner: 1,
+    moderator: 2,
+    member: 3,
+  };
+
   if (first.role !== second.role) {
-    return first.role === "owner" ? -1 : 1;
+    return roleOrder[first.role] - roleOrder[second.role];
   }
 
   const firstStatus = statusOrder[first.status] ?? 99;
@@ -948,7 +1442,11 @@ function compareMembers(first: SnapshotMember, second: SnapshotMember) {
 }
 
 function formatRole(role: string) {
-  return role === "owner" ? "Owner" : "Member";
+  if (role === "owner") {
+    return "Owner";
+  }
+
+  return role === "moderator" ? "Moderator" : "Member";
 }
 
 function formatStatus(status: string) {
@@ -3,6 +3,7 @@ export type RouteMapViewportMode = "fit_route" | "manual";
 export type RouteMapPoint = {
   latitude: number;
   longitude: number;
+  clientRecordedAt?: string;
   recordedAt: string;
 };
 
@@ -13,6 +13,7 @@ export function routeSnapshotToMapState(snapshot: RouteSnapshot): RouteMapState
         points: path.points.map((point): RouteMapPoint => ({
           latitude: point.latitude,
           longitude: point.longitude,
+          clientRecordedAt: point.clientRecordedAt,
           recordedAt: point.recordedAt,
         })),
       }));
@@ -110,6 +111,53 @@ export function appendLiveRoutePoint(
   };
 }
 
+// Backfilled points can predate points the path already has, so they are merged in
+// client time order instead of appended.
+export function mergeBackfilledRoutePoints(
+  state: RouteMapState,
+  update: {
+    memberId: string;
+    segmentId?: string;
+    points: RouteMapPoint[];
+  },
+): RouteMapState {
+  if (update.points.length === 0) {
+    return state;
+  }
+
+  return {
+    ...state,
+    members: state.members.map((member) => {
+      if (member.id !== update.memberId) {
+        return member;
+      }
+
+      const pathIndex = member.paths.findIndex(
+        (path) => path.id === update.segmentId,
+      );
+      const existing = pathIndex >= 0 ? member.paths[pathIndex].points : [];
+      const path = {
+        id: update.segmentId,
+        points: [...existing, ...update.points].sort(
+          (first, second) => pointTime(first) - pointTime(second),
+        ),
+      };
+      const paths =
+        pathIndex >= 0
+          ? member.paths.map((current, index) =>
+              index === pathIndex ? path : current,
+            )
+          : [...member.paths, path];
+
+      return {
+        ...member,
+        paths,
+        latestPoint: latestPointFromPaths(paths),
+      };
+    }),
+  };
+}
+
 export function updateMapMemberStatus(
   state: RouteMapState,
   memberId: string,
@@ -128,11 +176,11 @@ function latestPointFromPaths(
 ): RouteMapPoint | undefined {
   return paths
     .flatMap((path) => path.points)
-    .sort(
-      (first, second) =>
-        new Date(second.recordedAt).getTime() -
-        new Date(first.recordedAt).getTime(),
-    )[0];
+    .sort((first, second) => pointTime(second) - pointTime(first))[0];
+}
+
+function pointTime(point: RouteMapPoint) {
+  return new Date(point.clientRecordedAt ?? point.recordedAt).getTime();
 }
 
 function countPoints(paths: Array<{ points: RouteMapPoint[] }>) {
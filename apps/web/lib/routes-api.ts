@@ -15,6 +15,8 @@ export type RouteSummary = {
   closedAt: string | null;
 };
 
+export type MemberRole = "owner" | "moderator" | "member";
+
 export type MemberSummary = {
   id: string;
   routeId: string;
@@ -22,6 +24,8 @@ export type MemberSummary = {
   displayName: string;
   transportMode: TransportMode;
   isOwner: boolean;
+  isModerator?: boolean;
+  canTrack?: boolean;
   status: string;
   color: string;
   joinedAt: string;
@@ -71,19 +75,53 @@ export type SnapshotMember = {
   id: string;
   displayName: string;
   transportMode: TransportMode;
-  role: "owner" | "member";
+  role: MemberRole;
+  canTrack?: boolean;
   status: string;
   color: string;
   joinedAt: string;
   leftAt: string | null;
+  transportChanges?: TransportChange[];
   paths: PathSegment[];
 };
 
+export type TransportChange = {
+  memberId: string;
+  fromTransportMode: TransportMode;
+  transportMode: TransportMode;
+  changedAt: string;
+};
+
+export type TrackingPermissionChange = {
+  memberId: string;
+  granted: boolean;
+  changedBy: string;
+  changedAt: string;
+};
+
+export type TransportStretch = {
+  transportMode: TransportMode;
+  fromSeq: number;
+};
+
 export type PathSegment = {
   id?: string;
   startedAt?: string;
   endedAt?: string;
   points: RoutePoint[];
+  pointCount?: number;
+  lastSeq?: number;
+  transportModes?: TransportStretch[];
+  snapped?: SnappedGeometry;
+};
+
+export type SnappedGeometry = {
+  matcher: string;
+  // One line per continuously matched stretch; unmatched gaps are not bridged.
+  lines: [number, number][][];
+  confidence?: number;
+  sourceSeq: number;
+  matchedAt: string;
 };
 
 export type RoutePoint = {
@@ -97,7 +135,7 @@ export type RoutePoint = {
 
 export type ViewerCapabilities = {
   memberId: string;
-  role: "owner" | "member";
+  role: MemberRole;
   status: string;
   canStartSharing: boolean;
   canStopSharing: boolean;
@@ -105,12 +143,36 @@ export type ViewerCapabilities = {
   canCloseRoute: boolean;
   canDeleteRoute: boolean;
   canEditRoute: boolean;
+  canGrantTracking?: boolean;
+  canRemoveMembers?: boolean;
+  canAssignRoles?: boolean;
+  canJoinTrackingWaitlist?: boolean;
+  trackingWaitlistPosition?: number;
+  trackingReservedUntil?: string;
+  trackingWaitlist?: TrackingWaitlistEntry[];
+};
+
+export type TrackingWaitlistEntry = {
+  memberId: string;
+  position: number;
+  queuedAt: string;
+  reservedUntil?: string;
 };
 
 export type RouteSnapshot = {
   route: RouteSummary;
   members: SnapshotMember[];
   viewer: ViewerCapabilities;
+  lastEventSeq?: number;
+  detail?: SnapshotDetail;
+  toleranceM?: number;
+};
+
+export type SnapshotDetail = "full" | "simplified" | "header";
+
+export type RouteSnapshotOptions = {
+  detail?: SnapshotDetail;
+  toleranceM?: number;
 };
 
 const apiUrl = process.env.NEXT_PUBLIC_API_URL ?? "http://localhost:8080";
@@ -215,12 +277,25 @@ export async function joinRoute(
 export async function getRouteSnapshot(
   code: string,
   memberToken: string,
+  options: RouteSnapshotOptions = {},
 ): Promise<RouteSnapshot> {
-  const response = await fetch(`${apiUrl}/routes/${encodeURIComponent(code)}`, {
-    headers: {
-      Authorization: `Bearer ${memberToken}`,
+  const query = new URLSearchParams();
+  if (options.detail) {
+    query.set("detail", options.detail);
+  }
+  if (options.toleranceM !== undefined) {
+    query.set("tolerance", String(options.toleranceM));
+  }
+  const search = query.toString() ? `?${query.toString()}` : "";
+
+  const response = await fetch(
+    `${apiUrl}/routes/${encodeURIComponent(code)}${search}`,
+    {
+      headers: {
+        Authorization: `Bearer ${memberToken}`,
+      },
     },
-  });
+  );
 
   if (!response.ok) {
     let errorCode: string | undefined;
@@ -241,6 +316,72 @@ export async function getRouteSnapshot(
   return (await response.json()) as RouteSnapshot;
 }
 
+export type SegmentPoint = RoutePoint & {
+  segmentId: string;
+  memberId: string;
+};
+
+export type RoutePointPage = {
+  points: SegmentPoint[];
+  nextSegment?: string;
+  nextAfterSeq?: number;
+};
+
+export type RoutePointPageQuery = {
+  member?: string;
+  segment?: string;
+  afterSeq?: number;
+  limit?: number;
+};
+
+export async function getRoutePoints(
+  code: string,
+  memberToken: string,
+  pageQuery: RoutePointPageQuery = {},
+): Promise<RoutePointPage> {
+  const query = new URLSearchParams();
+  if (pageQuery.member) {
+    query.set("member", pageQuery.member);
+  }
+  if (pageQuery.segment) {
+    query.set("segment", pageQuery.segment);
+  }
+  if (pageQuery.afterSeq !== undefined) {
+    query.set("after_seq", String(pageQuery.afterSeq));
+  }
+  if (pageQuery.limit !== undefined) {
+    query.set("limit", String(pageQuery.limit));
+  }
+  const search = query.toString() ? `?${query.toString()}` : "";
+
+  const response = await fetch(
+    `${apiUrl}/routes/${encodeURIComponent(code)}/points${search}`,
+    {
+      headers: {
+        Authorization: `Bearer ${memberToken}`,
+      },
+    },
+  );
+
+  if (!response.ok) {
+    let errorCode: string | undefined;
+    try {
+      const payload = (await response.json()) as { error?: string };
+      errorCode = payload.error;
+    } catch {
+      errorCode = undefined;
+    }
+
+    throw new ApiError(
+      routeErrorMessage(response.status, errorCode),
+      response.status,
+      errorCode,
+    );
+  }
+
+  return (await response.json()) as RoutePointPage;
+}
+
 function routeErrorMessage(status: number, code?: string): string {
   if (code === "invalid_input" || status === 400) {
     return "Check the details and try again.";
@@ -250,6 +391,14 @@ function routeErrorMessage(status: number, code?: string): string {
     return "The password is not correct.";
   }
 
+  if (code === "member_banned") {
+    return "The route owner removed you from this route.";
+  }
+
+  if (code === "rate_limited" || status === 429) {
+    return "Too many attempts. Wait a moment and try again.";
+  }
+
   if (code === "unauthorized" || status === 401 || status === 403) {
     return "Route access expired. Join again to continue.";
   }
@@ -0,0 +1,14 @@
+CREATE TABLE route_events (
+    id BIGSERIAL PRIMARY KEY,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    actor_member_id UUID REFERENCES route_members(id) ON DELETE SET NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}'::JSONB,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX route_events_route_id_idx
+    ON route_events (route_id, id);
+
+CREATE INDEX route_events_occurred_at_idx
+    ON route_events (occurred_at);
@@ -0,0 +1,13 @@
+CREATE TABLE chat_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    member_id UUID NOT NULL REFERENCES route_members(id) ON DELETE CASCADE,
+    body TEXT NOT NULL CHECK (char_length(body) > 0),
+    posted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX chat_messages_route_posted_at_idx
+    ON chat_messages (route_id, posted_at, id);
+
+CREATE INDEX chat_messages_member_posted_at_idx
+    ON chat_messages (member_id, posted_at);
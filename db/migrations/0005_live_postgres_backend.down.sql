@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS live_broker_messages;
+DROP TABLE IF EXISTS live_connections;
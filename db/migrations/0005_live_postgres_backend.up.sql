@@ -0,0 +1,16 @@
+CREATE UNLOGGED TABLE live_connections (
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    member_id UUID NOT NULL REFERENCES route_members(id) ON DELETE CASCADE,
+    connection_id TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (route_id, member_id)
+);
+
+CREATE UNLOGGED TABLE live_broker_messages (
+    id BIGSERIAL PRIMARY KEY,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX live_broker_messages_created_at_idx
+    ON live_broker_messages (created_at);
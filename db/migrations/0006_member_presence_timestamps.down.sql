@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS position_points_member_recorded_at_idx;
+DROP INDEX IF EXISTS route_members_presence_sweep_idx;
+
+ALTER TABLE route_members
+    DROP COLUMN IF EXISTS last_seen_at,
+    DROP COLUMN IF EXISTS status_changed_at;
@@ -0,0 +1,10 @@
+ALTER TABLE route_members
+    ADD COLUMN status_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX route_members_presence_sweep_idx
+    ON route_members (status, status_changed_at)
+    WHERE status IN ('tracking', 'stale', 'spectating');
+
+CREATE INDEX position_points_member_recorded_at_idx
+    ON position_points (route_id, member_id, recorded_at DESC);
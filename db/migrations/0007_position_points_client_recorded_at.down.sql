@@ -0,0 +1,8 @@
+ALTER TABLE position_points
+    DROP CONSTRAINT IF EXISTS position_points_segment_seq_unique;
+
+CREATE UNIQUE INDEX IF NOT EXISTS position_points_segment_seq_unique_idx
+    ON position_points (segment_id, seq);
+
+DROP INDEX IF EXISTS position_points_route_replay_idx;
+DROP INDEX IF EXISTS position_points_member_client_recorded_at_idx;
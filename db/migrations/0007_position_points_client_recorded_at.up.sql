@@ -0,0 +1,14 @@
+CREATE INDEX position_points_member_client_recorded_at_idx
+    ON position_points (member_id, client_recorded_at)
+    WHERE client_recorded_at IS NOT NULL;
+
+CREATE INDEX position_points_route_replay_idx
+    ON position_points (route_id, (COALESCE(client_recorded_at, recorded_at)), segment_id, seq);
+
+-- Deferrable so a batch backfill can renumber a segment's points into client time order in one
+-- statement; uniqueness is still checked at the end of every statement.
+DROP INDEX position_points_segment_seq_unique_idx;
+
+ALTER TABLE position_points
+    ADD CONSTRAINT position_points_segment_seq_unique
+    UNIQUE (segment_id, seq) DEFERRABLE INITIALLY IMMEDIATE;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS path_segment_snapped_geometries;
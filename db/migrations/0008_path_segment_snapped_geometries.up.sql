@@ -0,0 +1,14 @@
+CREATE TABLE path_segment_snapped_geometries (
+    segment_id UUID PRIMARY KEY REFERENCES path_segments(id) ON DELETE CASCADE,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    matcher TEXT NOT NULL,
+    source_seq BIGINT NOT NULL,
+    geometry geography(MULTILINESTRING, 4326),
+    confidence DOUBLE PRECISION,
+    matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    retry_after TIMESTAMPTZ
+);
+
+CREATE INDEX path_segment_snapped_geometries_route_id_idx
+    ON path_segment_snapped_geometries (route_id);
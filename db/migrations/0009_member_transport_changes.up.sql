@@ -0,0 +1,13 @@
+CREATE TABLE member_transport_changes (
+    id BIGSERIAL PRIMARY KEY,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    member_id UUID NOT NULL REFERENCES route_members(id) ON DELETE CASCADE,
+    from_transport_mode TEXT NOT NULL,
+    to_transport_mode TEXT NOT NULL CHECK (
+        to_transport_mode IN ('walking', 'bicycle', 'car', 'bus', 'train', 'boat', 'airplane')
+    ),
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX member_transport_changes_route_changed_at_idx
+    ON member_transport_changes (route_id, changed_at, id);
@@ -0,0 +1,15 @@
+UPDATE path_segments
+SET end_reason = 'stopped'
+WHERE end_reason = 'permission_revoked';
+
+ALTER TABLE path_segments
+    DROP CONSTRAINT IF EXISTS path_segments_end_reason_check;
+
+ALTER TABLE path_segments
+    ADD CONSTRAINT path_segments_end_reason_check
+    CHECK (end_reason IN ('stopped', 'disconnected', 'left', 'route_closed'));
+
+DROP TABLE IF EXISTS member_tracking_grants;
+
+ALTER TABLE route_members
+    DROP COLUMN IF EXISTS can_track;
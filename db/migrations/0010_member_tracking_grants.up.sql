@@ -0,0 +1,21 @@
+ALTER TABLE route_members
+    ADD COLUMN can_track BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE member_tracking_grants (
+    id BIGSERIAL PRIMARY KEY,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    member_id UUID NOT NULL REFERENCES route_members(id) ON DELETE CASCADE,
+    granted BOOLEAN NOT NULL,
+    changed_by UUID REFERENCES route_members(id) ON DELETE SET NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX member_tracking_grants_route_changed_at_idx
+    ON member_tracking_grants (route_id, changed_at, id);
+
+ALTER TABLE path_segments
+    DROP CONSTRAINT IF EXISTS path_segments_end_reason_check;
+
+ALTER TABLE path_segments
+    ADD CONSTRAINT path_segments_end_reason_check
+    CHECK (end_reason IN ('stopped', 'disconnected', 'left', 'route_closed', 'permission_revoked'));
@@ -0,0 +1,29 @@
+DROP TABLE IF EXISTS route_member_bans;
+
+UPDATE path_segments
+SET end_reason = 'left'
+WHERE end_reason = 'removed';
+
+ALTER TABLE path_segments
+    DROP CONSTRAINT IF EXISTS path_segments_end_reason_check;
+
+ALTER TABLE path_segments
+    ADD CONSTRAINT path_segments_end_reason_check
+    CHECK (end_reason IN ('stopped', 'disconnected', 'left', 'route_closed', 'permission_revoked'));
+
+UPDATE route_members
+SET status = 'left'
+WHERE status = 'removed';
+
+DROP INDEX IF EXISTS route_members_route_alias_unique_idx;
+
+CREATE UNIQUE INDEX route_members_route_alias_unique_idx
+    ON route_members (route_id, LOWER(display_name))
+    WHERE status <> 'left';
+
+ALTER TABLE route_members
+    DROP CONSTRAINT IF EXISTS route_members_status_check;
+
+ALTER TABLE route_members
+    ADD CONSTRAINT route_members_status_check
+    CHECK (status IN ('tracking', 'spectating', 'stale', 'offline', 'left'));
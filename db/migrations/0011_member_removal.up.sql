@@ -0,0 +1,28 @@
+ALTER TABLE route_members
+    DROP CONSTRAINT IF EXISTS route_members_status_check;
+
+ALTER TABLE route_members
+    ADD CONSTRAINT route_members_status_check
+    CHECK (status IN ('tracking', 'spectating', 'stale', 'offline', 'left', 'removed'));
+
+DROP INDEX IF EXISTS route_members_route_alias_unique_idx;
+
+CREATE UNIQUE INDEX route_members_route_alias_unique_idx
+    ON route_members (route_id, LOWER(display_name))
+    WHERE status NOT IN ('left', 'removed');
+
+ALTER TABLE path_segments
+    DROP CONSTRAINT IF EXISTS path_segments_end_reason_check;
+
+ALTER TABLE path_segments
+    ADD CONSTRAINT path_segments_end_reason_check
+    CHECK (end_reason IN ('stopped', 'disconnected', 'left', 'route_closed', 'permission_revoked', 'removed'));
+
+CREATE TABLE route_member_bans (
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    client_id TEXT NOT NULL,
+    member_id UUID REFERENCES route_members(id) ON DELETE SET NULL,
+    banned_by UUID REFERENCES route_members(id) ON DELETE SET NULL,
+    banned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (route_id, client_id)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE route_members
+    DROP CONSTRAINT IF EXISTS route_members_single_role_check;
+
+ALTER TABLE route_members
+    DROP COLUMN IF EXISTS is_moderator;
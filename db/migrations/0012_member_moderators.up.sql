@@ -0,0 +1,6 @@
+ALTER TABLE route_members
+    ADD COLUMN is_moderator BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE route_members
+    ADD CONSTRAINT route_members_single_role_check
+    CHECK (NOT (is_owner AND is_moderator));
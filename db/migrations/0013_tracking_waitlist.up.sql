@@ -0,0 +1,15 @@
+CREATE TABLE tracking_waitlist (
+    id BIGSERIAL PRIMARY KEY,
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    member_id UUID NOT NULL REFERENCES route_members(id) ON DELETE CASCADE,
+    queued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reserved_until TIMESTAMPTZ,
+    UNIQUE (route_id, member_id)
+);
+
+CREATE INDEX tracking_waitlist_route_order_idx
+    ON tracking_waitlist (route_id, id);
+
+CREATE INDEX tracking_waitlist_reserved_until_idx
+    ON tracking_waitlist (reserved_until)
+    WHERE reserved_until IS NOT NULL;